@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/shizhMSFT/wink-code/internal/checkpoint"
+	"github.com/shizhMSFT/wink-code/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoToFlag      int
+	undoForceFlag   bool
+	undoSessionFlag string
+
+	checkpointsSessionFlag string
+)
+
+// newUndoCmd creates the "undo" command
+func newUndoCmd() *cobra.Command {
+	undoCmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Undo file changes made by the agent",
+		Long: `Restore files changed by the agent from the checkpoints in ~/.wink/checkpoints.
+
+Without flags the last turn (prompt) of the latest session in this directory is
+undone. --to <turn> undoes every turn after <turn>; --to 0 undoes the whole session.
+Files edited after the agent changed them are reported as conflicts and left alone
+unless --force is given.`,
+		Args: cobra.NoArgs,
+		RunE: runUndo,
+	}
+
+	undoCmd.Flags().IntVar(&undoToFlag, "to", 0, "Restore files to how they were after this turn")
+	undoCmd.Flags().BoolVar(&undoForceFlag, "force", false, "Restore files even if they changed after the agent modified them")
+	undoCmd.Flags().StringVar(&undoSessionFlag, "session", "", "Session ID or prefix (default: latest session in this directory)")
+
+	return undoCmd
+}
+
+// newCheckpointsCmd creates the "checkpoints" command and its subcommands
+func newCheckpointsCmd() *cobra.Command {
+	checkpointsCmd := &cobra.Command{
+		Use:   "checkpoints",
+		Short: "Inspect file checkpoints recorded by the agent",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the turns and files that can be undone",
+		Args:  cobra.NoArgs,
+		RunE:  runCheckpointsList,
+	}
+	listCmd.Flags().StringVar(&checkpointsSessionFlag, "session", "", "Session ID or prefix (default: latest session in this directory)")
+	checkpointsCmd.AddCommand(listCmd)
+
+	return checkpointsCmd
+}
+
+// openCheckpoints returns the checkpoint store for a session, or the latest one for
+// the current directory
+func openCheckpoints(sessionID string) (*checkpoint.Store, error) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	if sessionID == "" {
+		return checkpoint.Latest(workingDir)
+	}
+	return checkpoint.Find(sessionID, workingDir)
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	store, err := openCheckpoints(undoSessionFlag)
+	if err != nil {
+		return err
+	}
+
+	last := store.LastTurn()
+	if last == 0 {
+		return fmt.Errorf("nothing to undo: every change in session %s has already been undone", shortID(store.SessionID()))
+	}
+
+	toTurn := last - 1
+	if cmd.Flags().Changed("to") {
+		if undoToFlag < 0 || undoToFlag >= last {
+			return fmt.Errorf("--to must be between 0 and %d (the last turn with changes), got %d", last-1, undoToFlag)
+		}
+		toTurn = undoToFlag
+	}
+
+	result, err := store.Undo(toTurn, undoForceFlag)
+	if err != nil {
+		return err
+	}
+
+	turns := make([]string, len(result.Turns))
+	for i, turn := range result.Turns {
+		turns[i] = fmt.Sprintf("%d", turn)
+	}
+	ui.PrintSuccess(fmt.Sprintf("Undid turn(s) %s of session %s", strings.Join(turns, ", "), shortID(store.SessionID())))
+	for _, path := range result.Restored {
+		ui.PrintInfo("  restored " + path)
+	}
+	for _, path := range result.Removed {
+		ui.PrintInfo("  removed  " + path)
+	}
+	for _, c := range result.Forced {
+		ui.PrintWarning(fmt.Sprintf("Overwrote %s (%s)", c.Path, c.Reason))
+	}
+	return nil
+}
+
+func runCheckpointsList(cmd *cobra.Command, args []string) error {
+	store, err := openCheckpoints(checkpointsSessionFlag)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "Session %s (%s)\n", shortID(store.SessionID()), store.WorkingDir())
+
+	changes := store.Changes()
+	for _, turn := range store.Turns() {
+		var lines []string
+		undone := true
+		for _, c := range changes {
+			if c.Turn != turn.Number {
+				continue
+			}
+			undone = undone && c.Undone
+			lines = append(lines, fmt.Sprintf("    %s %s (%s)", changeKind(c), c.Path, c.Tool))
+		}
+
+		status := ""
+		if undone {
+			status = " [undone]"
+		}
+		fmt.Fprintf(os.Stdout, "\nTurn %d  %s%s\n  %q\n", turn.Number, turn.StartedAt.Format("2006-01-02 15:04"), status, truncateRunes(turn.Prompt, 70))
+		for _, line := range lines {
+			fmt.Fprintln(os.Stdout, line)
+		}
+	}
+
+	ui.PrintInfo("\nUndo the last turn with 'wink undo', or go back to a turn with 'wink undo --to <turn>'.")
+	return nil
+}
+
+// changeKind labels a change as added, modified or deleted
+func changeKind(c checkpoint.Change) string {
+	switch {
+	case !c.Before.Exists:
+		return "A"
+	case !c.After.Exists:
+		return "D"
+	default:
+		return "M"
+	}
+}
+
+// shortID returns the short form of a session ID shown to users
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with an ellipsis
+func truncateRunes(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/index"
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// newIndexCmd creates the "index" command and its subcommands
+func newIndexCmd() *cobra.Command {
+	indexCmd := &cobra.Command{
+		Use:   "index",
+		Short: "Manage the trigram index that speeds up grep_search",
+		Long: `Manage the trigram index of this directory in .wink/index.
+
+grep_search uses the index to read only the files that can match a pattern, and
+updates it from file sizes and modification times before every search, so results
+are the same with and without it. Files ignored by .gitignore or .winkignore are not
+indexed. Set "search_index": true in the config to build it on first use.`,
+	}
+
+	indexCmd.AddCommand(&cobra.Command{
+		Use:   "build",
+		Short: "Create the index or update it with the files that changed",
+		Args:  cobra.NoArgs,
+		RunE:  runIndexBuild,
+	})
+	indexCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show the size of the index and how many files changed since it was updated",
+		Args:  cobra.NoArgs,
+		RunE:  runIndexStatus,
+	})
+	indexCmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "Delete the index and the embeddings stored by semantic_search",
+		Args:  cobra.NoArgs,
+		RunE:  runIndexClear,
+	})
+
+	return indexCmd
+}
+
+func runIndexBuild(cmd *cobra.Command, args []string) error {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	progress := ui.NewProgressIndicator("Indexing " + workingDir)
+	progress.Start()
+	stats, err := tools.BuildSearchIndex(ctx, workingDir)
+	progress.Stop()
+	if err != nil {
+		return fmt.Errorf("failed to build search index: %w", err)
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Indexed %d files in %s (%d read, %d removed)",
+		stats.Files, stats.Elapsed.Round(time.Millisecond), stats.Added, stats.Removed))
+	return nil
+}
+
+func runIndexStatus(cmd *cobra.Command, args []string) error {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	status, err := tools.SearchIndexStatus(cmd.Context(), workingDir)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Index:\t%s\n", status.Dir)
+	fmt.Fprintf(w, "Updated:\t%s\n", status.UpdatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "Files:\t%d (%d binary, %d too large to index)\n", status.Files, status.Binary, status.TooLarge)
+	fmt.Fprintf(w, "Trigrams:\t%d\n", status.Trigrams)
+	fmt.Fprintf(w, "Size:\t%s\n", ui.FormatBytes(status.DiskBytes))
+	fmt.Fprintf(w, "Changed since update:\t%d changed or new, %d removed\n", status.Changed, status.Removed)
+	w.Flush()
+
+	if status.Changed > 0 || status.Removed > 0 {
+		ui.PrintInfo("\nThe next search updates the index; 'wink index build' does it now.")
+	}
+	return nil
+}
+
+func runIndexClear(cmd *cobra.Command, args []string) error {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	if err := index.Clear(workingDir); err != nil {
+		return err
+	}
+	ui.PrintSuccess("Removed " + index.Dir(workingDir))
+	return nil
+}
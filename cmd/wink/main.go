@@ -4,10 +4,14 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/shizhMSFT/wink-code/internal/agent"
+	"github.com/shizhMSFT/wink-code/internal/config"
+	"github.com/shizhMSFT/wink-code/internal/llm"
 	"github.com/shizhMSFT/wink-code/internal/logging"
 	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/pkg/types"
 	"github.com/spf13/cobra"
 )
 
@@ -21,9 +25,19 @@ var (
 var (
 	promptFlag   string
 	modelFlag    string
+	profileFlag  string
 	continueFlag bool
 	debugFlag    bool
 	timeoutFlag  int
+
+	loadTimeoutFlag int
+	keepAliveFlag   string
+
+	thinkFlag        bool
+	noThinkFlag      bool
+	hideThinkingFlag bool
+	keepThinkingFlag bool
+	imageFlags       []string
 )
 
 func main() {
@@ -43,13 +57,30 @@ integration with a safe approval workflow.`,
 	// Flags
 	rootCmd.Flags().StringVarP(&promptFlag, "prompt", "p", "", "Natural language prompt (required)")
 	rootCmd.Flags().StringVarP(&modelFlag, "model", "m", "qwen3:8b", "LLM model to use")
+	rootCmd.Flags().StringVar(&profileFlag, "profile", "", "Named model profile from config (sets model and generation options)")
 	rootCmd.Flags().BoolVar(&continueFlag, "continue", false, "Continue previous session")
 	rootCmd.Flags().BoolVarP(&debugFlag, "debug", "d", false, "Enable verbose debug logging")
-	rootCmd.Flags().IntVar(&timeoutFlag, "timeout", 30, "LLM API timeout in seconds (default: 30s, min: 5s)")
+	rootCmd.Flags().IntVar(&timeoutFlag, "timeout", 30, "Seconds to wait for each LLM response, excluding model loading (default: 30s, min: 5s)")
+	rootCmd.Flags().IntVar(&loadTimeoutFlag, "load-timeout", 0, "Seconds to wait for the model to load into memory (default: load_timeout_seconds from config, 300s)")
+	rootCmd.Flags().StringVar(&keepAliveFlag, "keep-alive", "", "How long Ollama keeps the model loaded after a request, e.g. 10m, or -1 for indefinitely (default: keep_alive from config)")
+
+	rootCmd.Flags().BoolVar(&thinkFlag, "think", false, "Enable reasoning for models that support it")
+	rootCmd.Flags().BoolVar(&noThinkFlag, "no-think", false, "Disable reasoning for models that support it")
+	rootCmd.Flags().BoolVar(&hideThinkingFlag, "hide-thinking", false, "Don't display the model's reasoning")
+	rootCmd.Flags().BoolVar(&keepThinkingFlag, "keep-thinking", false, "Send earlier reasoning back to the model in later requests")
+	rootCmd.MarkFlagsMutuallyExclusive("think", "no-think")
+	rootCmd.Flags().StringSliceVar(&imageFlags, "image", nil, "PNG/JPEG image in the workspace to attach to the prompt (repeatable)")
 
 	// Mark prompt as required (unless --continue is used)
 	rootCmd.MarkFlagRequired("prompt")
 
+	// Subcommands
+	rootCmd.AddCommand(newModelsCmd())
+	rootCmd.AddCommand(newUsageCmd())
+	rootCmd.AddCommand(newUndoCmd())
+	rootCmd.AddCommand(newCheckpointsCmd())
+	rootCmd.AddCommand(newIndexCmd())
+
 	// Execute
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -83,16 +114,38 @@ func run(cmd *cobra.Command, args []string) error {
 	logging.Debug("Working directory", "path", workingDir)
 
 	// Get configuration (use defaults for now, TODO: load from config file)
-	ollamaURL := os.Getenv("WINK_OLLAMA_URL")
-	if ollamaURL == "" {
-		ollamaURL = "http://localhost:11434"
-	}
+	ollamaURL := resolveOllamaURL()
 
 	model := modelFlag
 	if envModel := os.Getenv("WINK_MODEL"); envModel != "" {
 		model = envModel
 	}
 
+	cfgManager, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := cfgManager.Get()
+
+	// Resolve model profile (an explicit --model still wins over the profile's model)
+	var options types.GenerationOptions
+	if profileFlag != "" {
+		profile, err := cfgManager.Profile(profileFlag)
+		if err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("model") {
+			model = profile.Model
+		}
+		options = profile.GenerationOptions
+	}
+
+	// --think/--no-think override the profile's setting
+	if thinkFlag || noThinkFlag {
+		think := thinkFlag
+		options.Think = &think
+	}
+
 	// Determine timeout with precedence: flag > env > default
 	timeoutSeconds := timeoutFlag
 	if envTimeout := os.Getenv("WINK_TIMEOUT"); envTimeout != "" && timeoutFlag == 30 {
@@ -112,7 +165,26 @@ func run(cmd *cobra.Command, args []string) error {
 		logging.Warn("Timeout is very high", "timeout", timeoutSeconds, "recommended_max", 300)
 	}
 
-	logging.Debug("Configuration", "model", model, "timeout", timeoutSeconds, "ollama_url", ollamaURL)
+	// Model loading is bounded separately from generation: flag > config > default
+	loadTimeoutSeconds := cfg.LoadTimeoutSeconds
+	if cmd.Flags().Changed("load-timeout") {
+		if loadTimeoutFlag < 5 {
+			return fmt.Errorf("load timeout must be at least 5 seconds, got %d", loadTimeoutFlag)
+		}
+		loadTimeoutSeconds = loadTimeoutFlag
+	}
+
+	keepAliveSetting := cfg.KeepAlive
+	if cmd.Flags().Changed("keep-alive") {
+		keepAliveSetting = keepAliveFlag
+	}
+	keepAlive, err := llm.ParseKeepAlive(keepAliveSetting)
+	if err != nil {
+		return err
+	}
+
+	logging.Debug("Configuration", "model", model, "profile", profileFlag, "timeout", timeoutSeconds,
+		"load_timeout", loadTimeoutSeconds, "keep_alive", keepAliveSetting, "ollama_url", ollamaURL)
 
 	// Create agent
 	agentInstance, err := agent.NewAgent(ollamaURL, model, timeoutSeconds)
@@ -120,8 +192,25 @@ func run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create agent: %w", err)
 	}
 
+	agentInstance.SetGenerationOptions(profileFlag, options)
+	agentInstance.SetModelLoading(time.Duration(loadTimeoutSeconds)*time.Second, keepAlive)
+	agentInstance.SetThinkingDisplay(hideThinkingFlag, keepThinkingFlag)
+
+	// Load attached images through the workspace path jail
+	if len(imageFlags) > 0 {
+		images := make([]types.ContentPart, 0, len(imageFlags))
+		for _, path := range imageFlags {
+			loaded, err := tools.LoadImage(workingDir, path, cfg.MaxImageDimension)
+			if err != nil {
+				return fmt.Errorf("failed to attach image: %w", err)
+			}
+			images = append(images, loaded.Part)
+		}
+		agentInstance.AttachImages(images)
+	}
+
 	// Register tools
-	if err := registerTools(agentInstance); err != nil {
+	if err := registerTools(agentInstance, cfg, ollamaURL); err != nil {
 		return fmt.Errorf("failed to register tools: %w", err)
 	}
 
@@ -134,10 +223,22 @@ func run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// resolveOllamaURL returns the Ollama base URL from WINK_OLLAMA_URL or the default
+func resolveOllamaURL() string {
+	if ollamaURL := os.Getenv("WINK_OLLAMA_URL"); ollamaURL != "" {
+		return ollamaURL
+	}
+	return "http://localhost:11434"
+}
+
 // registerTools registers all available tools with the agent
-func registerTools(a *agent.Agent) error {
+func registerTools(a *agent.Agent, cfg *types.Config, ollamaURL string) error {
+	// File-writing tools format and syntax-check what they write
+	postEdit := tools.NewPostEditPipeline(cfg.PostEdit)
+
 	// Register create_file tool
 	createFile := tools.NewCreateFileTool()
+	createFile.SetPostEditPipeline(postEdit)
 	if err := a.RegisterTool(createFile); err != nil {
 		return fmt.Errorf("failed to register create_file tool: %w", err)
 	}
@@ -150,16 +251,56 @@ func registerTools(a *agent.Agent) error {
 
 	// Register replace_string_in_file tool
 	replaceString := tools.NewReplaceStringInFileTool()
+	replaceString.SetPostEditPipeline(postEdit)
 	if err := a.RegisterTool(replaceString); err != nil {
 		return fmt.Errorf("failed to register replace_string_in_file tool: %w", err)
 	}
 
+	// Register multi_edit tool
+	multiEdit := tools.NewMultiEditTool()
+	multiEdit.SetPostEditPipeline(postEdit)
+	if err := a.RegisterTool(multiEdit); err != nil {
+		return fmt.Errorf("failed to register multi_edit tool: %w", err)
+	}
+
+	// Register apply_patch tool
+	applyPatch := tools.NewApplyPatchTool()
+	applyPatch.SetPostEditPipeline(postEdit)
+	if err := a.RegisterTool(applyPatch); err != nil {
+		return fmt.Errorf("failed to register apply_patch tool: %w", err)
+	}
+
+	// Register structured_edit tool
+	structuredEdit := tools.NewStructuredEditTool()
+	structuredEdit.SetPostEditPipeline(postEdit)
+	if err := a.RegisterTool(structuredEdit); err != nil {
+		return fmt.Errorf("failed to register structured_edit tool: %w", err)
+	}
+
 	// Register create_directory tool
 	createDir := tools.NewCreateDirectoryTool()
 	if err := a.RegisterTool(createDir); err != nil {
 		return fmt.Errorf("failed to register create_directory tool: %w", err)
 	}
 
+	// Register delete_path tool
+	deletePath := tools.NewDeletePathTool()
+	if err := a.RegisterTool(deletePath); err != nil {
+		return fmt.Errorf("failed to register delete_path tool: %w", err)
+	}
+
+	// Register move_path tool
+	movePath := tools.NewMovePathTool()
+	if err := a.RegisterTool(movePath); err != nil {
+		return fmt.Errorf("failed to register move_path tool: %w", err)
+	}
+
+	// Register copy_path tool
+	copyPath := tools.NewCopyPathTool()
+	if err := a.RegisterTool(copyPath); err != nil {
+		return fmt.Errorf("failed to register copy_path tool: %w", err)
+	}
+
 	// Register list_dir tool
 	listDir := tools.NewListDirTool()
 	if err := a.RegisterTool(listDir); err != nil {
@@ -174,10 +315,30 @@ func registerTools(a *agent.Agent) error {
 
 	// Register grep_search tool
 	grepSearch := tools.NewGrepSearchTool()
+	grepSearch.SetAutoIndex(cfg.SearchIndex)
 	if err := a.RegisterTool(grepSearch); err != nil {
 		return fmt.Errorf("failed to register grep_search tool: %w", err)
 	}
 
+	// Register semantic_search tool
+	embedder := llm.NewOllamaEmbedder(llm.NewOllamaClient(ollamaURL), cfg.EmbeddingModel)
+	semanticSearch := tools.NewSemanticSearchTool(embedder)
+	if err := a.RegisterTool(semanticSearch); err != nil {
+		return fmt.Errorf("failed to register semantic_search tool: %w", err)
+	}
+
+	// Register code_outline tool
+	codeOutline := tools.NewCodeOutlineTool()
+	if err := a.RegisterTool(codeOutline); err != nil {
+		return fmt.Errorf("failed to register code_outline tool: %w", err)
+	}
+
+	// Register find_symbol tool
+	findSymbol := tools.NewFindSymbolTool()
+	if err := a.RegisterTool(findSymbol); err != nil {
+		return fmt.Errorf("failed to register find_symbol tool: %w", err)
+	}
+
 	// Register run_in_terminal tool
 	runInTerminal := tools.NewRunInTerminalTool()
 	if err := a.RegisterTool(runInTerminal); err != nil {
@@ -196,7 +357,13 @@ func registerTools(a *agent.Agent) error {
 		return fmt.Errorf("failed to register fetch_webpage tool: %w", err)
 	}
 
-	logging.Debug("Registered tools", "count", 10)
+	// Register view_image tool (removed at run time for models without vision)
+	viewImage := tools.NewViewImageTool(cfg.MaxImageDimension)
+	if err := a.RegisterTool(viewImage); err != nil {
+		return fmt.Errorf("failed to register view_image tool: %w", err)
+	}
+
+	logging.Debug("Registered tools", "count", 20)
 
 	return nil
 }
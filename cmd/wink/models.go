@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/llm"
+	"github.com/shizhMSFT/wink-code/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// modelsRequestTimeout bounds the list and show requests
+const modelsRequestTimeout = 10 * time.Second
+
+// newModelsCmd creates the "models" command and its subcommands
+func newModelsCmd() *cobra.Command {
+	modelsCmd := &cobra.Command{
+		Use:   "models",
+		Short: "Manage Ollama models",
+		Long: `List, pull and inspect models served by Ollama (WINK_OLLAMA_URL,
+default http://localhost:11434).`,
+	}
+
+	modelsCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List locally available models",
+		Args:  cobra.NoArgs,
+		RunE:  runModelsList,
+	})
+
+	modelsCmd.AddCommand(&cobra.Command{
+		Use:   "pull <name>",
+		Short: "Download a model",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runModelsPull,
+	})
+
+	modelsCmd.AddCommand(&cobra.Command{
+		Use:   "show <name>",
+		Short: "Show model details, context length and capabilities",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runModelsShow,
+	})
+
+	return modelsCmd
+}
+
+func runModelsList(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(cmd.Context(), modelsRequestTimeout)
+	defer cancel()
+
+	client := llm.NewOllamaClient(resolveOllamaURL())
+	models, err := client.ListModels(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list models: %w", err)
+	}
+
+	if len(models) == 0 {
+		ui.PrintInfo("No models installed. Pull one with: wink models pull qwen3:8b")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPARAMS\tQUANT\tSIZE\tMODIFIED")
+	for _, m := range models {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			m.Name,
+			m.Details.ParameterSize,
+			m.Details.QuantizationLevel,
+			ui.FormatBytes(m.Size),
+			m.ModifiedAt.Format("2006-01-02 15:04"),
+		)
+	}
+	return w.Flush()
+}
+
+func runModelsPull(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	client := llm.NewOllamaClient(resolveOllamaURL())
+
+	bar := ui.NewProgressBar()
+	err := client.PullModel(cmd.Context(), name, func(p llm.PullProgress) {
+		bar.Update(p.Status, p.Completed, p.Total)
+	})
+	bar.Finish()
+	if err != nil {
+		return fmt.Errorf("failed to pull model '%s': %w", name, err)
+	}
+
+	ui.PrintSuccess(fmt.Sprintf("Pulled model %s", name))
+	return nil
+}
+
+func runModelsShow(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), modelsRequestTimeout)
+	defer cancel()
+
+	client := llm.NewOllamaClient(resolveOllamaURL())
+	info, err := client.ShowModel(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to show model '%s': %w", name, err)
+	}
+
+	contextLength := "unknown"
+	if info.ContextLength > 0 {
+		contextLength = fmt.Sprintf("%d tokens", info.ContextLength)
+	}
+
+	toolSupport := "no"
+	if info.SupportsTools() {
+		toolSupport = "yes"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Model:\t%s\n", info.Name)
+	fmt.Fprintf(w, "Family:\t%s\n", info.Details.Family)
+	fmt.Fprintf(w, "Parameters:\t%s\n", info.Details.ParameterSize)
+	fmt.Fprintf(w, "Quantization:\t%s\n", info.Details.QuantizationLevel)
+	fmt.Fprintf(w, "Context length:\t%s\n", contextLength)
+	fmt.Fprintf(w, "Capabilities:\t%s\n", strings.Join(info.Capabilities, ", "))
+	fmt.Fprintf(w, "Tool calling:\t%s\n", toolSupport)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !info.SupportsTools() {
+		ui.PrintWarning("This model cannot call tools; wink will not be able to act on files or run commands with it.")
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/ui"
+	"github.com/shizhMSFT/wink-code/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageByFlag   string
+	usageDaysFlag int
+	usageJSONFlag bool
+)
+
+// newUsageCmd creates the "usage" command
+func newUsageCmd() *cobra.Command {
+	usageCmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Report token usage from the local ledger",
+		Long: `Aggregate the token usage recorded in ~/.wink/usage for every LLM call
+by day, model, project directory or session.`,
+		Args: cobra.NoArgs,
+		RunE: runUsage,
+	}
+
+	usageCmd.Flags().StringVar(&usageByFlag, "by", "day", "Group by: day, model, project or session")
+	usageCmd.Flags().IntVar(&usageDaysFlag, "days", 30, "Only include the last N days (0 for all)")
+	usageCmd.Flags().BoolVar(&usageJSONFlag, "json", false, "Output as JSON")
+
+	return usageCmd
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	groupBy, err := usage.ParseGroupBy(usageByFlag)
+	if err != nil {
+		return err
+	}
+	if usageDaysFlag < 0 {
+		return fmt.Errorf("--days must not be negative, got %d", usageDaysFlag)
+	}
+
+	ledger, err := usage.NewLedger()
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+
+	var since time.Time
+	if usageDaysFlag > 0 {
+		since = time.Now().AddDate(0, 0, -usageDaysFlag)
+	}
+
+	records, err := ledger.Read(since)
+	if err != nil {
+		return fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+
+	summaries := usage.Aggregate(records, groupBy)
+	total := usage.Total(summaries)
+
+	if usageJSONFlag {
+		report := map[string]interface{}{
+			"group_by": groupBy,
+			"days":     usageDaysFlag,
+			"groups":   summaries,
+			"total":    total,
+		}
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal report: %w", err)
+		}
+		fmt.Fprintln(os.Stdout, string(data))
+		return nil
+	}
+
+	if len(summaries) == 0 {
+		ui.PrintInfo("No usage recorded yet.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\tCALLS\tPROMPT\tCOMPLETION\tTOTAL\tAVG LATENCY\n", groupByHeader(groupBy))
+	for _, s := range append(summaries, total) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%dms\n",
+			s.Key, s.Calls, s.PromptTokens, s.CompletionTokens, s.TotalTokens, s.AvgLatencyMs)
+	}
+	return w.Flush()
+}
+
+// groupByHeader returns the table header for the grouping column
+func groupByHeader(groupBy usage.GroupBy) string {
+	switch groupBy {
+	case usage.GroupByModel:
+		return "MODEL"
+	case usage.GroupByProject:
+		return "PROJECT"
+	case usage.GroupBySession:
+		return "SESSION"
+	default:
+		return "DAY"
+	}
+}
@@ -4,27 +4,52 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"runtime"
+	"strings"
 	"time"
 
+	"github.com/shizhMSFT/wink-code/internal/checkpoint"
 	"github.com/shizhMSFT/wink-code/internal/llm"
 	"github.com/shizhMSFT/wink-code/internal/logging"
 	"github.com/shizhMSFT/wink-code/internal/tools"
 	"github.com/shizhMSFT/wink-code/internal/ui"
+	"github.com/shizhMSFT/wink-code/internal/usage"
 	"github.com/shizhMSFT/wink-code/pkg/types"
 )
 
 // Agent orchestrates the interaction between user, LLM, and tools
 type Agent struct {
 	llmClient        *llm.Client
+	ollamaClient     *llm.OllamaClient
+	modelInfo        *llm.ModelInfo
 	toolRegistry     *tools.Registry
 	approvalWorkflow *tools.ApprovalWorkflow
 	sessionManager   *SessionManager
 	contextManager   *ContextManager
+	usageLedger      *usage.Ledger
 	baseURL          string
+	profile          string
+	hideThinking     bool
+	loadTimeout      time.Duration
+	keepAlive        interface{}
+	images           []types.ContentPart
+	checkpoints      *checkpoint.Store
+	turn             checkpoint.Turn
+	reads            *tools.ReadTracker
 }
 
+const (
+	// modelInfoTimeout bounds the capability and loaded-model checks performed before a run
+	modelInfoTimeout = 5 * time.Second
+	// DefaultLoadTimeout is how long a model may take to load into memory
+	DefaultLoadTimeout = 5 * time.Minute
+	// maxDerivedContext caps the context window taken from the model's maximum, whose
+	// memory cost can exceed what a local machine has; a profile's num_ctx can go higher
+	maxDerivedContext = 32768
+)
+
 // contains checks if a string contains a substring
 func contains(s, substr string) bool {
 	if len(substr) == 0 {
@@ -42,6 +67,7 @@ func contains(s, substr string) bool {
 func NewAgent(baseURL, model string, timeoutSeconds int) (*Agent, error) {
 	// Initialize components
 	llmClient := llm.NewClient(baseURL, model, timeoutSeconds)
+	ollamaClient := llm.NewOllamaClient(baseURL)
 
 	toolRegistry := tools.NewRegistry()
 
@@ -57,13 +83,22 @@ func NewAgent(baseURL, model string, timeoutSeconds int) (*Agent, error) {
 
 	contextManager := NewContextManager(100) // Max 100 messages
 
+	// Usage tracking is best effort and never blocks a run
+	usageLedger, err := usage.NewLedger()
+	if err != nil {
+		logging.Warn("Usage ledger unavailable", "error", err)
+	}
+
 	return &Agent{
 		llmClient:        llmClient,
+		ollamaClient:     ollamaClient,
 		toolRegistry:     toolRegistry,
 		approvalWorkflow: approvalWorkflow,
 		sessionManager:   sessionManager,
 		contextManager:   contextManager,
+		usageLedger:      usageLedger,
 		baseURL:          baseURL,
+		loadTimeout:      DefaultLoadTimeout,
 	}, nil
 }
 
@@ -72,8 +107,57 @@ func (a *Agent) RegisterTool(tool types.Tool) error {
 	return a.toolRegistry.Register(tool)
 }
 
+// SetGenerationOptions applies generation options to all requests. profile names the
+// model profile they came from ("" if none) and is recorded in the session.
+func (a *Agent) SetGenerationOptions(profile string, options types.GenerationOptions) {
+	a.profile = profile
+	a.llmClient.SetGenerationOptions(options)
+	if options.NumCtx != nil {
+		a.llmClient.SetContextLimit(*options.NumCtx)
+	}
+	logging.Debug("Using generation options", "profile", profile, "options", options)
+}
+
+// SetModelLoading sets how long the model may take to load before the first request
+// (DefaultLoadTimeout if <= 0) and how long Ollama keeps it loaded afterwards
+// (a value returned by llm.ParseKeepAlive, nil for the server default)
+func (a *Agent) SetModelLoading(loadTimeout time.Duration, keepAlive interface{}) {
+	if loadTimeout <= 0 {
+		loadTimeout = DefaultLoadTimeout
+	}
+	a.loadTimeout = loadTimeout
+	a.keepAlive = keepAlive
+	a.llmClient.SetKeepAlive(keepAlive)
+}
+
+// SetThinkingDisplay controls whether reasoning is printed and whether earlier reasoning
+// is sent back to the model in later requests
+func (a *Agent) SetThinkingDisplay(hide, keepInContext bool) {
+	a.hideThinking = hide
+	a.llmClient.SetIncludeReasoning(keepInContext)
+}
+
+// SetApprovalPrompter replaces the interactive approval prompt used for tool calls
+func (a *Agent) SetApprovalPrompter(prompt tools.ApprovalPrompter) {
+	a.approvalWorkflow.SetPrompter(prompt)
+}
+
+// AttachImages attaches images to the next prompt
+func (a *Agent) AttachImages(images []types.ContentPart) {
+	a.images = images
+}
+
 // Run executes the agent with a user prompt
 func (a *Agent) Run(ctx context.Context, prompt string, workingDir string, continueSession bool) error {
+	// Warn early if the model can't call tools or see images
+	a.checkModelCapabilities(ctx)
+	a.applyVisionSupport()
+
+	// Load the model up front so load time doesn't count against the generation timeout
+	if err := a.warmUpModel(ctx); err != nil {
+		return err
+	}
+
 	// Load or create session
 	var session *types.Session
 	var err error
@@ -93,14 +177,31 @@ func (a *Agent) Run(ctx context.Context, prompt string, workingDir string, conti
 		logging.Info("Created new session", "session_id", session.ID)
 	}
 
+	// Record the profile and effective generation options for reproducibility
+	options := a.llmClient.GenerationOptions()
+	session.Profile = a.profile
+	session.GenerationOptions = &options
+
 	// Add user message
 	userMessage := types.Message{
 		Role:      types.MessageRoleUser,
 		Content:   prompt,
+		Parts:     a.images,
 		Timestamp: time.Now(),
 	}
 	a.contextManager.AddMessage(session, userMessage)
 
+	// Snapshot files before tools change them so the turn can be undone
+	session.Turns = nextTurn(session)
+	a.turn = checkpoint.Turn{Number: session.Turns, Prompt: prompt, StartedAt: time.Now()}
+	a.checkpoints, err = checkpoint.Open(session.ID, session.WorkingDir)
+	if err != nil {
+		logging.Warn("Checkpoints unavailable; changes in this run can't be undone", "error", err)
+	}
+
+	// Remember what the agent has seen of each file so stale edits can be refused
+	a.reads = tools.NewReadTracker(session)
+
 	// Agent loop
 	maxIterations := 10 // Prevent infinite loops
 	for iteration := 0; iteration < maxIterations; iteration++ {
@@ -109,24 +210,37 @@ func (a *Agent) Run(ctx context.Context, prompt string, workingDir string, conti
 		// Get available tools
 		availableTools := a.toolRegistry.GetAll()
 
+		// Make sure the request fits the model's context window
+		if err := a.fitContext(session, availableTools); err != nil {
+			return err
+		}
+
 		// Call LLM
+		callStart := time.Now()
 		response, err := a.llmClient.ChatCompletion(ctx, session.Messages, availableTools)
 		if err != nil {
 			// User-friendly error messages for common issues
+			var overflow *llm.ContextOverflowError
+			if errors.As(err, &overflow) {
+				return fmt.Errorf("%w. Use a profile with a larger num_ctx (see --profile)", err)
+			}
 			if contains(err.Error(), "connection refused") || contains(err.Error(), "no such host") {
 				return fmt.Errorf("unable to connect to LLM server at %s. Please ensure Ollama is running with 'ollama serve'",
 					a.baseURL)
 			}
 			if contains(err.Error(), "model") && contains(err.Error(), "not found") {
-				return fmt.Errorf("model '%s' not found. Try pulling it with: ollama pull %s",
+				return fmt.Errorf("model '%s' not found. Try pulling it with: wink models pull %s",
 					a.Model(), a.Model())
 			}
 			if contains(err.Error(), "timeout") || contains(err.Error(), "deadline exceeded") {
-				return fmt.Errorf("LLM request timed out after 30 seconds. The server may be overloaded or the request too complex")
+				return fmt.Errorf("LLM request timed out after %s waiting for a response (model loading is not included). "+
+					"The server may be overloaded or the request too complex; increase --timeout to wait longer", a.llmClient.Timeout())
 			}
 			return fmt.Errorf("LLM request failed: %w\n\nTry:\n  - Ensure Ollama is running: ollama serve\n  - Check model is available: ollama list\n  - Use --debug flag for detailed logs", err)
 		}
 
+		a.recordUsage(session, response.Usage.PromptTokens, response.Usage.CompletionTokens, time.Since(callStart))
+
 		// Check if we have a response
 		if len(response.Choices) == 0 {
 			return fmt.Errorf("no response from LLM")
@@ -134,13 +248,27 @@ func (a *Agent) Run(ctx context.Context, prompt string, workingDir string, conti
 
 		choice := response.Choices[0]
 
+		// Keep reasoning out of the answer and the future context
+		content, reasoning := llm.SplitReasoning(choice.Message.Content)
+		if choice.Message.ReasoningContent != "" {
+			reasoning = strings.TrimSpace(choice.Message.ReasoningContent + "\n\n" + reasoning)
+		}
+
 		// Add assistant message
 		assistantMessage := types.Message{
 			Role:      types.MessageRoleAssistant,
-			Content:   choice.Message.Content,
+			Content:   content,
 			Timestamp: time.Now(),
 			ToolCalls: []types.ToolCall{},
 		}
+		if reasoning != "" {
+			assistantMessage.Metadata = map[string]interface{}{
+				"reasoning": reasoning,
+			}
+			if !a.hideThinking {
+				ui.PrintThinking(reasoning)
+			}
+		}
 
 		// Check for tool calls
 		if len(choice.Message.ToolCalls) > 0 {
@@ -168,13 +296,14 @@ func (a *Agent) Run(ctx context.Context, prompt string, workingDir string, conti
 		// If no tool calls, we're done
 		if len(assistantMessage.ToolCalls) == 0 {
 			// Display final response
-			if choice.Message.Content != "" {
-				ui.PrintOutput(choice.Message.Content)
+			if content != "" {
+				ui.PrintOutput(content)
 			}
 			break
 		}
 
 		// Execute tool calls
+		var attachments []types.ContentPart
 		for _, toolCall := range assistantMessage.ToolCalls {
 			result, err := a.executeToolCall(ctx, session, toolCall)
 			if err != nil {
@@ -183,10 +312,12 @@ func (a *Agent) Run(ctx context.Context, prompt string, workingDir string, conti
 					"error", err,
 				)
 				// Add error result to context
-				result = &types.ToolResult{
-					ToolCallID: toolCall.ID,
-					Success:    false,
-					Error:      err.Error(),
+				if result == nil {
+					result = &types.ToolResult{
+						ToolCallID: toolCall.ID,
+						Success:    false,
+						Error:      err.Error(),
+					}
 				}
 			}
 
@@ -196,19 +327,37 @@ func (a *Agent) Run(ctx context.Context, prompt string, workingDir string, conti
 			// Add tool result message
 			toolResultMessage := types.Message{
 				Role:      types.MessageRoleTool,
-				Content:   result.Output,
+				Content:   toolResultContent(result),
 				Timestamp: time.Now(),
 				Metadata: map[string]interface{}{
 					"tool_call_id": result.ToolCallID,
 				},
 			}
 			a.contextManager.AddMessage(session, toolResultMessage)
+			attachments = append(attachments, result.Attachments...)
 
 			// Display result
 			formatter := ui.NewFormatter(types.OutputFormatHuman)
 			ui.PrintInfo(formatter.FormatToolResult(result))
 		}
 
+		// Tool messages can't carry images, so attach them in a follow-up user message
+		if len(attachments) > 0 {
+			sources := make([]string, 0, len(attachments))
+			for _, part := range attachments {
+				sources = append(sources, part.Source)
+			}
+			a.contextManager.AddMessage(session, types.Message{
+				Role:      types.MessageRoleUser,
+				Content:   fmt.Sprintf("Attached image(s) requested with view_image: %s", strings.Join(sources, ", ")),
+				Parts:     attachments,
+				Timestamp: time.Now(),
+				Metadata: map[string]interface{}{
+					"attachment": true,
+				},
+			})
+		}
+
 		// Save session after each iteration
 		if err := a.sessionManager.Save(session); err != nil {
 			logging.Warn("Failed to save session", "error", err)
@@ -243,6 +392,153 @@ func (a *Agent) Run(ctx context.Context, prompt string, workingDir string, conti
 	return nil
 }
 
+// checkModelCapabilities queries the model's capabilities and warns when tool calling is unsupported.
+// Failures are non-fatal: the chat request will surface connection or model errors.
+func (a *Agent) checkModelCapabilities(ctx context.Context) {
+	showCtx, cancel := context.WithTimeout(ctx, modelInfoTimeout)
+	defer cancel()
+
+	info, err := a.ollamaClient.ShowModel(showCtx, a.Model())
+	if err != nil {
+		logging.Debug("Failed to query model capabilities", "model", a.Model(), "error", err)
+		return
+	}
+	a.modelInfo = info
+
+	// Only pass the think toggle to models that can reason
+	options := a.llmClient.GenerationOptions()
+	if options.Think != nil && !info.HasCapability(llm.CapabilityThinking) {
+		logging.Warn("Model does not support thinking, ignoring think option", "model", a.Model())
+		options.Think = nil
+		a.llmClient.SetGenerationOptions(options)
+	}
+
+	// A profile's num_ctx takes precedence over the model's context length. Otherwise
+	// num_ctx is sent explicitly, so Ollama runs with the window the overflow check
+	// measures against instead of its smaller default.
+	if a.llmClient.ContextLimit() == 0 && info.ContextLength > 0 {
+		numCtx := min(info.ContextLength, maxDerivedContext)
+		options.NumCtx = &numCtx
+		a.llmClient.SetGenerationOptions(options)
+		a.llmClient.SetContextLimit(numCtx)
+	}
+
+	if !info.SupportsTools() {
+		logging.Warn("Model does not support tool calling", "model", a.Model())
+		ui.PrintWarning(fmt.Sprintf("Model '%s' does not support tool calling. It will not be able to read, write or search files.\n"+
+			"  Try a tool-capable model (e.g. qwen3:8b) or check with: wink models show %s", a.Model(), a.Model()))
+	}
+}
+
+// warmUpModel loads the model into memory unless it is already loaded, bounded by the
+// load timeout rather than the generation timeout
+func (a *Agent) warmUpModel(ctx context.Context) error {
+	checkCtx, cancel := context.WithTimeout(ctx, modelInfoTimeout)
+	loaded, err := a.ollamaClient.IsLoaded(checkCtx, a.Model())
+	cancel()
+	if err != nil {
+		// Without Ollama's native API the first request loads the model instead
+		logging.Debug("Failed to query loaded models, skipping warm-up", "model", a.Model(), "error", err)
+		return nil
+	}
+	if loaded {
+		logging.Debug("Model already loaded", "model", a.Model())
+		return nil
+	}
+
+	progress := ui.NewProgressIndicator(fmt.Sprintf("Loading model %s", a.Model()))
+	progress.Start()
+	numCtx := 0
+	if options := a.llmClient.GenerationOptions(); options.NumCtx != nil {
+		numCtx = *options.NumCtx
+	}
+	err = a.ollamaClient.LoadModel(ctx, a.Model(), a.loadTimeout, a.keepAlive, numCtx)
+	progress.Stop()
+	if err == nil {
+		return nil
+	}
+
+	var loadErr *llm.ModelLoadError
+	if errors.As(err, &loadErr) && loadErr.TimedOut() {
+		return fmt.Errorf("%w. Large models can take several minutes to load; increase --load-timeout to wait longer", err)
+	}
+
+	// Other failures (e.g. a missing model) are reported by the first request
+	logging.Warn("Model warm-up failed", "model", a.Model(), "error", err)
+	return nil
+}
+
+// applyVisionSupport disables image input when the model is known to lack vision
+func (a *Agent) applyVisionSupport() {
+	if a.modelInfo == nil || a.modelInfo.HasCapability(llm.CapabilityVision) {
+		return
+	}
+
+	if a.toolRegistry.Unregister("view_image") {
+		logging.Debug("Disabled view_image tool, model lacks vision", "model", a.Model())
+	}
+	if len(a.images) > 0 {
+		ui.PrintWarning(fmt.Sprintf("Model '%s' does not support images; ignoring %d attached image(s)", a.Model(), len(a.images)))
+		a.images = nil
+	}
+}
+
+// fitContext compacts the conversation when the next request would exceed the prompt budget
+func (a *Agent) fitContext(session *types.Session, availableTools []types.Tool) error {
+	budget := a.llmClient.PromptBudget()
+	if budget <= 0 {
+		return nil
+	}
+
+	fits := func(messages []types.Message) bool {
+		return a.llmClient.EstimateRequestTokens(messages, availableTools) <= budget
+	}
+
+	dropped, ok := a.contextManager.Compact(session, fits)
+	if dropped > 0 {
+		logging.Info("Compacted conversation context", "dropped_messages", dropped, "budget_tokens", budget)
+		ui.PrintInfo(fmt.Sprintf("Context nearly full: dropped %d older message(s) from the conversation", dropped))
+	}
+	if !ok {
+		estimated := a.llmClient.EstimateRequestTokens(session.Messages, availableTools)
+		return fmt.Errorf("request needs ~%d tokens but only %d are available in the model's %d-token context, even after dropping older history.\n\n"+
+			"Try:\n  - Shorten the prompt or ask for smaller file ranges\n  - Use a profile with a larger num_ctx (see --profile)",
+			estimated, budget, a.llmClient.ContextLimit())
+	}
+	return nil
+}
+
+// recordUsage appends an LLM call to the usage ledger
+func (a *Agent) recordUsage(session *types.Session, promptTokens, completionTokens int, latency time.Duration) {
+	if a.usageLedger == nil {
+		return
+	}
+
+	record := usage.Record{
+		Timestamp:        time.Now(),
+		SessionID:        session.ID,
+		Model:            a.Model(),
+		ProjectDir:       session.WorkingDir,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		LatencyMs:        latency.Milliseconds(),
+	}
+	if err := a.usageLedger.Append(record); err != nil {
+		logging.Warn("Failed to record usage", "error", err)
+	}
+}
+
+// toolResultContent is the text the model sees for a tool result; failures include the error
+func toolResultContent(result *types.ToolResult) string {
+	if result.Success || result.Error == "" {
+		return result.Output
+	}
+	if result.Output == "" {
+		return "Error: " + result.Error
+	}
+	return "Error: " + result.Error + "\n" + result.Output
+}
+
 // executeToolCall executes a single tool call with approval
 func (a *Agent) executeToolCall(ctx context.Context, session *types.Session, toolCall types.ToolCall) (*types.ToolResult, error) {
 	logging.Debug("Executing tool call",
@@ -256,8 +552,25 @@ func (a *Agent) executeToolCall(ctx context.Context, session *types.Session, too
 		return nil, fmt.Errorf("failed to get tool: %w", err)
 	}
 
+	// Refuse edits based on an outdated read before asking for approval
+	if err := a.checkStaleReads(tool, toolCall, session.WorkingDir); err != nil {
+		result := &types.ToolResult{
+			ToolCallID: toolCall.ID,
+			Success:    false,
+			Error:      err.Error(),
+		}
+		var staleErr *tools.StaleReadError
+		if errors.As(err, &staleErr) && staleErr.Diff != "" {
+			result.Output = "Changes made on disk since the last read:\n" + staleErr.Diff
+		}
+		return result, err
+	}
+
+	// Compute the change up front so it can be reviewed before approval
+	preview := previewToolCall(tool, toolCall.Parameters, session.WorkingDir)
+
 	// Check approval
-	approved, autoApproved, ruleDescription, err := a.approvalWorkflow.CheckApproval(toolCall.ToolName, toolCall.Parameters, tool)
+	approved, autoApproved, ruleDescription, err := a.approvalWorkflow.CheckApproval(toolCall.ToolName, toolCall.Parameters, tool, preview)
 	if err != nil {
 		return nil, fmt.Errorf("approval check failed: %w", err)
 	}
@@ -277,9 +590,31 @@ func (a *Agent) executeToolCall(ctx context.Context, session *types.Session, too
 		ui.PrintInfo(formatter.FormatAutoApproval(toolCall.ToolName, ruleDescription))
 	}
 
+	// Snapshot the files the tool is about to change
+	pending, err := a.beginCheckpoint(tool, toolCall, session.WorkingDir)
+	if err != nil {
+		return &types.ToolResult{
+			ToolCallID: toolCall.ID,
+			Success:    false,
+			Error:      fmt.Sprintf("%v; the operation was not run so it stays undoable", err),
+		}, err
+	}
+
 	// Execute tool
 	result, err := a.toolRegistry.Execute(ctx, toolCall.ToolName, toolCall.Parameters, session.WorkingDir)
+
+	// Record what changed, including partial writes of failed calls
+	if pending != nil {
+		if _, cerr := pending.Commit(); cerr != nil {
+			logging.Warn("Failed to record checkpoint", "tool", toolCall.ToolName, "error", cerr)
+		}
+	}
 	if err != nil {
+		// Keep the tool's own failure report (e.g. per-hunk patch results) when there is one
+		if result != nil {
+			result.ToolCallID = toolCall.ID
+			return result, err
+		}
 		return &types.ToolResult{
 			ToolCallID:      toolCall.ID,
 			Success:         false,
@@ -291,9 +626,120 @@ func (a *Agent) executeToolCall(ctx context.Context, session *types.Session, too
 	// Set tool call ID
 	result.ToolCallID = toolCall.ID
 
+	// The agent now knows the current content of what it read or wrote
+	a.trackFiles(tool, toolCall, session.WorkingDir)
+
+	// Keep the reviewed diff with the result
+	if preview != nil {
+		if result.Metadata == nil {
+			result.Metadata = make(map[string]interface{})
+		}
+		result.Metadata["diff"] = preview.Diff
+	}
+
 	return result, nil
 }
 
+// nextTurn returns the number of the prompt being started. Sessions saved before
+// turns were counted fall back to counting prompts, skipping image attachment messages.
+func nextTurn(session *types.Session) int {
+	if session.Turns > 0 {
+		return session.Turns + 1
+	}
+	turns := 0
+	for _, msg := range session.Messages {
+		if startsTurn(msg) {
+			turns++
+		}
+	}
+	return max(turns, 1)
+}
+
+// beginCheckpoint snapshots the targets of a file-modifying tool call. It returns nil
+// when the tool doesn't modify files, checkpoints are unavailable or the parameters
+// are invalid (execution then reports the error).
+func (a *Agent) beginCheckpoint(tool types.Tool, toolCall types.ToolCall, workingDir string) (*checkpoint.Pending, error) {
+	modifier, ok := tool.(types.FileModifyingTool)
+	if !ok || a.checkpoints == nil {
+		return nil, nil
+	}
+
+	targets, err := modifier.TargetPaths(toolCall.Parameters, workingDir)
+	if err != nil {
+		return nil, nil
+	}
+
+	pending, err := a.checkpoints.Begin(a.turn, toolCall.ToolName, toolCall.ID, targets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	return pending, nil
+}
+
+// checkStaleReads returns a *tools.StaleReadError when a file the call modifies
+// changed on disk since the agent last read or wrote it
+func (a *Agent) checkStaleReads(tool types.Tool, toolCall types.ToolCall, workingDir string) error {
+	modifier, ok := tool.(types.FileModifyingTool)
+	if !ok || a.reads == nil {
+		return nil
+	}
+
+	targets, err := modifier.TargetPaths(toolCall.Parameters, workingDir)
+	if err != nil {
+		return nil
+	}
+	for _, target := range targets {
+		if err := a.reads.Check(target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trackFiles records the files a successful call read or changed
+func (a *Agent) trackFiles(tool types.Tool, toolCall types.ToolCall, workingDir string) {
+	if a.reads == nil {
+		return
+	}
+
+	var paths []string
+	if reader, ok := tool.(types.FileReadingTool); ok {
+		if read, err := reader.ReadPaths(toolCall.Parameters, workingDir); err == nil {
+			paths = append(paths, read...)
+		}
+	}
+	if modifier, ok := tool.(types.FileModifyingTool); ok {
+		if targets, err := modifier.TargetPaths(toolCall.Parameters, workingDir); err == nil {
+			paths = append(paths, targets...)
+		}
+	}
+
+	for _, path := range paths {
+		if err := a.reads.Refresh(path); err != nil {
+			logging.Warn("Failed to track file state", "path", path, "error", err)
+		}
+	}
+}
+
+// previewToolCall returns the change a previewable tool would make, or nil when the
+// tool has no preview or its parameters are invalid (execution reports the error)
+func previewToolCall(tool types.Tool, params map[string]interface{}, workingDir string) *types.ToolPreview {
+	previewable, ok := tool.(types.PreviewableTool)
+	if !ok {
+		return nil
+	}
+	if err := tool.Validate(params, workingDir); err != nil {
+		return nil
+	}
+
+	preview, err := previewable.Preview(params, workingDir)
+	if err != nil {
+		logging.Debug("Tool preview unavailable", "tool", tool.Name(), "error", err)
+		return nil
+	}
+	return preview
+}
+
 // Model returns the model being used
 func (a *Agent) Model() string {
 	return a.llmClient.Model()
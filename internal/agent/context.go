@@ -38,6 +38,68 @@ func (cm *ContextManager) PruneMessages(session *types.Session) {
 	session.Messages = session.Messages[startIndex:]
 }
 
+// Compact drops the oldest conversation turns until fits reports that the remaining
+// messages fit the context window. Turns are dropped whole (up to the next prompt)
+// so tool results are never separated from the assistant message that requested them.
+// Leading system messages and the latest turn are always kept.
+// Returns the number of messages dropped and whether the result fits.
+func (cm *ContextManager) Compact(session *types.Session, fits func([]types.Message) bool) (int, bool) {
+	if fits(session.Messages) {
+		return 0, true
+	}
+
+	// Preserve leading system messages
+	systemCount := 0
+	for systemCount < len(session.Messages) && session.Messages[systemCount].Role == types.MessageRoleSystem {
+		systemCount++
+	}
+	system := session.Messages[:systemCount]
+	history := session.Messages[systemCount:]
+
+	dropped := 0
+	for {
+		// Find the start of the next turn after the first message
+		next := -1
+		for i := 1; i < len(history); i++ {
+			if startsTurn(history[i]) {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			// Only the latest turn remains
+			break
+		}
+
+		dropped += next
+		history = history[next:]
+
+		candidate := make([]types.Message, 0, len(system)+len(history))
+		candidate = append(candidate, system...)
+		candidate = append(candidate, history...)
+		if fits(candidate) {
+			session.Messages = candidate
+			return dropped, true
+		}
+	}
+
+	remaining := make([]types.Message, 0, len(system)+len(history))
+	remaining = append(remaining, system...)
+	remaining = append(remaining, history...)
+	session.Messages = remaining
+	return dropped, false
+}
+
+// startsTurn reports whether a message is a user prompt. Images attached by view_image
+// are sent as user messages too, but belong to the turn that requested them.
+func startsTurn(msg types.Message) bool {
+	if msg.Role != types.MessageRoleUser {
+		return false
+	}
+	attachment, _ := msg.Metadata["attachment"].(bool)
+	return !attachment
+}
+
 // GetMessages returns messages suitable for LLM context
 func (cm *ContextManager) GetMessages(session *types.Session) []types.Message {
 	return session.Messages
@@ -0,0 +1,75 @@
+package agent_test
+
+import (
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/agent"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+func TestContextManagerCompact(t *testing.T) {
+	newSession := func() *types.Session {
+		return &types.Session{Messages: []types.Message{
+			{Role: types.MessageRoleSystem, Content: "system"},
+			{Role: types.MessageRoleUser, Content: "turn 1"},
+			{Role: types.MessageRoleAssistant, Content: "", ToolCalls: []types.ToolCall{{ID: "1", ToolName: "read_file"}}},
+			{Role: types.MessageRoleTool, Content: "result 1"},
+			{Role: types.MessageRoleUser, Content: "turn 2"},
+			{Role: types.MessageRoleAssistant, Content: "answer 2"},
+			{Role: types.MessageRoleUser, Content: "turn 3"},
+		}}
+	}
+	cm := agent.NewContextManager(100)
+
+	t.Run("already fits", func(t *testing.T) {
+		session := newSession()
+		dropped, ok := cm.Compact(session, func([]types.Message) bool { return true })
+		if dropped != 0 || !ok || len(session.Messages) != 7 {
+			t.Errorf("expected no change, dropped=%d ok=%v len=%d", dropped, ok, len(session.Messages))
+		}
+	})
+
+	t.Run("drops whole oldest turn", func(t *testing.T) {
+		session := newSession()
+		dropped, ok := cm.Compact(session, func(m []types.Message) bool { return len(m) <= 4 })
+		if !ok || dropped != 3 {
+			t.Fatalf("expected 3 messages dropped, got dropped=%d ok=%v", dropped, ok)
+		}
+		if session.Messages[0].Role != types.MessageRoleSystem {
+			t.Error("system message should be preserved")
+		}
+		if session.Messages[1].Content != "turn 2" {
+			t.Errorf("expected history to start at turn 2, got %q", session.Messages[1].Content)
+		}
+	})
+
+	t.Run("image attachment stays in its turn", func(t *testing.T) {
+		session := &types.Session{Messages: []types.Message{
+			{Role: types.MessageRoleSystem, Content: "system"},
+			{Role: types.MessageRoleUser, Content: "old prompt"},
+			{Role: types.MessageRoleAssistant, Content: "old answer"},
+			{Role: types.MessageRoleUser, Content: "describe screenshot.png"},
+			{Role: types.MessageRoleAssistant, ToolCalls: []types.ToolCall{{ID: "1", ToolName: "view_image"}}},
+			{Role: types.MessageRoleTool, Content: "Loaded image screenshot.png"},
+			{Role: types.MessageRoleUser, Content: "Attached image(s)", Metadata: map[string]interface{}{"attachment": true}},
+		}}
+		dropped, ok := cm.Compact(session, func([]types.Message) bool { return false })
+		if ok {
+			t.Fatal("expected compaction to report it does not fit")
+		}
+		if dropped != 2 || len(session.Messages) != 5 || session.Messages[1].Content != "describe screenshot.png" {
+			t.Errorf("expected system and the whole latest turn, dropped=%d messages=%v", dropped, session.Messages)
+		}
+	})
+
+	t.Run("cannot fit latest turn", func(t *testing.T) {
+		session := newSession()
+		dropped, ok := cm.Compact(session, func([]types.Message) bool { return false })
+		if ok {
+			t.Fatal("expected compaction to report it does not fit")
+		}
+		if dropped != 5 || len(session.Messages) != 2 || session.Messages[1].Content != "turn 3" {
+			t.Errorf("expected only system and latest turn, dropped=%d messages=%v", dropped, session.Messages)
+		}
+	})
+}
@@ -0,0 +1,600 @@
+// Package checkpoint snapshots workspace files before the agent changes them so the
+// changes can be undone
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	checkpointsDir = ".wink/checkpoints"
+	manifestFile   = "manifest.json"
+	blobsDir       = "blobs"
+)
+
+// ErrNoCheckpoints is returned when no checkpoint store exists for a workspace
+var ErrNoCheckpoints = errors.New("no checkpoints found for this directory")
+
+// Turn is one prompt of a session; changes are grouped and undone by turn
+type Turn struct {
+	Number    int       `json:"number"`
+	Prompt    string    `json:"prompt"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// FileState is the content of a file at one point in time
+type FileState struct {
+	Exists bool        `json:"exists"`
+	Hash   string      `json:"hash,omitempty"` // SHA-256 of the content; the blob is stored for before-states
+	Mode   os.FileMode `json:"mode,omitempty"`
+}
+
+// Change records one file touched by one tool call
+type Change struct {
+	Turn       int       `json:"turn"`
+	Tool       string    `json:"tool"`
+	ToolCallID string    `json:"tool_call_id,omitempty"`
+	Path       string    `json:"path"` // relative to the working directory, slash-separated
+	Before     FileState `json:"before"`
+	After      FileState `json:"after"`
+	Timestamp  time.Time `json:"timestamp"`
+	Undone     bool      `json:"undone,omitempty"`
+}
+
+// Manifest is the persisted index of a session's checkpoints
+type Manifest struct {
+	SessionID  string    `json:"session_id"`
+	WorkingDir string    `json:"working_dir"`
+	Turns      []Turn    `json:"turns"`
+	Changes    []Change  `json:"changes"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Store keeps the checkpoints of one session under ~/.wink/checkpoints/<session>
+type Store struct {
+	dir      string
+	manifest Manifest
+}
+
+// rootDir returns the directory holding every session's checkpoints
+func rootDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, checkpointsDir), nil
+}
+
+// Open returns the checkpoint store of a session, loading it if it exists. Nothing
+// is written until the first change is recorded.
+func Open(sessionID, workingDir string) (*Store, error) {
+	root, err := rootDir()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		dir: filepath.Join(root, sessionID),
+		manifest: Manifest{
+			SessionID:  sessionID,
+			WorkingDir: workingDir,
+		},
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, manifestFile))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &s.manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse checkpoint manifest: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read checkpoint manifest: %w", err)
+	}
+
+	return s, nil
+}
+
+// Latest returns the most recently updated checkpoint store for a working directory
+func Latest(workingDir string) (*Store, error) {
+	root, err := rootDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoCheckpoints
+		}
+		return nil, fmt.Errorf("failed to read checkpoints directory: %w", err)
+	}
+
+	var latest *Store
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		s, err := Open(entry.Name(), workingDir)
+		if err != nil || len(s.manifest.Changes) == 0 || !samePath(s.manifest.WorkingDir, workingDir) {
+			continue
+		}
+		if latest == nil || s.manifest.UpdatedAt.After(latest.manifest.UpdatedAt) {
+			latest = s
+		}
+	}
+
+	if latest == nil {
+		return nil, ErrNoCheckpoints
+	}
+	return latest, nil
+}
+
+// Find returns the checkpoint store of the session whose ID starts with prefix
+func Find(prefix, workingDir string) (*Store, error) {
+	root, err := rootDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read checkpoints directory: %w", err)
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			matches = append(matches, entry.Name())
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no checkpoints found for session '%s'", prefix)
+	case 1:
+		return Open(matches[0], workingDir)
+	default:
+		return nil, fmt.Errorf("session prefix '%s' is ambiguous (%d sessions match)", prefix, len(matches))
+	}
+}
+
+// samePath reports whether two paths name the same directory
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && filepath.Clean(absA) == filepath.Clean(absB)
+}
+
+// SessionID returns the session the store belongs to
+func (s *Store) SessionID() string {
+	return s.manifest.SessionID
+}
+
+// WorkingDir returns the working directory the recorded paths are relative to
+func (s *Store) WorkingDir() string {
+	return s.manifest.WorkingDir
+}
+
+// Changes returns every recorded change, oldest first
+func (s *Store) Changes() []Change {
+	return s.manifest.Changes
+}
+
+// Turns returns the turns that changed files, oldest first
+func (s *Store) Turns() []Turn {
+	return s.manifest.Turns
+}
+
+// LastTurn returns the latest turn with changes that have not been undone, or 0
+func (s *Store) LastTurn() int {
+	last := 0
+	for _, c := range s.manifest.Changes {
+		if !c.Undone && c.Turn > last {
+			last = c.Turn
+		}
+	}
+	return last
+}
+
+// Pending is a snapshot taken before a tool call, completed by Commit afterwards
+type Pending struct {
+	store      *Store
+	turn       Turn
+	tool       string
+	toolCallID string
+	targets    []string
+	before     map[string]FileState
+}
+
+// Begin snapshots the files at the target paths (files or directories, absolute)
+// before a tool changes them. Missing targets are recorded as not existing.
+func (s *Store) Begin(turn Turn, tool, toolCallID string, targets []string) (*Pending, error) {
+	p := &Pending{
+		store:      s,
+		turn:       turn,
+		tool:       tool,
+		toolCallID: toolCallID,
+		targets:    targets,
+		before:     make(map[string]FileState),
+	}
+
+	for _, target := range targets {
+		err := s.walkFiles(target, func(path string) error {
+			state, err := s.snapshot(path)
+			if err != nil {
+				return err
+			}
+			p.before[path] = state
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to checkpoint '%s': %w", s.relPath(target), err)
+		}
+	}
+
+	return p, nil
+}
+
+// Commit records the state of the targets after the tool ran, keeping only files
+// that actually changed, and returns how many were recorded
+func (p *Pending) Commit() (int, error) {
+	s := p.store
+
+	after := make(map[string]FileState)
+	for _, target := range p.targets {
+		err := s.walkFiles(target, func(path string) error {
+			state, err := hashFile(path)
+			if err != nil {
+				return err
+			}
+			after[path] = state
+			return nil
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to record changes to '%s': %w", s.relPath(target), err)
+		}
+	}
+
+	// Files present before but gone now, and files that appeared
+	paths := make(map[string]bool)
+	for path := range p.before {
+		paths[path] = true
+	}
+	for path := range after {
+		paths[path] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	now := time.Now()
+	recorded := 0
+	for _, path := range sorted {
+		before, after := p.before[path], after[path]
+		if before.Exists == after.Exists && before.Hash == after.Hash {
+			continue
+		}
+		s.manifest.Changes = append(s.manifest.Changes, Change{
+			Turn:       p.turn.Number,
+			Tool:       p.tool,
+			ToolCallID: p.toolCallID,
+			Path:       s.relPath(path),
+			Before:     before,
+			After:      after,
+			Timestamp:  now,
+		})
+		recorded++
+	}
+
+	if recorded == 0 {
+		return 0, nil
+	}
+
+	if !s.hasTurn(p.turn.Number) {
+		s.manifest.Turns = append(s.manifest.Turns, p.turn)
+	}
+	if err := s.save(); err != nil {
+		return 0, err
+	}
+	return recorded, nil
+}
+
+// hasTurn reports whether the turn is already recorded
+func (s *Store) hasTurn(number int) bool {
+	for _, t := range s.manifest.Turns {
+		if t.Number == number {
+			return true
+		}
+	}
+	return false
+}
+
+// walkFiles calls fn for the regular files at or under path. A missing path is
+// reported as itself so its absence can be recorded; symlinks are not followed.
+func (s *Store) walkFiles(path string, fn func(path string) error) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return fn(path)
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if info.Mode().IsRegular() {
+			return fn(path)
+		}
+		return nil
+	}
+
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			return fn(p)
+		}
+		return nil
+	})
+}
+
+// snapshot hashes a file and stores its content as a blob
+func (s *Store) snapshot(path string) (FileState, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return FileState{}, nil
+	}
+	if err != nil {
+		return FileState{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FileState{}, err
+	}
+
+	state := FileState{Exists: true, Hash: hashBytes(data), Mode: info.Mode().Perm()}
+	blob := filepath.Join(s.dir, blobsDir, state.Hash)
+	if _, err := os.Stat(blob); err == nil {
+		return state, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(blob), 0755); err != nil {
+		return FileState{}, fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	// Write under a temporary name so an interrupted write never leaves a bad blob
+	tmp := blob + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return FileState{}, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, blob); err != nil {
+		return FileState{}, fmt.Errorf("failed to store snapshot: %w", err)
+	}
+	return state, nil
+}
+
+// restoreFile replaces the content of path, or of the file a symlink at path points to,
+// through a temporary file and a rename, so an interrupted undo never leaves a truncated file
+func restoreFile(path string, data []byte, mode os.FileMode) (err error) {
+	if target, evalErr := filepath.EvalSymlinks(path); evalErr == nil {
+		path = target
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".wink-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// hashFile returns the current state of a file without storing it
+func hashFile(path string) (FileState, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return FileState{}, nil
+	}
+	if err != nil {
+		return FileState{}, err
+	}
+	if info.IsDir() {
+		return FileState{}, fmt.Errorf("'%s' is a directory", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FileState{}, err
+	}
+	return FileState{Exists: true, Hash: hashBytes(data), Mode: info.Mode().Perm()}, nil
+}
+
+// hashBytes returns the hex SHA-256 of data
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// relPath converts an absolute path to a slash-separated path relative to the working directory
+func (s *Store) relPath(path string) string {
+	rel, err := filepath.Rel(s.manifest.WorkingDir, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// absPath converts a recorded path back to an absolute path
+func (s *Store) absPath(rel string) string {
+	return filepath.Join(s.manifest.WorkingDir, filepath.FromSlash(rel))
+}
+
+// save writes the manifest atomically
+func (s *Store) save() error {
+	s.manifest.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	path := filepath.Join(s.dir, manifestFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint manifest: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write checkpoint manifest: %w", err)
+	}
+	return nil
+}
+
+// Conflict is a file that changed after the agent last wrote it
+type Conflict struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ConflictError reports files that can't be restored without losing later edits
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+// Error implements the error interface
+func (e *ConflictError) Error() string {
+	details := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		details[i] = fmt.Sprintf("%s (%s)", c.Path, c.Reason)
+	}
+	return fmt.Sprintf("%d file(s) changed after the agent modified them: %s; use --force to restore anyway",
+		len(e.Conflicts), strings.Join(details, ", "))
+}
+
+// UndoResult describes what an undo restored
+type UndoResult struct {
+	Turns    []int      `json:"turns"`
+	Restored []string   `json:"restored"` // files written back to their earlier content
+	Removed  []string   `json:"removed"`  // files the agent created, now deleted
+	Forced   []Conflict `json:"forced,omitempty"`
+}
+
+// Undo restores every file changed in turns after toTurn to its state before those
+// turns. Files edited since the agent last wrote them are conflicts: unless force is
+// set, nothing is restored when any exist.
+func (s *Store) Undo(toTurn int, force bool) (*UndoResult, error) {
+	// The earliest and latest undone change per file
+	type span struct {
+		first, last int
+	}
+	spans := make(map[string]*span)
+	var order []string
+	turns := make(map[int]bool)
+	for i, c := range s.manifest.Changes {
+		if c.Undone || c.Turn <= toTurn {
+			continue
+		}
+		turns[c.Turn] = true
+		if sp, ok := spans[c.Path]; ok {
+			sp.last = i
+			continue
+		}
+		spans[c.Path] = &span{first: i, last: i}
+		order = append(order, c.Path)
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("nothing to undo after turn %d", toTurn)
+	}
+
+	// The file must still be as the agent left it
+	var conflicts []Conflict
+	for _, path := range order {
+		expected := s.manifest.Changes[spans[path].last].After
+		current, err := hashFile(s.absPath(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read '%s': %w", path, err)
+		}
+		switch {
+		case current.Exists == expected.Exists && current.Hash == expected.Hash:
+		case !current.Exists:
+			conflicts = append(conflicts, Conflict{Path: path, Reason: "deleted since"})
+		case !expected.Exists:
+			conflicts = append(conflicts, Conflict{Path: path, Reason: "recreated since"})
+		default:
+			conflicts = append(conflicts, Conflict{Path: path, Reason: "modified since"})
+		}
+	}
+	if len(conflicts) > 0 && !force {
+		return nil, &ConflictError{Conflicts: conflicts}
+	}
+
+	result := &UndoResult{Forced: conflicts}
+	for _, path := range order {
+		before := s.manifest.Changes[spans[path].first].Before
+		abs := s.absPath(path)
+
+		if !before.Exists {
+			if err := os.Remove(abs); err != nil && !os.IsNotExist(err) {
+				return result, fmt.Errorf("failed to remove '%s': %w", path, err)
+			}
+			result.Removed = append(result.Removed, path)
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, blobsDir, before.Hash))
+		if err != nil {
+			return result, fmt.Errorf("snapshot of '%s' is missing: %w", path, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			return result, fmt.Errorf("failed to create directory for '%s': %w", path, err)
+		}
+		if err := restoreFile(abs, data, before.Mode); err != nil {
+			return result, fmt.Errorf("failed to restore '%s': %w", path, err)
+		}
+		result.Restored = append(result.Restored, path)
+	}
+
+	for i := range s.manifest.Changes {
+		if c := &s.manifest.Changes[i]; c.Turn > toTurn {
+			c.Undone = true
+		}
+	}
+	for turn := range turns {
+		result.Turns = append(result.Turns, turn)
+	}
+	sort.Ints(result.Turns)
+
+	if err := s.save(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,260 @@
+package checkpoint_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/checkpoint"
+)
+
+// newStore opens a store for a fresh workspace with HOME redirected to a temp dir
+func newStore(t *testing.T) (*checkpoint.Store, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+
+	workDir := t.TempDir()
+	store, err := checkpoint.Open("session-1", workDir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	return store, workDir
+}
+
+// change runs fn between Begin and Commit for the given workspace paths
+func change(t *testing.T, store *checkpoint.Store, workDir string, turn int, paths []string, fn func()) {
+	t.Helper()
+	targets := make([]string, len(paths))
+	for i, p := range paths {
+		targets[i] = filepath.Join(workDir, p)
+	}
+
+	pending, err := store.Begin(checkpoint.Turn{Number: turn, Prompt: "turn", StartedAt: time.Now()}, "test_tool", "call_1", targets)
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	fn()
+	if _, err := pending.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	return string(data)
+}
+
+func TestUndoLastTurn(t *testing.T) {
+	store, workDir := newStore(t)
+	existing := filepath.Join(workDir, "main.go")
+	created := filepath.Join(workDir, "pkg", "new.go")
+	writeFile(t, existing, "v1")
+
+	change(t, store, workDir, 1, []string{"main.go"}, func() { writeFile(t, existing, "v2") })
+	change(t, store, workDir, 2, []string{"main.go"}, func() { writeFile(t, existing, "v3") })
+	change(t, store, workDir, 2, []string{"pkg/new.go"}, func() { writeFile(t, created, "new") })
+
+	if got := store.LastTurn(); got != 2 {
+		t.Fatalf("Expected last turn 2, got %d", got)
+	}
+
+	result, err := store.Undo(1, false)
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if got := readFile(t, existing); got != "v2" {
+		t.Errorf("Expected main.go restored to v2, got %q", got)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("Expected pkg/new.go to be removed")
+	}
+	if len(result.Restored) != 1 || len(result.Removed) != 1 || result.Removed[0] != "pkg/new.go" {
+		t.Errorf("Unexpected undo result: %+v", result)
+	}
+
+	// The undo is persisted: reopening sees turn 1 as the last one
+	reopened, err := checkpoint.Open("session-1", workDir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if got := reopened.LastTurn(); got != 1 {
+		t.Errorf("Expected last turn 1 after undo, got %d", got)
+	}
+}
+
+func TestUndoToTurn(t *testing.T) {
+	store, workDir := newStore(t)
+	path := filepath.Join(workDir, "notes.txt")
+
+	change(t, store, workDir, 1, []string{"notes.txt"}, func() { writeFile(t, path, "one") })
+	change(t, store, workDir, 2, []string{"notes.txt"}, func() { writeFile(t, path, "two") })
+	change(t, store, workDir, 3, []string{"notes.txt"}, func() { writeFile(t, path, "three") })
+
+	if _, err := store.Undo(1, false); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if got := readFile(t, path); got != "one" {
+		t.Errorf("Expected notes.txt as after turn 1, got %q", got)
+	}
+
+	if _, err := store.Undo(0, false); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected notes.txt removed after undoing every turn")
+	}
+
+	if _, err := store.Undo(0, false); err == nil || !strings.Contains(err.Error(), "nothing to undo") {
+		t.Errorf("Expected nothing to undo, got %v", err)
+	}
+}
+
+func TestUndoRestoresDeletedDirectory(t *testing.T) {
+	store, workDir := newStore(t)
+	writeFile(t, filepath.Join(workDir, "dir", "a.txt"), "a")
+	writeFile(t, filepath.Join(workDir, "dir", "sub", "b.sh"), "#!/bin/sh")
+	if err := os.Chmod(filepath.Join(workDir, "dir", "sub", "b.sh"), 0755); err != nil {
+		t.Fatalf("chmod failed: %v", err)
+	}
+
+	change(t, store, workDir, 1, []string{"dir"}, func() {
+		if err := os.RemoveAll(filepath.Join(workDir, "dir")); err != nil {
+			t.Fatalf("remove failed: %v", err)
+		}
+	})
+
+	if _, err := store.Undo(0, false); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if got := readFile(t, filepath.Join(workDir, "dir", "a.txt")); got != "a" {
+		t.Errorf("Expected dir/a.txt restored, got %q", got)
+	}
+	info, err := os.Stat(filepath.Join(workDir, "dir", "sub", "b.sh"))
+	if err != nil {
+		t.Fatalf("Expected dir/sub/b.sh restored: %v", err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected executable mode restored, got %v", info.Mode())
+	}
+}
+
+func TestUndoReplacesFilesAtomically(t *testing.T) {
+	store, workDir := newStore(t)
+	path := filepath.Join(workDir, "config.yaml")
+	writeFile(t, path, "v1")
+
+	change(t, store, workDir, 1, []string{"config.yaml"}, func() { writeFile(t, path, "v2") })
+	// A hard link keeps the replaced file's content, which only happens when the file is renamed over
+	if err := os.Link(path, filepath.Join(workDir, "old.yaml")); err != nil {
+		t.Fatalf("link failed: %v", err)
+	}
+
+	if _, err := store.Undo(0, false); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if got := readFile(t, path); got != "v1" {
+		t.Errorf("Expected config.yaml restored to v1, got %q", got)
+	}
+	if got := readFile(t, filepath.Join(workDir, "old.yaml")); got != "v2" {
+		t.Errorf("Expected the replaced file left intact, got %q", got)
+	}
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected no temporary files left, got %v", entries)
+	}
+}
+
+func TestUndoConflicts(t *testing.T) {
+	store, workDir := newStore(t)
+	path := filepath.Join(workDir, "main.go")
+	writeFile(t, path, "original")
+
+	change(t, store, workDir, 1, []string{"main.go"}, func() { writeFile(t, path, "agent") })
+
+	// Someone edits the file after the agent did
+	writeFile(t, path, "user edit")
+
+	_, err := store.Undo(0, false)
+	var conflictErr *checkpoint.ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("Expected a conflict error, got %v", err)
+	}
+	if len(conflictErr.Conflicts) != 1 || conflictErr.Conflicts[0].Path != "main.go" || conflictErr.Conflicts[0].Reason != "modified since" {
+		t.Errorf("Unexpected conflicts: %+v", conflictErr.Conflicts)
+	}
+	if got := readFile(t, path); got != "user edit" {
+		t.Errorf("Expected conflicting file left alone, got %q", got)
+	}
+
+	result, err := store.Undo(0, true)
+	if err != nil {
+		t.Fatalf("Forced undo failed: %v", err)
+	}
+	if got := readFile(t, path); got != "original" {
+		t.Errorf("Expected forced undo to restore original, got %q", got)
+	}
+	if len(result.Forced) != 1 {
+		t.Errorf("Expected the overwritten conflict to be reported, got %+v", result.Forced)
+	}
+}
+
+func TestUnchangedFilesAreNotRecorded(t *testing.T) {
+	store, workDir := newStore(t)
+	writeFile(t, filepath.Join(workDir, "same.txt"), "same")
+
+	change(t, store, workDir, 1, []string{"same.txt", "missing.txt"}, func() {})
+
+	if n := len(store.Changes()); n != 0 {
+		t.Errorf("Expected no changes recorded, got %d", n)
+	}
+	if _, err := checkpoint.Latest(workDir); !errors.Is(err, checkpoint.ErrNoCheckpoints) {
+		t.Errorf("Expected no checkpoints for the workspace, got %v", err)
+	}
+}
+
+func TestLatestAndFind(t *testing.T) {
+	store, workDir := newStore(t)
+	path := filepath.Join(workDir, "a.txt")
+	change(t, store, workDir, 1, []string{"a.txt"}, func() { writeFile(t, path, "a") })
+
+	latest, err := checkpoint.Latest(workDir)
+	if err != nil {
+		t.Fatalf("Latest failed: %v", err)
+	}
+	if latest.SessionID() != "session-1" {
+		t.Errorf("Expected session-1, got %s", latest.SessionID())
+	}
+
+	if _, err := checkpoint.Latest(t.TempDir()); !errors.Is(err, checkpoint.ErrNoCheckpoints) {
+		t.Errorf("Expected no checkpoints for another directory, got %v", err)
+	}
+
+	found, err := checkpoint.Find("sess", workDir)
+	if err != nil || found.SessionID() != "session-1" {
+		t.Errorf("Expected Find by prefix to return session-1, got %v, %v", found, err)
+	}
+	if _, err := checkpoint.Find("nope", workDir); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+}
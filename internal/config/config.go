@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/shizhMSFT/wink-code/internal/llm"
 	"github.com/shizhMSFT/wink-code/pkg/types"
 	"github.com/spf13/viper"
 )
@@ -107,9 +110,79 @@ func (m *Manager) Validate() error {
 	if m.config.MaxSessionMessages < 10 || m.config.MaxSessionMessages > 1000 {
 		return fmt.Errorf("max_session_messages must be between 10 and 1000")
 	}
+	if n := m.config.LoadTimeoutSeconds; n != 0 && (n < 5 || n > 3600) {
+		return fmt.Errorf("load_timeout_seconds must be between 5 and 3600")
+	}
+	if _, err := llm.ParseKeepAlive(m.config.KeepAlive); err != nil {
+		return err
+	}
+	if err := ValidatePostEdit(m.config.PostEdit); err != nil {
+		return fmt.Errorf("post_edit: %w", err)
+	}
+	for name, profile := range m.config.Profiles {
+		if err := ValidateProfile(profile); err != nil {
+			return fmt.Errorf("profile '%s': %w", name, err)
+		}
+	}
 	return nil
 }
 
+// ValidateProfile checks that a model profile has a model and sane generation options
+func ValidateProfile(profile types.ModelProfile) error {
+	if profile.Model == "" {
+		return fmt.Errorf("model cannot be empty")
+	}
+	if t := profile.Temperature; t != nil && (*t < 0 || *t > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if p := profile.TopP; p != nil && (*p <= 0 || *p > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1")
+	}
+	if n := profile.MaxTokens; n != nil && *n < 1 {
+		return fmt.Errorf("max_tokens must be positive")
+	}
+	if n := profile.NumCtx; n != nil && *n < 256 {
+		return fmt.Errorf("num_ctx must be at least 256")
+	}
+	return nil
+}
+
+// ValidatePostEdit checks that formatters are keyed by extension and name a command
+func ValidatePostEdit(cfg *types.PostEditConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	for ext, command := range cfg.Formatters {
+		if !strings.HasPrefix(ext, ".") || len(ext) < 2 {
+			return fmt.Errorf("formatter key '%s' must be a file extension such as '.py'", ext)
+		}
+		if len(command) == 0 || command[0] == "" {
+			return fmt.Errorf("formatter for '%s' must name a command", ext)
+		}
+	}
+	if n := cfg.TimeoutSeconds; n < 0 || n > 120 {
+		return fmt.Errorf("timeout_seconds must be between 0 and 120 (0 uses the default)")
+	}
+	return nil
+}
+
+// Profile returns the named model profile
+func (m *Manager) Profile(name string) (*types.ModelProfile, error) {
+	profile, ok := m.config.Profiles[name]
+	if !ok {
+		available := make([]string, 0, len(m.config.Profiles))
+		for n := range m.config.Profiles {
+			available = append(available, n)
+		}
+		sort.Strings(available)
+		if len(available) == 0 {
+			return nil, fmt.Errorf("profile '%s' not found: no profiles defined in %s", name, m.configPath)
+		}
+		return nil, fmt.Errorf("profile '%s' not found (available: %s)", name, strings.Join(available, ", "))
+	}
+	return &profile, nil
+}
+
 // Get returns the current configuration
 func (m *Manager) Get() *types.Config {
 	return m.config
@@ -0,0 +1,136 @@
+package config_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/config"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// writeConfig writes cfg to $HOME/.wink/config.json in a temporary home directory
+func writeConfig(t *testing.T, cfg *types.Config) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to marshal config: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(home, ".wink"), 0755); err != nil {
+		t.Fatalf("failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".wink", "config.json"), data, 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+func TestManagerProfile(t *testing.T) {
+	temperature := 0.2
+	numCtx := 32768
+
+	cfg := types.DefaultConfig()
+	cfg.Profiles = map[string]types.ModelProfile{
+		"coder": {
+			Model: "qwen3-coder:30b",
+			GenerationOptions: types.GenerationOptions{
+				Temperature: &temperature,
+				NumCtx:      &numCtx,
+			},
+		},
+	}
+	writeConfig(t, cfg)
+
+	manager, err := config.NewManager()
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	profile, err := manager.Profile("coder")
+	if err != nil {
+		t.Fatalf("Profile failed: %v", err)
+	}
+	if profile.Model != "qwen3-coder:30b" {
+		t.Errorf("expected model qwen3-coder:30b, got %s", profile.Model)
+	}
+	if profile.Temperature == nil || *profile.Temperature != 0.2 {
+		t.Errorf("expected temperature 0.2, got %v", profile.Temperature)
+	}
+	if profile.NumCtx == nil || *profile.NumCtx != 32768 {
+		t.Errorf("expected num_ctx 32768, got %v", profile.NumCtx)
+	}
+
+	_, err = manager.Profile("missing")
+	if err == nil || !strings.Contains(err.Error(), "available: coder") {
+		t.Errorf("expected not-found error listing available profiles, got %v", err)
+	}
+}
+
+func TestProfileFlatJSON(t *testing.T) {
+	// Profiles are written flat in config.json, options alongside the model
+	data := []byte(`{"model": "qwen3-coder:30b", "temperature": 0.2, "num_ctx": 32768}`)
+
+	var profile types.ModelProfile
+	if err := json.Unmarshal(data, &profile); err != nil {
+		t.Fatalf("failed to parse profile: %v", err)
+	}
+	if profile.Model != "qwen3-coder:30b" || profile.NumCtx == nil || *profile.NumCtx != 32768 {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	bad := 3.0
+	tests := []struct {
+		name    string
+		profile types.ModelProfile
+		wantErr bool
+	}{
+		{name: "valid", profile: types.ModelProfile{Model: "qwen3:8b"}},
+		{name: "missing model", profile: types.ModelProfile{}, wantErr: true},
+		{
+			name:    "temperature out of range",
+			profile: types.ModelProfile{Model: "qwen3:8b", GenerationOptions: types.GenerationOptions{Temperature: &bad}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.ValidateProfile(tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePostEdit(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *types.PostEditConfig
+		wantErr bool
+	}{
+		{name: "unset", cfg: nil},
+		{name: "valid", cfg: &types.PostEditConfig{Formatters: map[string][]string{".py": {"black", "-q", "-"}}, TimeoutSeconds: 30}},
+		{name: "key is not an extension", cfg: &types.PostEditConfig{Formatters: map[string][]string{"py": {"black"}}}, wantErr: true},
+		{name: "empty command", cfg: &types.PostEditConfig{Formatters: map[string][]string{".sh": {}}}, wantErr: true},
+		{name: "timeout 0 uses the default", cfg: &types.PostEditConfig{TimeoutSeconds: 0}},
+		{name: "timeout out of range", cfg: &types.PostEditConfig{TimeoutSeconds: 600}, wantErr: true},
+		{name: "negative timeout", cfg: &types.PostEditConfig{TimeoutSeconds: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := config.ValidatePostEdit(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePostEdit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,508 @@
+// Package index maintains on-disk indexes of a workspace: a trigram index so content
+// searches only read the files that can match, and embedding vectors for semantic search
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	indexDir      = ".wink/index"
+	indexFile     = "trigrams.gob"
+	ignoreFile    = ".gitignore"
+	formatVersion = 1
+	// maxIndexedFileSize is the largest file whose trigrams are indexed; larger files
+	// are always searched
+	maxIndexedFileSize = 10 * 1024 * 1024
+	// binarySniffSize is how much of a file is checked for NUL bytes, as grep_search does
+	binarySniffSize = 512
+	// racyWindow is how recent a modification time must be for the file to be read
+	// again on the next sync, since a same-size edit within the timestamp
+	// granularity would go unnoticed
+	racyWindow = 2 * time.Second
+)
+
+// ErrNoIndex is returned when a workspace has no search index
+var ErrNoIndex = errors.New("no search index for this directory; build one with 'wink index build'")
+
+// entry flags
+const (
+	flagBinary uint8 = 1 << iota
+	flagTooLarge
+	flagUnreadable
+	flagRacy
+	flagRemoved
+)
+
+// File is a workspace file as listed by a walk
+type File struct {
+	Path    string // relative to the workspace, slash-separated
+	Size    int64
+	ModTime time.Time
+}
+
+// entry is an indexed file
+type entry struct {
+	Path    string
+	Size    int64
+	ModTime int64
+	Flags   uint8
+}
+
+// persisted is the on-disk form of an index; posting lists are delta-encoded varints
+type persisted struct {
+	Version   int
+	Files     []entry
+	Postings  map[uint32][]byte
+	UpdatedAt time.Time
+}
+
+// Index maps trigrams to the workspace files containing them. Changed files get a new
+// ID and their old one is marked removed, so posting lists stay sorted by ID and are
+// only compacted when saved.
+type Index struct {
+	root      string
+	files     []entry
+	byPath    map[string]uint32
+	postings  map[uint32][]uint32
+	removed   int
+	updatedAt time.Time
+	dirty     bool
+}
+
+// SyncStats describes what a sync changed
+type SyncStats struct {
+	Files   int // files in the workspace
+	Added   int // new or changed files that were read
+	Removed int // files no longer in the workspace
+	Elapsed time.Duration
+}
+
+// Status describes an index
+type Status struct {
+	Dir       string
+	Files     int // indexed files, including binary and oversized ones
+	Binary    int
+	TooLarge  int
+	Trigrams  int
+	DiskBytes int64
+	UpdatedAt time.Time
+	// Changed and Removed count files that differ from the index when Stale was called
+	Changed int
+	Removed int
+}
+
+// Dir returns the index directory of a workspace
+func Dir(root string) string {
+	return filepath.Join(root, filepath.FromSlash(indexDir))
+}
+
+// Exists reports whether the workspace has an index
+func Exists(root string) bool {
+	_, err := os.Stat(filepath.Join(Dir(root), indexFile))
+	return err == nil
+}
+
+// Open loads the index of a workspace, or returns an empty one if there is none or it
+// was written by another version. Nothing is written until Save.
+func Open(root string) (*Index, error) {
+	ix := &Index{
+		root:     root,
+		byPath:   map[string]uint32{},
+		postings: map[uint32][]uint32{},
+	}
+
+	data, err := os.ReadFile(filepath.Join(Dir(root), indexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ix, nil
+		}
+		return nil, fmt.Errorf("failed to read search index: %w", err)
+	}
+
+	var p persisted
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&p); err != nil || p.Version != formatVersion {
+		// A damaged or outdated index is rebuilt rather than trusted
+		return ix, nil
+	}
+
+	ix.files = p.Files
+	ix.updatedAt = p.UpdatedAt
+	for id, e := range ix.files {
+		ix.byPath[e.Path] = uint32(id)
+	}
+	for t, encoded := range p.Postings {
+		ix.postings[t] = decodePostings(encoded)
+	}
+	return ix, nil
+}
+
+// Clear deletes the index of a workspace
+func Clear(root string) error {
+	if _, err := os.Stat(Dir(root)); os.IsNotExist(err) {
+		return ErrNoIndex
+	}
+	if err := os.RemoveAll(Dir(root)); err != nil {
+		return fmt.Errorf("failed to remove search index: %w", err)
+	}
+	return nil
+}
+
+// Sync brings the index up to date with the files of a walk, reading the files whose
+// size or modification time changed and forgetting files that are gone
+func (ix *Index) Sync(ctx context.Context, files []File) (SyncStats, error) {
+	start := time.Now()
+	stats := SyncStats{Files: len(files)}
+
+	seen := make(map[string]bool, len(files))
+	var changed []File
+	for _, f := range files {
+		seen[f.Path] = true
+		if id, ok := ix.byPath[f.Path]; ok {
+			e := ix.files[id]
+			if e.Size == f.Size && e.ModTime == f.ModTime.UnixNano() && e.Flags&flagRacy == 0 {
+				continue
+			}
+		}
+		changed = append(changed, f)
+	}
+	for path, id := range ix.byPath {
+		if !seen[path] {
+			ix.remove(id)
+			stats.Removed++
+		}
+	}
+
+	read, err := ix.readFiles(ctx, changed, start)
+	if err != nil {
+		return stats, err
+	}
+	for _, r := range read {
+		if id, ok := ix.byPath[r.entry.Path]; ok {
+			ix.remove(id)
+		}
+		id := uint32(len(ix.files))
+		ix.files = append(ix.files, r.entry)
+		ix.byPath[r.entry.Path] = id
+		for _, t := range r.trigrams {
+			ix.postings[t] = append(ix.postings[t], id)
+		}
+	}
+
+	stats.Added = len(read)
+	if stats.Added > 0 || stats.Removed > 0 || ix.updatedAt.IsZero() {
+		ix.dirty = true
+		ix.updatedAt = time.Now()
+	}
+	stats.Elapsed = time.Since(start)
+	return stats, nil
+}
+
+// Stale reports how many files of a walk changed or disappeared since the index was
+// updated, without reading them
+func (ix *Index) Stale(files []File) (changed, removed int) {
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		seen[f.Path] = true
+		id, ok := ix.byPath[f.Path]
+		if !ok || ix.files[id].Size != f.Size || ix.files[id].ModTime != f.ModTime.UnixNano() {
+			changed++
+		}
+	}
+	for path := range ix.byPath {
+		if !seen[path] {
+			removed++
+		}
+	}
+	return changed, removed
+}
+
+// remove marks a file ID as no longer in the workspace
+func (ix *Index) remove(id uint32) {
+	delete(ix.byPath, ix.files[id].Path)
+	ix.files[id].Flags |= flagRemoved
+	ix.removed++
+}
+
+// fileTrigrams is a file read by a sync
+type fileTrigrams struct {
+	entry    entry
+	trigrams []uint32
+}
+
+// readFiles extracts the trigrams of files in parallel, keeping their order
+func (ix *Index) readFiles(ctx context.Context, files []File, syncStart time.Time) ([]fileTrigrams, error) {
+	out := make([]fileTrigrams, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.GOMAXPROCS(0), 16, max(len(files), 1)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			set := newTrigramSet()
+			for i := range next {
+				out[i] = ix.readFile(files[i], set, syncStart)
+			}
+		}()
+	}
+
+	var err error
+	for i := range files {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return out, err
+}
+
+// readFile extracts one file's trigrams. Binary and unreadable files are recorded
+// without any, since grep_search skips them too.
+func (ix *Index) readFile(f File, set *trigramSet, syncStart time.Time) fileTrigrams {
+	e := entry{Path: f.Path, Size: f.Size, ModTime: f.ModTime.UnixNano()}
+	if syncStart.Sub(f.ModTime) < racyWindow {
+		e.Flags |= flagRacy
+	}
+	if f.Size > maxIndexedFileSize {
+		e.Flags |= flagTooLarge
+		return fileTrigrams{entry: e}
+	}
+
+	data, err := os.ReadFile(filepath.Join(ix.root, filepath.FromSlash(f.Path)))
+	if err != nil {
+		e.Flags |= flagUnreadable | flagRacy
+		return fileTrigrams{entry: e}
+	}
+	if bytes.IndexByte(data[:min(len(data), binarySniffSize)], 0) >= 0 {
+		e.Flags |= flagBinary
+		return fileTrigrams{entry: e}
+	}
+	return fileTrigrams{entry: e, trigrams: set.extract(Fold(data))}
+}
+
+// Candidates are the files that may match a query
+type Candidates struct {
+	ix  *Index
+	all bool
+	ids map[uint32]bool
+}
+
+// Candidates evaluates a query against the index
+func (ix *Index) Candidates(q *Query) *Candidates {
+	ids, all := ix.eval(q)
+	c := &Candidates{ix: ix, all: all}
+	if !all {
+		c.ids = make(map[uint32]bool, len(ids))
+		for _, id := range ids {
+			c.ids[id] = true
+		}
+	}
+	return c
+}
+
+// Check reports whether a file must be searched, and whether it is binary or
+// unreadable and so skipped by searches altogether. Files the index has not seen or
+// did not read because of their size are always searched.
+func (c *Candidates) Check(path string) (search, skipped bool) {
+	id, ok := c.ix.byPath[path]
+	if !ok {
+		return true, false
+	}
+	e := c.ix.files[id]
+	switch {
+	case e.Flags&(flagBinary|flagUnreadable) != 0:
+		return false, true
+	case e.Flags&flagTooLarge != 0, c.all:
+		return true, false
+	}
+	return c.ids[id], false
+}
+
+// eval returns the sorted IDs matching a query, or all=true for every file
+func (ix *Index) eval(q *Query) (ids []uint32, all bool) {
+	switch q.op {
+	case queryAnd:
+		all = true
+		for _, t := range q.trigrams {
+			list := ix.postings[t]
+			if all {
+				ids, all = list, false
+			} else {
+				ids = intersect(ids, list)
+			}
+			if len(ids) == 0 {
+				return nil, false
+			}
+		}
+		for _, sub := range q.sub {
+			subIDs, subAll := ix.eval(sub)
+			if subAll {
+				continue
+			}
+			if all {
+				ids, all = subIDs, false
+			} else {
+				ids = intersect(ids, subIDs)
+			}
+			if len(ids) == 0 {
+				return nil, false
+			}
+		}
+		return ids, all
+	case queryOr:
+		for _, sub := range q.sub {
+			subIDs, subAll := ix.eval(sub)
+			if subAll {
+				return nil, true
+			}
+			ids = union(ids, subIDs)
+		}
+		return ids, false
+	}
+	return nil, true
+}
+
+// Status describes the index
+func (ix *Index) Status() Status {
+	s := Status{
+		Dir:       Dir(ix.root),
+		Files:     len(ix.byPath),
+		Trigrams:  len(ix.postings),
+		UpdatedAt: ix.updatedAt,
+	}
+	for _, id := range ix.byPath {
+		switch flags := ix.files[id].Flags; {
+		case flags&flagBinary != 0:
+			s.Binary++
+		case flags&flagTooLarge != 0:
+			s.TooLarge++
+		}
+	}
+	if info, err := os.Stat(filepath.Join(s.Dir, indexFile)); err == nil {
+		s.DiskBytes = info.Size()
+	}
+	return s
+}
+
+// Save writes the index if it changed, dropping removed files. The index directory
+// gets a .gitignore so the index never shows up in version control or searches.
+func (ix *Index) Save() error {
+	if !ix.dirty {
+		return nil
+	}
+	ix.compact()
+
+	p := persisted{
+		Version:   formatVersion,
+		Files:     ix.files,
+		Postings:  make(map[uint32][]byte, len(ix.postings)),
+		UpdatedAt: ix.updatedAt,
+	}
+	for t, list := range ix.postings {
+		p.Postings[t] = encodePostings(list)
+	}
+	if err := writeIndexFile(ix.root, indexFile, &p); err != nil {
+		return err
+	}
+	ix.dirty = false
+	return nil
+}
+
+// writeIndexFile gob-encodes v into a file of the index directory, replacing it atomically
+func writeIndexFile(root, name string, v interface{}) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return fmt.Errorf("failed to encode search index: %w", err)
+	}
+
+	dir := Dir(root)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create search index directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ignoreFile), []byte("*\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write search index: %w", err)
+	}
+	return nil
+}
+
+// compact renumbers the remaining files and drops removed IDs from posting lists
+func (ix *Index) compact() {
+	if ix.removed == 0 {
+		return
+	}
+	remap := make([]uint32, len(ix.files))
+	files := ix.files[:0]
+	for id, e := range ix.files {
+		if e.Flags&flagRemoved != 0 {
+			remap[id] = ^uint32(0)
+			continue
+		}
+		remap[id] = uint32(len(files))
+		ix.byPath[e.Path] = uint32(len(files))
+		files = append(files, e)
+	}
+	ix.files = files
+
+	for t, list := range ix.postings {
+		kept := list[:0]
+		for _, id := range list {
+			if remap[id] != ^uint32(0) {
+				kept = append(kept, remap[id])
+			}
+		}
+		if len(kept) == 0 {
+			delete(ix.postings, t)
+		} else {
+			ix.postings[t] = kept
+		}
+	}
+	ix.removed = 0
+}
+
+// encodePostings delta-encodes a sorted posting list as varints
+func encodePostings(list []uint32) []byte {
+	buf := make([]byte, 0, len(list)*2)
+	prev := uint32(0)
+	for _, id := range list {
+		buf = binary.AppendUvarint(buf, uint64(id-prev))
+		prev = id
+	}
+	return buf
+}
+
+// decodePostings reverses encodePostings
+func decodePostings(buf []byte) []uint32 {
+	var list []uint32
+	prev := uint32(0)
+	for len(buf) > 0 {
+		delta, n := binary.Uvarint(buf)
+		if n <= 0 {
+			break
+		}
+		prev += uint32(delta)
+		list = append(list, prev)
+		buf = buf[n:]
+	}
+	return list
+}
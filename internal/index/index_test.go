@@ -0,0 +1,234 @@
+package index_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/index"
+)
+
+// workspace writes files under a temp dir and returns it
+func workspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range files {
+		write(t, root, path, content)
+	}
+	return root
+}
+
+func write(t *testing.T, root, path, content string) {
+	t.Helper()
+	full := filepath.Join(root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatalf("mkdir failed: %v", err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+}
+
+// list returns the files of a workspace as a walk would, skipping the index itself
+func list(t *testing.T, root string) []index.File {
+	t.Helper()
+	var files []index.File
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == index.Dir(root) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		files = append(files, index.File{Path: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+	return files
+}
+
+// candidates returns the files a query can't rule out
+func candidates(ix *index.Index, q *index.Query, files []index.File) []string {
+	c := ix.Candidates(q)
+	var out []string
+	for _, f := range files {
+		if search, _ := c.Check(f.Path); search {
+			out = append(out, f.Path)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// TestQuery tests which files literal and regexp queries select
+func TestQuery(t *testing.T) {
+	root := workspace(t, map[string]string{
+		"server.go":  "func ListenAndServe(addr string) error\n",
+		"client.go":  "func Dial(addr string) (*Conn, error)\n",
+		"readme.md":  "Use STRASSE for the Kelvin scale (K)\n",
+		"unicode.go": "// ΣΊΣΥΦΟΣ\n",
+		"image.png":  "\x89PNG\x00\x00ListenAndServe",
+	})
+	files := list(t, root)
+
+	ix, err := index.Open(root)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if _, err := ix.Sync(context.Background(), files); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		literal string
+		regexp  string
+		want    []string
+	}{
+		{name: "literal", literal: "ListenAndServe", want: []string{"server.go"}},
+		{name: "literal in every text file", literal: "func", want: []string{"client.go", "server.go"}},
+		{name: "literal matches folded text", literal: "listenandserve", want: []string{"server.go"}},
+		{name: "non-ASCII literal is folded", literal: "σίσυφος", want: []string{"unicode.go"}},
+		{name: "short literal selects everything", literal: "ab", want: []string{"client.go", "readme.md", "server.go", "unicode.go"}},
+		{name: "absent literal selects nothing", literal: "Websocket", want: nil},
+		{name: "regexp concatenation", regexp: `func \w+\(addr string\) error`, want: []string{"server.go"}},
+		{name: "regexp alternation", regexp: `Dial|Listen`, want: []string{"client.go", "server.go"}},
+		{name: "case-insensitive regexp", regexp: `(?i)strasse`, want: []string{"readme.md"}},
+		{name: "optional parts are not required", regexp: `(?:Websocket)?Dial`, want: []string{"client.go"}},
+		{name: "regexp without literals selects everything", regexp: `\w+\(`, want: []string{"client.go", "readme.md", "server.go", "unicode.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := index.LiteralQuery(tt.literal)
+			if tt.regexp != "" {
+				q, err = index.RegexpQuery(tt.regexp)
+				if err != nil {
+					t.Fatalf("RegexpQuery failed: %v", err)
+				}
+			}
+			got := candidates(ix, q, files)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected candidates %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected candidates %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+
+	if _, skipped := ix.Candidates(index.LiteralQuery("PNG")).Check("image.png"); !skipped {
+		t.Error("expected binary file to be skipped")
+	}
+}
+
+// TestSync tests incremental updates and persistence
+func TestSync(t *testing.T) {
+	root := workspace(t, map[string]string{
+		"a.txt":     "alpha beta\n",
+		"b.txt":     "gamma delta\n",
+		"dir/c.txt": "epsilon\n",
+	})
+	ctx := context.Background()
+
+	ix, err := index.Open(root)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	stats, err := ix.Sync(ctx, list(t, root))
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if stats.Files != 3 || stats.Added != 3 || stats.Removed != 0 {
+		t.Errorf("expected 3 files read on first sync, got %+v", stats)
+	}
+	if err := ix.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if !index.Exists(root) {
+		t.Fatal("expected the index to exist after Save")
+	}
+
+	// Change a file (backdated so it is not re-read as racily clean), remove and add others
+	write(t, root, "a.txt", "alpha zeta\n")
+	old := time.Now().Add(-time.Hour)
+	for _, f := range []string{"a.txt", "b.txt", "dir/c.txt"} {
+		if err := os.Chtimes(filepath.Join(root, f), old, old); err != nil {
+			t.Fatalf("Chtimes failed: %v", err)
+		}
+	}
+	if err := os.Remove(filepath.Join(root, "dir", "c.txt")); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	write(t, root, "d.txt", "eta theta\n")
+
+	reopened, err := index.Open(root)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	files := list(t, root)
+	if changed, removed := reopened.Stale(files); changed != 3 || removed != 1 {
+		t.Errorf("expected 3 changed and 1 removed before sync, got %d and %d", changed, removed)
+	}
+	stats, err = reopened.Sync(ctx, files)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if stats.Added != 3 || stats.Removed != 1 {
+		t.Errorf("expected 3 files read and 1 removed, got %+v", stats)
+	}
+	if err := reopened.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	final, err := index.Open(root)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	files = list(t, root)
+	if changed, removed := final.Stale(files); changed != 0 || removed != 0 {
+		t.Errorf("expected an up-to-date index, got %d changed and %d removed", changed, removed)
+	}
+	for literal, want := range map[string]string{"zeta": "a.txt", "theta": "d.txt", "gamma": "b.txt"} {
+		got := candidates(final, index.LiteralQuery(literal), files)
+		if len(got) != 1 || got[0] != want {
+			t.Errorf("expected %q only in %s, got %v", literal, want, got)
+		}
+	}
+	for _, gone := range []string{"beta", "epsilon"} {
+		if got := candidates(final, index.LiteralQuery(gone), files); len(got) != 0 {
+			t.Errorf("expected no candidates for removed text %q, got %v", gone, got)
+		}
+	}
+
+	status := final.Status()
+	if status.Files != 3 || status.Trigrams == 0 || status.DiskBytes == 0 || status.UpdatedAt.IsZero() {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	if err := index.Clear(root); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if index.Exists(root) {
+		t.Error("expected the index to be gone after Clear")
+	}
+	if err := index.Clear(root); !errors.Is(err, index.ErrNoIndex) {
+		t.Errorf("expected ErrNoIndex clearing twice, got %v", err)
+	}
+}
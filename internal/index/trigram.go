@@ -0,0 +1,214 @@
+package index
+
+import (
+	"regexp/syntax"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Trigrams are taken from case-folded text, so one index serves case-sensitive and
+// case-insensitive searches alike; a trigram is three bytes packed into a uint32.
+
+// Fold maps every rune of data to a canonical member of its case-folding orbit, so
+// text matching a pattern case-insensitively folds to the folded pattern. Invalid
+// UTF-8 becomes U+FFFD, as it does for the regexp engine.
+func Fold(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); {
+		c := data[i]
+		if c < utf8.RuneSelf {
+			if 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			out = append(out, c)
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		out = utf8.AppendRune(out, foldRune(r))
+		i += size
+	}
+	return out
+}
+
+// foldRune returns the lower-case ASCII letter of an orbit that has one (such as
+// K, k and the Kelvin sign), otherwise the orbit's smallest rune
+func foldRune(r rune) rune {
+	least := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		least = min(least, f)
+	}
+	if 'A' <= least && least <= 'Z' {
+		return least + 'a' - 'A'
+	}
+	return least
+}
+
+// trigramSet collects the distinct trigrams of a file using a bitmap over all 2^24
+// trigrams, reused between files
+type trigramSet struct {
+	bits []uint64
+	list []uint32
+}
+
+// newTrigramSet creates an empty set
+func newTrigramSet() *trigramSet {
+	return &trigramSet{bits: make([]uint64, 1<<24/64)}
+}
+
+// extract returns the distinct trigrams of folded text and clears the set
+func (s *trigramSet) extract(text []byte) []uint32 {
+	s.list = s.list[:0]
+	for i := 0; i+2 < len(text); i++ {
+		t := uint32(text[i])<<16 | uint32(text[i+1])<<8 | uint32(text[i+2])
+		if s.bits[t>>6]&(1<<(t&63)) == 0 {
+			s.bits[t>>6] |= 1 << (t & 63)
+			s.list = append(s.list, t)
+		}
+	}
+	for _, t := range s.list {
+		s.bits[t>>6] &^= 1 << (t & 63)
+	}
+	return append([]uint32(nil), s.list...)
+}
+
+// queryOp is how a query combines its trigrams and subqueries
+type queryOp int
+
+const (
+	// queryAll matches every file
+	queryAll queryOp = iota
+	// queryAnd matches files with every trigram that match every subquery
+	queryAnd
+	// queryOr matches files that match any subquery
+	queryOr
+)
+
+// Query selects the files that may contain a match of a pattern. It never rules
+// out a file that matches, so searching only its candidates gives the same results
+// as searching every file.
+type Query struct {
+	op       queryOp
+	trigrams []uint32
+	sub      []*Query
+}
+
+// allQuery matches every file
+var allQuery = &Query{op: queryAll}
+
+// LiteralQuery returns the query for a plain-text pattern
+func LiteralQuery(text string) *Query {
+	folded := Fold([]byte(text))
+	if len(folded) < 3 {
+		return allQuery
+	}
+	set := newTrigramSet()
+	return &Query{op: queryAnd, trigrams: set.extract(folded)}
+}
+
+// RegexpQuery returns the query for a regular expression in Go syntax
+func RegexpQuery(pattern string) (*Query, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	return regexpQuery(re.Simplify()), nil
+}
+
+// regexpQuery derives a query from the literal text a regexp requires: literals in a
+// concatenation must all be present, and one branch of an alternation
+func regexpQuery(re *syntax.Regexp) *Query {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return LiteralQuery(string(re.Rune))
+	case syntax.OpCapture, syntax.OpPlus:
+		return regexpQuery(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return regexpQuery(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		q := &Query{op: queryAnd}
+		var run []rune
+		flush := func() {
+			q.and(LiteralQuery(string(run)))
+			run = run[:0]
+		}
+		for _, sub := range re.Sub {
+			if sub.Op == syntax.OpLiteral {
+				run = append(run, sub.Rune...)
+				continue
+			}
+			flush()
+			q.and(regexpQuery(sub))
+		}
+		flush()
+		if len(q.trigrams) == 0 && len(q.sub) == 0 {
+			return allQuery
+		}
+		return q
+	case syntax.OpAlternate:
+		q := &Query{op: queryOr}
+		for _, sub := range re.Sub {
+			s := regexpQuery(sub)
+			if s.op == queryAll {
+				return allQuery
+			}
+			q.sub = append(q.sub, s)
+		}
+		return q
+	}
+	return allQuery
+}
+
+// and adds a required subquery to an AND query
+func (q *Query) and(s *Query) {
+	switch s.op {
+	case queryAll:
+	case queryAnd:
+		q.trigrams = append(q.trigrams, s.trigrams...)
+		q.sub = append(q.sub, s.sub...)
+	default:
+		q.sub = append(q.sub, s)
+	}
+}
+
+// intersect returns the IDs in both sorted lists
+func intersect(a, b []uint32) []uint32 {
+	var out []uint32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// union returns the IDs in either sorted list
+func union(a, b []uint32) []uint32 {
+	out := make([]uint32, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			out = append(out, a[i])
+			i++
+		case a[i] > b[j]:
+			out = append(out, b[j])
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	return append(out, b[j:]...)
+}
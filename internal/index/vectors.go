@@ -0,0 +1,392 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	vectorFile          = "vectors.gob"
+	vectorFormatVersion = 1
+	// maxEmbeddedFileSize is the largest file that is chunked and embedded
+	maxEmbeddedFileSize = 1024 * 1024
+	// embedBatchSize is how many chunks are sent to the embedder at once
+	embedBatchSize = 32
+)
+
+// Chunk is a part of a file embedded as one unit, such as a function or a paragraph
+type Chunk struct {
+	StartLine int
+	EndLine   int
+	// Symbol names the declaration the chunk holds, "" for other text
+	Symbol string
+	Text   string
+	Vector []float32
+}
+
+// Chunker splits the text of a file into chunks without vectors; path is relative
+// to the workspace and slash-separated
+type Chunker func(path string, src []byte) []Chunk
+
+// Embedder computes vectors for texts; vectors of different models are not comparable
+type Embedder interface {
+	Model() string
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// vectorEntry is a file with its embedded chunks
+type vectorEntry struct {
+	Path    string
+	Size    int64
+	ModTime int64
+	Flags   uint8
+	Chunks  []Chunk
+}
+
+// persistedVectors is the on-disk form of a vector index; each file's vectors are
+// stored as little-endian float32s, since gob would widen them to float64
+type persistedVectors struct {
+	Version   int
+	Model     string
+	Files     []vectorEntry
+	Vectors   [][]byte
+	UpdatedAt time.Time
+}
+
+// VectorIndex holds the embedded chunks of the workspace files
+type VectorIndex struct {
+	root      string
+	model     string
+	files     map[string]*vectorEntry
+	updatedAt time.Time
+	dirty     bool
+}
+
+// Hit is a chunk found by a vector search
+type Hit struct {
+	Path  string
+	Chunk Chunk
+	// Score is the cosine similarity of the chunk and the query
+	Score float32
+}
+
+// VectorsExist reports whether the workspace has a vector index
+func VectorsExist(root string) bool {
+	_, err := os.Stat(filepath.Join(Dir(root), vectorFile))
+	return err == nil
+}
+
+// OpenVectors loads the vector index of a workspace, or returns an empty one if
+// there is none or it was written by another version
+func OpenVectors(root string) (*VectorIndex, error) {
+	vx := &VectorIndex{root: root, files: map[string]*vectorEntry{}}
+
+	data, err := os.ReadFile(filepath.Join(Dir(root), vectorFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return vx, nil
+		}
+		return nil, fmt.Errorf("failed to read vector index: %w", err)
+	}
+
+	var p persistedVectors
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&p); err != nil || p.Version != vectorFormatVersion || len(p.Vectors) != len(p.Files) {
+		return vx, nil
+	}
+
+	vx.model = p.Model
+	vx.updatedAt = p.UpdatedAt
+	for i := range p.Files {
+		e := &p.Files[i]
+		vectors := decodeVectors(p.Vectors[i], len(e.Chunks))
+		for j := range e.Chunks {
+			e.Chunks[j].Vector = vectors[j]
+		}
+		vx.files[e.Path] = e
+	}
+	return vx, nil
+}
+
+// Sync brings the index up to date with the files of a walk, chunking and embedding
+// the files whose size or modification time changed. Files embedded before an error
+// are kept, so an interrupted sync resumes where it stopped.
+func (vx *VectorIndex) Sync(ctx context.Context, files []File, chunk Chunker, embedder Embedder) (SyncStats, error) {
+	start := time.Now()
+	stats := SyncStats{Files: len(files)}
+
+	// Vectors of another model can't be compared with the query
+	if vx.model != embedder.Model() {
+		if len(vx.files) > 0 {
+			vx.files = map[string]*vectorEntry{}
+		}
+		vx.model = embedder.Model()
+		vx.dirty = true
+	}
+
+	seen := make(map[string]bool, len(files))
+	var changed []File
+	for _, f := range files {
+		seen[f.Path] = true
+		if e, ok := vx.files[f.Path]; ok && e.Size == f.Size && e.ModTime == f.ModTime.UnixNano() && e.Flags&flagRacy == 0 {
+			continue
+		}
+		changed = append(changed, f)
+	}
+	for path := range vx.files {
+		if !seen[path] {
+			delete(vx.files, path)
+			stats.Removed++
+			vx.dirty = true
+		}
+	}
+
+	// Chunks of several files are embedded together, committing each file once all
+	// of its chunks have vectors
+	var pending []*vectorEntry
+	var texts []string
+	flush := func() error {
+		if len(texts) > 0 {
+			vectors, err := embedder.Embed(ctx, texts)
+			if err != nil {
+				return err
+			}
+			if len(vectors) != len(texts) {
+				return fmt.Errorf("embedding model '%s' returned %d vectors for %d texts", embedder.Model(), len(vectors), len(texts))
+			}
+			i := 0
+			for _, e := range pending {
+				for j := range e.Chunks {
+					e.Chunks[j].Vector = NormalizeVector(vectors[i])
+					i++
+				}
+			}
+		}
+		for _, e := range pending {
+			vx.files[e.Path] = e
+			stats.Added++
+			vx.dirty = true
+		}
+		pending, texts = pending[:0], texts[:0]
+		return nil
+	}
+
+	for _, f := range changed {
+		if err := ctx.Err(); err != nil {
+			return vx.finish(stats, start), err
+		}
+		e := vx.readFile(f, chunk, start)
+		pending = append(pending, e)
+		for _, c := range e.Chunks {
+			texts = append(texts, embeddingInput(e.Path, c))
+		}
+		if len(texts) >= embedBatchSize {
+			if err := flush(); err != nil {
+				return vx.finish(stats, start), err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return vx.finish(stats, start), err
+	}
+	if vx.updatedAt.IsZero() {
+		vx.dirty = true
+	}
+	return vx.finish(stats, start), nil
+}
+
+// finish stamps the update time of a sync that changed the index
+func (vx *VectorIndex) finish(stats SyncStats, start time.Time) SyncStats {
+	if vx.dirty {
+		vx.updatedAt = time.Now()
+	}
+	stats.Elapsed = time.Since(start)
+	return stats
+}
+
+// readFile chunks one file. Binary, oversized and unreadable files get no chunks.
+func (vx *VectorIndex) readFile(f File, chunk Chunker, syncStart time.Time) *vectorEntry {
+	e := &vectorEntry{Path: f.Path, Size: f.Size, ModTime: f.ModTime.UnixNano()}
+	if syncStart.Sub(f.ModTime) < racyWindow {
+		e.Flags |= flagRacy
+	}
+	if f.Size > maxEmbeddedFileSize {
+		e.Flags |= flagTooLarge
+		return e
+	}
+
+	data, err := os.ReadFile(filepath.Join(vx.root, filepath.FromSlash(f.Path)))
+	if err != nil {
+		e.Flags |= flagUnreadable | flagRacy
+		return e
+	}
+	if bytes.IndexByte(data[:min(len(data), binarySniffSize)], 0) >= 0 {
+		e.Flags |= flagBinary
+		return e
+	}
+	e.Chunks = chunk(f.Path, data)
+	return e
+}
+
+// embeddingInput is the text embedded for a chunk; the path and symbol give the
+// model context the chunk itself may lack
+func embeddingInput(path string, c Chunk) string {
+	if c.Symbol != "" {
+		return path + " " + c.Symbol + "\n" + c.Text
+	}
+	return path + "\n" + c.Text
+}
+
+// Search returns the k chunks most similar to the query vector, best first, among
+// the files accepted by filter (nil accepts all)
+func (vx *VectorIndex) Search(query []float32, k int, filter func(path string) bool) []Hit {
+	query = NormalizeVector(append([]float32(nil), query...))
+	var hits []Hit
+	for path, e := range vx.files {
+		if filter != nil && !filter(path) {
+			continue
+		}
+		for _, c := range e.Chunks {
+			hits = append(hits, Hit{Path: path, Chunk: c, Score: dot(query, c.Vector)})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Path != hits[j].Path {
+			return hits[i].Path < hits[j].Path
+		}
+		return hits[i].Chunk.StartLine < hits[j].Chunk.StartLine
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// Model returns the embedding model of the stored vectors
+func (vx *VectorIndex) Model() string {
+	return vx.model
+}
+
+// Chunks returns the number of embedded chunks
+func (vx *VectorIndex) Chunks() int {
+	n := 0
+	for _, e := range vx.files {
+		n += len(e.Chunks)
+	}
+	return n
+}
+
+// Save writes the index if it changed
+func (vx *VectorIndex) Save() error {
+	if !vx.dirty {
+		return nil
+	}
+
+	paths := make([]string, 0, len(vx.files))
+	for path := range vx.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	p := persistedVectors{
+		Version:   vectorFormatVersion,
+		Model:     vx.model,
+		Files:     make([]vectorEntry, 0, len(paths)),
+		Vectors:   make([][]byte, 0, len(paths)),
+		UpdatedAt: vx.updatedAt,
+	}
+	for _, path := range paths {
+		e := *vx.files[path]
+		e.Chunks = append([]Chunk(nil), e.Chunks...)
+		var vectors [][]float32
+		for i := range e.Chunks {
+			vectors = append(vectors, e.Chunks[i].Vector)
+			e.Chunks[i].Vector = nil
+		}
+		p.Files = append(p.Files, e)
+		p.Vectors = append(p.Vectors, encodeVectors(vectors))
+	}
+
+	if err := writeIndexFile(vx.root, vectorFile, &p); err != nil {
+		return err
+	}
+	vx.dirty = false
+	return nil
+}
+
+// encodeVectors packs vectors of one length as a length prefix and float32 bits
+func encodeVectors(vectors [][]float32) []byte {
+	if len(vectors) == 0 {
+		return nil
+	}
+	dims := len(vectors[0])
+	buf := binary.AppendUvarint(nil, uint64(dims))
+	for _, v := range vectors {
+		for i := 0; i < dims; i++ {
+			var x float32
+			if i < len(v) {
+				x = v[i]
+			}
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(x))
+		}
+	}
+	return buf
+}
+
+// decodeVectors unpacks n vectors written by encodeVectors
+func decodeVectors(buf []byte, n int) [][]float32 {
+	vectors := make([][]float32, n)
+	dims, size := binary.Uvarint(buf)
+	if n == 0 || size <= 0 || len(buf)-size != n*int(dims)*4 {
+		return vectors
+	}
+	buf = buf[size:]
+	for i := range vectors {
+		v := make([]float32, dims)
+		for j := range v {
+			v[j] = math.Float32frombits(binary.LittleEndian.Uint32(buf))
+			buf = buf[4:]
+		}
+		vectors[i] = v
+	}
+	return vectors
+}
+
+// NormalizeVector scales v to unit length in place, so cosine similarity is a dot
+// product; a zero vector is left as is
+func NormalizeVector(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	scale := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= scale
+	}
+	return v
+}
+
+// dot returns the dot product of two vectors, 0 if their lengths differ
+func dot(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
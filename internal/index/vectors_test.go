@@ -0,0 +1,207 @@
+package index_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/index"
+	"github.com/shizhMSFT/wink-code/internal/llm"
+)
+
+// countingEmbedder records how many texts it embeds and can fail after a number of calls
+type countingEmbedder struct {
+	*llm.StubEmbedder
+	model    string
+	texts    int
+	failFrom int
+	calls    int
+}
+
+func (e *countingEmbedder) Model() string {
+	return e.model
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	if e.failFrom > 0 && e.calls >= e.failFrom {
+		return nil, errors.New("embedding model unavailable")
+	}
+	e.texts += len(texts)
+	return e.StubEmbedder.Embed(ctx, texts)
+}
+
+// shortEmbedder drops the last vector of every batch, like a misbehaving model server
+type shortEmbedder struct {
+	*llm.StubEmbedder
+}
+
+func (e shortEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors, err := e.StubEmbedder.Embed(ctx, texts)
+	return vectors[:len(vectors)-1], err
+}
+
+// paragraphs chunks a file at blank lines
+func paragraphs(path string, src []byte) []index.Chunk {
+	var chunks []index.Chunk
+	line := 1
+	for _, p := range strings.Split(string(src), "\n\n") {
+		n := strings.Count(p, "\n")
+		if strings.TrimSpace(p) != "" {
+			chunks = append(chunks, index.Chunk{StartLine: line, EndLine: line + n, Text: p})
+		}
+		line += n + 2
+	}
+	return chunks
+}
+
+// backdate moves modification times out of the racy window so unchanged files are not re-read
+func backdate(t *testing.T, root string, paths ...string) {
+	t.Helper()
+	old := time.Now().Add(-time.Hour)
+	for _, p := range paths {
+		if err := os.Chtimes(filepath.Join(root, filepath.FromSlash(p)), old, old); err != nil {
+			t.Fatalf("Chtimes failed: %v", err)
+		}
+	}
+}
+
+// TestVectorIndex tests embedding, search, incremental updates and persistence
+func TestVectorIndex(t *testing.T) {
+	root := workspace(t, map[string]string{
+		"paths.go":  "// validate paths inside the workspace\n\nfunc validatePath(p string) error",
+		"render.go": "// render markdown output\n\nfunc renderMarkdown(doc string)",
+		"logo.png":  "\x89PNG\x00\x00validate path",
+	})
+	backdate(t, root, "paths.go", "render.go", "logo.png")
+	ctx := context.Background()
+	embedder := &countingEmbedder{StubEmbedder: llm.NewStubEmbedder(128), model: "stub"}
+
+	vx, err := index.OpenVectors(root)
+	if err != nil {
+		t.Fatalf("OpenVectors failed: %v", err)
+	}
+	stats, err := vx.Sync(ctx, list(t, root), paragraphs, embedder)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if stats.Added != 3 || vx.Chunks() != 4 || embedder.texts != 4 {
+		t.Errorf("expected 4 chunks from 3 files, got %+v with %d chunks and %d texts embedded", stats, vx.Chunks(), embedder.texts)
+	}
+	if err := vx.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if !index.VectorsExist(root) {
+		t.Fatal("expected the vector index to exist after Save")
+	}
+
+	query, _ := embedder.StubEmbedder.Embed(ctx, []string{"where do we validate paths"})
+	hits := vx.Search(query[0], 3, nil)
+	if len(hits) != 3 || hits[0].Path != "paths.go" || hits[1].Path != "paths.go" || hits[2].Score > hits[1].Score {
+		t.Fatalf("expected the paths.go chunks first, got %+v", hits)
+	}
+	if hits := vx.Search(query[0], 10, func(path string) bool { return path == "render.go" }); len(hits) != 2 || hits[0].Path != "render.go" {
+		t.Errorf("expected only render.go chunks with a filter, got %+v", hits)
+	}
+
+	// A reopened index has the same vectors and only embeds what changed
+	write(t, root, "render.go", "// render markdown output\n\nfunc renderMarkdown(doc string, width int)")
+	backdate(t, root, "render.go")
+	if err := os.Remove(filepath.Join(root, "logo.png")); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	reopened, err := index.OpenVectors(root)
+	if err != nil {
+		t.Fatalf("OpenVectors failed: %v", err)
+	}
+	if reopened.Model() != "stub" || reopened.Chunks() != 4 {
+		t.Fatalf("expected the saved index back, got model %q with %d chunks", reopened.Model(), reopened.Chunks())
+	}
+	if got := reopened.Search(query[0], 2, nil); got[0].Path != hits[0].Path || got[0].Score != hits[0].Score {
+		t.Errorf("expected the same top hit after reopening, got %+v", got[0])
+	}
+	embedder.texts = 0
+	stats, err = reopened.Sync(ctx, list(t, root), paragraphs, embedder)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if stats.Added != 1 || stats.Removed != 1 || embedder.texts != 2 {
+		t.Errorf("expected only render.go embedded and logo.png removed, got %+v with %d texts", stats, embedder.texts)
+	}
+
+	// Switching models embeds everything again
+	other := &countingEmbedder{StubEmbedder: llm.NewStubEmbedder(64), model: "other"}
+	if stats, err = reopened.Sync(ctx, list(t, root), paragraphs, other); err != nil || stats.Added != 2 {
+		t.Errorf("expected both files embedded with a new model, got %+v, %v", stats, err)
+	}
+}
+
+// TestVectorIndexInterrupted tests that files embedded before a failure are kept
+func TestVectorIndexInterrupted(t *testing.T) {
+	files := map[string]string{}
+	var paths []string
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		path := name + ".md"
+		var text strings.Builder
+		for i := 0; i < 10; i++ {
+			text.WriteString("paragraph about " + name + "\n\n")
+		}
+		files[path] = text.String()
+		paths = append(paths, path)
+	}
+	root := workspace(t, files)
+	backdate(t, root, paths...)
+	ctx := context.Background()
+
+	vx, err := index.OpenVectors(root)
+	if err != nil {
+		t.Fatalf("OpenVectors failed: %v", err)
+	}
+	failing := &countingEmbedder{StubEmbedder: llm.NewStubEmbedder(32), model: "stub", failFrom: 2}
+	stats, err := vx.Sync(ctx, list(t, root), paragraphs, failing)
+	if err == nil {
+		t.Fatal("expected the embedding error")
+	}
+	if stats.Added == 0 || stats.Added == len(paths) {
+		t.Fatalf("expected some files embedded before the failure, got %+v", stats)
+	}
+	kept := stats.Added
+
+	embedder := &countingEmbedder{StubEmbedder: llm.NewStubEmbedder(32), model: "stub"}
+	stats, err = vx.Sync(ctx, list(t, root), paragraphs, embedder)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if stats.Added != len(paths)-kept {
+		t.Errorf("expected the remaining %d files embedded, got %+v", len(paths)-kept, stats)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	write(t, root, "k.md", "new paragraph\n")
+	if _, err := vx.Sync(cancelled, list(t, root), paragraphs, embedder); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled sync, got %v", err)
+	}
+}
+
+// TestVectorIndexShortEmbedding tests that missing vectors fail the sync instead of indexing garbage
+func TestVectorIndexShortEmbedding(t *testing.T) {
+	root := workspace(t, map[string]string{"a.md": "first paragraph\n\nsecond paragraph\n"})
+	backdate(t, root, "a.md")
+
+	vx, err := index.OpenVectors(root)
+	if err != nil {
+		t.Fatalf("OpenVectors failed: %v", err)
+	}
+	stats, err := vx.Sync(context.Background(), list(t, root), paragraphs, shortEmbedder{llm.NewStubEmbedder(32)})
+	if err == nil || !strings.Contains(err.Error(), "returned 1 vectors for 2 texts") {
+		t.Fatalf("expected a vector count error, got %v", err)
+	}
+	if stats.Added != 0 || vx.Chunks() != 0 {
+		t.Errorf("expected nothing indexed, got %+v with %d chunks", stats, vx.Chunks())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/sashabaranov/go-openai"
@@ -18,6 +19,11 @@ type Client struct {
 	client           *openai.Client
 	model            string
 	timeout          time.Duration
+	options          types.GenerationOptions
+	estimator        *TokenEstimator
+	includeReasoning bool
+	contextLimit     int
+	keepAlive        interface{}
 	totalTokens      int
 	promptTokens     int
 	completionTokens int
@@ -27,11 +33,13 @@ type Client struct {
 func NewClient(baseURL, model string, timeoutSeconds int) *Client {
 	config := openai.DefaultConfig("ollama") // Ollama doesn't require real API key
 	config.BaseURL = baseURL + "/v1"
+	config.HTTPClient = &extensionDoer{client: &http.Client{}}
 
 	return &Client{
 		client:           openai.NewClientWithConfig(config),
 		model:            model,
 		timeout:          time.Duration(timeoutSeconds) * time.Second,
+		estimator:        NewTokenEstimator(nil),
 		totalTokens:      0,
 		promptTokens:     0,
 		completionTokens: 0,
@@ -52,6 +60,22 @@ func (c *Client) ChatCompletion(ctx context.Context, messages []types.Message, t
 			Content: msg.Content,
 		}
 
+		// Tool results must reference the call they answer
+		if id, ok := msg.Metadata["tool_call_id"].(string); ok && msg.Role == types.MessageRoleTool {
+			openaiMsg.ToolCallID = id
+		}
+
+		// Reasoning is kept out of the context unless explicitly requested
+		if reasoning, ok := msg.Metadata["reasoning"].(string); ok && c.includeReasoning && reasoning != "" {
+			openaiMsg.Content = thinkOpenTag + "\n" + reasoning + "\n" + thinkCloseTag + "\n\n" + msg.Content
+		}
+
+		// Multi-part messages replace Content with a list of parts
+		if len(msg.Parts) > 0 {
+			openaiMsg.MultiContent = convertContentParts(openaiMsg.Content, msg.Parts)
+			openaiMsg.Content = ""
+		}
+
 		// Add tool calls if present
 		if len(msg.ToolCalls) > 0 {
 			openaiMsg.ToolCalls = make([]openai.ToolCall, 0, len(msg.ToolCalls))
@@ -83,11 +107,19 @@ func (c *Client) ChatCompletion(ctx context.Context, messages []types.Message, t
 		})
 	}
 
+	// Refuse requests that can't fit instead of letting the server silently truncate them
+	estimatedTokens := c.EstimateRequestTokens(messages, tools)
+	if c.contextLimit > 0 && estimatedTokens > c.contextLimit {
+		return nil, &ContextOverflowError{EstimatedTokens: estimatedTokens, ContextLimit: c.contextLimit}
+	}
+
 	// Log request
 	logging.Debug("LLM API request",
 		"model", c.model,
 		"message_count", len(openaiMessages),
 		"tool_count", len(openaiTools),
+		"estimated_tokens", estimatedTokens,
+		"context_limit", c.contextLimit,
 	)
 
 	// Create request
@@ -96,9 +128,19 @@ func (c *Client) ChatCompletion(ctx context.Context, messages []types.Message, t
 		Messages: openaiMessages,
 		Tools:    openaiTools,
 	}
+	applyGenerationOptions(&req, c.options)
+	extraFields := generationExtraFields(c.options)
+	if c.keepAlive != nil {
+		extraFields["keep_alive"] = c.keepAlive
+	}
+	ctx = withExtraFields(ctx, extraFields)
 
 	// Start progress indicator
-	progress := ui.NewProgressIndicator("Waiting for LLM response")
+	progressMessage := "Waiting for LLM response"
+	if c.contextLimit > 0 {
+		progressMessage = fmt.Sprintf("Waiting for LLM response (context %d%% full)", estimatedTokens*100/c.contextLimit)
+	}
+	progress := ui.NewProgressIndicator(progressMessage)
 	progress.Start()
 	defer progress.Stop()
 
@@ -139,11 +181,96 @@ func (c *Client) Model() string {
 	return c.model
 }
 
+// Timeout returns the time allowed for each chat completion response
+func (c *Client) Timeout() time.Duration {
+	return c.timeout
+}
+
+// SetKeepAlive sets how long Ollama keeps the model loaded after each request
+// (a value returned by ParseKeepAlive, nil for the server default)
+func (c *Client) SetKeepAlive(keepAlive interface{}) {
+	c.keepAlive = keepAlive
+}
+
+// SetGenerationOptions sets the sampling options sent with every request
+func (c *Client) SetGenerationOptions(opts types.GenerationOptions) {
+	c.options = opts
+}
+
+// GenerationOptions returns the sampling options sent with every request
+func (c *Client) GenerationOptions() types.GenerationOptions {
+	return c.options
+}
+
+// SetIncludeReasoning controls whether earlier reasoning is sent back in later requests
+func (c *Client) SetIncludeReasoning(include bool) {
+	c.includeReasoning = include
+}
+
+// SetTokenizer replaces the tokenizer used to estimate request sizes
+func (c *Client) SetTokenizer(tokenizer Tokenizer) {
+	c.estimator = NewTokenEstimator(tokenizer)
+}
+
+// SetContextLimit sets the model's context window in tokens (0 disables the check)
+func (c *Client) SetContextLimit(tokens int) {
+	c.contextLimit = tokens
+}
+
+// ContextLimit returns the model's context window in tokens (0 if unknown)
+func (c *Client) ContextLimit() int {
+	return c.contextLimit
+}
+
+// PromptBudget returns the tokens available for the prompt, reserving room for the
+// response (max_tokens if set, otherwise a tenth of the context). 0 means unlimited.
+func (c *Client) PromptBudget() int {
+	if c.contextLimit <= 0 {
+		return 0
+	}
+	reserve := c.contextLimit / 10
+	if c.options.MaxTokens != nil && *c.options.MaxTokens < c.contextLimit {
+		reserve = *c.options.MaxTokens
+	}
+	return c.contextLimit - reserve
+}
+
+// EstimateRequestTokens estimates the prompt tokens of a request before sending it
+func (c *Client) EstimateRequestTokens(messages []types.Message, tools []types.Tool) int {
+	return c.estimator.EstimateMessages(messages) + c.estimator.EstimateTools(tools)
+}
+
 // GetTokenUsage returns cumulative token usage statistics
 func (c *Client) GetTokenUsage() (total, prompt, completion int) {
 	return c.totalTokens, c.promptTokens, c.completionTokens
 }
 
+// convertContentParts converts text and image parts to OpenAI message parts
+func convertContentParts(content string, parts []types.ContentPart) []openai.ChatMessagePart {
+	result := make([]openai.ChatMessagePart, 0, len(parts)+1)
+	if content != "" {
+		result = append(result, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: content})
+	}
+
+	for _, part := range parts {
+		switch part.Type {
+		case types.ContentPartText:
+			result = append(result, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: part.Text})
+		case types.ContentPartImage:
+			result = append(result, openai.ChatMessagePart{
+				Type: openai.ChatMessagePartTypeImageURL,
+				ImageURL: &openai.ChatMessageImageURL{
+					URL: "data:" + part.MIMEType + ";base64," + part.Data,
+				},
+			})
+		default:
+			logging.Warn("Skipping unknown content part type", "type", part.Type)
+		}
+	}
+
+	return result
+}
+
 // mustMarshalJSON marshals to JSON string, panics on error (should never happen with valid data)
 func mustMarshalJSON(v interface{}) string {
 	// OpenAI SDK expects JSON string for function arguments
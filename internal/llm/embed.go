@@ -0,0 +1,114 @@
+// Package llm provides text embedders for semantic search
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/shizhMSFT/wink-code/internal/index"
+)
+
+// DefaultEmbeddingModel is the Ollama model used for semantic search unless configured
+const DefaultEmbeddingModel = "nomic-embed-text"
+
+// OllamaEmbedder embeds texts with a local Ollama embedding model
+type OllamaEmbedder struct {
+	client *OllamaClient
+	model  string
+}
+
+// NewOllamaEmbedder creates an embedder for an Ollama model ("" uses DefaultEmbeddingModel)
+func NewOllamaEmbedder(client *OllamaClient, model string) *OllamaEmbedder {
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	return &OllamaEmbedder{client: client, model: model}
+}
+
+// Model returns the embedding model name
+func (e *OllamaEmbedder) Model() string {
+	return e.model
+}
+
+// Embed returns the embeddings of texts
+func (e *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors, err := e.client.Embed(ctx, e.model, texts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed with '%s' (pull it with 'wink models pull %s'): %w", e.model, e.model, err)
+	}
+	return vectors, nil
+}
+
+// StubEmbedder is a deterministic embedder for tests that needs no model: it hashes
+// the words of a text, split at camelCase and snake_case boundaries, into a fixed
+// number of dimensions, so texts sharing words are similar
+type StubEmbedder struct {
+	dims int
+}
+
+// NewStubEmbedder creates a stub embedder producing vectors of dims dimensions
+func NewStubEmbedder(dims int) *StubEmbedder {
+	return &StubEmbedder{dims: dims}
+}
+
+// Model returns the stub model name
+func (e *StubEmbedder) Model() string {
+	return "stub"
+}
+
+// Embed returns the hashed word vectors of texts
+func (e *StubEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		v := make([]float32, e.dims)
+		for _, word := range splitWords(text) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			sum := h.Sum32()
+			if sum&1 == 0 {
+				v[int(sum>>1)%e.dims]++
+			} else {
+				v[int(sum>>1)%e.dims]--
+			}
+		}
+		vectors[i] = index.NormalizeVector(v)
+	}
+	return vectors, nil
+}
+
+// splitWords lower-cases the words of text, splitting identifiers into their parts
+// and dropping a plural "s" so "paths" and "validatePath" share "path"
+func splitWords(text string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 1 {
+			w := strings.ToLower(string(word))
+			if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+				w = w[:len(w)-1]
+			}
+			words = append(words, w)
+		}
+		word = word[:0]
+	}
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			word = append(word, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return words
+}
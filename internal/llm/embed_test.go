@@ -0,0 +1,53 @@
+package llm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/llm"
+)
+
+// cosine returns the similarity of two unit vectors
+func cosine(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func TestStubEmbedder(t *testing.T) {
+	embedder := llm.NewStubEmbedder(256)
+	texts := []string{
+		"where do we validate paths",
+		"func validatePath(workingDir, path string) error",
+		"func renderMarkdown(w io.Writer, doc string)",
+	}
+
+	first, err := embedder.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	second, err := embedder.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	for i := range first {
+		if len(first[i]) != 256 {
+			t.Fatalf("expected 256 dimensions, got %d", len(first[i]))
+		}
+		for j := range first[i] {
+			if first[i][j] != second[i][j] {
+				t.Fatalf("expected identical vectors for %q", texts[i])
+			}
+		}
+	}
+
+	related, unrelated := cosine(first[0], first[1]), cosine(first[0], first[2])
+	if related <= unrelated {
+		t.Errorf("expected the query closer to validatePath (%.2f) than to renderMarkdown (%.2f)", related, unrelated)
+	}
+	if self := cosine(first[1], first[1]); self < 0.999 || self > 1.001 {
+		t.Errorf("expected unit vectors, got length %.3f", self)
+	}
+}
@@ -0,0 +1,437 @@
+// Package llmtest provides a scriptable fake LLM server for tests. It speaks the
+// OpenAI chat completions protocol (as served by Ollama under /v1) plus the Ollama
+// show, tags, ps and model-loading APIs, and answers each chat request with the
+// next scripted step.
+package llmtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Request is a chat completion request received by the server
+type Request struct {
+	openai.ChatCompletionRequest
+	// Raw is the decoded JSON body, including fields the OpenAI types don't model
+	Raw map[string]interface{}
+}
+
+// LastMessage returns the final message of the request
+func (r *Request) LastMessage() openai.ChatCompletionMessage {
+	if len(r.Messages) == 0 {
+		return openai.ChatCompletionMessage{}
+	}
+	return r.Messages[len(r.Messages)-1]
+}
+
+// HasTool reports whether a tool with the given name was offered to the model
+func (r *Request) HasTool(name string) bool {
+	for _, tool := range r.Tools {
+		if tool.Function != nil && tool.Function.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ToolCall is a scripted tool call returned by the model
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments map[string]interface{}
+}
+
+// Step is one scripted exchange: optional assertions on the request and the response
+type Step struct {
+	expect           func(t testing.TB, req *Request)
+	content          string
+	toolCalls        []ToolCall
+	chunks           []string
+	status           int
+	errorMessage     string
+	delay            time.Duration
+	promptTokens     int
+	completionTokens int
+}
+
+// Reply scripts a plain assistant answer
+func Reply(content string) Step {
+	return Step{content: content, promptTokens: 10, completionTokens: 5}
+}
+
+// CallTools scripts an assistant message requesting one or more tool calls
+func CallTools(calls ...ToolCall) Step {
+	return Step{toolCalls: calls, promptTokens: 10, completionTokens: 5}
+}
+
+// CallTool scripts an assistant message requesting a single tool call
+func CallTool(name string, args map[string]interface{}) Step {
+	return CallTools(ToolCall{Name: name, Arguments: args})
+}
+
+// Stream scripts an answer delivered as separate content chunks when the client streams
+// (non-streaming requests receive the concatenated content)
+func Stream(chunks ...string) Step {
+	return Step{content: strings.Join(chunks, ""), chunks: chunks, promptTokens: 10, completionTokens: len(chunks)}
+}
+
+// Fail scripts an HTTP error response in the OpenAI error format
+func Fail(status int, message string) Step {
+	return Step{status: status, errorMessage: message}
+}
+
+// Expect adds assertions run against the request before responding
+func (s Step) Expect(fn func(t testing.TB, req *Request)) Step {
+	s.expect = fn
+	return s
+}
+
+// After delays the response, e.g. to exercise client timeouts
+func (s Step) After(delay time.Duration) Step {
+	s.delay = delay
+	return s
+}
+
+// WithUsage sets the token usage reported for the step
+func (s Step) WithUsage(promptTokens, completionTokens int) Step {
+	s.promptTokens = promptTokens
+	s.completionTokens = completionTokens
+	return s
+}
+
+// Server is a scripted fake LLM server
+type Server struct {
+	*httptest.Server
+
+	t            testing.TB
+	mu           sync.Mutex
+	script       []Step
+	next         int
+	requests     []*Request
+	capabilities []string
+	contextLen   int
+	loadDelay    time.Duration
+	loaded       bool
+	loads        []map[string]interface{}
+}
+
+// NewServer starts a server that answers chat requests with the given steps in order.
+// The server is closed and checked for unconsumed steps when the test ends.
+func NewServer(t testing.TB, steps ...Step) *Server {
+	t.Helper()
+
+	s := &Server{
+		t:            t,
+		script:       steps,
+		capabilities: []string{"completion", "tools"},
+		contextLen:   32768,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", s.handleChat)
+	mux.HandleFunc("/api/show", s.handleShow)
+	mux.HandleFunc("/api/tags", s.handleTags)
+	mux.HandleFunc("/api/ps", s.handlePs)
+	mux.HandleFunc("/api/generate", s.handleGenerate)
+	s.Server = httptest.NewServer(mux)
+
+	t.Cleanup(func() {
+		s.Close()
+		s.AssertDone()
+	})
+
+	return s
+}
+
+// SetCapabilities sets the capabilities and context length reported by /api/show
+func (s *Server) SetCapabilities(contextLength int, capabilities ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.capabilities = capabilities
+	s.contextLen = contextLength
+}
+
+// SetLoadDelay makes loading the model take the given time
+func (s *Server) SetLoadDelay(delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadDelay = delay
+}
+
+// SetLoaded sets whether the ps API reports the model as already loaded
+func (s *Server) SetLoaded(loaded bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loaded = loaded
+}
+
+// Loads returns the bodies of the model load requests received so far
+func (s *Server) Loads() []map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]interface{}(nil), s.loads...)
+}
+
+// Requests returns the chat requests received so far
+func (s *Server) Requests() []*Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Request(nil), s.requests...)
+}
+
+// AssertDone reports an error if scripted steps were never requested
+func (s *Server) AssertDone() {
+	s.t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.next < len(s.script) {
+		s.t.Errorf("llmtest: %d of %d scripted steps were not consumed", len(s.script)-s.next, len(s.script))
+	}
+}
+
+// handleChat answers a chat completion request with the next step
+func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
+	var raw map[string]interface{}
+	var req Request
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&raw); err != nil {
+		s.t.Errorf("llmtest: invalid request body: %v", err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	data, _ := json.Marshal(raw)
+	if err := json.Unmarshal(data, &req.ChatCompletionRequest); err != nil {
+		s.t.Errorf("llmtest: invalid chat completion request: %v", err)
+		http.Error(w, "invalid chat completion request", http.StatusBadRequest)
+		return
+	}
+	req.Raw = raw
+
+	s.mu.Lock()
+	s.requests = append(s.requests, &req)
+	if s.next >= len(s.script) {
+		s.mu.Unlock()
+		s.t.Errorf("llmtest: unexpected request #%d (only %d steps scripted), last message: %q",
+			len(s.requests), len(s.script), req.LastMessage().Content)
+		writeError(w, http.StatusInternalServerError, "no scripted response")
+		return
+	}
+	step := s.script[s.next]
+	s.next++
+	s.mu.Unlock()
+
+	if step.expect != nil {
+		step.expect(s.t, &req)
+	}
+
+	if step.delay > 0 {
+		select {
+		case <-time.After(step.delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if step.status != 0 {
+		writeError(w, step.status, step.errorMessage)
+		return
+	}
+
+	if req.Stream {
+		s.writeStream(w, step)
+		return
+	}
+
+	message := openai.ChatCompletionMessage{
+		Role:      openai.ChatMessageRoleAssistant,
+		Content:   step.content,
+		ToolCalls: step.openaiToolCalls(),
+	}
+	finishReason := openai.FinishReasonStop
+	if len(message.ToolCalls) > 0 {
+		finishReason = openai.FinishReasonToolCalls
+	}
+
+	resp := openai.ChatCompletionResponse{
+		ID:      fmt.Sprintf("chatcmpl-%d", len(s.requests)),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []openai.ChatCompletionChoice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: finishReason,
+		}},
+		Usage: openai.Usage{
+			PromptTokens:     step.promptTokens,
+			CompletionTokens: step.completionTokens,
+			TotalTokens:      step.promptTokens + step.completionTokens,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// writeStream sends the step as server-sent events
+func (s *Server) writeStream(w http.ResponseWriter, step Step) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(delta openai.ChatCompletionStreamChoiceDelta, finishReason openai.FinishReason) {
+		chunk := openai.ChatCompletionStreamResponse{
+			ID:     "chatcmpl-stream",
+			Object: "chat.completion.chunk",
+			Choices: []openai.ChatCompletionStreamChoice{{
+				Index:        0,
+				Delta:        delta,
+				FinishReason: finishReason,
+			}},
+		}
+		data, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	chunks := step.chunks
+	if len(chunks) == 0 && step.content != "" {
+		chunks = []string{step.content}
+	}
+	for i, chunk := range chunks {
+		delta := openai.ChatCompletionStreamChoiceDelta{Content: chunk}
+		if i == 0 {
+			delta.Role = openai.ChatMessageRoleAssistant
+		}
+		send(delta, "")
+	}
+
+	finishReason := openai.FinishReasonStop
+	if toolCalls := step.openaiToolCalls(); len(toolCalls) > 0 {
+		for i := range toolCalls {
+			index := i
+			toolCalls[i].Index = &index
+		}
+		send(openai.ChatCompletionStreamChoiceDelta{ToolCalls: toolCalls}, "")
+		finishReason = openai.FinishReasonToolCalls
+	}
+	send(openai.ChatCompletionStreamChoiceDelta{}, finishReason)
+
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// handleShow answers Ollama's show API with the configured capabilities
+func (s *Server) handleShow(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"details":      map[string]string{"family": "fake", "parameter_size": "1B"},
+		"model_info":   map[string]interface{}{"fake.context_length": s.contextLen},
+		"capabilities": s.capabilities,
+	})
+}
+
+// handleTags answers Ollama's tags API with a single fake model
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"models":[{"name":"fake:latest","size":1024,"details":{"family":"fake","parameter_size":"1B"}}]}`)
+}
+
+// handlePs answers Ollama's ps API, listing the fake model once it is loaded
+func (s *Server) handlePs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	loaded := s.loaded
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !loaded {
+		fmt.Fprint(w, `{"models":[]}`)
+		return
+	}
+	fmt.Fprint(w, `{"models":[{"name":"fake:latest","model":"fake:latest","size":1024,"size_vram":1024}]}`)
+}
+
+// handleGenerate answers prompt-less generate requests, which only load the model
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if prompt, _ := body["prompt"].(string); prompt != "" {
+		s.t.Errorf("llmtest: generate with a prompt is not supported")
+		http.Error(w, "generate with a prompt is not supported", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	s.loads = append(s.loads, body)
+	delay := s.loadDelay
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	s.mu.Lock()
+	s.loaded = true
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"model":%q,"response":"","done":true,"done_reason":"load"}`, body["model"])
+}
+
+// openaiToolCalls converts scripted tool calls, generating IDs where missing
+func (s Step) openaiToolCalls() []openai.ToolCall {
+	if len(s.toolCalls) == 0 {
+		return nil
+	}
+
+	calls := make([]openai.ToolCall, 0, len(s.toolCalls))
+	for i, tc := range s.toolCalls {
+		id := tc.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i+1)
+		}
+		args, _ := json.Marshal(tc.Arguments)
+		calls = append(calls, openai.ToolCall{
+			ID:   id,
+			Type: openai.ToolTypeFunction,
+			Function: openai.FunctionCall{
+				Name:      tc.Name,
+				Arguments: string(args),
+			},
+		})
+	}
+	return calls
+}
+
+// writeError writes an OpenAI-style error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    "api_error",
+		},
+	})
+}
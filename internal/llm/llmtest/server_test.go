@@ -0,0 +1,111 @@
+package llmtest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/shizhMSFT/wink-code/internal/llm"
+	"github.com/shizhMSFT/wink-code/internal/llm/llmtest"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+func TestServerScript(t *testing.T) {
+	server := llmtest.NewServer(t,
+		llmtest.CallTool("read_file", map[string]interface{}{"path": "a.txt"}),
+		llmtest.Reply("done").WithUsage(42, 7),
+	)
+	client := llm.NewClient(server.URL, "fake:latest", 5)
+	messages := []types.Message{{Role: types.MessageRoleUser, Content: "read a.txt"}}
+
+	resp, err := client.ChatCompletion(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	calls := resp.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].Function.Name != "read_file" || calls[0].ID != "call_1" {
+		t.Fatalf("Unexpected tool calls: %+v", calls)
+	}
+	if calls[0].Function.Arguments != `{"path":"a.txt"}` {
+		t.Errorf("Unexpected arguments: %s", calls[0].Function.Arguments)
+	}
+
+	resp, err = client.ChatCompletion(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "done" || resp.Usage.PromptTokens != 42 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+	if n := len(server.Requests()); n != 2 {
+		t.Errorf("Expected 2 recorded requests, got %d", n)
+	}
+}
+
+func TestServerStream(t *testing.T) {
+	server := llmtest.NewServer(t, llmtest.Stream("Hel", "lo"))
+	config := openai.DefaultConfig("")
+	config.BaseURL = server.URL + "/v1"
+	client := openai.NewClientWithConfig(config)
+
+	stream, err := client.CreateChatCompletionStream(context.Background(), openai.ChatCompletionRequest{
+		Model:    "fake:latest",
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+		Stream:   true,
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	var content string
+	var chunks int
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			content += chunk.Choices[0].Delta.Content
+			chunks++
+		}
+	}
+	if content != "Hello" || chunks != 2 {
+		t.Errorf("Expected 2 chunks forming %q, got %d chunks %q", "Hello", chunks, content)
+	}
+}
+
+func TestServerErrorsAndDelays(t *testing.T) {
+	server := llmtest.NewServer(t,
+		llmtest.Fail(500, "boom"),
+		llmtest.Reply("late").After(2*time.Second),
+	)
+	client := llm.NewClient(server.URL, "fake:latest", 1)
+	messages := []types.Message{{Role: types.MessageRoleUser, Content: "hi"}}
+
+	if _, err := client.ChatCompletion(context.Background(), messages, nil); err == nil {
+		t.Error("Expected error for failed step")
+	}
+	if _, err := client.ChatCompletion(context.Background(), messages, nil); err == nil {
+		t.Error("Expected timeout for delayed step")
+	}
+}
+
+func TestServerShow(t *testing.T) {
+	server := llmtest.NewServer(t)
+	server.SetCapabilities(8192, llm.CapabilityCompletion, llm.CapabilityVision)
+
+	info, err := llm.NewOllamaClient(server.URL).ShowModel(context.Background(), "fake:latest")
+	if err != nil {
+		t.Fatalf("ShowModel failed: %v", err)
+	}
+	if info.ContextLength != 8192 || info.SupportsTools() || !info.HasCapability(llm.CapabilityVision) {
+		t.Errorf("Unexpected model info: %+v", info)
+	}
+}
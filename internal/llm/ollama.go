@@ -0,0 +1,379 @@
+// Package llm provides access to Ollama's native model management API
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/logging"
+)
+
+// Model capabilities reported by Ollama's show API
+const (
+	CapabilityCompletion = "completion"
+	CapabilityTools      = "tools"
+	CapabilityVision     = "vision"
+	CapabilityThinking   = "thinking"
+	CapabilityEmbedding  = "embedding"
+)
+
+// OllamaClient talks to Ollama's native HTTP API (/api/*)
+type OllamaClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// ModelDetails describes a model's format and quantization
+type ModelDetails struct {
+	Format            string `json:"format"`
+	Family            string `json:"family"`
+	ParameterSize     string `json:"parameter_size"`
+	QuantizationLevel string `json:"quantization_level"`
+}
+
+// ModelSummary is a locally available model as returned by the tags API
+type ModelSummary struct {
+	Name       string       `json:"name"`
+	Size       int64        `json:"size"`
+	Digest     string       `json:"digest"`
+	ModifiedAt time.Time    `json:"modified_at"`
+	Details    ModelDetails `json:"details"`
+}
+
+// ModelInfo holds detailed information about a single model
+type ModelInfo struct {
+	Name          string       `json:"name"`
+	Details       ModelDetails `json:"details"`
+	ContextLength int          `json:"context_length"`
+	Capabilities  []string     `json:"capabilities"`
+}
+
+// PullProgress reports the state of a model download
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RunningModel is a model currently loaded in memory as returned by the ps API
+type RunningModel struct {
+	Name      string    `json:"name"`
+	Model     string    `json:"model"`
+	Size      int64     `json:"size"`
+	SizeVRAM  int64     `json:"size_vram"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ModelLoadError reports that a model failed to load into memory
+type ModelLoadError struct {
+	Model   string
+	Timeout time.Duration
+	Err     error
+}
+
+// Error implements the error interface
+func (e *ModelLoadError) Error() string {
+	if e.TimedOut() {
+		return fmt.Sprintf("model '%s' did not finish loading within %s", e.Model, e.Timeout)
+	}
+	return fmt.Sprintf("failed to load model '%s': %v", e.Model, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ModelLoadError) Unwrap() error {
+	return e.Err
+}
+
+// TimedOut reports whether loading was cut short by the load timeout
+func (e *ModelLoadError) TimedOut() bool {
+	return errors.Is(e.Err, context.DeadlineExceeded)
+}
+
+// NewOllamaClient creates a client for Ollama's native API
+func NewOllamaClient(baseURL string) *OllamaClient {
+	return &OllamaClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		// No client-level timeout: pulls can take a long time, callers bound requests via context
+		httpClient: &http.Client{},
+	}
+}
+
+// HasCapability reports whether the model advertises the given capability
+func (m *ModelInfo) HasCapability(capability string) bool {
+	for _, c := range m.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsTools reports whether the model can perform tool calling
+func (m *ModelInfo) SupportsTools() bool {
+	return m.HasCapability(CapabilityTools)
+}
+
+// ListModels returns the models available locally
+func (o *OllamaClient) ListModels(ctx context.Context) ([]ModelSummary, error) {
+	var resp struct {
+		Models []ModelSummary `json:"models"`
+	}
+	if err := o.doJSON(ctx, http.MethodGet, "/api/tags", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Models, nil
+}
+
+// ShowModel returns details, context length and capabilities of a model
+func (o *OllamaClient) ShowModel(ctx context.Context, name string) (*ModelInfo, error) {
+	var resp struct {
+		Template      string                 `json:"template"`
+		Details       ModelDetails           `json:"details"`
+		ModelInfo     map[string]interface{} `json:"model_info"`
+		ProjectorInfo map[string]interface{} `json:"projector_info"`
+		Capabilities  []string               `json:"capabilities"`
+	}
+	if err := o.doJSON(ctx, http.MethodPost, "/api/show", map[string]interface{}{"model": name}, &resp); err != nil {
+		return nil, err
+	}
+
+	info := &ModelInfo{
+		Name:          name,
+		Details:       resp.Details,
+		ContextLength: contextLengthFromModelInfo(resp.ModelInfo),
+		Capabilities:  resp.Capabilities,
+	}
+
+	// Older Ollama versions don't report capabilities, so infer them
+	if len(info.Capabilities) == 0 {
+		info.Capabilities = []string{CapabilityCompletion}
+		if strings.Contains(resp.Template, ".Tools") {
+			info.Capabilities = append(info.Capabilities, CapabilityTools)
+		}
+		if len(resp.ProjectorInfo) > 0 {
+			info.Capabilities = append(info.Capabilities, CapabilityVision)
+		}
+	}
+
+	logging.Debug("Model info",
+		"model", name,
+		"context_length", info.ContextLength,
+		"capabilities", info.Capabilities,
+	)
+
+	return info, nil
+}
+
+// RunningModels returns the models currently loaded in memory
+func (o *OllamaClient) RunningModels(ctx context.Context) ([]RunningModel, error) {
+	var resp struct {
+		Models []RunningModel `json:"models"`
+	}
+	if err := o.doJSON(ctx, http.MethodGet, "/api/ps", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Models, nil
+}
+
+// IsLoaded reports whether the model is currently loaded in memory
+func (o *OllamaClient) IsLoaded(ctx context.Context, name string) (bool, error) {
+	models, err := o.RunningModels(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range models {
+		if m.Name == name || m.Model == name || m.Name == name+":latest" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// LoadModel loads a model into memory without generating anything, waiting at most
+// timeout (0 for no limit). keepAlive is a value returned by ParseKeepAlive (nil uses
+// the server default), and numCtx the context window to load it with (0 for the server
+// default), which must match later requests or Ollama loads the model again.
+// Failures are returned as *ModelLoadError.
+func (o *OllamaClient) LoadModel(ctx context.Context, name string, timeout time.Duration, keepAlive interface{}, numCtx int) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// A generate request without a prompt only loads the model
+	body := map[string]interface{}{"model": name}
+	if keepAlive != nil {
+		body["keep_alive"] = keepAlive
+	}
+	if numCtx > 0 {
+		body["options"] = map[string]interface{}{"num_ctx": numCtx}
+	}
+
+	startTime := time.Now()
+	if err := o.doJSON(ctx, http.MethodPost, "/api/generate", body, nil); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return &ModelLoadError{Model: name, Timeout: timeout, Err: err}
+	}
+
+	logging.Debug("Model loaded", "model", name, "duration_ms", time.Since(startTime).Milliseconds())
+	return nil
+}
+
+// Embed returns the embedding vectors of inputs, in order, computed by an embedding model
+func (o *OllamaClient) Embed(ctx context.Context, model string, inputs []string) ([][]float32, error) {
+	var resp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	body := map[string]interface{}{"model": model, "input": inputs}
+	if err := o.doJSON(ctx, http.MethodPost, "/api/embed", body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(resp.Embeddings), len(inputs))
+	}
+	return resp.Embeddings, nil
+}
+
+// ParseKeepAlive converts a keep_alive setting into the value sent to Ollama: a
+// duration such as "10m", or a number of seconds where a negative value keeps the
+// model loaded indefinitely and 0 unloads it right away. "" returns nil (server default).
+func ParseKeepAlive(value string) (interface{}, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return seconds, nil
+	}
+	if _, err := time.ParseDuration(value); err != nil {
+		return nil, fmt.Errorf("invalid keep_alive '%s': use a duration like 10m or a number of seconds (-1 keeps the model loaded)", value)
+	}
+	return value, nil
+}
+
+// PullModel downloads a model, reporting progress through fn
+func (o *OllamaClient) PullModel(ctx context.Context, name string, fn func(PullProgress)) error {
+	body, err := json.Marshal(map[string]interface{}{"model": name, "stream": true})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/api/pull", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return readAPIError(resp)
+	}
+
+	// The pull API streams newline-delimited JSON progress updates
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var progress PullProgress
+		if err := json.Unmarshal(line, &progress); err != nil {
+			return fmt.Errorf("failed to parse pull progress: %w", err)
+		}
+		if progress.Error != "" {
+			return fmt.Errorf("pull failed: %s", progress.Error)
+		}
+		if fn != nil {
+			fn(progress)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read pull progress: %w", err)
+	}
+
+	return nil
+}
+
+// doJSON sends a JSON request and decodes the JSON response into out
+func (o *OllamaClient) doJSON(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, o.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ollama API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return readAPIError(resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse ollama response: %w", err)
+	}
+	return nil
+}
+
+// readAPIError converts a non-200 response into an error
+func readAPIError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &apiErr); err == nil && apiErr.Error != "" {
+		return fmt.Errorf("ollama API error (%d): %s", resp.StatusCode, apiErr.Error)
+	}
+	return fmt.Errorf("ollama API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(data)))
+}
+
+// contextLengthFromModelInfo extracts "<arch>.context_length" from model_info
+func contextLengthFromModelInfo(modelInfo map[string]interface{}) int {
+	for key, value := range modelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if n, ok := value.(float64); ok {
+			return int(n)
+		}
+	}
+	return 0
+}
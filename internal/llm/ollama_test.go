@@ -0,0 +1,298 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/llm"
+)
+
+// newOllamaServer starts a fake Ollama server with the given handlers
+func newOllamaServer(t *testing.T, handlers map[string]http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	for path, h := range handlers {
+		mux.HandleFunc(path, h)
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOllamaClientListModels(t *testing.T) {
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/api/tags": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"models":[{"name":"qwen3:8b","size":5200000000,"details":{"family":"qwen3","parameter_size":"8.2B","quantization_level":"Q4_K_M"}}]}`)
+		},
+	})
+
+	models, err := llm.NewOllamaClient(server.URL).ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels failed: %v", err)
+	}
+	if len(models) != 1 || models[0].Name != "qwen3:8b" {
+		t.Fatalf("unexpected models: %+v", models)
+	}
+	if models[0].Details.ParameterSize != "8.2B" {
+		t.Errorf("expected parameter size 8.2B, got %s", models[0].Details.ParameterSize)
+	}
+}
+
+func TestOllamaClientShowModel(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    string
+		wantTools   bool
+		wantVision  bool
+		wantContext int
+	}{
+		{
+			name:        "capabilities reported",
+			response:    `{"capabilities":["completion","tools","thinking"],"model_info":{"general.architecture":"qwen3","qwen3.context_length":40960}}`,
+			wantTools:   true,
+			wantContext: 40960,
+		},
+		{
+			name:        "no tool support",
+			response:    `{"capabilities":["completion"],"model_info":{"gemma.context_length":8192}}`,
+			wantTools:   false,
+			wantContext: 8192,
+		},
+		{
+			name:        "inferred from template",
+			response:    `{"template":"{{ if .Tools }}tools{{ end }}","projector_info":{"clip.has_vision_encoder":true}}`,
+			wantTools:   true,
+			wantVision:  true,
+			wantContext: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newOllamaServer(t, map[string]http.HandlerFunc{
+				"/api/show": func(w http.ResponseWriter, r *http.Request) {
+					var req map[string]string
+					if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req["model"] != "test-model" {
+						t.Errorf("unexpected show request: %v %v", req, err)
+					}
+					fmt.Fprint(w, tt.response)
+				},
+			})
+
+			info, err := llm.NewOllamaClient(server.URL).ShowModel(context.Background(), "test-model")
+			if err != nil {
+				t.Fatalf("ShowModel failed: %v", err)
+			}
+			if info.SupportsTools() != tt.wantTools {
+				t.Errorf("SupportsTools() = %v, want %v", info.SupportsTools(), tt.wantTools)
+			}
+			if info.HasCapability(llm.CapabilityVision) != tt.wantVision {
+				t.Errorf("vision capability = %v, want %v", info.HasCapability(llm.CapabilityVision), tt.wantVision)
+			}
+			if info.ContextLength != tt.wantContext {
+				t.Errorf("ContextLength = %d, want %d", info.ContextLength, tt.wantContext)
+			}
+		})
+	}
+}
+
+func TestOllamaClientShowModelNotFound(t *testing.T) {
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/api/show": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"model 'missing' not found"}`)
+		},
+	})
+
+	_, err := llm.NewOllamaClient(server.URL).ShowModel(context.Background(), "missing")
+	if err == nil {
+		t.Fatal("expected error for missing model")
+	}
+	if want := "model 'missing' not found"; !strings.Contains(err.Error(), want) {
+		t.Errorf("expected error to contain %q, got %v", want, err)
+	}
+}
+
+func TestOllamaClientPullModel(t *testing.T) {
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/api/pull": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+			fmt.Fprintln(w, `{"status":"pulling abc","digest":"abc","total":100,"completed":50}`)
+			fmt.Fprintln(w, `{"status":"pulling abc","digest":"abc","total":100,"completed":100}`)
+			fmt.Fprintln(w, `{"status":"success"}`)
+		},
+	})
+
+	var updates []llm.PullProgress
+	err := llm.NewOllamaClient(server.URL).PullModel(context.Background(), "qwen3:8b", func(p llm.PullProgress) {
+		updates = append(updates, p)
+	})
+	if err != nil {
+		t.Fatalf("PullModel failed: %v", err)
+	}
+	if len(updates) != 4 {
+		t.Fatalf("expected 4 progress updates, got %d", len(updates))
+	}
+	if updates[2].Completed != 100 || updates[2].Total != 100 {
+		t.Errorf("unexpected progress: %+v", updates[2])
+	}
+	if updates[3].Status != "success" {
+		t.Errorf("expected final status 'success', got %q", updates[3].Status)
+	}
+}
+
+func TestOllamaClientPullModelStreamError(t *testing.T) {
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/api/pull": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+			fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+		},
+	})
+
+	err := llm.NewOllamaClient(server.URL).PullModel(context.Background(), "nope", nil)
+	if err == nil {
+		t.Fatal("expected error from streamed pull failure")
+	}
+}
+
+func TestOllamaClientIsLoaded(t *testing.T) {
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/api/ps": func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"models":[{"name":"qwen3:8b","model":"qwen3:8b","size_vram":5200000000}]}`)
+		},
+	})
+	client := llm.NewOllamaClient(server.URL)
+
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{"qwen3:8b", true},
+		{"qwen3:30b", false},
+	}
+	for _, tt := range tests {
+		loaded, err := client.IsLoaded(context.Background(), tt.model)
+		if err != nil {
+			t.Fatalf("IsLoaded failed: %v", err)
+		}
+		if loaded != tt.want {
+			t.Errorf("IsLoaded(%q) = %v, want %v", tt.model, loaded, tt.want)
+		}
+	}
+}
+
+func TestOllamaClientLoadModel(t *testing.T) {
+	var body map[string]interface{}
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/api/generate": func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("invalid body: %v", err)
+			}
+			if body["model"] == "slow" {
+				<-r.Context().Done()
+				return
+			}
+			fmt.Fprint(w, `{"model":"qwen3:8b","done":true,"done_reason":"load"}`)
+		},
+	})
+	client := llm.NewOllamaClient(server.URL)
+
+	if err := client.LoadModel(context.Background(), "qwen3:8b", time.Second, "10m", 8192); err != nil {
+		t.Fatalf("LoadModel failed: %v", err)
+	}
+	if body["keep_alive"] != "10m" {
+		t.Errorf("expected keep_alive 10m, got %v", body["keep_alive"])
+	}
+	if _, ok := body["prompt"]; ok {
+		t.Errorf("expected no prompt in load request")
+	}
+	if options, _ := body["options"].(map[string]interface{}); options["num_ctx"] != 8192.0 {
+		t.Errorf("expected num_ctx 8192 in load request, got %v", body["options"])
+	}
+
+	err := client.LoadModel(context.Background(), "slow", 50*time.Millisecond, nil, 0)
+	var loadErr *llm.ModelLoadError
+	if !errors.As(err, &loadErr) || !loadErr.TimedOut() {
+		t.Fatalf("expected load timeout, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "did not finish loading within 50ms") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestOllamaClientEmbed(t *testing.T) {
+	var body map[string]interface{}
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/api/embed": func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("invalid body: %v", err)
+			}
+			switch body["model"] {
+			case "missing":
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"error":"model \"missing\" not found, try pulling it first"}`)
+			case "short":
+				fmt.Fprint(w, `{"model":"short","embeddings":[[0.1,0.2]]}`)
+			default:
+				fmt.Fprint(w, `{"model":"nomic-embed-text","embeddings":[[0.1,0.2],[0.3,0.4]]}`)
+			}
+		},
+	})
+	client := llm.NewOllamaClient(server.URL)
+
+	vectors, err := client.Embed(context.Background(), "nomic-embed-text", []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(vectors) != 2 || vectors[1][0] != 0.3 {
+		t.Errorf("unexpected embeddings: %v", vectors)
+	}
+	if inputs, ok := body["input"].([]interface{}); !ok || len(inputs) != 2 || inputs[0] != "first" {
+		t.Errorf("expected both inputs in the request, got %v", body["input"])
+	}
+
+	if _, err := client.Embed(context.Background(), "short", []string{"first", "second"}); err == nil {
+		t.Error("expected an error when fewer embeddings than inputs are returned")
+	}
+
+	_, err = llm.NewOllamaEmbedder(client, "missing").Embed(context.Background(), []string{"text"})
+	if err == nil || !strings.Contains(err.Error(), "wink models pull missing") {
+		t.Errorf("expected a hint to pull the missing model, got %v", err)
+	}
+	if model := llm.NewOllamaEmbedder(client, "").Model(); model != llm.DefaultEmbeddingModel {
+		t.Errorf("expected the default embedding model, got %s", model)
+	}
+}
+
+func TestParseKeepAlive(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    interface{}
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"10m", "10m", false},
+		{"1h30m", "1h30m", false},
+		{"-1", -1, false},
+		{"0", 0, false},
+		{"300", 300, false},
+		{"forever", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := llm.ParseKeepAlive(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseKeepAlive(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseKeepAlive(%q) = %v (%T), want %v (%T)", tt.value, got, got, tt.want, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Package llm applies generation options and Ollama request extensions
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/sashabaranov/go-openai"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// extraFieldsKey is the context key for request body fields the OpenAI SDK can't express
+type extraFieldsKey struct{}
+
+// withExtraFields attaches top-level JSON fields to be merged into the outgoing request body
+func withExtraFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, extraFieldsKey{}, fields)
+}
+
+// extensionDoer is an openai.HTTPDoer that merges extra fields (such as Ollama's
+// "options") into JSON request bodies before sending them
+type extensionDoer struct {
+	client *http.Client
+}
+
+// Do sends the request, rewriting its body when extra fields are attached to the context
+func (d *extensionDoer) Do(req *http.Request) (*http.Response, error) {
+	fields, _ := req.Context().Value(extraFieldsKey{}).(map[string]interface{})
+	if len(fields) == 0 || req.Body == nil {
+		return d.client.Do(req)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("failed to parse request body: %w", err)
+	}
+	for key, value := range fields {
+		body[key] = value
+	}
+
+	data, err = json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	return d.client.Do(req)
+}
+
+// applyGenerationOptions copies the options the OpenAI request type supports
+func applyGenerationOptions(req *openai.ChatCompletionRequest, opts types.GenerationOptions) {
+	if opts.Temperature != nil {
+		req.Temperature = float32(*opts.Temperature)
+	}
+	if opts.TopP != nil {
+		req.TopP = float32(*opts.TopP)
+	}
+	if opts.Seed != nil {
+		seed := *opts.Seed
+		req.Seed = &seed
+	}
+	if opts.MaxTokens != nil {
+		req.MaxTokens = *opts.MaxTokens
+	}
+	if len(opts.Stop) > 0 {
+		req.Stop = opts.Stop
+	}
+}
+
+// generationExtraFields returns the fields that must bypass the OpenAI request type:
+// an explicit zero temperature (dropped by omitempty), Ollama's "think" toggle and
+// Ollama's native "options"
+func generationExtraFields(opts types.GenerationOptions) map[string]interface{} {
+	fields := map[string]interface{}{}
+
+	if opts.Temperature != nil && *opts.Temperature == 0 {
+		fields["temperature"] = 0
+	}
+	if opts.Think != nil {
+		fields["think"] = *opts.Think
+	}
+
+	ollamaOptions := map[string]interface{}{}
+	if opts.NumCtx != nil {
+		ollamaOptions["num_ctx"] = *opts.NumCtx
+	}
+	if len(ollamaOptions) > 0 {
+		fields["options"] = ollamaOptions
+	}
+
+	return fields
+}
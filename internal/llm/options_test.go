@@ -0,0 +1,132 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/llm"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// chatResponse is a minimal chat completion response body
+const chatResponse = `{"id":"1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`
+
+func TestClientSendsGenerationOptions(t *testing.T) {
+	var body map[string]interface{}
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/v1/chat/completions": func(w http.ResponseWriter, r *http.Request) {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("failed to decode request: %v", err)
+			}
+			fmt.Fprint(w, chatResponse)
+		},
+	})
+
+	temperature := 0.0
+	topP := 0.9
+	seed := 42
+	maxTokens := 512
+	numCtx := 32768
+
+	client := llm.NewClient(server.URL, "qwen3-coder:30b", 5)
+	client.SetGenerationOptions(types.GenerationOptions{
+		Temperature: &temperature,
+		TopP:        &topP,
+		Seed:        &seed,
+		MaxTokens:   &maxTokens,
+		Stop:        []string{"</done>"},
+		NumCtx:      &numCtx,
+	})
+	client.SetKeepAlive(-1)
+
+	messages := []types.Message{{Role: types.MessageRoleUser, Content: "hi"}}
+	if _, err := client.ChatCompletion(context.Background(), messages, nil); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	// A zero temperature must still be sent explicitly
+	if v, ok := body["temperature"]; !ok || v.(float64) != 0 {
+		t.Errorf("expected temperature 0 in request, got %v (present=%v)", v, ok)
+	}
+	if body["top_p"].(float64) < 0.89 || body["top_p"].(float64) > 0.91 {
+		t.Errorf("expected top_p 0.9, got %v", body["top_p"])
+	}
+	if body["seed"].(float64) != 42 {
+		t.Errorf("expected seed 42, got %v", body["seed"])
+	}
+	if body["max_tokens"].(float64) != 512 {
+		t.Errorf("expected max_tokens 512, got %v", body["max_tokens"])
+	}
+	if stop, ok := body["stop"].([]interface{}); !ok || len(stop) != 1 || stop[0] != "</done>" {
+		t.Errorf("expected stop sequence, got %v", body["stop"])
+	}
+	options, ok := body["options"].(map[string]interface{})
+	if !ok || options["num_ctx"].(float64) != 32768 {
+		t.Errorf("expected options.num_ctx 32768, got %v", body["options"])
+	}
+	if body["keep_alive"] != float64(-1) {
+		t.Errorf("expected keep_alive -1, got %v", body["keep_alive"])
+	}
+}
+
+func TestClientWithoutGenerationOptions(t *testing.T) {
+	var body map[string]interface{}
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/v1/chat/completions": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			fmt.Fprint(w, chatResponse)
+		},
+	})
+
+	client := llm.NewClient(server.URL, "qwen3:8b", 5)
+	messages := []types.Message{{Role: types.MessageRoleUser, Content: "hi"}}
+	if _, err := client.ChatCompletion(context.Background(), messages, nil); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	for _, key := range []string{"temperature", "top_p", "seed", "options"} {
+		if _, ok := body[key]; ok {
+			t.Errorf("expected %s to be omitted, got %v", key, body[key])
+		}
+	}
+}
+
+func TestClientSendsImageParts(t *testing.T) {
+	var body map[string]interface{}
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/v1/chat/completions": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			fmt.Fprint(w, chatResponse)
+		},
+	})
+
+	client := llm.NewClient(server.URL, "qwen2.5vl:7b", 5)
+	messages := []types.Message{{
+		Role:    types.MessageRoleUser,
+		Content: "what is wrong with this UI?",
+		Parts: []types.ContentPart{
+			{Type: types.ContentPartImage, MIMEType: "image/png", Data: "aGVsbG8="},
+		},
+	}}
+	if _, err := client.ChatCompletion(context.Background(), messages, nil); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	msg := body["messages"].([]interface{})[0].(map[string]interface{})
+	parts, ok := msg["content"].([]interface{})
+	if !ok || len(parts) != 2 {
+		t.Fatalf("expected 2 content parts, got %v", msg["content"])
+	}
+	text := parts[0].(map[string]interface{})
+	if text["type"] != "text" || text["text"] != "what is wrong with this UI?" {
+		t.Errorf("unexpected text part: %v", text)
+	}
+	img := parts[1].(map[string]interface{})
+	url := img["image_url"].(map[string]interface{})["url"]
+	if img["type"] != "image_url" || url != "data:image/png;base64,aGVsbG8=" {
+		t.Errorf("unexpected image part: %v", img)
+	}
+}
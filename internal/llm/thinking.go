@@ -0,0 +1,60 @@
+// Package llm separates reasoning ("thinking") output from model answers
+package llm
+
+import (
+	"strings"
+)
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// SplitReasoning separates <think>...</think> blocks from the answer content.
+// An unterminated block is treated as reasoning up to the end of the content, and a
+// closing tag without an opening one (templates that pre-fill "<think>") marks
+// everything before it as reasoning.
+func SplitReasoning(content string) (answer, reasoning string) {
+	var answerParts, reasoningParts []string
+
+	rest := content
+	// Handle a leading close tag with no matching open tag
+	if closeIdx := strings.Index(rest, thinkCloseTag); closeIdx >= 0 {
+		if openIdx := strings.Index(rest, thinkOpenTag); openIdx < 0 || openIdx > closeIdx {
+			reasoningParts = append(reasoningParts, rest[:closeIdx])
+			rest = rest[closeIdx+len(thinkCloseTag):]
+		}
+	}
+
+	for {
+		openIdx := strings.Index(rest, thinkOpenTag)
+		if openIdx < 0 {
+			answerParts = append(answerParts, rest)
+			break
+		}
+
+		answerParts = append(answerParts, rest[:openIdx])
+		rest = rest[openIdx+len(thinkOpenTag):]
+
+		closeIdx := strings.Index(rest, thinkCloseTag)
+		if closeIdx < 0 {
+			reasoningParts = append(reasoningParts, rest)
+			break
+		}
+
+		reasoningParts = append(reasoningParts, rest[:closeIdx])
+		rest = rest[closeIdx+len(thinkCloseTag):]
+	}
+
+	answer = strings.TrimSpace(strings.Join(answerParts, ""))
+
+	trimmed := make([]string, 0, len(reasoningParts))
+	for _, part := range reasoningParts {
+		if part = strings.TrimSpace(part); part != "" {
+			trimmed = append(trimmed, part)
+		}
+	}
+	reasoning = strings.Join(trimmed, "\n\n")
+
+	return answer, reasoning
+}
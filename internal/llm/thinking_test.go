@@ -0,0 +1,116 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/llm"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+func TestSplitReasoning(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		wantAnswer    string
+		wantReasoning string
+	}{
+		{
+			name:       "no reasoning",
+			content:    "Hello there",
+			wantAnswer: "Hello there",
+		},
+		{
+			name:          "leading think block",
+			content:       "<think>\nThe user wants a greeting.\n</think>\n\nHello!",
+			wantAnswer:    "Hello!",
+			wantReasoning: "The user wants a greeting.",
+		},
+		{
+			name:          "multiple blocks",
+			content:       "<think>a</think>first <think>b</think>second",
+			wantAnswer:    "first second",
+			wantReasoning: "a\n\nb",
+		},
+		{
+			name:          "unterminated block",
+			content:       "<think>still thinking",
+			wantAnswer:    "",
+			wantReasoning: "still thinking",
+		},
+		{
+			name:          "close tag only",
+			content:       "reasoning from a prefilled template</think>\nAnswer",
+			wantAnswer:    "Answer",
+			wantReasoning: "reasoning from a prefilled template",
+		},
+		{
+			name:       "empty think block",
+			content:    "<think>\n\n</think>\n\nDone.",
+			wantAnswer: "Done.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer, reasoning := llm.SplitReasoning(tt.content)
+			if answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", answer, tt.wantAnswer)
+			}
+			if reasoning != tt.wantReasoning {
+				t.Errorf("reasoning = %q, want %q", reasoning, tt.wantReasoning)
+			}
+		})
+	}
+}
+
+func TestClientReasoningInContext(t *testing.T) {
+	var bodies []map[string]interface{}
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/v1/chat/completions": func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			bodies = append(bodies, body)
+			fmt.Fprint(w, chatResponse)
+		},
+	})
+
+	think := false
+	client := llm.NewClient(server.URL, "qwen3:8b", 5)
+	client.SetGenerationOptions(types.GenerationOptions{Think: &think})
+
+	messages := []types.Message{
+		{Role: types.MessageRoleUser, Content: "hi"},
+		{Role: types.MessageRoleAssistant, Content: "Hello!", Metadata: map[string]interface{}{"reasoning": "secret plan"}},
+		{Role: types.MessageRoleUser, Content: "again"},
+	}
+
+	// Reasoning is excluded by default
+	if _, err := client.ChatCompletion(context.Background(), messages, nil); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	// ...and included when requested
+	client.SetIncludeReasoning(true)
+	if _, err := client.ChatCompletion(context.Background(), messages, nil); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	assistantContent := func(body map[string]interface{}) string {
+		msgs := body["messages"].([]interface{})
+		return msgs[1].(map[string]interface{})["content"].(string)
+	}
+
+	if got := assistantContent(bodies[0]); got != "Hello!" {
+		t.Errorf("expected reasoning excluded by default, got %q", got)
+	}
+	if got := assistantContent(bodies[1]); !strings.Contains(got, "secret plan") {
+		t.Errorf("expected reasoning included, got %q", got)
+	}
+	if v, ok := bodies[0]["think"]; !ok || v != false {
+		t.Errorf("expected think=false to be sent, got %v", bodies[0]["think"])
+	}
+}
@@ -0,0 +1,100 @@
+// Package llm provides local token estimation for requests
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+const (
+	// messageOverheadTokens approximates per-message framing (role, separators)
+	messageOverheadTokens = 4
+	// toolOverheadTokens approximates per-tool framing in the prompt template
+	toolOverheadTokens = 8
+	// imageTokens approximates the cost of one downscaled image
+	imageTokens = 768
+)
+
+// Tokenizer counts the tokens in a piece of text
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// HeuristicTokenizer estimates tokens without a model vocabulary:
+// roughly 4 ASCII characters per token, and one token per non-ASCII rune
+type HeuristicTokenizer struct{}
+
+// CountTokens returns the estimated number of tokens in text
+func (HeuristicTokenizer) CountTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < 128 {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// TokenEstimator estimates the prompt size of a request before it is sent
+type TokenEstimator struct {
+	tokenizer Tokenizer
+}
+
+// NewTokenEstimator creates an estimator using the given tokenizer (heuristic if nil)
+func NewTokenEstimator(tokenizer Tokenizer) *TokenEstimator {
+	if tokenizer == nil {
+		tokenizer = HeuristicTokenizer{}
+	}
+	return &TokenEstimator{tokenizer: tokenizer}
+}
+
+// EstimateMessages estimates the tokens used by messages, including tool calls
+func (e *TokenEstimator) EstimateMessages(messages []types.Message) int {
+	total := 0
+	for _, msg := range messages {
+		total += messageOverheadTokens + e.tokenizer.CountTokens(msg.Content)
+		for _, part := range msg.Parts {
+			if part.Type == types.ContentPartImage {
+				total += imageTokens
+			} else {
+				total += e.tokenizer.CountTokens(part.Text)
+			}
+		}
+		for _, tc := range msg.ToolCalls {
+			total += e.tokenizer.CountTokens(tc.ToolName) + e.tokenizer.CountTokens(mustMarshalJSON(tc.Parameters))
+		}
+	}
+	return total
+}
+
+// EstimateTools estimates the tokens used by tool names, descriptions and schemas
+func (e *TokenEstimator) EstimateTools(tools []types.Tool) int {
+	total := 0
+	for _, tool := range tools {
+		schema, err := json.Marshal(tool.ParametersSchema())
+		if err != nil {
+			schema = nil
+		}
+		total += toolOverheadTokens +
+			e.tokenizer.CountTokens(tool.Name()) +
+			e.tokenizer.CountTokens(tool.Description()) +
+			e.tokenizer.CountTokens(string(schema))
+	}
+	return total
+}
+
+// ContextOverflowError reports a request that cannot fit the model's context window
+type ContextOverflowError struct {
+	EstimatedTokens int
+	ContextLimit    int
+}
+
+// Error implements the error interface
+func (e *ContextOverflowError) Error() string {
+	return fmt.Sprintf("request needs ~%d tokens but the model context limit is %d tokens",
+		e.EstimatedTokens, e.ContextLimit)
+}
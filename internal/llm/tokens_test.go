@@ -0,0 +1,107 @@
+package llm_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/llm"
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// wordTokenizer counts whitespace-separated words, to test pluggable tokenizers
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestHeuristicTokenizer(t *testing.T) {
+	tokenizer := llm.HeuristicTokenizer{}
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "", want: 0},
+		{text: "abcd", want: 1},
+		{text: "abcde", want: 2},
+		{text: strings.Repeat("x", 400), want: 100},
+		{text: "你好", want: 2}, // non-ASCII runes count individually
+	}
+
+	for _, tt := range tests {
+		if got := tokenizer.CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenEstimator(t *testing.T) {
+	estimator := llm.NewTokenEstimator(wordTokenizer{})
+
+	messages := []types.Message{
+		{Role: types.MessageRoleUser, Content: "create a file please"},
+		{Role: types.MessageRoleAssistant, Content: "ok", ToolCalls: []types.ToolCall{
+			{ID: "1", ToolName: "create_file", Parameters: map[string]interface{}{"path": "a.txt"}},
+		}},
+	}
+
+	got := estimator.EstimateMessages(messages)
+	// 2 messages * 4 overhead + 4 words + 1 word + tool name (1) + params JSON (1)
+	if got != 15 {
+		t.Errorf("EstimateMessages() = %d, want 15", got)
+	}
+
+	toolTokens := estimator.EstimateTools([]types.Tool{tools.NewCreateFileTool()})
+	if toolTokens <= 8 {
+		t.Errorf("expected tool schema to contribute tokens, got %d", toolTokens)
+	}
+}
+
+func TestClientRejectsOversizedRequest(t *testing.T) {
+	called := false
+	server := newOllamaServer(t, map[string]http.HandlerFunc{
+		"/v1/chat/completions": func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		},
+	})
+
+	client := llm.NewClient(server.URL, "qwen3:8b", 5)
+	client.SetContextLimit(100)
+
+	messages := []types.Message{{Role: types.MessageRoleUser, Content: strings.Repeat("word ", 200)}}
+	_, err := client.ChatCompletion(context.Background(), messages, nil)
+
+	var overflow *llm.ContextOverflowError
+	if !errors.As(err, &overflow) {
+		t.Fatalf("expected ContextOverflowError, got %v", err)
+	}
+	if overflow.ContextLimit != 100 || overflow.EstimatedTokens <= 100 {
+		t.Errorf("unexpected overflow details: %+v", overflow)
+	}
+	if called {
+		t.Error("oversized request should not be sent to the server")
+	}
+}
+
+func TestClientPromptBudget(t *testing.T) {
+	client := llm.NewClient("http://localhost:11434", "qwen3:8b", 5)
+	if client.PromptBudget() != 0 {
+		t.Errorf("expected unlimited budget without a context limit, got %d", client.PromptBudget())
+	}
+
+	client.SetContextLimit(10000)
+	if client.PromptBudget() != 9000 {
+		t.Errorf("expected default reserve of 10%%, got budget %d", client.PromptBudget())
+	}
+
+	maxTokens := 2000
+	client.SetGenerationOptions(types.GenerationOptions{MaxTokens: &maxTokens})
+	if client.PromptBudget() != 8000 {
+		t.Errorf("expected max_tokens reserve, got budget %d", client.PromptBudget())
+	}
+}
@@ -11,9 +11,14 @@ import (
 	"github.com/shizhMSFT/wink-code/pkg/types"
 )
 
+// ApprovalPrompter asks for a decision on a tool call that no rule auto-approves;
+// preview is nil for tools that cannot show their change in advance
+type ApprovalPrompter func(toolName string, params map[string]interface{}, tool types.Tool, preview *types.ToolPreview) (ui.ApprovalResponse, error)
+
 // ApprovalWorkflow handles tool execution approval
 type ApprovalWorkflow struct {
 	approvalManager *config.ApprovalManager
+	prompt          ApprovalPrompter
 }
 
 // NewApprovalWorkflow creates a new approval workflow
@@ -25,12 +30,18 @@ func NewApprovalWorkflow() (*ApprovalWorkflow, error) {
 
 	return &ApprovalWorkflow{
 		approvalManager: approvalManager,
+		prompt:          ui.PromptForApproval,
 	}, nil
 }
 
+// SetPrompter replaces the interactive approval prompt, e.g. for non-interactive runs and tests
+func (aw *ApprovalWorkflow) SetPrompter(prompt ApprovalPrompter) {
+	aw.prompt = prompt
+}
+
 // CheckApproval checks if a tool call should be approved
 // Returns: (approved bool, autoApproved bool, ruleDescription string, error)
-func (aw *ApprovalWorkflow) CheckApproval(toolName string, params map[string]interface{}, tool types.Tool) (bool, bool, string, error) {
+func (aw *ApprovalWorkflow) CheckApproval(toolName string, params map[string]interface{}, tool types.Tool, preview *types.ToolPreview) (bool, bool, string, error) {
 	// Check auto-approval rules
 	rule, err := aw.approvalManager.MatchRule(toolName, params)
 	if err != nil {
@@ -49,7 +60,7 @@ func (aw *ApprovalWorkflow) CheckApproval(toolName string, params map[string]int
 	}
 
 	// Prompt user for approval
-	response, err := ui.PromptForApproval(toolName, params, tool)
+	response, err := aw.prompt(toolName, params, tool, preview)
 	if err != nil {
 		return false, false, "", fmt.Errorf("failed to get approval: %w", err)
 	}
@@ -0,0 +1,246 @@
+// Package tools implements unified diff generation for change previews
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines shown around each change
+	diffContextLines = 3
+	// maxDiffTrace bounds the memory used by the diff search; larger diffs fall back
+	// to replacing the whole changed region
+	maxDiffTrace = 8 * 1024 * 1024
+)
+
+// diffOp is one line of an edit script: ' ' keeps, '-' removes and '+' adds a line
+type diffOp struct {
+	kind byte
+	line string // includes its line terminator, if any
+}
+
+// UnifiedDiff returns the unified diff that turns oldText into newText, labelled with
+// oldName and newName, or "" when the texts are equal
+func UnifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	b.WriteString(formatHunks(diffLines(splitDiffLines(oldText), splitDiffLines(newText))))
+	return b.String()
+}
+
+// fileDiff diffs a workspace file; a nil oldText means the file is created and a
+// nil newText that it is deleted
+func fileDiff(path string, oldText, newText *string) string {
+	oldName, newName := "a/"+path, "b/"+path
+	var before, after string
+	if oldText == nil {
+		oldName = devNull
+	} else {
+		before = *oldText
+	}
+	if newText == nil {
+		newName = devNull
+	} else {
+		after = *newText
+	}
+
+	diff := UnifiedDiff(oldName, newName, before, after)
+	if diff == "" && oldName != newName && (oldText == nil || newText == nil) {
+		// Creating or deleting an empty file has no hunks but is still a change
+		diff = fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName)
+	}
+	return diff
+}
+
+// splitDiffLines splits text into lines that keep their terminators, so a missing
+// final newline makes the last line differ
+func splitDiffLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a minimal line edit script with Myers' algorithm, after
+// stripping the common prefix and suffix
+func diffLines(a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+// myersDiff finds the shortest edit script between a and b
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	replaceAll := func() []diffOp {
+		ops := make([]diffOp, 0, n+m)
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+	if n == 0 || m == 0 {
+		return replaceAll()
+	}
+
+	limit := n + m
+	offset := limit
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+
+search:
+	for d := 0; d <= limit; d++ {
+		if (d+1)*len(v) > maxDiffTrace {
+			return replaceAll()
+		}
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards to recover the script
+	var reversed []diffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, diffOp{'+', b[y-1]})
+			} else {
+				reversed = append(reversed, diffOp{'-', a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	ops := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
+
+// formatHunks renders an edit script as unified diff hunks
+func formatHunks(ops []diffOp) string {
+	var b strings.Builder
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Extend the hunk while the next change is close enough to share context
+		start := max(0, i-diffContextLines)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContextLines {
+				break
+			}
+		}
+		end = min(len(ops), end+diffContextLines)
+
+		oldStart, newStart := 1, 1
+		for _, op := range ops[:start] {
+			if op.kind != '+' {
+				oldStart++
+			}
+			if op.kind != '-' {
+				newStart++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, op := range ops[start:end] {
+			b.WriteByte(op.kind)
+			b.WriteString(strings.TrimSuffix(op.line, "\n"))
+			b.WriteByte('\n')
+			if !strings.HasSuffix(op.line, "\n") {
+				b.WriteString("\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+
+	return b.String()
+}
+
+// hunkRange formats one side of a hunk header the way diff -u does
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	default:
+		return fmt.Sprintf("%d,%d", start, count)
+	}
+}
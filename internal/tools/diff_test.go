@@ -0,0 +1,175 @@
+package tools_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// TestUnifiedDiff tests diff generation, checking that every diff applies back cleanly
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		oldText  string
+		newText  string
+		wantDiff string
+	}{
+		{
+			name:     "identical texts",
+			oldText:  "a\nb\n",
+			newText:  "a\nb\n",
+			wantDiff: "",
+		},
+		{
+			name:     "single line change",
+			oldText:  "a\nb\nc\n",
+			newText:  "a\nB\nc\n",
+			wantDiff: "--- a/f.txt\n+++ b/f.txt\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:     "append to file",
+			oldText:  "1\n2\n3\n4\n5\n",
+			newText:  "1\n2\n3\n4\n5\n6\n",
+			wantDiff: "--- a/f.txt\n+++ b/f.txt\n@@ -3,3 +3,4 @@\n 3\n 4\n 5\n+6\n",
+		},
+		{
+			name:     "missing final newline",
+			oldText:  "a\nb",
+			newText:  "a\nb\n",
+			wantDiff: "--- a/f.txt\n+++ b/f.txt\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name:     "distant changes produce separate hunks",
+			oldText:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			newText:  "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			wantDiff: "--- a/f.txt\n+++ b/f.txt\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+		{
+			name:    "interleaved insertions and deletions",
+			oldText: "func a() {}\nfunc b() {}\nfunc c() {}\nfunc d() {}\n",
+			newText: "func a() {}\nfunc b2() {}\nfunc c() {}\nfunc c2() {}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := tools.UnifiedDiff("a/f.txt", "b/f.txt", tt.oldText, tt.newText)
+			if tt.wantDiff != "" || tt.oldText == tt.newText {
+				if diff != tt.wantDiff {
+					t.Errorf("expected diff:\n%s\ngot:\n%s", tt.wantDiff, diff)
+				}
+			}
+			if diff == "" {
+				return
+			}
+
+			// The diff must turn the old text into the new one
+			workingDir := t.TempDir()
+			path := filepath.Join(workingDir, "f.txt")
+			if err := os.WriteFile(path, []byte(tt.oldText), 0644); err != nil {
+				t.Fatalf("setup failed: %v", err)
+			}
+			params := map[string]interface{}{"patch": diff}
+			if _, err := runTool(tools.NewApplyPatchTool(), params, workingDir); err != nil {
+				t.Fatalf("generated diff did not apply: %v\n%s", err, diff)
+			}
+			got, _ := os.ReadFile(path)
+			if string(got) != tt.newText {
+				t.Errorf("applying the diff gave %q, want %q", string(got), tt.newText)
+			}
+		})
+	}
+}
+
+// TestToolPreviews tests that write tools preview their change without writing
+func TestToolPreviews(t *testing.T) {
+	const original = "package main\n\nfunc main() {}\n"
+
+	tests := []struct {
+		name         string
+		tool         types.PreviewableTool
+		params       map[string]interface{}
+		wantDiff     string
+		wantAffected []string
+	}{
+		{
+			name:         "create_file",
+			tool:         tools.NewCreateFileTool(),
+			params:       map[string]interface{}{"path": "new.txt", "content": "hello\nworld\n"},
+			wantDiff:     "--- /dev/null\n+++ b/new.txt\n@@ -0,0 +1,2 @@\n+hello\n+world\n",
+			wantAffected: []string{"new.txt"},
+		},
+		{
+			name: "replace_string_in_file",
+			tool: tools.NewReplaceStringInFileTool(),
+			params: map[string]interface{}{
+				"path":       "main.go",
+				"old_string": "func main() {}",
+				"new_string": "func main() {\n\tprintln(1)\n}",
+			},
+			wantDiff:     "--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,5 @@\n package main\n \n-func main() {}\n+func main() {\n+\tprintln(1)\n+}\n",
+			wantAffected: []string{"main.go"},
+		},
+		{
+			name: "multi_edit",
+			tool: tools.NewMultiEditTool(),
+			params: map[string]interface{}{
+				"path": "main.go",
+				"edits": []interface{}{
+					map[string]interface{}{"old_string": "package main", "new_string": "package app"},
+				},
+			},
+			wantDiff:     "--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n-package main\n+package app\n \n func main() {}\n",
+			wantAffected: []string{"main.go"},
+		},
+		{
+			name: "apply_patch",
+			tool: tools.NewApplyPatchTool(),
+			params: map[string]interface{}{
+				"patch": "--- main.go\n+++ main.go\n@@ -3 +3 @@\n-func main() {}\n+func main() { run() }\n",
+			},
+			wantDiff:     "--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n package main\n \n-func main() {}\n+func main() { run() }\n",
+			wantAffected: []string{"main.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			path := filepath.Join(workingDir, "main.go")
+			if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+				t.Fatalf("setup failed: %v", err)
+			}
+
+			preview, err := tt.tool.Preview(tt.params, workingDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if preview.Diff != tt.wantDiff {
+				t.Errorf("expected diff:\n%s\ngot:\n%s", tt.wantDiff, preview.Diff)
+			}
+			if strings.Join(preview.FilesAffected, ",") != strings.Join(tt.wantAffected, ",") {
+				t.Errorf("expected files affected %v, got %v", tt.wantAffected, preview.FilesAffected)
+			}
+
+			// Previewing must not touch the workspace
+			got, _ := os.ReadFile(path)
+			if string(got) != original {
+				t.Errorf("preview modified main.go: %q", string(got))
+			}
+			if _, err := os.Stat(filepath.Join(workingDir, "new.txt")); !os.IsNotExist(err) {
+				t.Error("preview created new.txt")
+			}
+
+			// Execute still succeeds after a preview
+			if _, err := tt.tool.Execute(context.Background(), tt.params, workingDir); err != nil {
+				t.Fatalf("execute failed: %v", err)
+			}
+		})
+	}
+}
@@ -141,7 +141,7 @@ func (l *ListDirTool) Name() string {
 
 // Description returns the tool description
 func (l *ListDirTool) Description() string {
-	return "List contents of a directory"
+	return "List contents of a directory. Entries ignored by .gitignore or .winkignore and VCS directories are hidden unless include_ignored is set."
 }
 
 // ParametersSchema returns the JSON schema for parameters
@@ -154,6 +154,10 @@ func (l *ListDirTool) ParametersSchema() map[string]interface{} {
 				"description": "Relative path to directory (default: current directory)",
 				"default":     ".",
 			},
+			"include_ignored": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Also list entries excluded by .gitignore, .winkignore and .git/info/exclude, and VCS directories (default: false)",
+			},
 		},
 	}
 }
@@ -186,6 +190,10 @@ func (l *ListDirTool) Validate(params map[string]interface{}, workingDir string)
 		return fmt.Errorf("path '%s' is not a directory", path)
 	}
 
+	if _, err := boolParam(params, "include_ignored"); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -220,6 +228,22 @@ func (l *ListDirTool) Execute(ctx context.Context, params map[string]interface{}
 		}, fmt.Errorf("failed to read directory '%s': %w", path, err)
 	}
 
+	// Hide ignored entries unless asked for them
+	includeIgnored, _ := params["include_ignored"].(bool)
+	hidden := 0
+	if !includeIgnored {
+		matcher := newIgnoreMatcher(workingDir)
+		visible := entries[:0]
+		for _, entry := range entries {
+			if (entry.IsDir() && vcsDirs[entry.Name()]) || matcher.ignored(filepath.Join(resolvedPath, entry.Name()), entry.IsDir()) {
+				hidden++
+				continue
+			}
+			visible = append(visible, entry)
+		}
+		entries = visible
+	}
+
 	// Count files and directories
 	fileCount := 0
 	dirCount := 0
@@ -252,6 +276,9 @@ func (l *ListDirTool) Execute(ctx context.Context, params map[string]interface{}
 	if len(entries) > maxDirEntries {
 		output += fmt.Sprintf("  ... (%d more entries not shown)\n", len(entries)-maxDirEntries)
 	}
+	if hidden > 0 {
+		output += fmt.Sprintf("  (%d ignored entries hidden; set include_ignored to list them)\n", hidden)
+	}
 
 	executionTime := time.Since(startTime).Milliseconds()
 
@@ -272,6 +299,7 @@ func (l *ListDirTool) Execute(ctx context.Context, params map[string]interface{}
 			"total_entries": len(entries),
 			"files":         fileCount,
 			"directories":   dirCount,
+			"hidden":        hidden,
 		},
 	}, nil
 }
@@ -0,0 +1,412 @@
+// Package tools implements the multi_edit tool
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/logging"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// maxEditsPerCall bounds the number of edits in a single multi_edit call
+const maxEditsPerCall = 50
+
+// stringEdit is one replacement requested by multi_edit
+type stringEdit struct {
+	OldString  string `json:"old_string"`
+	NewString  string `json:"new_string"`
+	ReplaceAll bool   `json:"replace_all"`
+}
+
+// editRegion is a replaced span of the content, tracked so it can be reported as a
+// line range of the final file
+type editRegion struct {
+	edit  int
+	start int
+	end   int
+}
+
+// LineRange is an inclusive range of 1-based line numbers
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// String formats the range as "4" or "4-6"
+func (r LineRange) String() string {
+	if r.Start == r.End {
+		return fmt.Sprintf("%d", r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// parseEdits converts the edits parameter into stringEdits. Models occasionally send
+// the array JSON-encoded as a string, so that is accepted too.
+func parseEdits(value interface{}) ([]stringEdit, error) {
+	if s, ok := value.(string); ok {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+			return nil, fmt.Errorf("edits parameter must be an array of edits")
+		}
+		value = decoded
+	}
+
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("edits parameter is required and must be a non-empty array")
+	}
+	if len(items) > maxEditsPerCall {
+		return nil, fmt.Errorf("too many edits (%d); at most %d are allowed per call", len(items), maxEditsPerCall)
+	}
+
+	edits := make([]stringEdit, 0, len(items))
+	for i, item := range items {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("edit #%d must be an object with old_string and new_string", i+1)
+		}
+
+		var edit stringEdit
+		edit.OldString, ok = obj["old_string"].(string)
+		if !ok || edit.OldString == "" {
+			return nil, fmt.Errorf("edit #%d: old_string is required and must be a non-empty string", i+1)
+		}
+		edit.NewString, ok = obj["new_string"].(string)
+		if !ok {
+			return nil, fmt.Errorf("edit #%d: new_string is required and must be a string", i+1)
+		}
+		if v, present := obj["replace_all"]; present {
+			edit.ReplaceAll, ok = v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("edit #%d: replace_all must be a boolean", i+1)
+			}
+		}
+		if edit.OldString == edit.NewString {
+			return nil, fmt.Errorf("edit #%d: old_string and new_string are identical", i+1)
+		}
+
+		edits = append(edits, edit)
+	}
+
+	return edits, nil
+}
+
+// matchEditEOL rewrites the edits to use the line ending of a file that uses one
+// consistently, so multi-line strings match and inserted text does not mix endings
+func matchEditEOL(content string, edits []stringEdit) {
+	conv := detectConventions(content)
+	for i := range edits {
+		edits[i].OldString = conv.normalizeEOL(edits[i].OldString)
+		edits[i].NewString = conv.normalizeEOL(edits[i].NewString)
+	}
+}
+
+// applyEdits applies edits in order to content, returning the new content, the number
+// of occurrences replaced per edit and the replaced regions
+func applyEdits(content string, edits []stringEdit) (string, []int, []editRegion, error) {
+	original := content
+	counts := make([]int, len(edits))
+	var regions []editRegion
+
+	for i, edit := range edits {
+		occurrences := strings.Count(content, edit.OldString)
+		switch {
+		case occurrences == 0 && strings.Contains(original, edit.OldString):
+			return "", nil, nil, fmt.Errorf("edit #%d: old_string no longer matches after the earlier edits changed it", i+1)
+		case occurrences == 0:
+			return "", nil, nil, fmt.Errorf("edit #%d: old_string not found in file", i+1)
+		case occurrences > 1 && !edit.ReplaceAll:
+			return "", nil, nil, fmt.Errorf("edit #%d: old_string matches %d times; include more surrounding context or set replace_all", i+1, occurrences)
+		}
+
+		pos := 0
+		for {
+			idx := strings.Index(content[pos:], edit.OldString)
+			if idx < 0 {
+				break
+			}
+			start := pos + idx
+			end := start + len(edit.OldString)
+			content = content[:start] + edit.NewString + content[end:]
+
+			// Keep earlier regions pointing at the same text
+			delta := len(edit.NewString) - len(edit.OldString)
+			for j := range regions {
+				r := &regions[j]
+				switch {
+				case r.start >= end:
+					r.start += delta
+					r.end += delta
+				case r.end > start:
+					// Overlapping an earlier edit: the regions merge
+					r.start = min(r.start, start)
+					r.end = max(r.end+delta, start+len(edit.NewString))
+				}
+			}
+			regions = append(regions, editRegion{edit: i, start: start, end: start + len(edit.NewString)})
+
+			counts[i]++
+			pos = start + len(edit.NewString)
+			if !edit.ReplaceAll {
+				break
+			}
+		}
+	}
+
+	return content, counts, regions, nil
+}
+
+// regionLines converts a byte region of content into a line range. Deletions map to
+// the line where the text was removed.
+func regionLines(content string, r editRegion) LineRange {
+	start := 1 + strings.Count(content[:r.start], "\n")
+	end := start
+	if r.end > r.start {
+		end = 1 + strings.Count(content[:r.end-1], "\n")
+	}
+	return LineRange{Start: start, End: end}
+}
+
+// MultiEditTool implements the multi_edit tool
+type MultiEditTool struct {
+	postEditStage
+}
+
+// NewMultiEditTool creates a new multi_edit tool instance
+func NewMultiEditTool() *MultiEditTool {
+	return &MultiEditTool{}
+}
+
+// Name returns the tool name
+func (t *MultiEditTool) Name() string {
+	return "multi_edit"
+}
+
+// Description returns the tool description
+func (t *MultiEditTool) Description() string {
+	return "Apply several string replacements to one file in a single step. Edits are applied in order, " +
+		"and the file is written only if every edit matches. Each old_string must match exactly once unless replace_all is set."
+}
+
+// ParametersSchema returns the JSON schema for parameters
+func (t *MultiEditTool) ParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Relative path to the file to modify",
+			},
+			"edits": map[string]interface{}{
+				"type":        "array",
+				"description": "Replacements to apply in order; later edits see the result of earlier ones",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"old_string": map[string]interface{}{
+							"type":        "string",
+							"description": "Exact string to find",
+						},
+						"new_string": map[string]interface{}{
+							"type":        "string",
+							"description": "String to replace it with",
+						},
+						"replace_all": map[string]interface{}{
+							"type":        "boolean",
+							"description": "Replace every occurrence instead of requiring a unique match (default: false)",
+						},
+					},
+					"required": []string{"old_string", "new_string"},
+				},
+			},
+		},
+		"required": []string{"path", "edits"},
+	}
+}
+
+// Validate checks if parameters are valid
+func (t *MultiEditTool) Validate(params map[string]interface{}, workingDir string) error {
+	path, ok := params["path"].(string)
+	if !ok || path == "" {
+		return fmt.Errorf("path parameter is required and must be a non-empty string")
+	}
+
+	if _, err := parseEdits(params["edits"]); err != nil {
+		return err
+	}
+
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return err
+	}
+
+	fileInfo, err := os.Stat(resolvedPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("file '%s' not found", path)
+		}
+		return fmt.Errorf("cannot access file '%s': %w", path, err)
+	}
+	if fileInfo.IsDir() {
+		return fmt.Errorf("path '%s' is a directory, not a file", path)
+	}
+
+	return nil
+}
+
+// Preview returns the diff the edits would produce
+func (t *MultiEditTool) Preview(params map[string]interface{}, workingDir string) (*types.ToolPreview, error) {
+	path, _ := params["path"].(string)
+
+	edits, err := parseEdits(params["edits"])
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file '%s': %w", path, err)
+	}
+
+	oldContent := string(content)
+	matchEditEOL(oldContent, edits)
+	newContent, _, _, err := applyEdits(oldContent, edits)
+	if err != nil {
+		return nil, err
+	}
+	newContent = t.postEdit.process(resolvedPath, path, newContent).content
+
+	return &types.ToolPreview{
+		Diff:          fileDiff(path, &oldContent, &newContent),
+		FilesAffected: []string{path},
+	}, nil
+}
+
+// TargetPaths returns the file to be modified
+func (t *MultiEditTool) TargetPaths(params map[string]interface{}, workingDir string) ([]string, error) {
+	return singleTarget(params, "path", workingDir)
+}
+
+// Execute applies the edits and writes the file once
+func (t *MultiEditTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
+
+	path := params["path"].(string)
+
+	failure := func(err error) (*types.ToolResult, error) {
+		return &types.ToolResult{
+			Success:         false,
+			Error:           err.Error(),
+			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+		}, err
+	}
+
+	edits, err := parseEdits(params["edits"])
+	if err != nil {
+		return failure(err)
+	}
+
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return failure(err)
+	}
+
+	fileInfo, err := os.Stat(resolvedPath)
+	if err != nil {
+		return failure(fmt.Errorf("cannot access file '%s': %w", path, err))
+	}
+
+	content, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		return failure(fmt.Errorf("failed to read file '%s': %w", path, err))
+	}
+
+	matchEditEOL(string(content), edits)
+	newContent, counts, regions, err := applyEdits(string(content), edits)
+	if err != nil {
+		return failure(fmt.Errorf("%w (no changes were written to '%s')", err, path))
+	}
+	postEdit := t.postEdit.process(resolvedPath, path, newContent)
+
+	if err := writeFileAtomic(resolvedPath, []byte(postEdit.content), fileInfo.Mode().Perm()); err != nil {
+		return failure(fmt.Errorf("failed to write file '%s': %w", path, err))
+	}
+
+	// Report where each edit landed in the final file. A formatter may have moved
+	// the edited text, so line ranges are only reported for unformatted content.
+	formatted := postEdit.content != newContent
+	ranges := make([][]LineRange, len(edits))
+	if !formatted {
+		for _, r := range regions {
+			ranges[r.edit] = append(ranges[r.edit], regionLines(postEdit.content, r))
+		}
+	}
+
+	editResults := make([]map[string]interface{}, 0, len(edits))
+	var allRanges []string
+	total := 0
+	for i := range edits {
+		total += counts[i]
+		editResult := map[string]interface{}{
+			"edit":        i + 1,
+			"occurrences": counts[i],
+		}
+		if !formatted {
+			editResult["line_ranges"] = ranges[i]
+		}
+		editResults = append(editResults, editResult)
+		for _, r := range ranges[i] {
+			allRanges = append(allRanges, r.String())
+		}
+	}
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	logging.Info("Edits applied to file",
+		"path", SanitizePathForDisplay(workingDir, resolvedPath),
+		"edits", len(edits),
+		"replacements", total,
+		"execution_time_ms", executionTime,
+	)
+
+	metadata := map[string]interface{}{
+		"edits":        editResults,
+		"replacements": total,
+	}
+	postEdit.addMetadata(metadata)
+
+	output := fmt.Sprintf("Applied %d edit(s) (%d replacement(s)) to %s", len(edits), total, path)
+	if formatted {
+		output += " (changed lines not reported, the formatter changed the file; re-read it for current line numbers)"
+	} else {
+		output += ", changed lines: " + strings.Join(allRanges, ", ")
+	}
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          output + postEdit.notes(),
+		ExecutionTimeMs: executionTime,
+		FilesAffected:   []string{path},
+		Metadata:        metadata,
+	}, nil
+}
+
+// RequiresApproval returns true as file modification requires approval
+func (t *MultiEditTool) RequiresApproval() bool {
+	return true
+}
+
+// RiskLevel returns the risk level for this tool
+func (t *MultiEditTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelDangerous
+}
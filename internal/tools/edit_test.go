@@ -0,0 +1,153 @@
+package tools_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// TestMultiEditTool tests the multi_edit tool
+func TestMultiEditTool(t *testing.T) {
+	tool := tools.NewMultiEditTool()
+
+	if tool.Name() != "multi_edit" {
+		t.Errorf("expected name 'multi_edit', got '%s'", tool.Name())
+	}
+	if !tool.RequiresApproval() {
+		t.Error("multi_edit should require approval")
+	}
+	if tool.RiskLevel() != types.RiskLevelDangerous {
+		t.Errorf("expected risk level %v, got %v", types.RiskLevelDangerous, tool.RiskLevel())
+	}
+
+	const original = "package main\n\nfunc a() {}\n\nfunc b() {}\n\nvar x = 1\nvar y = 1\n"
+
+	edit := func(oldString, newString string, replaceAll bool) map[string]interface{} {
+		return map[string]interface{}{"old_string": oldString, "new_string": newString, "replace_all": replaceAll}
+	}
+
+	tests := []struct {
+		name        string
+		edits       interface{}
+		wantErr     bool
+		errContains string
+		wantContent string
+		wantRanges  [][]tools.LineRange
+	}{
+		{
+			name: "success - ordered edits with line ranges",
+			edits: []interface{}{
+				edit("func a() {}", "func a() {\n\treturn\n}", false),
+				edit("func b() {}", "func c() {}", false),
+			},
+			wantContent: "package main\n\nfunc a() {\n\treturn\n}\n\nfunc c() {}\n\nvar x = 1\nvar y = 1\n",
+			wantRanges: [][]tools.LineRange{
+				{{Start: 3, End: 5}},
+				{{Start: 7, End: 7}},
+			},
+		},
+		{
+			name: "success - replace_all and a later edit that builds on it",
+			edits: []interface{}{
+				edit("= 1", "= 2", true),
+				edit("var y = 2", "var y = 3", false),
+			},
+			wantContent: "package main\n\nfunc a() {}\n\nfunc b() {}\n\nvar x = 2\nvar y = 3\n",
+			wantRanges: [][]tools.LineRange{
+				{{Start: 7, End: 7}, {Start: 8, End: 8}},
+				{{Start: 8, End: 8}},
+			},
+		},
+		{
+			name:        "success - edits passed as a JSON string",
+			edits:       `[{"old_string": "package main", "new_string": "package app"}]`,
+			wantContent: strings.Replace(original, "package main", "package app", 1),
+			wantRanges:  [][]tools.LineRange{{{Start: 1, End: 1}}},
+		},
+		{
+			name: "error - one missing edit leaves the file untouched",
+			edits: []interface{}{
+				edit("func a() {}", "func A() {}", false),
+				edit("func missing() {}", "", false),
+			},
+			wantErr:     true,
+			errContains: "edit #2: old_string not found",
+			wantContent: original,
+		},
+		{
+			name:        "error - ambiguous match without replace_all",
+			edits:       []interface{}{edit("= 1", "= 2", false)},
+			wantErr:     true,
+			errContains: "matches 2 times",
+			wantContent: original,
+		},
+		{
+			name: "error - earlier edit consumed the text",
+			edits: []interface{}{
+				edit("var x = 1", "var z = 1", false),
+				edit("var x", "var w", false),
+			},
+			wantErr:     true,
+			errContains: "earlier edits changed it",
+			wantContent: original,
+		},
+		{
+			name:        "error - empty edits",
+			edits:       []interface{}{},
+			wantErr:     true,
+			errContains: "non-empty array",
+			wantContent: original,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			path := filepath.Join(workingDir, "main.go")
+			if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+				t.Fatalf("setup failed: %v", err)
+			}
+
+			params := map[string]interface{}{"path": "main.go", "edits": tt.edits}
+			var result *types.ToolResult
+			err := tool.Validate(params, workingDir)
+			if err == nil {
+				result, err = tool.Execute(context.Background(), params, workingDir)
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+			if string(content) != tt.wantContent {
+				t.Errorf("expected content %q, got %q", tt.wantContent, string(content))
+			}
+
+			if tt.wantRanges != nil {
+				edits := result.Metadata["edits"].([]map[string]interface{})
+				for i, want := range tt.wantRanges {
+					if got := edits[i]["line_ranges"].([]tools.LineRange); !reflect.DeepEqual(got, want) {
+						t.Errorf("edit #%d: expected ranges %v, got %v", i+1, want, got)
+					}
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,7 +19,9 @@ const (
 )
 
 // CreateFileTool implements the create_file tool
-type CreateFileTool struct{}
+type CreateFileTool struct {
+	postEditStage
+}
 
 // NewCreateFileTool creates a new create_file tool instance
 func NewCreateFileTool() *CreateFileTool {
@@ -48,6 +51,10 @@ func (t *CreateFileTool) ParametersSchema() map[string]interface{} {
 				"type":        "string",
 				"description": "Content to write to the file",
 			},
+			"executable": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Make the file executable, for scripts (default: false)",
+			},
 		},
 		"required": []string{"path", "content"},
 	}
@@ -72,6 +79,12 @@ func (t *CreateFileTool) Validate(params map[string]interface{}, workingDir stri
 			len(content), maxFileSize)
 	}
 
+	if v, present := params["executable"]; present {
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("executable parameter must be a boolean")
+		}
+	}
+
 	// Validate path is within working directory
 	resolvedPath, err := ResolvePath(workingDir, path)
 	if err != nil {
@@ -93,6 +106,7 @@ func (t *CreateFileTool) Execute(ctx context.Context, params map[string]interfac
 	// Extract parameters
 	path := params["path"].(string)
 	content := params["content"].(string)
+	executable, _ := params["executable"].(bool)
 
 	// Resolve path
 	resolvedPath, err := ResolvePath(workingDir, path)
@@ -104,10 +118,14 @@ func (t *CreateFileTool) Execute(ctx context.Context, params map[string]interfac
 		}, err
 	}
 
+	postEdit := t.postEdit.process(resolvedPath, path, content)
+	content = postEdit.content
+
 	// Log operation
 	logging.Debug("Creating file",
 		"path", SanitizePathForDisplay(workingDir, resolvedPath),
 		"size_bytes", len(content),
+		"executable", executable,
 	)
 
 	// Create parent directories if needed
@@ -121,8 +139,13 @@ func (t *CreateFileTool) Execute(ctx context.Context, params map[string]interfac
 		}, fmt.Errorf("failed to create directory '%s': %w", dir, err)
 	}
 
+	perm := os.FileMode(0644)
+	if executable {
+		perm = 0755
+	}
+
 	// Write file
-	if err := os.WriteFile(resolvedPath, []byte(content), 0644); err != nil {
+	if err := writeFileAtomic(resolvedPath, []byte(content), perm); err != nil {
 		errMsg := fmt.Sprintf("failed to write file '%s': %v", path, err)
 		return &types.ToolResult{
 			Success:         false,
@@ -146,240 +169,56 @@ func (t *CreateFileTool) Execute(ctx context.Context, params map[string]interfac
 		"execution_time_ms", executionTime,
 	)
 
+	metadata := map[string]interface{}{
+		"size_bytes": fileSize,
+		"path":       path,
+		"executable": executable,
+	}
+	postEdit.addMetadata(metadata)
+
 	return &types.ToolResult{
 		Success:         true,
-		Output:          fmt.Sprintf("Created file: %s (%d bytes)", path, fileSize),
+		Output:          fmt.Sprintf("Created file: %s (%d bytes)", path, fileSize) + postEdit.notes(),
 		ExecutionTimeMs: executionTime,
 		FilesAffected:   []string{path},
-		Metadata: map[string]interface{}{
-			"size_bytes": fileSize,
-			"path":       path,
-		},
+		Metadata:        metadata,
 	}, nil
 }
 
-// RequiresApproval returns true as file creation requires approval
-func (t *CreateFileTool) RequiresApproval() bool {
-	return true
-}
-
-// RiskLevel returns the risk level for this tool
-func (t *CreateFileTool) RiskLevel() types.RiskLevel {
-	return types.RiskLevelSafeWrite
-}
-
-// ReadFileTool implements the read_file tool
-type ReadFileTool struct{}
-
-// NewReadFileTool creates a new read_file tool instance
-func NewReadFileTool() *ReadFileTool {
-	return &ReadFileTool{}
-}
-
-// Name returns the tool name
-func (r *ReadFileTool) Name() string {
-	return "read_file"
-}
-
-// Description returns the tool description
-func (r *ReadFileTool) Description() string {
-	return "Read the contents of a file, optionally specifying a line range"
-}
-
-// ParametersSchema returns the JSON schema for parameters
-func (r *ReadFileTool) ParametersSchema() map[string]interface{} {
-	return map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"path": map[string]interface{}{
-				"type":        "string",
-				"description": "Relative path to the file to read",
-			},
-			"start_line": map[string]interface{}{
-				"type":        "integer",
-				"description": "Starting line number (1-indexed, optional)",
-			},
-			"end_line": map[string]interface{}{
-				"type":        "integer",
-				"description": "Ending line number (1-indexed, inclusive, optional)",
-			},
-		},
-		"required": []string{"path"},
-	}
-}
-
-// Validate checks if parameters are valid
-func (r *ReadFileTool) Validate(params map[string]interface{}, workingDir string) error {
-	// Check required parameters
-	path, ok := params["path"].(string)
-	if !ok || path == "" {
-		return fmt.Errorf("path parameter is required and must be a non-empty string")
-	}
-
-	// Validate path is within working directory and exists
+// Preview returns the new file as an all-added diff
+func (t *CreateFileTool) Preview(params map[string]interface{}, workingDir string) (*types.ToolPreview, error) {
+	path, _ := params["path"].(string)
+	content, _ := params["content"].(string)
 	resolvedPath, err := ResolvePath(workingDir, path)
 	if err != nil {
-		return err
-	}
-
-	// Check if file exists
-	fileInfo, err := os.Stat(resolvedPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("file '%s' not found", path)
-		}
-		return fmt.Errorf("cannot access file '%s': %w", path, err)
-	}
-
-	// Check if it's a regular file
-	if fileInfo.IsDir() {
-		return fmt.Errorf("path '%s' is a directory, not a file", path)
+		return nil, err
 	}
-
-	// Validate line range if specified
-	if startLine, ok := params["start_line"].(float64); ok {
-		if startLine < 1 {
-			return fmt.Errorf("start_line must be positive, got %.0f", startLine)
-		}
-
-		if endLine, ok := params["end_line"].(float64); ok {
-			if endLine < startLine {
-				return fmt.Errorf("end_line (%.0f) must be >= start_line (%.0f)", endLine, startLine)
-			}
-		}
-	}
-
-	return nil
+	content = t.postEdit.process(resolvedPath, path, content).content
+	return &types.ToolPreview{
+		Diff:          fileDiff(path, nil, &content),
+		FilesAffected: []string{path},
+	}, nil
 }
 
-// Execute reads the file
-func (r *ReadFileTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
-	startTime := time.Now()
-
-	// Extract parameters
-	path := params["path"].(string)
-
-	// Resolve path
-	resolvedPath, err := ResolvePath(workingDir, path)
-	if err != nil {
-		return &types.ToolResult{
-			Success:         false,
-			Error:           err.Error(),
-			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
-		}, err
-	}
-
-	// Read file
-	content, err := os.ReadFile(resolvedPath)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed to read file '%s': %v", path, err)
-		return &types.ToolResult{
-			Success:         false,
-			Error:           errMsg,
-			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
-		}, fmt.Errorf("failed to read file '%s': %w", path, err)
-	}
-
-	// Get file info
-	fileSize := int64(len(content))
-
-	// Check size limit
-	if fileSize > maxFileSize {
-		logging.Warn("File exceeds size limit, truncating",
-			"path", path,
-			"size_bytes", fileSize,
-			"limit_bytes", maxFileSize,
-		)
-		content = content[:maxFileSize]
-	}
-
-	// Handle line range if specified
-	output := string(content)
-	totalLines := len(strings.Split(output, "\n"))
-	linesReturned := totalLines
-
-	if startLine, ok := params["start_line"].(float64); ok {
-		lines := strings.Split(output, "\n")
-		start := int(startLine) - 1 // Convert to 0-indexed
-		end := len(lines)
-
-		if endLine, ok := params["end_line"].(float64); ok {
-			end = int(endLine)
-			if end > len(lines) {
-				end = len(lines)
-			}
-		}
-
-		if start >= len(lines) {
-			return &types.ToolResult{
-				Success:         false,
-				Error:           fmt.Sprintf("line range invalid - file has %d lines, requested start line %d", len(lines), int(startLine)),
-				ExecutionTimeMs: time.Since(startTime).Milliseconds(),
-			}, fmt.Errorf("line range invalid")
-		}
-
-		if start < 0 {
-			start = 0
-		}
-
-		lines = lines[start:end]
-		output = strings.Join(lines, "\n")
-		linesReturned = len(lines)
-
-		logging.Debug("Reading file with line range",
-			"path", path,
-			"start_line", int(startLine),
-			"end_line", end,
-			"lines_returned", linesReturned,
-		)
-	}
-
-	executionTime := time.Since(startTime).Milliseconds()
-
-	logging.Info("File read",
-		"path", SanitizePathForDisplay(workingDir, resolvedPath),
-		"size_bytes", fileSize,
-		"lines", linesReturned,
-		"execution_time_ms", executionTime,
-	)
-
-	// Format output
-	var outputMsg string
-	if linesReturned < totalLines {
-		outputMsg = fmt.Sprintf("Contents of %s (lines %d-%d):\n%s",
-			path,
-			int(params["start_line"].(float64)),
-			int(params["start_line"].(float64))+linesReturned-1,
-			output)
-	} else {
-		outputMsg = fmt.Sprintf("Contents of %s:\n%s", path, output)
-	}
-
-	return &types.ToolResult{
-		Success:         true,
-		Output:          outputMsg,
-		ExecutionTimeMs: executionTime,
-		FilesAffected:   []string{},
-		Metadata: map[string]interface{}{
-			"total_lines":     totalLines,
-			"lines_returned":  linesReturned,
-			"file_size_bytes": fileSize,
-		},
-	}, nil
+// TargetPaths returns the file to be created
+func (t *CreateFileTool) TargetPaths(params map[string]interface{}, workingDir string) ([]string, error) {
+	return singleTarget(params, "path", workingDir)
 }
 
-// RequiresApproval returns true as file reading requires approval
-func (r *ReadFileTool) RequiresApproval() bool {
+// RequiresApproval returns true as file creation requires approval
+func (t *CreateFileTool) RequiresApproval() bool {
 	return true
 }
 
 // RiskLevel returns the risk level for this tool
-func (r *ReadFileTool) RiskLevel() types.RiskLevel {
-	return types.RiskLevelReadOnly
+func (t *CreateFileTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelSafeWrite
 }
 
 // ReplaceStringInFileTool implements the replace_string_in_file tool
-type ReplaceStringInFileTool struct{}
+type ReplaceStringInFileTool struct {
+	postEditStage
+}
 
 // NewReplaceStringInFileTool creates a new replace_string_in_file tool instance
 func NewReplaceStringInFileTool() *ReplaceStringInFileTool {
@@ -393,7 +232,9 @@ func (t *ReplaceStringInFileTool) Name() string {
 
 // Description returns the tool description
 func (t *ReplaceStringInFileTool) Description() string {
-	return "Replace a specific string in a file with new content. Only replaces the first occurrence."
+	return "Replace a string in a file with new content. old_string must match exactly once unless replace_all or " +
+		"expected_occurrences is set. Differences in line endings and indentation are tolerated; when nothing matches, " +
+		"the closest regions of the file are returned."
 }
 
 // ParametersSchema returns the JSON schema for parameters
@@ -407,12 +248,20 @@ func (t *ReplaceStringInFileTool) ParametersSchema() map[string]interface{} {
 			},
 			"old_string": map[string]interface{}{
 				"type":        "string",
-				"description": "Exact string to find and replace",
+				"description": "String to find and replace, including enough surrounding context to be unique",
 			},
 			"new_string": map[string]interface{}{
 				"type":        "string",
 				"description": "String to replace with",
 			},
+			"replace_all": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Replace every occurrence (default: false)",
+			},
+			"expected_occurrences": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of occurrences expected; all of them are replaced, and the edit fails if the count differs",
+			},
 		},
 		"required": []string{"path", "old_string", "new_string"},
 	}
@@ -436,6 +285,19 @@ func (t *ReplaceStringInFileTool) Validate(params map[string]interface{}, workin
 		return fmt.Errorf("new_string parameter is required and must be a string")
 	}
 
+	if v, present := params["replace_all"]; present {
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("replace_all parameter must be a boolean")
+		}
+	}
+
+	if v, present := params["expected_occurrences"]; present {
+		n, ok := v.(float64)
+		if !ok || n < 1 || n != float64(int(n)) {
+			return fmt.Errorf("expected_occurrences parameter must be a positive integer")
+		}
+	}
+
 	// Validate path is within working directory and exists
 	resolvedPath, err := ResolvePath(workingDir, path)
 	if err != nil {
@@ -459,108 +321,194 @@ func (t *ReplaceStringInFileTool) Validate(params map[string]interface{}, workin
 	return nil
 }
 
-// Execute replaces the string in the file
-func (t *ReplaceStringInFileTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
-	startTime := time.Now()
+// replacePlan is the outcome of locating and replacing old_string, before writing
+type replacePlan struct {
+	resolvedPath string
+	mode         os.FileMode
+	oldContent   string
+	newContent   string
+	matchLines   []int
+	strategy     MatchStrategy
+	postEdit     postEditResult
+}
 
-	// Extract parameters
+// plan locates old_string and computes the new file content. On failure the
+// returned string carries any near-miss report for the model.
+func (t *ReplaceStringInFileTool) plan(params map[string]interface{}, workingDir string) (*replacePlan, string, error) {
 	path := params["path"].(string)
 	oldString := params["old_string"].(string)
 	newString := params["new_string"].(string)
+	replaceAll, _ := params["replace_all"].(bool)
+	expectedOccurrences := 0
+	if n, ok := params["expected_occurrences"].(float64); ok {
+		expectedOccurrences = int(n)
+	}
 
 	// Resolve path
 	resolvedPath, err := ResolvePath(workingDir, path)
 	if err != nil {
-		return &types.ToolResult{
-			Success:         false,
-			Error:           err.Error(),
-			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
-		}, err
+		return nil, "", err
+	}
+
+	fileInfo, err := os.Stat(resolvedPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot access file '%s': %w", path, err)
 	}
 
 	// Read file
 	content, err := os.ReadFile(resolvedPath)
 	if err != nil {
-		errMsg := fmt.Sprintf("failed to read file '%s': %v", path, err)
-		return &types.ToolResult{
-			Success:         false,
-			Error:           errMsg,
-			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
-		}, fmt.Errorf("failed to read file '%s': %w", path, err)
+		return nil, "", fmt.Errorf("failed to read file '%s': %w", path, err)
 	}
 
 	contentStr := string(content)
 
-	// Check if old string exists
-	if !strings.Contains(contentStr, oldString) {
-		errMsg := fmt.Sprintf("string '%s' not found in file", oldString)
-		return &types.ToolResult{
-			Success:         false,
-			Error:           errMsg,
-			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
-		}, fmt.Errorf("%s", errMsg)
+	// Locate the string, tolerating line ending and indentation differences
+	matches, strategy := findMatches(contentStr, oldString)
+	if len(matches) == 0 {
+		candidates := closestCandidates(contentStr, oldString)
+		if len(candidates) == 0 {
+			return nil, "", fmt.Errorf("string not found in file '%s' and no similar region exists", path)
+		}
+		best := candidates[0]
+		return nil, formatCandidates(candidates),
+			fmt.Errorf("string not found in file '%s'; closest match at lines %d-%d (%.0f%% similar)",
+				path, best.StartLine, best.EndLine, best.Similarity*100)
+	}
+
+	matchLines := make([]int, len(matches))
+	for i, m := range matches {
+		matchLines[i] = lineNumberAt(contentStr, m.start)
+	}
+
+	switch {
+	case expectedOccurrences > 0 && len(matches) != expectedOccurrences:
+		return nil, "", fmt.Errorf("expected %d occurrence(s) in '%s' but found %d (at lines %s)",
+			expectedOccurrences, path, len(matches), joinInts(matchLines))
+	case expectedOccurrences == 0 && !replaceAll && len(matches) > 1:
+		return nil, "", fmt.Errorf("string found %d times in '%s' (at lines %s); include more context to make it unique, "+
+			"or set replace_all or expected_occurrences", len(matches), path, joinInts(matchLines))
+	}
+
+	// Replace from the end so earlier offsets stay valid
+	newContent := contentStr
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		replacement := adaptReplacement(contentStr, oldString, newString, strategy, m)
+		newContent = newContent[:m.start] + replacement + newContent[m.end:]
+	}
+
+	postEdit := t.postEdit.process(resolvedPath, path, keepConventions(contentStr, newContent))
+	return &replacePlan{
+		resolvedPath: resolvedPath,
+		mode:         fileInfo.Mode().Perm(),
+		oldContent:   contentStr,
+		newContent:   postEdit.content,
+		matchLines:   matchLines,
+		strategy:     strategy,
+		postEdit:     postEdit,
+	}, "", nil
+}
+
+// Preview returns the diff the replacement would produce
+func (t *ReplaceStringInFileTool) Preview(params map[string]interface{}, workingDir string) (*types.ToolPreview, error) {
+	r, _, err := t.plan(params, workingDir)
+	if err != nil {
+		return nil, err
 	}
+	path := params["path"].(string)
+	return &types.ToolPreview{
+		Diff:          fileDiff(path, &r.oldContent, &r.newContent),
+		FilesAffected: []string{path},
+	}, nil
+}
 
-	// Count occurrences
-	occurrences := strings.Count(contentStr, oldString)
+// Execute replaces the string in the file
+func (t *ReplaceStringInFileTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
 
-	// Replace only the first occurrence
-	newContent := strings.Replace(contentStr, oldString, newString, 1)
+	path := params["path"].(string)
 
-	// Write file
-	if err := os.WriteFile(resolvedPath, []byte(newContent), 0644); err != nil {
-		errMsg := fmt.Sprintf("failed to write file '%s': %v", path, err)
+	failure := func(output string, err error) (*types.ToolResult, error) {
 		return &types.ToolResult{
 			Success:         false,
-			Error:           errMsg,
+			Output:          output,
+			Error:           err.Error(),
 			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
-		}, fmt.Errorf("failed to write file '%s': %w", path, err)
+		}, err
 	}
 
-	executionTime := time.Since(startTime).Milliseconds()
+	r, output, err := t.plan(params, workingDir)
+	if err != nil {
+		return failure(output, err)
+	}
 
-	// Determine which line was changed (approximate)
-	lines := strings.Split(contentStr, "\n")
-	changedLine := 0
-	charsProcessed := 0
-	for i, line := range lines {
-		if strings.Contains(line, oldString) {
-			changedLine = i + 1 // 1-indexed
-			break
-		}
-		charsProcessed += len(line) + 1 // +1 for newline
-		if charsProcessed >= len(contentStr) {
-			break
-		}
+	// Write file
+	if err := writeFileAtomic(r.resolvedPath, []byte(r.newContent), r.mode); err != nil {
+		return failure("", fmt.Errorf("failed to write file '%s': %w", path, err))
 	}
 
+	executionTime := time.Since(startTime).Milliseconds()
+
 	logging.Info("String replaced in file",
-		"path", SanitizePathForDisplay(workingDir, resolvedPath),
-		"occurrences_found", occurrences,
-		"line_changed", changedLine,
+		"path", SanitizePathForDisplay(workingDir, r.resolvedPath),
+		"occurrences_replaced", len(r.matchLines),
+		"match_strategy", r.strategy,
+		"lines_changed", r.matchLines,
 		"execution_time_ms", executionTime,
 	)
 
 	// Create output message
-	output := fmt.Sprintf("Replaced 1 occurrence in %s", path)
-	if occurrences > 1 {
-		output = fmt.Sprintf("Replaced 1 occurrence in %s (found %d total occurrences, replaced only the first at line %d)",
-			path, occurrences, changedLine)
+	output = fmt.Sprintf("Replaced %d occurrence(s) in %s at line(s) %s", len(r.matchLines), path, joinInts(r.matchLines))
+	if r.strategy != MatchExact {
+		output += fmt.Sprintf(" (matched ignoring %s differences)", strings.ReplaceAll(string(r.strategy), "_", " "))
 	}
+	output += r.postEdit.notes()
+
+	metadata := map[string]interface{}{
+		"occurrences_found":    len(r.matchLines),
+		"occurrences_replaced": len(r.matchLines),
+		"lines_changed":        r.matchLines,
+		"match_strategy":       string(r.strategy),
+	}
+	r.postEdit.addMetadata(metadata)
 
 	return &types.ToolResult{
 		Success:         true,
 		Output:          output,
 		ExecutionTimeMs: executionTime,
 		FilesAffected:   []string{path},
-		Metadata: map[string]interface{}{
-			"occurrences_found":    occurrences,
-			"occurrences_replaced": 1,
-			"lines_changed":        []int{changedLine},
-		},
+		Metadata:        metadata,
 	}, nil
 }
 
+// TargetPaths returns the file to be modified
+func (t *ReplaceStringInFileTool) TargetPaths(params map[string]interface{}, workingDir string) ([]string, error) {
+	return singleTarget(params, "path", workingDir)
+}
+
+// singleTarget resolves the path parameter named param as a tool's only target
+func singleTarget(params map[string]interface{}, param, workingDir string) ([]string, error) {
+	path, ok := params[param].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("%s parameter is required and must be a non-empty string", param)
+	}
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return nil, err
+	}
+	return []string{resolvedPath}, nil
+}
+
+// joinInts formats numbers as a comma-separated list
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
 // RequiresApproval returns true as file modification requires approval
 func (t *ReplaceStringInFileTool) RequiresApproval() bool {
 	return true
@@ -420,6 +420,159 @@ func TestReplaceStringInFileTool(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success - CRLF file matched with LF old_string",
+			params: map[string]interface{}{
+				"path":       "win.txt",
+				"old_string": "one\ntwo",
+				"new_string": "1\n2",
+			},
+			setupFunc: func(workingDir string) error {
+				return os.WriteFile(filepath.Join(workingDir, "win.txt"), []byte("zero\r\none\r\ntwo\r\n"), 0644)
+			},
+			validateFunc: func(t *testing.T, workingDir string, result *types.ToolResult) {
+				content, _ := os.ReadFile(filepath.Join(workingDir, "win.txt"))
+				if string(content) != "zero\r\n1\r\n2\r\n" {
+					t.Errorf("expected CRLF preserved, got %q", string(content))
+				}
+				if result.Metadata["match_strategy"] != "line_endings" {
+					t.Errorf("expected line_endings strategy, got %v", result.Metadata["match_strategy"])
+				}
+			},
+		},
+		{
+			name: "success - spaces in old_string match tab-indented file",
+			params: map[string]interface{}{
+				"path":       "main.go",
+				"old_string": "    if x {\n        return 1\n    }",
+				"new_string": "    if x {\n        return 2\n    }",
+			},
+			setupFunc: func(workingDir string) error {
+				return os.WriteFile(filepath.Join(workingDir, "main.go"), []byte("func f() {\n\tif x {  \n\t\treturn 1\n\t}\n}\n"), 0644)
+			},
+			validateFunc: func(t *testing.T, workingDir string, result *types.ToolResult) {
+				content, _ := os.ReadFile(filepath.Join(workingDir, "main.go"))
+				if string(content) != "func f() {\n\tif x {\n\t    return 2\n\t}\n}\n" {
+					t.Errorf("unexpected content %q", string(content))
+				}
+				if result.Metadata["match_strategy"] != "indentation" {
+					t.Errorf("expected indentation strategy, got %v", result.Metadata["match_strategy"])
+				}
+			},
+		},
+		{
+			name: "success - replace_all",
+			params: map[string]interface{}{
+				"path":        "test.txt",
+				"old_string":  "a",
+				"new_string":  "b",
+				"replace_all": true,
+			},
+			setupFunc: func(workingDir string) error {
+				return os.WriteFile(filepath.Join(workingDir, "test.txt"), []byte("a\na\na"), 0644)
+			},
+			validateFunc: func(t *testing.T, workingDir string, result *types.ToolResult) {
+				content, _ := os.ReadFile(filepath.Join(workingDir, "test.txt"))
+				if string(content) != "b\nb\nb" {
+					t.Errorf("expected all occurrences replaced, got %q", string(content))
+				}
+				if result.Metadata["occurrences_replaced"] != 3 {
+					t.Errorf("expected 3 replacements, got %v", result.Metadata["occurrences_replaced"])
+				}
+			},
+		},
+		{
+			name: "success - expected_occurrences replaces all",
+			params: map[string]interface{}{
+				"path":                 "test.txt",
+				"old_string":           "a",
+				"new_string":           "b",
+				"expected_occurrences": float64(2),
+			},
+			setupFunc: func(workingDir string) error {
+				return os.WriteFile(filepath.Join(workingDir, "test.txt"), []byte("a a"), 0644)
+			},
+			validateFunc: func(t *testing.T, workingDir string, result *types.ToolResult) {
+				content, _ := os.ReadFile(filepath.Join(workingDir, "test.txt"))
+				if string(content) != "b b" {
+					t.Errorf("expected 'b b', got %q", string(content))
+				}
+			},
+		},
+		{
+			name: "error - ambiguous match",
+			params: map[string]interface{}{
+				"path":       "test.txt",
+				"old_string": "a",
+				"new_string": "b",
+			},
+			setupFunc: func(workingDir string) error {
+				return os.WriteFile(filepath.Join(workingDir, "test.txt"), []byte("a\na"), 0644)
+			},
+			validateFunc: func(t *testing.T, workingDir string, result *types.ToolResult) {
+				if result.Success || !strings.Contains(result.Error, "found 2 times") {
+					t.Errorf("expected ambiguity error, got %+v", result)
+				}
+				content, _ := os.ReadFile(filepath.Join(workingDir, "test.txt"))
+				if string(content) != "a\na" {
+					t.Errorf("expected file unchanged, got %q", string(content))
+				}
+			},
+		},
+		{
+			name: "error - expected_occurrences mismatch",
+			params: map[string]interface{}{
+				"path":                 "test.txt",
+				"old_string":           "a",
+				"new_string":           "b",
+				"expected_occurrences": float64(3),
+			},
+			setupFunc: func(workingDir string) error {
+				return os.WriteFile(filepath.Join(workingDir, "test.txt"), []byte("a\na"), 0644)
+			},
+			validateFunc: func(t *testing.T, workingDir string, result *types.ToolResult) {
+				if result.Success || !strings.Contains(result.Error, "expected 3 occurrence(s)") {
+					t.Errorf("expected occurrence count error, got %+v", result)
+				}
+			},
+		},
+		{
+			name: "error - near miss returns closest candidates",
+			params: map[string]interface{}{
+				"path":       "test.py",
+				"old_string": "def compute_total(items):\n    return sum(items)",
+				"new_string": "pass",
+			},
+			setupFunc: func(workingDir string) error {
+				content := "import os\n\ndef compute_totals(items):\n    return sum(item.price for item in items)\n"
+				return os.WriteFile(filepath.Join(workingDir, "test.py"), []byte(content), 0644)
+			},
+			validateFunc: func(t *testing.T, workingDir string, result *types.ToolResult) {
+				if result.Success {
+					t.Fatal("expected failure")
+				}
+				if !strings.Contains(result.Error, "closest match at lines 3-4") {
+					t.Errorf("expected closest match in error, got %q", result.Error)
+				}
+				if !strings.Contains(result.Output, "3: def compute_totals(items):") {
+					t.Errorf("expected numbered candidate text in output, got %q", result.Output)
+				}
+			},
+		},
+		{
+			name: "error - invalid expected_occurrences",
+			params: map[string]interface{}{
+				"path":                 "test.txt",
+				"old_string":           "a",
+				"new_string":           "b",
+				"expected_occurrences": float64(0),
+			},
+			setupFunc: func(workingDir string) error {
+				return os.WriteFile(filepath.Join(workingDir, "test.txt"), []byte("a"), 0644)
+			},
+			wantErr:     true,
+			errContains: "positive integer",
+		},
 		{
 			name: "error - file not found",
 			params: map[string]interface{}{
@@ -694,6 +847,59 @@ func TestListDirTool(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success - ignored entries hidden",
+			params: map[string]interface{}{
+				"path": ".",
+			},
+			setupFunc: func(workingDir string) error {
+				os.Mkdir(filepath.Join(workingDir, ".git"), 0755)
+				os.Mkdir(filepath.Join(workingDir, "build"), 0755)
+				os.WriteFile(filepath.Join(workingDir, "main.go"), []byte("package main"), 0644)
+				return os.WriteFile(filepath.Join(workingDir, ".gitignore"), []byte("build/\n"), 0644)
+			},
+			wantErr: false,
+			validateFunc: func(t *testing.T, result *types.ToolResult) {
+				if !strings.Contains(result.Output, "main.go") {
+					t.Errorf("expected output to contain 'main.go', got: %s", result.Output)
+				}
+				if strings.Contains(result.Output, "build") || strings.Contains(result.Output, ".git"+string(filepath.Separator)) {
+					t.Errorf("expected ignored entries to be hidden, got: %s", result.Output)
+				}
+				if !strings.Contains(result.Output, "2 ignored entries hidden") {
+					t.Errorf("expected hidden entries note, got: %s", result.Output)
+				}
+			},
+		},
+		{
+			name: "success - include ignored entries",
+			params: map[string]interface{}{
+				"path":            ".",
+				"include_ignored": true,
+			},
+			setupFunc: func(workingDir string) error {
+				os.Mkdir(filepath.Join(workingDir, "build"), 0755)
+				return os.WriteFile(filepath.Join(workingDir, ".gitignore"), []byte("build/\n"), 0644)
+			},
+			wantErr: false,
+			validateFunc: func(t *testing.T, result *types.ToolResult) {
+				if !strings.Contains(result.Output, "build") {
+					t.Errorf("expected output to contain 'build', got: %s", result.Output)
+				}
+				if strings.Contains(result.Output, "hidden") {
+					t.Errorf("expected no hidden entries note, got: %s", result.Output)
+				}
+			},
+		},
+		{
+			name: "error - include_ignored not a boolean",
+			params: map[string]interface{}{
+				"path":            ".",
+				"include_ignored": "yes",
+			},
+			wantErr:     true,
+			errContains: "include_ignored",
+		},
 		{
 			name: "error - directory not found",
 			params: map[string]interface{}{
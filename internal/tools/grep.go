@@ -0,0 +1,550 @@
+// Package tools implements the concurrent search engine behind grep_search
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/shizhMSFT/wink-code/internal/index"
+)
+
+const (
+	// defaultGrepPerFile is how many matching lines grep_search shows per file by default
+	defaultGrepPerFile = 20
+	// maxGrepContext bounds context_before and context_after
+	maxGrepContext = 20
+	// maxGrepLineBytes is how much of a line is searched; the rest of a longer line is skipped
+	maxGrepLineBytes = 1024 * 1024
+	// maxGrepLineChars clips displayed lines, such as minified code, around the match
+	maxGrepLineChars = 400
+	// maxGrepBufferSize is the largest file read into memory at once; larger files are
+	// streamed line by line and skipped in multiline mode
+	maxGrepBufferSize = 10 * 1024 * 1024
+	// binarySniffSize is how much of a file is checked for NUL bytes
+	binarySniffSize = 512
+)
+
+// grep_search output modes
+const (
+	grepOutputContent = "content"
+	grepOutputFiles   = "files_with_matches"
+	grepOutputCount   = "count"
+)
+
+// grepOptions control how one file is searched
+type grepOptions struct {
+	re *regexp.Regexp
+	// literal is set for case-sensitive plain-text patterns, which skip the regexp
+	// engine and files that don't contain them
+	literal string
+	// foldedLiteral is the case-folded text of a case-insensitive plain-text pattern;
+	// files that don't contain it once folded the same way are skipped
+	foldedLiteral string
+	// prefilter matches whole files that may contain a matching line, so files
+	// without one are skipped without splitting them into lines
+	prefilter *regexp.Regexp
+	// multiline matches re against the whole file instead of line by line
+	multiline bool
+	before    int
+	after     int
+	// countOnly counts matching lines without keeping them
+	countOnly bool
+	// firstOnly stops at the first matching line
+	firstOnly bool
+}
+
+// newGrepRegexp compiles a grep_search pattern; literal patterns are quoted, and
+// multiline patterns let ^ and $ match at line boundaries
+func newGrepRegexp(pattern string, isRegex, caseInsensitive, multiline bool) (*regexp.Regexp, error) {
+	if !isRegex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	flags := ""
+	if caseInsensitive {
+		flags += "i"
+	}
+	if multiline {
+		flags += "m"
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// newGrepPrefilter compiles a line pattern for matching against whole files, with ^
+// and $ matching at line boundaries. It returns nil for patterns anchored with \A or
+// \z, which could match a line but not the file.
+func newGrepPrefilter(pattern string, isRegex, caseInsensitive bool) *regexp.Regexp {
+	if !isRegex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	flags := "(?m)"
+	if caseInsensitive {
+		flags = "(?mi)"
+	}
+	tree, err := syntax.Parse(flags+pattern, syntax.Perl)
+	if err != nil || anchoredToText(tree) {
+		return nil
+	}
+	re, err := regexp.Compile(flags + pattern)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// anchoredToText reports whether a parsed pattern uses \A or \z
+func anchoredToText(re *syntax.Regexp) bool {
+	if re.Op == syntax.OpBeginText || re.Op == syntax.OpEndText {
+		return true
+	}
+	for _, sub := range re.Sub {
+		if anchoredToText(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// grepLine is a matching or context line of a file
+type grepLine struct {
+	num   int
+	text  string
+	match bool
+}
+
+// grepFileResult holds the matching lines of one file with their context
+type grepFileResult struct {
+	path    string
+	lines   []grepLine
+	matches int
+	// capped is set when the file has more matching lines than were kept
+	capped bool
+}
+
+// grepCollector gathers matching lines and the context around them as a file is read
+type grepCollector struct {
+	opts   *grepOptions
+	limit  int
+	result *grepFileResult
+	// pending holds the last opts.before non-matching lines
+	pending   []grepLine
+	afterLeft int
+}
+
+// add records one line and reports whether the rest of the file can be skipped
+func (c *grepCollector) add(num int, text string, match bool) bool {
+	r := c.result
+	if match {
+		if c.limit > 0 && r.matches >= c.limit {
+			r.capped = true
+			return true
+		}
+		r.matches++
+		if c.opts.firstOnly {
+			return true
+		}
+		if c.opts.countOnly {
+			return false
+		}
+		r.lines = append(r.lines, c.pending...)
+		c.pending = c.pending[:0]
+		r.lines = append(r.lines, grepLine{num: num, text: clipGrepLine(text, c.opts.re), match: true})
+		c.afterLeft = c.opts.after
+		return false
+	}
+
+	if c.opts.countOnly {
+		return false
+	}
+	if c.afterLeft > 0 {
+		r.lines = append(r.lines, grepLine{num: num, text: clipGrepLine(text, nil)})
+		c.afterLeft--
+		return false
+	}
+	if c.opts.before > 0 {
+		if len(c.pending) == c.opts.before {
+			c.pending = append(c.pending[:0], c.pending[1:]...)
+		}
+		c.pending = append(c.pending, grepLine{num: num, text: clipGrepLine(text, nil)})
+	}
+	return false
+}
+
+// matchLine reports whether a single line matches the pattern
+func (o *grepOptions) matchLine(line string) bool {
+	if o.literal != "" {
+		return strings.Contains(line, o.literal)
+	}
+	return o.re.MatchString(line)
+}
+
+// grepFile searches one file, keeping at most limit matching lines (0 for no limit).
+// Binary files yield a nil result.
+func grepFile(path string, opts *grepOptions, limit int) (*grepFileResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &grepCollector{opts: opts, limit: limit, result: &grepFileResult{}}
+
+	if info.Size() > maxGrepBufferSize {
+		if isBinaryFile(path) {
+			return nil, nil
+		}
+		if opts.multiline {
+			return c.result, nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return c.result, c.scanLines(file)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data[:min(len(data), binarySniffSize)], 0) >= 0 {
+		return nil, nil
+	}
+	if opts.literal != "" {
+		if !bytes.Contains(data, []byte(opts.literal)) {
+			return c.result, nil
+		}
+	} else if opts.foldedLiteral != "" {
+		if !bytes.Contains(index.Fold(data), []byte(opts.foldedLiteral)) {
+			return c.result, nil
+		}
+	} else if opts.prefilter != nil && bytes.IndexByte(data, '\r') < 0 && !opts.prefilter.Match(data) {
+		// Lines are matched without their CR, so CRLF files skip the prefilter
+		return c.result, nil
+	}
+	if opts.multiline {
+		c.scanWhole(string(data))
+		return c.result, nil
+	}
+	return c.result, c.scanLines(bytes.NewReader(data))
+}
+
+// scanLines matches the pattern line by line
+func (c *grepCollector) scanLines(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	for num := 1; ; num++ {
+		line, _, err := readLineBytes(reader, maxGrepLineBytes)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		text := strings.TrimSuffix(string(line), "\r")
+		if c.add(num, text, c.opts.matchLine(text)) {
+			return nil
+		}
+	}
+}
+
+// scanWhole matches the pattern against the whole file so matches can span lines;
+// every line a match touches counts as a matching line
+func (c *grepCollector) scanWhole(content string) {
+	lines := strings.Split(content, "\n")
+	if strings.HasSuffix(content, "\n") {
+		lines = lines[:len(lines)-1]
+	}
+	lineStarts := make([]int, len(lines))
+	offset := 0
+	for i, line := range lines {
+		lineStarts[i] = offset
+		offset += len(line) + 1
+	}
+	lineAt := func(pos int) int {
+		lo, hi := 0, len(lineStarts)-1
+		for lo < hi {
+			mid := (lo + hi + 1) / 2
+			if lineStarts[mid] <= pos {
+				lo = mid
+			} else {
+				hi = mid - 1
+			}
+		}
+		return lo
+	}
+
+	matched := map[int]bool{}
+	for _, loc := range c.opts.re.FindAllStringIndex(content, -1) {
+		end := loc[1]
+		if end > loc[0] {
+			end--
+		}
+		for i := lineAt(loc[0]); i <= lineAt(end); i++ {
+			matched[i] = true
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	for i, line := range lines {
+		if c.add(i+1, strings.TrimSuffix(line, "\r"), matched[i]) {
+			return
+		}
+	}
+}
+
+// clipGrepLine shortens a long line to maxGrepLineChars, keeping the first match of re
+// in view when there is one
+func clipGrepLine(text string, re *regexp.Regexp) string {
+	if len(text) <= maxGrepLineChars {
+		return text
+	}
+	start := 0
+	if re != nil {
+		if loc := re.FindStringIndex(text); loc != nil && loc[1] > maxGrepLineChars {
+			start = max(loc[0]-maxGrepLineChars/4, 0)
+		}
+	}
+	end := min(start+maxGrepLineChars, len(text))
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end--
+	}
+
+	clipped := text[start:end]
+	if start > 0 {
+		clipped = fmt.Sprintf("[%d bytes] ...%s", start, clipped)
+	}
+	if end < len(text) {
+		clipped += fmt.Sprintf("... [%d more bytes]", len(text)-end)
+	}
+	return clipped
+}
+
+// truncate keeps the first n matching lines, for a file that crosses the result limit
+func (r *grepFileResult) truncate(n int) {
+	seen := 0
+	for i, line := range r.lines {
+		if line.match {
+			if seen == n {
+				r.lines = r.lines[:i]
+				break
+			}
+			seen++
+		}
+	}
+	r.matches = n
+	r.capped = true
+}
+
+// format renders the file's lines ripgrep-style: "N:" marks a matching line, "N-" a
+// context line, and "--" separates groups that are not adjacent
+func (r *grepFileResult) format(b *strings.Builder, perFile int) {
+	b.WriteString(r.path + "\n")
+	prev := 0
+	for _, line := range r.lines {
+		if prev > 0 && line.num > prev+1 {
+			b.WriteString("  --\n")
+		}
+		sep := "-"
+		if line.match {
+			sep = ":"
+		}
+		fmt.Fprintf(b, "  %d%s %s\n", line.num, sep, line.text)
+		prev = line.num
+	}
+	if r.capped && r.matches == perFile {
+		fmt.Fprintf(b, "  ... (more matches; showing the first %d, raise max_per_file to see more)\n", perFile)
+	}
+}
+
+// grepSearch is one grep_search run over the workspace. A single goroutine enumerates
+// files in walk order while a bounded pool of workers scans them; results are consumed
+// in walk order, so output does not depend on scheduling. Once the result limit is hit
+// the walk and the workers stop early.
+type grepSearch struct {
+	workingDir     string
+	filePattern    string
+	includeIgnored bool
+	opts           *grepOptions
+	// limit bounds matching lines, or matching files when limitFiles is set
+	limit      int
+	limitFiles bool
+	// perFile bounds the matching lines kept per file; 0 keeps all
+	perFile int
+	workers int
+	// files, when set, replaces the walk with a list narrowed by the search index
+	files []grepJob
+}
+
+// grepOutcome is what a grep search found
+type grepOutcome struct {
+	results       []*grepFileResult
+	totalMatches  int
+	filesSearched int
+	limitReached  bool
+}
+
+// grepJob is a file to scan; seq is its position in walk order
+type grepJob struct {
+	seq  int
+	path string
+	rel  string
+	// ruledOut files cannot match according to the search index and are not read
+	ruledOut bool
+	// skipped files are binary or unreadable according to the search index
+	skipped bool
+}
+
+// grepDone is the outcome of scanning one file
+type grepDone struct {
+	seq    int
+	result *grepFileResult
+}
+
+// defaultGrepWorkers is the number of files scanned in parallel
+func defaultGrepWorkers() int {
+	return min(runtime.GOMAXPROCS(0), 16)
+}
+
+// run executes the search
+func (s *grepSearch) run(ctx context.Context) (*grepOutcome, error) {
+	searchCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	workers := max(s.workers, 1)
+	jobs := make(chan grepJob, workers*4)
+	done := make(chan grepDone, workers*4)
+
+	var walkErr error
+	go func() {
+		defer close(jobs)
+		if s.files != nil {
+			for _, job := range s.files {
+				select {
+				case jobs <- job:
+				case <-searchCtx.Done():
+					walkErr = searchCtx.Err()
+					return
+				}
+			}
+			return
+		}
+
+		seq := 0
+		walkErr = walkWorkspace(searchCtx, s.workingDir, s.workingDir, s.includeIgnored, func(path string, d fs.DirEntry) error {
+			if d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(s.workingDir, path)
+			if err != nil {
+				return nil
+			}
+			if s.filePattern != "" {
+				if matched, err := matchPathGlob(s.filePattern, rel); err != nil || !matched {
+					return nil
+				}
+			}
+			select {
+			case jobs <- grepJob{seq: seq, path: path, rel: rel}:
+				seq++
+				return nil
+			case <-searchCtx.Done():
+				return searchCtx.Err()
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				var result *grepFileResult
+				switch {
+				case job.skipped:
+				case job.ruledOut:
+					result = &grepFileResult{path: job.rel}
+				case searchCtx.Err() == nil:
+					// Unreadable files are skipped like binary ones
+					result, _ = grepFile(job.path, s.opts, s.perFile)
+					if result != nil {
+						result.path = job.rel
+					}
+				}
+				done <- grepDone{seq: job.seq, result: result}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Reassemble walk order; done is drained fully so no worker blocks
+	outcome := &grepOutcome{}
+	pending := map[int]*grepFileResult{}
+	next := 0
+	for d := range done {
+		pending[d.seq] = d.result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if !outcome.limitReached && result != nil {
+				s.consume(outcome, result)
+				if outcome.limitReached {
+					stop()
+				}
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if walkErr != nil && !(outcome.limitReached && errors.Is(walkErr, context.Canceled)) {
+		return nil, walkErr
+	}
+	return outcome, nil
+}
+
+// consume adds the next file's result in walk order, applying the result limit
+func (s *grepSearch) consume(outcome *grepOutcome, result *grepFileResult) {
+	outcome.filesSearched++
+	if result.matches == 0 {
+		return
+	}
+	if !s.limitFiles && result.matches > s.limit-outcome.totalMatches {
+		result.truncate(s.limit - outcome.totalMatches)
+	}
+	outcome.results = append(outcome.results, result)
+	outcome.totalMatches += result.matches
+
+	if s.limitFiles {
+		outcome.limitReached = len(outcome.results) >= s.limit
+	} else {
+		outcome.limitReached = outcome.totalMatches >= s.limit
+	}
+}
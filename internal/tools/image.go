@@ -0,0 +1,292 @@
+// Package tools implements the view_image tool and image loading
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/logging"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+const (
+	// DefaultMaxImageDimension is the longest side images are downscaled to
+	DefaultMaxImageDimension = 1024
+	maxImageFileSize         = 20 * 1024 * 1024 // 20MB
+	jpegQuality              = 85
+	// maxImagePixels rejects images whose declared size would make decoding allocate
+	// gigabytes, which a small compressed file can claim
+	maxImagePixels = 50 * 1000 * 1000
+)
+
+// LoadedImage is an image read from the workspace and prepared for the model
+type LoadedImage struct {
+	Part           types.ContentPart
+	Width          int
+	Height         int
+	OriginalWidth  int
+	OriginalHeight int
+}
+
+// LoadImage reads a PNG or JPEG file inside the working directory and downscales it
+// so that neither side exceeds maxDimension (DefaultMaxImageDimension if <= 0)
+func LoadImage(workingDir, path string, maxDimension int) (*LoadedImage, error) {
+	if maxDimension <= 0 {
+		maxDimension = DefaultMaxImageDimension
+	}
+
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return nil, err
+	}
+
+	fileInfo, err := os.Stat(resolvedPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("image '%s' not found", path)
+		}
+		return nil, fmt.Errorf("cannot access image '%s': %w", path, err)
+	}
+	if fileInfo.IsDir() {
+		return nil, fmt.Errorf("path '%s' is a directory, not an image", path)
+	}
+	if fileInfo.Size() > maxImageFileSize {
+		return nil, fmt.Errorf("image '%s' (%d bytes) exceeds maximum allowed size (%d bytes)",
+			path, fileInfo.Size(), maxImageFileSize)
+	}
+
+	data, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image '%s': %w", path, err)
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image '%s' (only PNG and JPEG are supported): %w", path, err)
+	}
+	if format != "png" && format != "jpeg" {
+		return nil, fmt.Errorf("unsupported image format '%s' for '%s' (only PNG and JPEG are supported)", format, path)
+	}
+	if pixels := int64(config.Width) * int64(config.Height); pixels > maxImagePixels {
+		return nil, fmt.Errorf("image '%s' (%dx%d) exceeds maximum allowed size (%d megapixels)",
+			path, config.Width, config.Height, maxImagePixels/1000000)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image '%s' (only PNG and JPEG are supported): %w", path, err)
+	}
+
+	bounds := img.Bounds()
+	loaded := &LoadedImage{
+		Width:          bounds.Dx(),
+		Height:         bounds.Dy(),
+		OriginalWidth:  bounds.Dx(),
+		OriginalHeight: bounds.Dy(),
+	}
+
+	// Re-encode only when the image had to be downscaled
+	if bounds.Dx() > maxDimension || bounds.Dy() > maxDimension {
+		scaled := downscaleImage(img, maxDimension)
+		loaded.Width = scaled.Bounds().Dx()
+		loaded.Height = scaled.Bounds().Dy()
+
+		var buf bytes.Buffer
+		if format == "png" {
+			err = png.Encode(&buf, scaled)
+		} else {
+			err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: jpegQuality})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode downscaled image '%s': %w", path, err)
+		}
+		data = buf.Bytes()
+	}
+
+	loaded.Part = types.ContentPart{
+		Type:     types.ContentPartImage,
+		MIMEType: "image/" + format,
+		Data:     base64.StdEncoding.EncodeToString(data),
+		Source:   filepath.ToSlash(SanitizePathForDisplay(workingDir, resolvedPath)),
+	}
+
+	return loaded, nil
+}
+
+// downscaleImage shrinks img so its longest side is maxDimension, averaging the
+// source pixels covered by each destination pixel (box filter)
+func downscaleImage(img image.Image, maxDimension int) image.Image {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	scale := float64(maxDimension) / float64(max(srcW, srcH))
+	dstW := max(1, int(float64(srcW)*scale+0.5))
+	dstH := max(1, int(float64(srcH)*scale+0.5))
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		sy0 := bounds.Min.Y + y*srcH/dstH
+		sy1 := max(sy0+1, bounds.Min.Y+(y+1)*srcH/dstH)
+
+		for x := 0; x < dstW; x++ {
+			sx0 := bounds.Min.X + x*srcW/dstW
+			sx1 := max(sx0+1, bounds.Min.X+(x+1)*srcW/dstW)
+
+			var r, g, b, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+
+	return dst
+}
+
+// ViewImageTool implements the view_image tool
+type ViewImageTool struct {
+	maxDimension int
+}
+
+// NewViewImageTool creates a new view_image tool that downscales images to maxDimension
+// (DefaultMaxImageDimension if <= 0)
+func NewViewImageTool(maxDimension int) *ViewImageTool {
+	if maxDimension <= 0 {
+		maxDimension = DefaultMaxImageDimension
+	}
+	return &ViewImageTool{maxDimension: maxDimension}
+}
+
+// Name returns the tool name
+func (t *ViewImageTool) Name() string {
+	return "view_image"
+}
+
+// Description returns the tool description
+func (t *ViewImageTool) Description() string {
+	return "Look at a PNG or JPEG image in the workspace (screenshots, diagrams). The image is attached to your next message."
+}
+
+// ParametersSchema returns the JSON schema for parameters
+func (t *ViewImageTool) ParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Relative path to the PNG or JPEG image",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+// Validate checks if parameters are valid
+func (t *ViewImageTool) Validate(params map[string]interface{}, workingDir string) error {
+	path, ok := params["path"].(string)
+	if !ok || path == "" {
+		return fmt.Errorf("path parameter is required and must be a non-empty string")
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png", ".jpg", ".jpeg":
+	default:
+		return fmt.Errorf("path '%s' is not a PNG or JPEG image", path)
+	}
+
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(resolvedPath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("image '%s' not found", path)
+		}
+		return fmt.Errorf("cannot access image '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+// Execute loads the image and attaches it to the result
+func (t *ViewImageTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
+
+	path := params["path"].(string)
+
+	loaded, err := LoadImage(workingDir, path, t.maxDimension)
+	if err != nil {
+		return &types.ToolResult{
+			Success:         false,
+			Error:           err.Error(),
+			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+		}, err
+	}
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	logging.Info("Image loaded",
+		"path", loaded.Part.Source,
+		"width", loaded.Width,
+		"height", loaded.Height,
+		"original_width", loaded.OriginalWidth,
+		"original_height", loaded.OriginalHeight,
+		"execution_time_ms", executionTime,
+	)
+
+	output := fmt.Sprintf("Loaded image %s (%dx%d). It is attached to the next message.", path, loaded.Width, loaded.Height)
+	if loaded.Width != loaded.OriginalWidth {
+		output = fmt.Sprintf("Loaded image %s (downscaled from %dx%d to %dx%d). It is attached to the next message.",
+			path, loaded.OriginalWidth, loaded.OriginalHeight, loaded.Width, loaded.Height)
+	}
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          output,
+		ExecutionTimeMs: executionTime,
+		FilesAffected:   []string{},
+		Metadata: map[string]interface{}{
+			"path":            path,
+			"mime_type":       loaded.Part.MIMEType,
+			"width":           loaded.Width,
+			"height":          loaded.Height,
+			"original_width":  loaded.OriginalWidth,
+			"original_height": loaded.OriginalHeight,
+		},
+		Attachments: []types.ContentPart{loaded.Part},
+	}, nil
+}
+
+// RequiresApproval returns true as reading files requires approval
+func (t *ViewImageTool) RequiresApproval() bool {
+	return true
+}
+
+// RiskLevel returns the risk level for this tool
+func (t *ViewImageTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelReadOnly
+}
@@ -0,0 +1,164 @@
+package tools_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// writeTestImage writes a solid-color image of the given size and format
+func writeTestImage(t *testing.T, path string, width, height int, format string) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 50, B: 50, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if format == "png" {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, nil)
+	}
+	if err != nil {
+		t.Fatalf("failed to encode test image: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write test image: %v", err)
+	}
+}
+
+func TestLoadImage(t *testing.T) {
+	workingDir := t.TempDir()
+	writeTestImage(t, filepath.Join(workingDir, "large.png"), 2000, 1000, "png")
+	writeTestImage(t, filepath.Join(workingDir, "small.jpg"), 64, 32, "jpeg")
+	if err := os.WriteFile(filepath.Join(workingDir, "notes.txt"), []byte("not an image"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	t.Run("downscales large image", func(t *testing.T) {
+		loaded, err := tools.LoadImage(workingDir, "large.png", 500)
+		if err != nil {
+			t.Fatalf("LoadImage failed: %v", err)
+		}
+		if loaded.Width != 500 || loaded.Height != 250 {
+			t.Errorf("expected 500x250, got %dx%d", loaded.Width, loaded.Height)
+		}
+		if loaded.OriginalWidth != 2000 || loaded.OriginalHeight != 1000 {
+			t.Errorf("expected original 2000x1000, got %dx%d", loaded.OriginalWidth, loaded.OriginalHeight)
+		}
+		if loaded.Part.Type != types.ContentPartImage || loaded.Part.MIMEType != "image/png" {
+			t.Errorf("unexpected part: type=%s mime=%s", loaded.Part.Type, loaded.Part.MIMEType)
+		}
+
+		data, err := base64.StdEncoding.DecodeString(loaded.Part.Data)
+		if err != nil {
+			t.Fatalf("invalid base64: %v", err)
+		}
+		cfg, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("downscaled data is not a PNG: %v", err)
+		}
+		if cfg.Width != 500 || cfg.Height != 250 {
+			t.Errorf("encoded image is %dx%d, want 500x250", cfg.Width, cfg.Height)
+		}
+	})
+
+	t.Run("keeps small image unchanged", func(t *testing.T) {
+		loaded, err := tools.LoadImage(workingDir, "small.jpg", 500)
+		if err != nil {
+			t.Fatalf("LoadImage failed: %v", err)
+		}
+		original, _ := os.ReadFile(filepath.Join(workingDir, "small.jpg"))
+		if loaded.Part.Data != base64.StdEncoding.EncodeToString(original) {
+			t.Error("expected small image to be attached unchanged")
+		}
+		if loaded.Part.MIMEType != "image/jpeg" || loaded.Part.Source != "small.jpg" {
+			t.Errorf("unexpected part: mime=%s source=%s", loaded.Part.MIMEType, loaded.Part.Source)
+		}
+	})
+
+	t.Run("rejects path outside working directory", func(t *testing.T) {
+		if _, err := tools.LoadImage(workingDir, "../outside.png", 500); err == nil {
+			t.Error("expected path traversal to be rejected")
+		}
+	})
+
+	t.Run("rejects image with too many pixels before decoding", func(t *testing.T) {
+		// A tiny PNG whose header declares 100000x100000 pixels
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+			t.Fatalf("failed to encode test image: %v", err)
+		}
+		data := buf.Bytes()
+		binary.BigEndian.PutUint32(data[16:], 100000)
+		binary.BigEndian.PutUint32(data[20:], 100000)
+		binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+		if err := os.WriteFile(filepath.Join(workingDir, "bomb.png"), data, 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		_, err := tools.LoadImage(workingDir, "bomb.png", 500)
+		if err == nil || !strings.Contains(err.Error(), "100000x100000") {
+			t.Errorf("expected the image to be rejected for its size, got %v", err)
+		}
+	})
+
+	t.Run("rejects non-image file", func(t *testing.T) {
+		if _, err := tools.LoadImage(workingDir, "notes.txt", 500); err == nil {
+			t.Error("expected non-image file to be rejected")
+		}
+	})
+}
+
+func TestViewImageTool(t *testing.T) {
+	workingDir := t.TempDir()
+	writeTestImage(t, filepath.Join(workingDir, "screenshot.png"), 300, 200, "png")
+
+	tool := tools.NewViewImageTool(100)
+	if tool.Name() != "view_image" {
+		t.Errorf("expected name 'view_image', got '%s'", tool.Name())
+	}
+	if tool.RiskLevel() != types.RiskLevelReadOnly {
+		t.Errorf("expected risk level %v, got %v", types.RiskLevelReadOnly, tool.RiskLevel())
+	}
+
+	if err := tool.Validate(map[string]interface{}{"path": "diagram.svg"}, workingDir); err == nil {
+		t.Error("expected unsupported extension to fail validation")
+	}
+	if err := tool.Validate(map[string]interface{}{"path": "missing.png"}, workingDir); err == nil {
+		t.Error("expected missing image to fail validation")
+	}
+
+	params := map[string]interface{}{"path": "screenshot.png"}
+	if err := tool.Validate(params, workingDir); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	result, err := tool.Execute(context.Background(), params, workingDir)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !result.Success || len(result.Attachments) != 1 {
+		t.Fatalf("expected one attachment, got success=%v attachments=%d", result.Success, len(result.Attachments))
+	}
+	if !strings.Contains(result.Output, "downscaled from 300x200 to 100x67") {
+		t.Errorf("unexpected output: %s", result.Output)
+	}
+}
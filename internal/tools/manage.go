@@ -0,0 +1,613 @@
+// Package tools implements file management tools (delete, move and copy)
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/logging"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// resolveManagedPath resolves a path for a file management operation, refusing the
+// workspace root itself
+func resolveManagedPath(workingDir, path, param string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("%s parameter is required and must be a non-empty string", param)
+	}
+
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return "", err
+	}
+
+	absWorking, err := filepath.Abs(workingDir)
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory '%s': %w", workingDir, err)
+	}
+	if filepath.Clean(absWorking) == resolvedPath || filepath.Clean(workingDir) == resolvedPath {
+		return "", fmt.Errorf("refusing to operate on the workspace root ('%s')", path)
+	}
+
+	return resolvedPath, nil
+}
+
+// boolParam reads an optional boolean parameter
+func boolParam(params map[string]interface{}, name string) (bool, error) {
+	v, present := params[name]
+	if !present || v == nil {
+		return false, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s parameter must be a boolean", name)
+	}
+	return b, nil
+}
+
+// isWithin reports whether path is dir itself or inside it
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// listTree returns every entry under root (root included) as paths relative to the
+// working directory, along with the number of files and directories
+func listTree(workingDir, root string) ([]string, int, int, error) {
+	var paths []string
+	files, dirs := 0, 0
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			dirs++
+		} else {
+			files++
+		}
+		paths = append(paths, SanitizePathForDisplay(workingDir, path))
+		return nil
+	})
+	return paths, files, dirs, err
+}
+
+// copyTree copies src to dst, recreating directories, preserving file modes and
+// copying symlinks as links rather than following them
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return fmt.Errorf("cannot copy special file '%s'", rel)
+		}
+	})
+}
+
+// copyFile copies a single regular file, refusing to overwrite an existing one
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// DeletePathTool implements the delete_path tool
+type DeletePathTool struct{}
+
+// NewDeletePathTool creates a new delete_path tool instance
+func NewDeletePathTool() *DeletePathTool {
+	return &DeletePathTool{}
+}
+
+// Name returns the tool name
+func (t *DeletePathTool) Name() string {
+	return "delete_path"
+}
+
+// Description returns the tool description
+func (t *DeletePathTool) Description() string {
+	return "Delete a file or directory. Non-empty directories are only deleted when recursive is true. " +
+		"Use this instead of running rm in the terminal."
+}
+
+// ParametersSchema returns the JSON schema for parameters
+func (t *DeletePathTool) ParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Relative path to the file or directory to delete",
+			},
+			"recursive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Delete a non-empty directory and everything in it (default: false)",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+// Validate checks if parameters are valid
+func (t *DeletePathTool) Validate(params map[string]interface{}, workingDir string) error {
+	path, _ := params["path"].(string)
+	resolvedPath, err := resolveManagedPath(workingDir, path, "path")
+	if err != nil {
+		return err
+	}
+
+	recursive, err := boolParam(params, "recursive")
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Lstat(resolvedPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("path '%s' not found", path)
+		}
+		return fmt.Errorf("cannot access path '%s': %w", path, err)
+	}
+
+	if info.IsDir() && !recursive {
+		entries, err := os.ReadDir(resolvedPath)
+		if err != nil {
+			return fmt.Errorf("failed to read directory '%s': %w", path, err)
+		}
+		if len(entries) > 0 {
+			return fmt.Errorf("directory '%s' is not empty (%d entries); set recursive to delete it with its contents", path, len(entries))
+		}
+	}
+
+	return nil
+}
+
+// Execute deletes the path
+func (t *DeletePathTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
+
+	path := params["path"].(string)
+	recursive, _ := boolParam(params, "recursive")
+
+	failure := func(err error) (*types.ToolResult, error) {
+		return &types.ToolResult{
+			Success:         false,
+			Error:           err.Error(),
+			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+		}, err
+	}
+
+	resolvedPath, err := resolveManagedPath(workingDir, path, "path")
+	if err != nil {
+		return failure(err)
+	}
+
+	info, err := os.Lstat(resolvedPath)
+	if err != nil {
+		return failure(fmt.Errorf("cannot access path '%s': %w", path, err))
+	}
+
+	// Collect what is about to disappear before removing it
+	affected := []string{SanitizePathForDisplay(workingDir, resolvedPath)}
+	files, dirs := 1, 0
+	if info.IsDir() {
+		affected, files, dirs, err = listTree(workingDir, resolvedPath)
+		if err != nil {
+			return failure(fmt.Errorf("failed to list directory '%s': %w", path, err))
+		}
+	}
+
+	if info.IsDir() && recursive {
+		err = os.RemoveAll(resolvedPath)
+	} else {
+		err = os.Remove(resolvedPath)
+	}
+	if err != nil {
+		return failure(fmt.Errorf("failed to delete '%s': %w", path, err))
+	}
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	logging.Info("Path deleted",
+		"path", SanitizePathForDisplay(workingDir, resolvedPath),
+		"files", files,
+		"directories", dirs,
+		"execution_time_ms", executionTime,
+	)
+
+	output := fmt.Sprintf("Deleted file: %s", path)
+	if info.IsDir() {
+		output = fmt.Sprintf("Deleted directory: %s (%d file(s), %d director(ies))", path, files, dirs)
+	}
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          output,
+		ExecutionTimeMs: executionTime,
+		FilesAffected:   affected,
+		Metadata: map[string]interface{}{
+			"path":        path,
+			"files":       files,
+			"directories": dirs,
+		},
+	}, nil
+}
+
+// TargetPaths returns the file or directory to be deleted
+func (t *DeletePathTool) TargetPaths(params map[string]interface{}, workingDir string) ([]string, error) {
+	path, _ := params["path"].(string)
+	resolvedPath, err := resolveManagedPath(workingDir, path, "path")
+	if err != nil {
+		return nil, err
+	}
+	return []string{resolvedPath}, nil
+}
+
+// RequiresApproval returns true as deletion requires approval
+func (t *DeletePathTool) RequiresApproval() bool {
+	return true
+}
+
+// RiskLevel returns the risk level for this tool
+func (t *DeletePathTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelDangerous
+}
+
+// transferPaths validates the source and destination shared by move_path and copy_path
+func transferPaths(params map[string]interface{}, workingDir string) (string, string, os.FileInfo, error) {
+	source, _ := params["source"].(string)
+	destination, _ := params["destination"].(string)
+
+	resolvedSource, err := resolveManagedPath(workingDir, source, "source")
+	if err != nil {
+		return "", "", nil, err
+	}
+	resolvedDest, err := resolveManagedPath(workingDir, destination, "destination")
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	info, err := os.Lstat(resolvedSource)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil, fmt.Errorf("source '%s' not found", source)
+		}
+		return "", "", nil, fmt.Errorf("cannot access source '%s': %w", source, err)
+	}
+
+	if _, err := os.Lstat(resolvedDest); err == nil {
+		return "", "", nil, fmt.Errorf("destination '%s' already exists", destination)
+	} else if !os.IsNotExist(err) {
+		return "", "", nil, fmt.Errorf("cannot access destination '%s': %w", destination, err)
+	}
+
+	if info.IsDir() && isWithin(resolvedSource, resolvedDest) {
+		return "", "", nil, fmt.Errorf("destination '%s' is inside source directory '%s'", destination, source)
+	}
+
+	return resolvedSource, resolvedDest, info, nil
+}
+
+// transferTargets resolves the named path parameters of move_path or copy_path
+func transferTargets(params map[string]interface{}, workingDir string, names ...string) ([]string, error) {
+	targets := make([]string, 0, len(names))
+	for _, name := range names {
+		path, _ := params[name].(string)
+		resolvedPath, err := resolveManagedPath(workingDir, path, name)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, resolvedPath)
+	}
+	return targets, nil
+}
+
+// transferSchema returns the parameter schema shared by move_path and copy_path
+func transferSchema(verb string) map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"source": map[string]interface{}{
+				"type":        "string",
+				"description": fmt.Sprintf("Relative path of the file or directory to %s", verb),
+			},
+			"destination": map[string]interface{}{
+				"type":        "string",
+				"description": "Relative path of the new location; must not exist yet (parent directories are created)",
+			},
+			"recursive": map[string]interface{}{
+				"type":        "boolean",
+				"description": fmt.Sprintf("Required to %s a directory with its contents (default: false)", verb),
+			},
+		},
+		"required": []string{"source", "destination"},
+	}
+}
+
+// MovePathTool implements the move_path tool
+type MovePathTool struct{}
+
+// NewMovePathTool creates a new move_path tool instance
+func NewMovePathTool() *MovePathTool {
+	return &MovePathTool{}
+}
+
+// Name returns the tool name
+func (t *MovePathTool) Name() string {
+	return "move_path"
+}
+
+// Description returns the tool description
+func (t *MovePathTool) Description() string {
+	return "Move or rename a file or directory within the workspace. Moving a directory requires recursive to be true. " +
+		"Use this instead of running mv in the terminal."
+}
+
+// ParametersSchema returns the JSON schema for parameters
+func (t *MovePathTool) ParametersSchema() map[string]interface{} {
+	return transferSchema("move")
+}
+
+// Validate checks if parameters are valid
+func (t *MovePathTool) Validate(params map[string]interface{}, workingDir string) error {
+	recursive, err := boolParam(params, "recursive")
+	if err != nil {
+		return err
+	}
+
+	_, _, info, err := transferPaths(params, workingDir)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() && !recursive {
+		return fmt.Errorf("source '%s' is a directory; set recursive to move it with its contents", params["source"])
+	}
+
+	return nil
+}
+
+// Execute moves the path
+func (t *MovePathTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
+
+	failure := func(err error) (*types.ToolResult, error) {
+		return &types.ToolResult{
+			Success:         false,
+			Error:           err.Error(),
+			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+		}, err
+	}
+
+	source := params["source"].(string)
+	destination := params["destination"].(string)
+
+	resolvedSource, resolvedDest, info, err := transferPaths(params, workingDir)
+	if err != nil {
+		return failure(err)
+	}
+
+	// Both the old and the new locations of every entry are affected
+	oldPaths := []string{SanitizePathForDisplay(workingDir, resolvedSource)}
+	files, dirs := 1, 0
+	if info.IsDir() {
+		oldPaths, files, dirs, err = listTree(workingDir, resolvedSource)
+		if err != nil {
+			return failure(fmt.Errorf("failed to list directory '%s': %w", source, err))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolvedDest), 0755); err != nil {
+		return failure(fmt.Errorf("failed to create parent directory for '%s': %w", destination, err))
+	}
+	if err := os.Rename(resolvedSource, resolvedDest); err != nil {
+		return failure(fmt.Errorf("failed to move '%s' to '%s': %w", source, destination, err))
+	}
+
+	affected := oldPaths
+	if info.IsDir() {
+		newPaths, _, _, err := listTree(workingDir, resolvedDest)
+		if err != nil {
+			return failure(fmt.Errorf("failed to list directory '%s': %w", destination, err))
+		}
+		affected = append(affected, newPaths...)
+	} else {
+		affected = append(affected, SanitizePathForDisplay(workingDir, resolvedDest))
+	}
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	logging.Info("Path moved",
+		"source", SanitizePathForDisplay(workingDir, resolvedSource),
+		"destination", SanitizePathForDisplay(workingDir, resolvedDest),
+		"files", files,
+		"directories", dirs,
+		"execution_time_ms", executionTime,
+	)
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          fmt.Sprintf("Moved %s to %s", source, destination),
+		ExecutionTimeMs: executionTime,
+		FilesAffected:   affected,
+		Metadata: map[string]interface{}{
+			"source":      source,
+			"destination": destination,
+			"files":       files,
+			"directories": dirs,
+		},
+	}, nil
+}
+
+// TargetPaths returns the source and destination of the move
+func (t *MovePathTool) TargetPaths(params map[string]interface{}, workingDir string) ([]string, error) {
+	return transferTargets(params, workingDir, "source", "destination")
+}
+
+// RequiresApproval returns true as moving files requires approval
+func (t *MovePathTool) RequiresApproval() bool {
+	return true
+}
+
+// RiskLevel returns the risk level for this tool
+func (t *MovePathTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelDangerous
+}
+
+// CopyPathTool implements the copy_path tool
+type CopyPathTool struct{}
+
+// NewCopyPathTool creates a new copy_path tool instance
+func NewCopyPathTool() *CopyPathTool {
+	return &CopyPathTool{}
+}
+
+// Name returns the tool name
+func (t *CopyPathTool) Name() string {
+	return "copy_path"
+}
+
+// Description returns the tool description
+func (t *CopyPathTool) Description() string {
+	return "Copy a file or directory to a new location within the workspace. Copying a directory requires recursive to be true. " +
+		"Existing files are never overwritten."
+}
+
+// ParametersSchema returns the JSON schema for parameters
+func (t *CopyPathTool) ParametersSchema() map[string]interface{} {
+	return transferSchema("copy")
+}
+
+// Validate checks if parameters are valid
+func (t *CopyPathTool) Validate(params map[string]interface{}, workingDir string) error {
+	recursive, err := boolParam(params, "recursive")
+	if err != nil {
+		return err
+	}
+
+	_, _, info, err := transferPaths(params, workingDir)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() && !recursive {
+		return fmt.Errorf("source '%s' is a directory; set recursive to copy it with its contents", params["source"])
+	}
+
+	return nil
+}
+
+// Execute copies the path
+func (t *CopyPathTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
+
+	failure := func(err error) (*types.ToolResult, error) {
+		return &types.ToolResult{
+			Success:         false,
+			Error:           err.Error(),
+			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+		}, err
+	}
+
+	source := params["source"].(string)
+	destination := params["destination"].(string)
+
+	resolvedSource, resolvedDest, _, err := transferPaths(params, workingDir)
+	if err != nil {
+		return failure(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolvedDest), 0755); err != nil {
+		return failure(fmt.Errorf("failed to create parent directory for '%s': %w", destination, err))
+	}
+	if err := copyTree(resolvedSource, resolvedDest); err != nil {
+		// Don't leave a partial copy behind
+		os.RemoveAll(resolvedDest)
+		return failure(fmt.Errorf("failed to copy '%s' to '%s': %w", source, destination, err))
+	}
+
+	// Only the newly created entries are affected
+	affected, files, dirs, err := listTree(workingDir, resolvedDest)
+	if err != nil {
+		return failure(fmt.Errorf("failed to list copy '%s': %w", destination, err))
+	}
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	logging.Info("Path copied",
+		"source", SanitizePathForDisplay(workingDir, resolvedSource),
+		"destination", SanitizePathForDisplay(workingDir, resolvedDest),
+		"files", files,
+		"directories", dirs,
+		"execution_time_ms", executionTime,
+	)
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          fmt.Sprintf("Copied %s to %s (%d file(s), %d director(ies))", source, destination, files, dirs),
+		ExecutionTimeMs: executionTime,
+		FilesAffected:   affected,
+		Metadata: map[string]interface{}{
+			"source":      source,
+			"destination": destination,
+			"files":       files,
+			"directories": dirs,
+		},
+	}, nil
+}
+
+// TargetPaths returns the destination of the copy
+func (t *CopyPathTool) TargetPaths(params map[string]interface{}, workingDir string) ([]string, error) {
+	return transferTargets(params, workingDir, "destination")
+}
+
+// RequiresApproval returns true as copying files requires approval
+func (t *CopyPathTool) RequiresApproval() bool {
+	return true
+}
+
+// RiskLevel returns the risk level for this tool; copies only ever create new files
+func (t *CopyPathTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelSafeWrite
+}
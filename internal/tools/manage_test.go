@@ -0,0 +1,235 @@
+package tools_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// setupTree creates files (and their parent directories) under workingDir
+func setupTree(t *testing.T, workingDir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(workingDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+	}
+}
+
+// runTool validates and executes a tool the way the registry does
+func runTool(tool types.Tool, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	if err := tool.Validate(params, workingDir); err != nil {
+		return nil, err
+	}
+	return tool.Execute(context.Background(), params, workingDir)
+}
+
+// TestFileManagementTools tests delete_path, move_path and copy_path
+func TestFileManagementTools(t *testing.T) {
+	deleteTool := tools.NewDeletePathTool()
+	moveTool := tools.NewMovePathTool()
+	copyTool := tools.NewCopyPathTool()
+
+	riskLevels := map[types.Tool]types.RiskLevel{
+		deleteTool: types.RiskLevelDangerous,
+		moveTool:   types.RiskLevelDangerous,
+		copyTool:   types.RiskLevelSafeWrite,
+	}
+	for tool, want := range riskLevels {
+		if !tool.RequiresApproval() {
+			t.Errorf("%s should require approval", tool.Name())
+		}
+		if tool.RiskLevel() != want {
+			t.Errorf("%s: expected risk level %v, got %v", tool.Name(), want, tool.RiskLevel())
+		}
+	}
+
+	tree := map[string]string{
+		"a.txt":         "a",
+		"dir/b.txt":     "b",
+		"dir/sub/c.txt": "c",
+	}
+
+	tests := []struct {
+		name         string
+		tool         types.Tool
+		params       map[string]interface{}
+		wantErr      bool
+		errContains  string
+		wantExist    []string
+		wantGone     []string
+		wantAffected []string
+	}{
+		{
+			name:         "delete - file",
+			tool:         deleteTool,
+			params:       map[string]interface{}{"path": "a.txt"},
+			wantGone:     []string{"a.txt"},
+			wantAffected: []string{"a.txt"},
+		},
+		{
+			name:         "delete - file through an unclean path",
+			tool:         deleteTool,
+			params:       map[string]interface{}{"path": "./dir/../a.txt"},
+			wantGone:     []string{"a.txt"},
+			wantAffected: []string{"a.txt"},
+		},
+		{
+			name:         "delete - recursive directory",
+			tool:         deleteTool,
+			params:       map[string]interface{}{"path": "dir", "recursive": true},
+			wantExist:    []string{"a.txt"},
+			wantGone:     []string{"dir"},
+			wantAffected: []string{"dir", "dir/b.txt", "dir/sub", "dir/sub/c.txt"},
+		},
+		{
+			name:        "delete - non-empty directory without recursive",
+			tool:        deleteTool,
+			params:      map[string]interface{}{"path": "dir"},
+			wantErr:     true,
+			errContains: "not empty",
+			wantExist:   []string{"dir/sub/c.txt"},
+		},
+		{
+			name:        "delete - workspace root",
+			tool:        deleteTool,
+			params:      map[string]interface{}{"path": ".", "recursive": true},
+			wantErr:     true,
+			errContains: "workspace root",
+			wantExist:   []string{"a.txt"},
+		},
+		{
+			name:        "delete - outside working directory",
+			tool:        deleteTool,
+			params:      map[string]interface{}{"path": "../a.txt"},
+			wantErr:     true,
+			errContains: "outside working directory",
+		},
+		{
+			name:        "delete - missing path",
+			tool:        deleteTool,
+			params:      map[string]interface{}{"path": "missing.txt"},
+			wantErr:     true,
+			errContains: "not found",
+		},
+		{
+			name:         "move - rename file into new directory",
+			tool:         moveTool,
+			params:       map[string]interface{}{"source": "a.txt", "destination": "new/a2.txt"},
+			wantExist:    []string{"new/a2.txt"},
+			wantGone:     []string{"a.txt"},
+			wantAffected: []string{"a.txt", "new/a2.txt"},
+		},
+		{
+			name:         "move - file through unclean paths",
+			tool:         moveTool,
+			params:       map[string]interface{}{"source": "./a.txt", "destination": "dir/sub/../a2.txt"},
+			wantExist:    []string{"dir/a2.txt"},
+			wantGone:     []string{"a.txt"},
+			wantAffected: []string{"a.txt", "dir/a2.txt"},
+		},
+		{
+			name:         "move - directory",
+			tool:         moveTool,
+			params:       map[string]interface{}{"source": "dir/sub", "destination": "moved", "recursive": true},
+			wantExist:    []string{"moved/c.txt"},
+			wantGone:     []string{"dir/sub"},
+			wantAffected: []string{"dir/sub", "dir/sub/c.txt", "moved", "moved/c.txt"},
+		},
+		{
+			name:        "move - directory without recursive",
+			tool:        moveTool,
+			params:      map[string]interface{}{"source": "dir", "destination": "moved"},
+			wantErr:     true,
+			errContains: "set recursive",
+			wantExist:   []string{"dir/b.txt"},
+		},
+		{
+			name:        "move - existing destination",
+			tool:        moveTool,
+			params:      map[string]interface{}{"source": "a.txt", "destination": "dir/b.txt"},
+			wantErr:     true,
+			errContains: "already exists",
+			wantExist:   []string{"a.txt", "dir/b.txt"},
+		},
+		{
+			name:        "move - into itself",
+			tool:        moveTool,
+			params:      map[string]interface{}{"source": "dir", "destination": "dir/sub/dir", "recursive": true},
+			wantErr:     true,
+			errContains: "inside source directory",
+		},
+		{
+			name:         "copy - file",
+			tool:         copyTool,
+			params:       map[string]interface{}{"source": "a.txt", "destination": "copy/a.txt"},
+			wantExist:    []string{"a.txt", "copy/a.txt"},
+			wantAffected: []string{"copy/a.txt"},
+		},
+		{
+			name:         "copy - directory",
+			tool:         copyTool,
+			params:       map[string]interface{}{"source": "dir", "destination": "dir2", "recursive": true},
+			wantExist:    []string{"dir/sub/c.txt", "dir2/b.txt", "dir2/sub/c.txt"},
+			wantAffected: []string{"dir2", "dir2/b.txt", "dir2/sub", "dir2/sub/c.txt"},
+		},
+		{
+			name:        "copy - invalid recursive type",
+			tool:        copyTool,
+			params:      map[string]interface{}{"source": "dir", "destination": "dir2", "recursive": "yes"},
+			wantErr:     true,
+			errContains: "must be a boolean",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			setupTree(t, workingDir, tree)
+
+			result, err := runTool(tt.tool, tt.params, workingDir)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, path := range tt.wantExist {
+				if _, err := os.Stat(filepath.Join(workingDir, path)); err != nil {
+					t.Errorf("expected %s to exist: %v", path, err)
+				}
+			}
+			for _, path := range tt.wantGone {
+				if _, err := os.Stat(filepath.Join(workingDir, path)); !os.IsNotExist(err) {
+					t.Errorf("expected %s to be gone", path)
+				}
+			}
+
+			if tt.wantAffected != nil {
+				got := make([]string, len(result.FilesAffected))
+				for i, path := range result.FilesAffected {
+					got[i] = filepath.ToSlash(path)
+				}
+				sort.Strings(got)
+				if strings.Join(got, ",") != strings.Join(tt.wantAffected, ",") {
+					t.Errorf("expected files affected %v, got %v", tt.wantAffected, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+// Package tools implements tolerant text matching for file edits
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MatchStrategy names the matcher layer that located an edit's target text
+type MatchStrategy string
+
+const (
+	// MatchExact - byte-for-byte match
+	MatchExact MatchStrategy = "exact"
+	// MatchLineEndings - match after normalizing CRLF to LF
+	MatchLineEndings MatchStrategy = "line_endings"
+	// MatchIndentation - whole-line match ignoring leading and trailing whitespace
+	MatchIndentation MatchStrategy = "indentation"
+)
+
+const (
+	// maxMatchCandidates is how many near misses are reported when nothing matches
+	maxMatchCandidates = 3
+	// minCandidateSimilarity is the lowest similarity worth reporting as a near miss
+	minCandidateSimilarity = 0.5
+)
+
+// textMatch is a region of the original content matched by the target text
+type textMatch struct {
+	start int
+	end   int
+	// indent is the leading whitespace of the first matched line (indentation layer only)
+	indent string
+}
+
+// MatchCandidate is a region that resembles the target text without matching it
+type MatchCandidate struct {
+	StartLine  int     `json:"start_line"`
+	EndLine    int     `json:"end_line"`
+	Similarity float64 `json:"similarity"`
+	Text       string  `json:"text"`
+}
+
+// findMatches locates non-overlapping occurrences of target in content, trying exact
+// matching first, then CRLF/LF normalization, then indentation-insensitive lines
+func findMatches(content, target string) ([]textMatch, MatchStrategy) {
+	if matches := exactMatches(content, target); len(matches) > 0 {
+		return matches, MatchExact
+	}
+	if strings.Contains(content, "\r") || strings.Contains(target, "\r") {
+		if matches := lineEndingMatches(content, target); len(matches) > 0 {
+			return matches, MatchLineEndings
+		}
+	}
+	if matches := indentationMatches(content, target); len(matches) > 0 {
+		return matches, MatchIndentation
+	}
+	return nil, ""
+}
+
+// exactMatches finds byte-for-byte occurrences
+func exactMatches(content, target string) []textMatch {
+	var matches []textMatch
+	for pos := 0; ; {
+		idx := strings.Index(content[pos:], target)
+		if idx < 0 {
+			return matches
+		}
+		start := pos + idx
+		matches = append(matches, textMatch{start: start, end: start + len(target)})
+		pos = start + len(target)
+	}
+}
+
+// lineEndingMatches matches with CRLF normalized to LF on both sides, mapping the
+// results back to offsets in the original content
+func lineEndingMatches(content, target string) []textMatch {
+	normalized := make([]byte, 0, len(content))
+	origIndex := make([]int, 0, len(content)+1)
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\r' && i+1 < len(content) && content[i+1] == '\n' {
+			continue
+		}
+		normalized = append(normalized, content[i])
+		origIndex = append(origIndex, i)
+	}
+	origIndex = append(origIndex, len(content))
+
+	var matches []textMatch
+	for _, m := range exactMatches(string(normalized), strings.ReplaceAll(target, "\r\n", "\n")) {
+		start := origIndex[m.start]
+		// A match starting at a line break includes the preceding \r
+		if start > 0 && content[start] == '\n' && content[start-1] == '\r' {
+			start--
+		}
+		// Map the end from the last matched byte so a trailing \r stays outside the match
+		matches = append(matches, textMatch{start: start, end: origIndex[m.end-1] + 1})
+	}
+	return matches
+}
+
+// contentLine is one line of the original content with its byte offsets
+type contentLine struct {
+	start   int
+	end     int // excluding the line ending
+	nextPos int // start of the following line
+	text    string
+}
+
+// splitContentLines splits content into lines, keeping byte offsets
+func splitContentLines(content string) []contentLine {
+	var lines []contentLine
+	pos := 0
+	for pos <= len(content) {
+		nl := strings.IndexByte(content[pos:], '\n')
+		if nl < 0 {
+			if pos < len(content) {
+				lines = append(lines, contentLine{start: pos, end: len(content), nextPos: len(content), text: content[pos:]})
+			}
+			break
+		}
+		end := pos + nl
+		text := content[pos:end]
+		lineEnd := end
+		if strings.HasSuffix(text, "\r") {
+			text = text[:len(text)-1]
+			lineEnd--
+		}
+		lines = append(lines, contentLine{start: pos, end: lineEnd, nextPos: end + 1, text: text})
+		pos = end + 1
+	}
+	return lines
+}
+
+// targetLines splits the target into lines, reporting whether it ended with a newline
+func targetLines(target string) ([]string, bool) {
+	target = strings.ReplaceAll(target, "\r\n", "\n")
+	trailingNewline := strings.HasSuffix(target, "\n")
+	return strings.Split(strings.TrimSuffix(target, "\n"), "\n"), trailingNewline
+}
+
+// indentationMatches matches whole lines while ignoring leading and trailing
+// whitespace, so tabs vs spaces and trailing blanks don't prevent a match
+func indentationMatches(content, target string) []textMatch {
+	want, trailingNewline := targetLines(target)
+	blank := true
+	for i := range want {
+		want[i] = strings.TrimSpace(want[i])
+		if want[i] != "" {
+			blank = false
+		}
+	}
+	if blank {
+		return nil
+	}
+
+	lines := splitContentLines(content)
+	var matches []textMatch
+	for i := 0; i+len(want) <= len(lines); {
+		matched := true
+		for j, w := range want {
+			if strings.TrimSpace(lines[i+j].text) != w {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			i++
+			continue
+		}
+
+		first, last := lines[i], lines[i+len(want)-1]
+		end := last.end
+		if trailingNewline {
+			end = last.nextPos
+		}
+		matches = append(matches, textMatch{
+			start:  first.start,
+			end:    end,
+			indent: leadingWhitespace(first.text),
+		})
+		i += len(want)
+	}
+	return matches
+}
+
+// adaptReplacement adjusts the replacement text to the file: indentation-layer
+// matches are re-indented from the old string's indentation to the file's, and
+// line endings follow the file when it uses CRLF
+func adaptReplacement(content, oldString, newString string, strategy MatchStrategy, m textMatch) string {
+	replacement := strings.ReplaceAll(newString, "\r\n", "\n")
+
+	if strategy == MatchIndentation {
+		oldLines, _ := targetLines(oldString)
+		oldIndent := leadingWhitespace(oldLines[0])
+		lines := strings.Split(replacement, "\n")
+		for i, line := range lines {
+			switch {
+			case strings.TrimSpace(line) == "":
+				lines[i] = ""
+			case strings.HasPrefix(line, oldIndent):
+				lines[i] = m.indent + line[len(oldIndent):]
+			}
+		}
+		replacement = strings.Join(lines, "\n")
+	}
+
+	if strings.Contains(content, "\r\n") {
+		replacement = strings.ReplaceAll(replacement, "\n", "\r\n")
+	}
+	return replacement
+}
+
+// leadingWhitespace returns the spaces and tabs at the start of s
+func leadingWhitespace(s string) string {
+	return s[:len(s)-len(strings.TrimLeft(s, " \t"))]
+}
+
+// lineNumberAt returns the 1-based line number of a byte offset
+func lineNumberAt(content string, offset int) int {
+	return 1 + strings.Count(content[:offset], "\n")
+}
+
+// closestCandidates returns the regions most similar to target, best first. Windows
+// of the target's line count are scored with a bigram similarity on the
+// whitespace-normalized text.
+func closestCandidates(content, target string) []MatchCandidate {
+	want, _ := targetLines(target)
+	wantText := normalizeWhitespace(strings.Join(want, "\n"))
+	if wantText == "" {
+		return nil
+	}
+	wantBigrams := bigrams(wantText)
+
+	lines := splitContentLines(content)
+	window := min(len(want), len(lines))
+	if window == 0 {
+		return nil
+	}
+
+	type scored struct {
+		start int
+		score float64
+	}
+	var scores []scored
+	for i := 0; i+window <= len(lines); i++ {
+		texts := make([]string, window)
+		for j := range texts {
+			texts[j] = lines[i+j].text
+		}
+		score := diceSimilarity(wantBigrams, bigrams(normalizeWhitespace(strings.Join(texts, "\n"))))
+		if score >= minCandidateSimilarity {
+			scores = append(scores, scored{start: i, score: score})
+		}
+	}
+	sort.SliceStable(scores, func(a, b int) bool { return scores[a].score > scores[b].score })
+
+	var candidates []MatchCandidate
+	taken := make(map[int]bool)
+	for _, s := range scores {
+		if len(candidates) == maxMatchCandidates {
+			break
+		}
+		// Skip windows overlapping a better candidate
+		overlaps := false
+		for j := s.start; j < s.start+window; j++ {
+			if taken[j] {
+				overlaps = true
+				break
+			}
+		}
+		if overlaps {
+			continue
+		}
+		for j := s.start; j < s.start+window; j++ {
+			taken[j] = true
+		}
+
+		var text strings.Builder
+		for j := s.start; j < s.start+window; j++ {
+			fmt.Fprintf(&text, "%d: %s\n", j+1, lines[j].text)
+		}
+		candidates = append(candidates, MatchCandidate{
+			StartLine:  s.start + 1,
+			EndLine:    s.start + window,
+			Similarity: float64(int(s.score*1000)) / 1000,
+			Text:       strings.TrimSuffix(text.String(), "\n"),
+		})
+	}
+	return candidates
+}
+
+// normalizeWhitespace trims each line and collapses runs of spaces and tabs
+func normalizeWhitespace(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// bigrams counts the character pairs of s
+func bigrams(s string) map[string]int {
+	counts := make(map[string]int)
+	runes := []rune(s)
+	for i := 0; i+1 < len(runes); i++ {
+		counts[string(runes[i:i+2])]++
+	}
+	if len(runes) == 1 {
+		counts[s]++
+	}
+	return counts
+}
+
+// diceSimilarity is the Sørensen–Dice coefficient of two bigram multisets (0 to 1)
+func diceSimilarity(a, b map[string]int) float64 {
+	total := 0
+	for _, n := range a {
+		total += n
+	}
+	for _, n := range b {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+
+	shared := 0
+	for gram, n := range a {
+		shared += min(n, b[gram])
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+// formatCandidates renders near misses for the model
+func formatCandidates(candidates []MatchCandidate) string {
+	var b strings.Builder
+	b.WriteString("Closest matches in the file:\n")
+	for _, c := range candidates {
+		fmt.Fprintf(&b, "--- lines %d-%d (%.0f%% similar)\n%s\n", c.StartLine, c.EndLine, c.Similarity*100, c.Text)
+	}
+	b.WriteString("Copy old_string exactly from one of these regions (without the line number prefixes).")
+	return b.String()
+}
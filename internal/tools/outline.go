@@ -0,0 +1,473 @@
+// Package tools implements the code_outline and find_symbol tools
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/logging"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+const (
+	// maxOutlineFileSize skips generated or minified sources too large to outline usefully
+	maxOutlineFileSize = 2 * 1024 * 1024
+	// defaultSymbolResults is how many declarations find_symbol returns by default
+	defaultSymbolResults = 50
+	// maxSimilarSymbols bounds the suggestions shown when no declaration matches exactly
+	maxSimilarSymbols = 10
+)
+
+// CodeOutlineTool implements the code_outline tool
+type CodeOutlineTool struct{}
+
+// NewCodeOutlineTool creates a new code_outline tool instance
+func NewCodeOutlineTool() *CodeOutlineTool {
+	return &CodeOutlineTool{}
+}
+
+// Name returns the tool name
+func (t *CodeOutlineTool) Name() string {
+	return "code_outline"
+}
+
+// Description returns the tool description
+func (t *CodeOutlineTool) Description() string {
+	return "List the declarations in a source file or directory with their line ranges: types, functions, methods, " +
+		"constants and variables for Go (parsed with go/ast), and classes, functions and methods for Python, " +
+		"JavaScript/TypeScript, Rust, Java, C#, C/C++, Ruby and shell. Use it to find what to read_file instead of reading whole files."
+}
+
+// ParametersSchema returns the JSON schema for parameters
+func (t *CodeOutlineTool) ParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Relative path to a source file, or to a directory (such as a Go package) to outline every supported file in it",
+			},
+			"include_tests": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Include Go _test.go files when outlining a directory (default: false)",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+// Validate checks if parameters are valid
+func (t *CodeOutlineTool) Validate(params map[string]interface{}, workingDir string) error {
+	path, ok := params["path"].(string)
+	if !ok || path == "" {
+		return fmt.Errorf("path parameter is required and must be a non-empty string")
+	}
+
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(resolvedPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("path '%s' not found", path)
+		}
+		return fmt.Errorf("cannot access path '%s': %w", path, err)
+	}
+	if !info.IsDir() && !outlineSupported(resolvedPath) {
+		return fmt.Errorf("no outline support for '%s'; supported: .go and %s", path, supportedOutlineExtensions())
+	}
+
+	if _, err := boolParam(params, "include_tests"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Execute outlines the file or directory
+func (t *CodeOutlineTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
+
+	path := params["path"].(string)
+	includeTests, _ := params["include_tests"].(bool)
+
+	failure := func(err error) (*types.ToolResult, error) {
+		return &types.ToolResult{
+			Success:         false,
+			Error:           err.Error(),
+			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+		}, err
+	}
+
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return failure(err)
+	}
+	info, err := os.Stat(resolvedPath)
+	if err != nil {
+		return failure(fmt.Errorf("cannot access path '%s': %w", path, err))
+	}
+
+	files := []string{resolvedPath}
+	if info.IsDir() {
+		files, err = outlineDirFiles(resolvedPath, includeTests)
+		if err != nil {
+			return failure(fmt.Errorf("failed to read directory '%s': %w", path, err))
+		}
+		if len(files) == 0 {
+			return failure(fmt.Errorf("no supported source files in '%s'", path))
+		}
+	}
+
+	var output strings.Builder
+	symbolCount := 0
+	outlined := 0
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return failure(err)
+		}
+
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return failure(fmt.Errorf("failed to read file '%s': %w", SanitizePathForDisplay(workingDir, file), err))
+		}
+		outline, err := outlineSource(file, src)
+		if err != nil {
+			return failure(err)
+		}
+
+		section := formatOutline(SanitizePathForDisplay(workingDir, file), outline)
+		if outlined > 0 && output.Len()+len(section) > readPageChars {
+			fmt.Fprintf(&output, "\n[Outline stopped at the %d-character budget; %d more file(s) not shown, outline them individually]",
+				readPageChars, len(files)-outlined)
+			break
+		}
+		if outlined > 0 {
+			output.WriteString("\n")
+		}
+		output.WriteString(section)
+		symbolCount += len(outline.Symbols)
+		outlined++
+	}
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	logging.Info("Code outlined",
+		"path", SanitizePathForDisplay(workingDir, resolvedPath),
+		"files", outlined,
+		"symbols", symbolCount,
+		"execution_time_ms", executionTime,
+	)
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          strings.TrimSuffix(output.String(), "\n"),
+		ExecutionTimeMs: executionTime,
+		FilesAffected:   []string{},
+		Metadata: map[string]interface{}{
+			"files":   outlined,
+			"symbols": symbolCount,
+		},
+	}, nil
+}
+
+// RequiresApproval returns true as reading source files requires approval
+func (t *CodeOutlineTool) RequiresApproval() bool {
+	return true
+}
+
+// RiskLevel returns the risk level for this tool
+func (t *CodeOutlineTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelReadOnly
+}
+
+// outlineDirFiles lists the supported source files directly inside dir
+func outlineDirFiles(dir string, includeTests bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !outlineSupported(name) {
+			continue
+		}
+		if !includeTests && strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files, nil
+}
+
+// formatOutline renders one file's declarations, one per line with its line range
+func formatOutline(display string, outline *fileOutline) string {
+	var b strings.Builder
+	header := outline.Language
+	if outline.Package != "" {
+		header += ", package " + outline.Package
+	}
+	fmt.Fprintf(&b, "%s (%s, %d symbol(s)):\n", display, header, len(outline.Symbols))
+	if outline.ParseError != "" {
+		fmt.Fprintf(&b, "  [parse error: %s; outline may be incomplete]\n", outline.ParseError)
+	}
+	if len(outline.Symbols) == 0 {
+		b.WriteString("  (no declarations found)\n")
+	}
+	for _, sym := range outline.Symbols {
+		fmt.Fprintf(&b, "  %-11s %s%s\n", sym.lineRange(), strings.Repeat("  ", sym.Depth), sym.Signature)
+	}
+	return b.String()
+}
+
+// supportedOutlineExtensions lists the regex-outlined extensions for error messages
+func supportedOutlineExtensions() string {
+	exts := make([]string, 0, len(languageSpecs))
+	for ext := range languageSpecs {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return strings.Join(exts, ", ")
+}
+
+// FindSymbolTool implements the find_symbol tool
+type FindSymbolTool struct{}
+
+// NewFindSymbolTool creates a new find_symbol tool instance
+func NewFindSymbolTool() *FindSymbolTool {
+	return &FindSymbolTool{}
+}
+
+// Name returns the tool name
+func (t *FindSymbolTool) Name() string {
+	return "find_symbol"
+}
+
+// Description returns the tool description
+func (t *FindSymbolTool) Description() string {
+	return "Find where a type, function, method, class, constant or variable is declared across the workspace. " +
+		"Accepts a plain name ('NewAgent') or a qualified method name ('Agent.Run') and returns each declaration's " +
+		"file and line range, ready for read_file with start_line/end_line. Files ignored by .gitignore or .winkignore are skipped unless include_ignored is set."
+}
+
+// ParametersSchema returns the JSON schema for parameters
+func (t *FindSymbolTool) ParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Symbol name, or Type.Method for methods (case-sensitive)",
+			},
+			"kind": map[string]interface{}{
+				"type":        "string",
+				"description": "Only return declarations of this kind: func, method, type, struct, interface, const, var, class, enum, trait or module (optional)",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory to search from (default: current directory)",
+				"default":     ".",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of declarations to return (default: 50)",
+			},
+			"include_ignored": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Also search files excluded by .gitignore, .winkignore and .git/info/exclude (default: false)",
+			},
+		},
+		"required": []string{"name"},
+	}
+}
+
+// Validate checks if parameters are valid
+func (t *FindSymbolTool) Validate(params map[string]interface{}, workingDir string) error {
+	name, ok := params["name"].(string)
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("name parameter is required and must be a non-empty string")
+	}
+	if strings.ContainsAny(name, " \t\n*(") {
+		return fmt.Errorf("name '%s' must be an identifier or Type.Method, not a pattern", name)
+	}
+
+	if kind, ok := params["kind"]; ok {
+		if _, isString := kind.(string); !isString {
+			return fmt.Errorf("kind must be a string")
+		}
+	}
+
+	if maxResults, ok := params["max_results"].(float64); ok && maxResults < 1 {
+		return fmt.Errorf("max_results must be positive, got %.0f", maxResults)
+	}
+
+	if _, err := boolParam(params, "include_ignored"); err != nil {
+		return err
+	}
+
+	if p, ok := params["path"].(string); ok && p != "" {
+		resolvedPath, err := ResolvePath(workingDir, p)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(resolvedPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("path '%s' not found", p)
+			}
+			return fmt.Errorf("cannot access path '%s': %w", p, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("path '%s' is not a directory", p)
+		}
+	}
+
+	return nil
+}
+
+// symbolMatch is a declaration found by find_symbol
+type symbolMatch struct {
+	file   string
+	symbol codeSymbol
+}
+
+// Execute searches the workspace for declarations of the symbol
+func (t *FindSymbolTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
+
+	name := strings.TrimSpace(params["name"].(string))
+	kind, _ := params["kind"].(string)
+	maxResults := defaultSymbolResults
+	if mr, ok := params["max_results"].(float64); ok {
+		maxResults = int(mr)
+	}
+	path := "."
+	if p, ok := params["path"].(string); ok && p != "" {
+		path = p
+	}
+	includeIgnored, _ := params["include_ignored"].(bool)
+
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return &types.ToolResult{
+			Success:         false,
+			Error:           err.Error(),
+			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+		}, err
+	}
+
+	// "Agent.Run" finds Run declared on Agent; the last segment is what appears in the source
+	container, symbolName := "", name
+	if i := strings.LastIndex(name, "."); i > 0 && i < len(name)-1 {
+		container, symbolName = name[:i], name[i+1:]
+	}
+	needle := []byte(strings.ToLower(symbolName))
+
+	var matches, similar []symbolMatch
+	filesScanned := 0
+
+	err = walkWorkspace(ctx, workingDir, resolvedPath, includeIgnored, func(p string, d fs.DirEntry) error {
+		if d.IsDir() || !outlineSupported(p) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxOutlineFileSize {
+			return nil
+		}
+
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return nil
+		}
+		filesScanned++
+		// Only parse files that mention the name at all
+		if !bytes.Contains(bytes.ToLower(src), needle) {
+			return nil
+		}
+
+		outline, err := outlineSource(p, src)
+		if err != nil {
+			return nil
+		}
+		display := SanitizePathForDisplay(workingDir, p)
+		for _, sym := range outline.Symbols {
+			if kind != "" && sym.Kind != kind {
+				continue
+			}
+			if sym.Name == symbolName && (container == "" || sym.Container == container) {
+				matches = append(matches, symbolMatch{file: display, symbol: sym})
+				if len(matches) >= maxResults {
+					return fs.SkipAll
+				}
+			} else if len(similar) < maxSimilarSymbols && strings.Contains(strings.ToLower(sym.qualifiedName()), strings.ToLower(name)) {
+				similar = append(similar, symbolMatch{file: display, symbol: sym})
+			}
+		}
+		return nil
+	})
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	if err != nil && err != fs.SkipAll {
+		return &types.ToolResult{
+			Success:         false,
+			Error:           fmt.Sprintf("symbol search failed: %v", err),
+			ExecutionTimeMs: executionTime,
+		}, fmt.Errorf("symbol search failed: %w", err)
+	}
+
+	var output strings.Builder
+	if len(matches) == 0 {
+		fmt.Fprintf(&output, "No declaration of '%s' found", name)
+		if len(similar) > 0 {
+			output.WriteString("; similar names:\n")
+			writeSymbolMatches(&output, similar)
+		}
+	} else {
+		fmt.Fprintf(&output, "Found %d declaration(s) of '%s':\n", len(matches), name)
+		writeSymbolMatches(&output, matches)
+		if len(matches) >= maxResults {
+			fmt.Fprintf(&output, "\nWarning: Reached limit of %d results", maxResults)
+		}
+	}
+
+	logging.Debug("find_symbol: found %d declarations of %s in %d files (%dms)", len(matches), name, filesScanned, executionTime)
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          strings.TrimSuffix(output.String(), "\n"),
+		ExecutionTimeMs: executionTime,
+		FilesAffected:   []string{},
+		Metadata: map[string]interface{}{
+			"matches":       len(matches),
+			"files_scanned": filesScanned,
+			"name":          name,
+		},
+	}, nil
+}
+
+// writeSymbolMatches renders declarations as file:lines, kind and signature
+func writeSymbolMatches(b *strings.Builder, matches []symbolMatch) {
+	for _, m := range matches {
+		fmt.Fprintf(b, "  %s:%s [%s] %s\n", m.file, m.symbol.lineRange(), m.symbol.Kind, m.symbol.Signature)
+	}
+}
+
+// RequiresApproval returns true as reading source files requires approval
+func (t *FindSymbolTool) RequiresApproval() bool {
+	return true
+}
+
+// RiskLevel returns the risk level for this tool
+func (t *FindSymbolTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelReadOnly
+}
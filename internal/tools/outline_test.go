@@ -0,0 +1,353 @@
+package tools_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+)
+
+const outlineGoSource = `package shop
+
+import "context"
+
+// Limit bounds a cart
+const Limit = 10
+
+type (
+	// ID identifies an item
+	ID string
+	Alias = ID
+)
+
+// Cart holds items
+type Cart[T any] struct {
+	items []T
+}
+
+// Store loads carts
+type Store interface {
+	Load(ctx context.Context) (*Cart[ID], error)
+}
+
+// Add appends an item
+func (c *Cart[T]) Add(item T) {
+	c.items = append(c.items, item)
+}
+
+// NewCart creates a cart
+func NewCart() *Cart[ID] {
+	return &Cart[ID]{}
+}
+`
+
+// TestCodeOutlineTool tests outlines of Go and regex-outlined languages
+func TestCodeOutlineTool(t *testing.T) {
+	tool := tools.NewCodeOutlineTool()
+
+	tests := []struct {
+		name        string
+		files       map[string]string
+		params      map[string]interface{}
+		wantErr     string
+		wantOutput  []string
+		wantMissing []string
+	}{
+		{
+			name:   "Go file",
+			files:  map[string]string{"shop/cart.go": outlineGoSource},
+			params: map[string]interface{}{"path": "shop/cart.go"},
+			wantOutput: []string{
+				"(Go, package shop, 7 symbol(s))",
+				"6           const Limit",
+				"10          type ID string",
+				"11          type Alias = ID",
+				"15-17       type Cart struct",
+				"20-22       type Store interface",
+				"25-27       func (c *Cart[T]) Add(item T)",
+				"30-32       func NewCart() *Cart[ID]",
+			},
+		},
+		{
+			name: "Go package skips tests by default",
+			files: map[string]string{
+				"shop/cart.go":      outlineGoSource,
+				"shop/cart_test.go": "package shop\n\nfunc TestCart() {}\n",
+				"shop/README.md":    "# shop\n",
+			},
+			params:      map[string]interface{}{"path": "shop"},
+			wantOutput:  []string{"cart.go (Go, package shop"},
+			wantMissing: []string{"TestCart", "README"},
+		},
+		{
+			name: "Go package with tests",
+			files: map[string]string{
+				"shop/cart.go":      outlineGoSource,
+				"shop/cart_test.go": "package shop\n\nfunc TestCart() {}\n",
+			},
+			params:     map[string]interface{}{"path": "shop", "include_tests": true},
+			wantOutput: []string{"func NewCart()", "3           func TestCart()"},
+		},
+		{
+			name:       "Go file with syntax error",
+			files:      map[string]string{"broken.go": "package broken\n\nfunc Good() {}\n\nfunc Bad( {\n"},
+			params:     map[string]interface{}{"path": "broken.go"},
+			wantOutput: []string{"[parse error:", "func Good()"},
+		},
+		{
+			name: "Python classes and methods",
+			files: map[string]string{"app.py": `import os
+
+
+class Server:
+    """Serves requests."""
+
+    def __init__(self, port):
+        self.port = port
+
+    async def handle(self,
+                     request):
+        return None
+
+
+def main():
+    Server(8080)
+`},
+			params: map[string]interface{}{"path": "app.py"},
+			wantOutput: []string{
+				"(Python, 4 symbol(s))",
+				"4-12        class Server",
+				"7-8           def __init__(self, port)",
+				"10-12         async def handle(self,",
+				"15-16       def main()",
+			},
+		},
+		{
+			name: "TypeScript with braces in strings",
+			files: map[string]string{"api.ts": `export interface Options {
+  retries: number;
+}
+
+export class Client {
+  private base = "}";
+
+  constructor(base: string) {
+    this.base = base;
+  }
+
+  async fetch<T>(path: string): Promise<T> {
+    if (path) {
+      return JSON.parse('{');
+    }
+    throw new Error("}}");
+  }
+}
+
+export const retry = async (n: number) => {
+  return n;
+};
+`},
+			params: map[string]interface{}{"path": "api.ts"},
+			wantOutput: []string{
+				"1-3         export interface Options",
+				"5-18        export class Client",
+				"8-10          constructor(base: string)",
+				"12-17         async fetch<T>(path: string): Promise<T>",
+				"20-22       export const retry = async (n: number) =>",
+			},
+			wantMissing: []string{"if (path)"},
+		},
+		{
+			name: "Rust impl blocks with lifetimes",
+			files: map[string]string{"lib.rs": `pub struct Parser<'a> {
+    input: &'a str,
+}
+
+impl<'a> Parser<'a> {
+    pub fn new(input: &'a str, other: &'a str) -> Self {
+        let c = '{';
+        Parser { input }
+    }
+}
+
+fn helper();
+`},
+			params: map[string]interface{}{"path": "lib.rs"},
+			wantOutput: []string{
+				"1-3         pub struct Parser<'a>",
+				"5-10        impl<'a> Parser<'a>",
+				"6-9           pub fn new(input: &'a str, other: &'a str) -> Self",
+				"12          fn helper();",
+			},
+		},
+		{
+			name:    "unsupported file type",
+			files:   map[string]string{"notes.txt": "hello\n"},
+			params:  map[string]interface{}{"path": "notes.txt"},
+			wantErr: "no outline support",
+		},
+		{
+			name:    "directory without sources",
+			files:   map[string]string{"docs/a.md": "# a\n"},
+			params:  map[string]interface{}{"path": "docs"},
+			wantErr: "no supported source files",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			setupTree(t, workingDir, tt.files)
+
+			result, err := runTool(tool, tt.params, workingDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(result.Output, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, result.Output)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(result.Output, missing) {
+					t.Errorf("expected output not to contain %q, got:\n%s", missing, result.Output)
+				}
+			}
+		})
+	}
+}
+
+// TestFindSymbolTool tests locating declarations across a workspace
+func TestFindSymbolTool(t *testing.T) {
+	tool := tools.NewFindSymbolTool()
+
+	workingDir := t.TempDir()
+	setupTree(t, workingDir, map[string]string{
+		"shop/cart.go": outlineGoSource,
+		"agent/agent.go": `package agent
+
+type Agent struct{}
+
+func (a *Agent) Run() error {
+	return nil
+}
+
+func Run() {}
+`,
+		"web/app.py":                 "class Agent:\n    def run(self):\n        pass\n",
+		".gitignore":                 "node_modules/\n",
+		"node_modules/lib/index.js":  "function NewCart() {}\n",
+		".git/hooks/NewCart.go":      "package hooks\n\nfunc NewCart() {}\n",
+		"shop/testdata/cart_copy.go": "package testdata\n\nfunc NewCart() {}\n",
+	})
+
+	tests := []struct {
+		name        string
+		params      map[string]interface{}
+		wantErr     string
+		wantOutput  []string
+		wantMissing []string
+		wantMatches int
+	}{
+		{
+			name:        "function by name",
+			params:      map[string]interface{}{"name": "NewCart"},
+			wantOutput:  []string{"shop/cart.go:30-32 [func] func NewCart() *Cart[ID]", "shop/testdata/cart_copy.go:3 [func]"},
+			wantMissing: []string{"node_modules", ".git"},
+			wantMatches: 2,
+		},
+		{
+			name:        "qualified method",
+			params:      map[string]interface{}{"name": "Agent.Run"},
+			wantOutput:  []string{"agent/agent.go:5-7 [method] func (a *Agent) Run() error"},
+			wantMissing: []string{"agent.go:9"},
+			wantMatches: 1,
+		},
+		{
+			name:        "generic receiver",
+			params:      map[string]interface{}{"name": "Cart.Add"},
+			wantOutput:  []string{"shop/cart.go:25-27 [method]"},
+			wantMatches: 1,
+		},
+		{
+			name:        "kind filter",
+			params:      map[string]interface{}{"name": "Run", "kind": "func"},
+			wantOutput:  []string{"agent/agent.go:9 [func] func Run()"},
+			wantMissing: []string{"[method]"},
+			wantMatches: 1,
+		},
+		{
+			name:        "class in another language",
+			params:      map[string]interface{}{"name": "Agent", "path": "web"},
+			wantOutput:  []string{"web/app.py:1-3 [class] class Agent"},
+			wantMatches: 1,
+		},
+		{
+			name:        "no exact match suggests similar names",
+			params:      map[string]interface{}{"name": "cart"},
+			wantOutput:  []string{"No declaration of 'cart' found; similar names:", "type Cart struct"},
+			wantMatches: 0,
+		},
+		{
+			name:    "patterns are rejected",
+			params:  map[string]interface{}{"name": "New*"},
+			wantErr: "not a pattern",
+		},
+		{
+			name:    "path must be a directory",
+			params:  map[string]interface{}{"name": "Run", "path": "agent/agent.go"},
+			wantErr: "is not a directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runTool(tool, tt.params, workingDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output := filepath.ToSlash(result.Output)
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(output, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, output)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(output, missing) {
+					t.Errorf("expected output not to contain %q, got:\n%s", missing, output)
+				}
+			}
+			if got := result.Metadata["matches"]; got != tt.wantMatches {
+				t.Errorf("expected %d matches, got %v", tt.wantMatches, got)
+			}
+		})
+	}
+}
+
+// TestCodeOutlineCancellation tests that outlining stops when the context is cancelled
+func TestCodeOutlineCancellation(t *testing.T) {
+	workingDir := t.TempDir()
+	setupTree(t, workingDir, map[string]string{"a.go": "package a\n"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tools.NewCodeOutlineTool().Execute(ctx, map[string]interface{}{"path": "."}, workingDir); err == nil {
+		t.Fatal("expected cancellation error")
+	}
+}
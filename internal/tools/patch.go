@@ -0,0 +1,951 @@
+// Package tools implements the apply_patch tool
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/logging"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+const (
+	// maxPatchFuzz is how many leading and trailing context lines may be ignored when a hunk doesn't match
+	maxPatchFuzz = 2
+	devNull      = "/dev/null"
+)
+
+// hunkHeaderPattern matches "@@ -start[,count] +start[,count] @@"
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// patchLine is a single line of a hunk: ' ' context, '-' removed or '+' added
+type patchLine struct {
+	op   byte
+	text string
+}
+
+// patchHunk is one "@@" section of a file patch
+type patchHunk struct {
+	header       string
+	oldStart     int
+	oldLines     int
+	newStart     int
+	newLines     int
+	lines        []patchLine
+	noNewlineOld bool
+	noNewlineNew bool
+}
+
+// filePatch holds the hunks for one file; an empty oldPath creates the file and an
+// empty newPath deletes it
+type filePatch struct {
+	oldPath string
+	newPath string
+	hunks   []*patchHunk
+}
+
+// HunkResult reports how a single hunk was applied
+type HunkResult struct {
+	Path       string `json:"path"`
+	Hunk       int    `json:"hunk"`
+	Header     string `json:"header"`
+	Applied    bool   `json:"applied"`
+	Line       int    `json:"line,omitempty"`
+	Offset     int    `json:"offset,omitempty"`
+	Fuzz       int    `json:"fuzz,omitempty"`
+	Whitespace bool   `json:"whitespace,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// path returns the path the patch applies to
+func (f *filePatch) path() string {
+	if f.newPath != "" {
+		return f.newPath
+	}
+	return f.oldPath
+}
+
+// action returns "create", "delete", "rename" or "modify"
+func (f *filePatch) action() string {
+	switch {
+	case f.oldPath == "":
+		return "create"
+	case f.newPath == "":
+		return "delete"
+	case f.oldPath != f.newPath:
+		return "rename"
+	default:
+		return "modify"
+	}
+}
+
+// complete reports whether the hunk holds as many lines as its header declares.
+// Hunks whose header has no line numbers are never complete.
+func (h *patchHunk) complete() bool {
+	if h.oldLines < 0 {
+		return false
+	}
+	oldSeen, newSeen := 0, 0
+	for _, l := range h.lines {
+		if l.op != '+' {
+			oldSeen++
+		}
+		if l.op != '-' {
+			newSeen++
+		}
+	}
+	return oldSeen >= h.oldLines && newSeen >= h.newLines
+}
+
+// trimOvershoot drops trailing blank context lines beyond the declared line counts,
+// e.g. the blank line separating two file sections
+func (h *patchHunk) trimOvershoot() {
+	for len(h.lines) > 0 {
+		last := h.lines[len(h.lines)-1]
+		if last.op != ' ' || last.text != "" {
+			return
+		}
+		h.lines = h.lines[:len(h.lines)-1]
+		if h.oldLines >= 0 && !h.complete() {
+			h.lines = append(h.lines, last)
+			return
+		}
+	}
+}
+
+// parsePatch parses a unified diff covering one or more files. It accepts plain
+// "---"/"+++" headers as well as git's "diff --git" format.
+func parsePatch(text string) ([]*filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var files []*filePatch
+	var current *filePatch
+	var hunk *patchHunk
+
+	startFile := func() {
+		current = &filePatch{}
+		files = append(files, current)
+		hunk = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		// Headers can't start inside a hunk, unless the hunk's length is unknown
+		inHunk := hunk != nil && !hunk.complete() && hunk.oldLines >= 0
+
+		switch {
+		case !inHunk && strings.HasPrefix(line, "diff --git "):
+			startFile()
+			// Default paths for git diffs without ---/+++ lines (e.g. empty new files)
+			if fields := strings.Fields(line); len(fields) == 4 {
+				current.oldPath = parsePatchPath(fields[2])
+				current.newPath = parsePatchPath(fields[3])
+			}
+
+		case !inHunk && hunk == nil && current != nil && strings.HasPrefix(line, "new file mode"):
+			current.oldPath = ""
+
+		case !inHunk && hunk == nil && current != nil && strings.HasPrefix(line, "deleted file mode"):
+			current.newPath = ""
+
+		case !inHunk && strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if current == nil || len(current.hunks) > 0 {
+				startFile()
+			}
+			current.oldPath = parsePatchPath(line[4:])
+			current.newPath = parsePatchPath(lines[i+1][4:])
+			hunk = nil
+			i++
+
+		case strings.HasPrefix(line, "@@") && !inHunk:
+			if current == nil {
+				return nil, fmt.Errorf("hunk header at line %d appears before any file header (---/+++)", i+1)
+			}
+			hunk = &patchHunk{header: strings.TrimSpace(line), oldLines: -1, newLines: -1}
+			// Bare "@@" headers are located by context alone
+			if m := hunkHeaderPattern.FindStringSubmatch(line); m != nil {
+				hunk.oldStart = atoiDefault(m[1], 0)
+				hunk.oldLines = atoiDefault(m[2], 1)
+				hunk.newStart = atoiDefault(m[3], 0)
+				hunk.newLines = atoiDefault(m[4], 1)
+			}
+			current.hunks = append(current.hunks, hunk)
+
+		case hunk != nil && strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" applies to the preceding line
+			if n := len(hunk.lines); n > 0 {
+				switch hunk.lines[n-1].op {
+				case '-':
+					hunk.noNewlineOld = true
+				case '+':
+					hunk.noNewlineNew = true
+				default:
+					hunk.noNewlineOld = true
+					hunk.noNewlineNew = true
+				}
+			}
+
+		case hunk != nil && line == "":
+			// Editors and models often strip the leading space of blank context lines
+			hunk.lines = append(hunk.lines, patchLine{op: ' '})
+
+		case hunk != nil && (line[0] == ' ' || line[0] == '-' || line[0] == '+'):
+			hunk.lines = append(hunk.lines, patchLine{op: line[0], text: line[1:]})
+
+		default:
+			// Commentary and extended headers (index, similarity, ...) end the current hunk
+			hunk = nil
+		}
+	}
+
+	for _, f := range files {
+		if f.oldPath == "" && f.newPath == "" {
+			return nil, fmt.Errorf("file patch without a path")
+		}
+		for _, h := range f.hunks {
+			h.trimOvershoot()
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file headers (---/+++) found; patch must be in unified diff format")
+	}
+
+	return files, nil
+}
+
+// parsePatchPath extracts the path from a ---/+++ header, stripping timestamps and
+// git's a/ and b/ prefixes. /dev/null yields "".
+func parsePatchPath(s string) string {
+	if tab := strings.IndexByte(s, '\t'); tab >= 0 {
+		s = s[:tab]
+	}
+	s = strings.TrimSpace(s)
+	if s == devNull {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+// atoiDefault parses s, returning def for an empty string
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// fileText is file content split into lines, remembering its line ending, byte order
+// mark and final newline
+type fileText struct {
+	lines        []string
+	crlf         bool
+	bom          bool
+	finalNewline bool
+}
+
+// splitFileText splits content into lines without their line endings. A leading
+// BOM is set aside so it does not get in the way of matching the first line.
+func splitFileText(content string) fileText {
+	text := fileText{crlf: strings.Contains(content, "\r\n"), bom: strings.HasPrefix(content, utf8BOM)}
+	content = strings.TrimPrefix(content, utf8BOM)
+	if content == "" {
+		return text
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	text.finalNewline = strings.HasSuffix(content, "\n")
+	text.lines = strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	return text
+}
+
+// String joins the lines back using the original line ending and BOM
+func (t fileText) String() string {
+	bom := ""
+	if t.bom {
+		bom = utf8BOM
+	}
+	if len(t.lines) == 0 {
+		return bom
+	}
+	eol := "\n"
+	if t.crlf {
+		eol = "\r\n"
+	}
+	s := bom + strings.Join(t.lines, eol)
+	if t.finalNewline {
+		s += eol
+	}
+	return s
+}
+
+// applyHunks applies hunks in order, returning the new text and a result per hunk.
+// The text is unchanged when any hunk fails.
+func applyHunks(path string, text fileText, hunks []*patchHunk) (fileText, []HunkResult, bool) {
+	lines := append([]string(nil), text.lines...)
+	results := make([]HunkResult, 0, len(hunks))
+	offset := 0
+	searchFrom := 0
+	ok := true
+
+	for i, h := range hunks {
+		result := HunkResult{Path: path, Hunk: i + 1, Header: h.header}
+
+		expected := max(h.oldStart-1, 0) + offset
+		if h.oldLines == 0 {
+			// Pure insertions name the line after which to insert
+			expected = h.oldStart + offset
+		}
+
+		hunkLines, pos, fuzz, whitespace, found := locateHunk(lines, h.lines, expected, searchFrom)
+		if !found {
+			result.Error = describeHunkMismatch(h, expected)
+			results = append(results, result)
+			ok = false
+			continue
+		}
+
+		// Rebuild the region, keeping the file's own text for context lines
+		var replacement []string
+		oldIndex := pos
+		for _, l := range hunkLines {
+			switch l.op {
+			case ' ':
+				replacement = append(replacement, lines[oldIndex])
+				oldIndex++
+			case '-':
+				oldIndex++
+			case '+':
+				replacement = append(replacement, l.text)
+			}
+		}
+
+		updated := make([]string, 0, len(lines)-(oldIndex-pos)+len(replacement))
+		updated = append(updated, lines[:pos]...)
+		updated = append(updated, replacement...)
+		updated = append(updated, lines[oldIndex:]...)
+
+		// Measure the offset from where the untrimmed hunk would start
+		anchor := pos - (leadingContext(h.lines) - leadingContext(hunkLines))
+
+		result.Applied = true
+		result.Line = max(anchor, 0) + 1
+		if h.oldLines >= 0 {
+			result.Offset = anchor - expected
+		}
+		result.Fuzz = fuzz
+		result.Whitespace = whitespace
+		results = append(results, result)
+
+		offset += (anchor - expected) + len(replacement) - (oldIndex - pos)
+		searchFrom = pos + len(replacement)
+		lines = updated
+	}
+
+	if !ok {
+		return text, results, false
+	}
+
+	out := fileText{lines: lines, crlf: text.crlf, bom: text.bom, finalNewline: text.finalNewline || len(text.lines) == 0}
+	for _, h := range hunks {
+		if h.noNewlineNew {
+			out.finalNewline = false
+		} else if h.noNewlineOld {
+			out.finalNewline = true
+		}
+	}
+	return out, results, true
+}
+
+// locateHunk finds where a hunk applies, trying the expected position first and then
+// positions further away. Without an exact match it ignores trailing whitespace and
+// then drops up to maxPatchFuzz leading and trailing context lines. It returns the
+// (possibly trimmed) hunk lines and their position.
+func locateHunk(lines []string, hunkLines []patchLine, expected, searchFrom int) ([]patchLine, int, int, bool, bool) {
+	for fuzz := 0; fuzz <= maxPatchFuzz; fuzz++ {
+		trimmed, ok := trimContext(hunkLines, fuzz)
+		if !ok {
+			break
+		}
+		var old []string
+		for _, l := range trimmed {
+			if l.op != '+' {
+				old = append(old, l.text)
+			}
+		}
+
+		// Dropped leading context shifts where the remaining lines start
+		start := expected + leadingContext(hunkLines) - leadingContext(trimmed)
+
+		for _, whitespace := range []bool{false, true} {
+			if pos, found := searchLines(lines, old, start, searchFrom, whitespace); found {
+				return trimmed, pos, fuzz, whitespace, true
+			}
+		}
+	}
+	return nil, 0, 0, false, false
+}
+
+// trimContext drops up to fuzz context lines from each end of a hunk
+func trimContext(hunkLines []patchLine, fuzz int) ([]patchLine, bool) {
+	if fuzz == 0 {
+		return hunkLines, true
+	}
+	lead := min(fuzz, leadingContext(hunkLines))
+	trail := 0
+	for i := len(hunkLines) - 1; i >= lead && trail < fuzz && hunkLines[i].op == ' '; i-- {
+		trail++
+	}
+	if lead == 0 && trail == 0 {
+		return nil, false
+	}
+	trimmed := hunkLines[lead : len(hunkLines)-trail]
+	for _, l := range trimmed {
+		if l.op != '+' {
+			return trimmed, true
+		}
+	}
+	// Nothing left to anchor the hunk
+	return nil, false
+}
+
+// leadingContext counts the context lines at the start of a hunk
+func leadingContext(hunkLines []patchLine) int {
+	n := 0
+	for _, l := range hunkLines {
+		if l.op != ' ' {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// searchLines finds old in lines at or after searchFrom, closest to start
+func searchLines(lines, old []string, start, searchFrom int, ignoreWhitespace bool) (int, bool) {
+	last := len(lines) - len(old)
+	if len(old) == 0 {
+		// Pure insertion: anchor at the expected position
+		return min(max(start, searchFrom, 0), len(lines)), true
+	}
+	if last < searchFrom {
+		return 0, false
+	}
+	start = min(max(start, searchFrom), last)
+
+	matches := func(pos int) bool {
+		for i, want := range old {
+			got := lines[pos+i]
+			if ignoreWhitespace {
+				got, want = strings.TrimRight(got, " \t"), strings.TrimRight(want, " \t")
+			}
+			if got != want {
+				return false
+			}
+		}
+		return true
+	}
+
+	for d := 0; start-d >= searchFrom || start+d <= last; d++ {
+		if start+d <= last && matches(start+d) {
+			return start + d, true
+		}
+		if d > 0 && start-d >= searchFrom && matches(start-d) {
+			return start - d, true
+		}
+	}
+	return 0, false
+}
+
+// describeHunkMismatch explains why a hunk could not be applied
+func describeHunkMismatch(h *patchHunk, expected int) string {
+	for _, l := range h.lines {
+		if l.op != '+' {
+			return fmt.Sprintf("context not found near line %d (first expected line: %q)", max(expected, 0)+1, l.text)
+		}
+	}
+	return fmt.Sprintf("could not place insertion at line %d", max(expected, 0)+1)
+}
+
+// newFileContent returns the added lines of a creation patch
+func newFileContent(f *filePatch) fileText {
+	text := fileText{finalNewline: true}
+	for _, h := range f.hunks {
+		for _, l := range h.lines {
+			if l.op != '-' {
+				text.lines = append(text.lines, l.text)
+			}
+		}
+		if h.noNewlineNew {
+			text.finalNewline = false
+		}
+	}
+	return text
+}
+
+// ApplyPatchTool implements the apply_patch tool
+type ApplyPatchTool struct {
+	postEditStage
+}
+
+// NewApplyPatchTool creates a new apply_patch tool instance
+func NewApplyPatchTool() *ApplyPatchTool {
+	return &ApplyPatchTool{}
+}
+
+// Name returns the tool name
+func (t *ApplyPatchTool) Name() string {
+	return "apply_patch"
+}
+
+// Description returns the tool description
+func (t *ApplyPatchTool) Description() string {
+	return "Apply a unified diff to one or more files. Use ---/+++ headers per file (/dev/null to create or delete a file) " +
+		"and @@ hunks with a few lines of context. Hunks are matched by context, tolerating shifted line numbers. " +
+		"The patch is applied only if every hunk matches."
+}
+
+// ParametersSchema returns the JSON schema for parameters
+func (t *ApplyPatchTool) ParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"patch": map[string]interface{}{
+				"type":        "string",
+				"description": "Unified diff with ---/+++ file headers and @@ hunks; paths are relative to the working directory",
+			},
+		},
+		"required": []string{"patch"},
+	}
+}
+
+// Validate checks if parameters are valid
+func (t *ApplyPatchTool) Validate(params map[string]interface{}, workingDir string) error {
+	patch, ok := params["patch"].(string)
+	if !ok || strings.TrimSpace(patch) == "" {
+		return fmt.Errorf("patch parameter is required and must be a non-empty string")
+	}
+
+	if len(patch) > maxFileSize {
+		return fmt.Errorf("patch size (%d bytes) exceeds maximum allowed size (%d bytes)", len(patch), maxFileSize)
+	}
+
+	files, err := parsePatch(patch)
+	if err != nil {
+		return fmt.Errorf("invalid patch: %w", err)
+	}
+
+	for _, f := range files {
+		for _, path := range []string{f.oldPath, f.newPath} {
+			if path == "" {
+				continue
+			}
+			if _, err := ResolvePath(workingDir, path); err != nil {
+				return err
+			}
+		}
+		if f.action() == "modify" && len(f.hunks) == 0 {
+			return fmt.Errorf("patch for '%s' has no hunks", f.path())
+		}
+	}
+
+	return nil
+}
+
+// pendingFile is the in-memory state of a file touched by a patch
+type pendingFile struct {
+	display  string
+	original *fileText // nil when the file did not exist
+	content  *fileText // nil when deleted
+	mode     os.FileMode
+	exists   bool
+}
+
+// patchPlan is the outcome of applying a patch in memory, before anything is written
+type patchPlan struct {
+	pending   map[string]*pendingFile // keyed by resolved path, so a file can appear more than once
+	order     []string
+	hunks     []HunkResult
+	summaries []string
+	affected  []string
+	failed    int
+	postEdit  []postEditResult
+}
+
+// planPatch parses the patch, applies every hunk in memory and runs the resulting
+// files through the post-edit pipeline. The returned plan is never nil, so hunk
+// results gathered before an error can still be reported.
+func planPatch(patch, workingDir string, postEdit *PostEditPipeline) (*patchPlan, error) {
+	plan := &patchPlan{pending: map[string]*pendingFile{}}
+
+	files, err := parsePatch(patch)
+	if err != nil {
+		return plan, fmt.Errorf("invalid patch: %v", err)
+	}
+
+	load := func(path string) (*pendingFile, error) {
+		resolved, err := ResolvePath(workingDir, path)
+		if err != nil {
+			return nil, err
+		}
+		if p, ok := plan.pending[resolved]; ok {
+			return p, nil
+		}
+		p := &pendingFile{display: path, mode: 0644}
+		info, err := os.Stat(resolved)
+		switch {
+		case err == nil && info.IsDir():
+			return nil, fmt.Errorf("path '%s' is a directory, not a file", path)
+		case err == nil:
+			data, err := os.ReadFile(resolved)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file '%s': %w", path, err)
+			}
+			text := splitFileText(string(data))
+			p.original = &text
+			p.content = &text
+			p.mode = info.Mode().Perm()
+			p.exists = true
+		case !os.IsNotExist(err):
+			return nil, fmt.Errorf("cannot access file '%s': %w", path, err)
+		}
+		plan.pending[resolved] = p
+		plan.order = append(plan.order, resolved)
+		return p, nil
+	}
+
+	for _, f := range files {
+		action := f.action()
+
+		switch action {
+		case "create":
+			target, err := load(f.newPath)
+			if err != nil {
+				return plan, err
+			}
+			if target.content != nil {
+				return plan, fmt.Errorf("cannot create '%s': file already exists", f.newPath)
+			}
+			text := newFileContent(f)
+			target.content = &text
+			for i, h := range f.hunks {
+				plan.hunks = append(plan.hunks, HunkResult{Path: f.newPath, Hunk: i + 1, Header: h.header, Applied: true, Line: 1})
+			}
+			plan.summaries = append(plan.summaries, fmt.Sprintf("  A %s (%d lines)", f.newPath, len(text.lines)))
+			plan.affected = append(plan.affected, f.newPath)
+
+		default:
+			source, err := load(f.oldPath)
+			if err != nil {
+				return plan, err
+			}
+			if source.content == nil {
+				return plan, fmt.Errorf("file '%s' not found", f.oldPath)
+			}
+
+			updated, results, ok := applyHunks(f.path(), *source.content, f.hunks)
+			plan.hunks = append(plan.hunks, results...)
+			if !ok {
+				for _, r := range results {
+					if !r.Applied {
+						plan.failed++
+					}
+				}
+				continue
+			}
+
+			switch action {
+			case "delete":
+				source.content = nil
+				plan.summaries = append(plan.summaries, fmt.Sprintf("  D %s", f.oldPath))
+				plan.affected = append(plan.affected, f.oldPath)
+			case "rename":
+				target, err := load(f.newPath)
+				if err != nil {
+					return plan, err
+				}
+				if target.content != nil {
+					return plan, fmt.Errorf("cannot rename '%s' to '%s': target already exists", f.oldPath, f.newPath)
+				}
+				target.content = &updated
+				target.mode = source.mode
+				source.content = nil
+				plan.summaries = append(plan.summaries, fmt.Sprintf("  R %s -> %s%s", f.oldPath, f.newPath, hunkNotes(results)))
+				plan.affected = append(plan.affected, f.oldPath, f.newPath)
+			default:
+				source.content = &updated
+				plan.summaries = append(plan.summaries, fmt.Sprintf("  M %s%s", f.path(), hunkNotes(results)))
+				plan.affected = append(plan.affected, f.path())
+			}
+		}
+	}
+
+	if plan.failed > 0 {
+		return plan, nil
+	}
+	for _, resolved := range plan.order {
+		p := plan.pending[resolved]
+		if p.content == nil {
+			continue
+		}
+		result := postEdit.process(resolved, p.display, p.content.String())
+		if result.content != p.content.String() {
+			text := splitFileText(result.content)
+			p.content = &text
+		}
+		plan.postEdit = append(plan.postEdit, result)
+	}
+
+	return plan, nil
+}
+
+// Preview returns the combined diff of every file the patch would change
+func (t *ApplyPatchTool) Preview(params map[string]interface{}, workingDir string) (*types.ToolPreview, error) {
+	patch, _ := params["patch"].(string)
+
+	plan, err := planPatch(patch, workingDir, t.postEdit)
+	if err != nil {
+		return nil, err
+	}
+	if plan.failed > 0 {
+		return nil, fmt.Errorf("%d of %d hunks failed to apply", plan.failed, len(plan.hunks))
+	}
+
+	var diff strings.Builder
+	for _, resolved := range plan.order {
+		p := plan.pending[resolved]
+		var before, after *string
+		if p.original != nil {
+			s := p.original.String()
+			before = &s
+		}
+		if p.content != nil {
+			s := p.content.String()
+			after = &s
+		}
+		diff.WriteString(fileDiff(filepath.ToSlash(p.display), before, after))
+	}
+
+	return &types.ToolPreview{
+		Diff:          diff.String(),
+		FilesAffected: plan.affected,
+	}, nil
+}
+
+// TargetPaths returns every file named in the patch
+func (t *ApplyPatchTool) TargetPaths(params map[string]interface{}, workingDir string) ([]string, error) {
+	patch, _ := params["patch"].(string)
+	files, err := parsePatch(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+
+	var targets []string
+	seen := make(map[string]bool)
+	for _, f := range files {
+		for _, path := range []string{f.oldPath, f.newPath} {
+			if path == "" || path == devNull {
+				continue
+			}
+			resolved, err := ResolvePath(workingDir, path)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[resolved] {
+				seen[resolved] = true
+				targets = append(targets, resolved)
+			}
+		}
+	}
+	return targets, nil
+}
+
+// Execute applies the patch. Nothing is written unless every hunk applies.
+func (t *ApplyPatchTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
+
+	patch := params["patch"].(string)
+
+	failure := func(output, errMsg string, hunks []HunkResult) (*types.ToolResult, error) {
+		return &types.ToolResult{
+			Success:         false,
+			Output:          output,
+			Error:           errMsg,
+			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+			Metadata: map[string]interface{}{
+				"hunks": hunks,
+			},
+		}, fmt.Errorf("%s", errMsg)
+	}
+
+	plan, err := planPatch(patch, workingDir, t.postEdit)
+	if err != nil {
+		return failure("", err.Error(), plan.hunks)
+	}
+	hunkResults, summaries, affected, failed := plan.hunks, plan.summaries, plan.affected, plan.failed
+
+	if failed > 0 {
+		var report strings.Builder
+		fmt.Fprintf(&report, "Patch not applied (no files changed): %d of %d hunks failed\n", failed, len(hunkResults))
+		for _, r := range hunkResults {
+			if r.Applied {
+				fmt.Fprintf(&report, "  ok     %s hunk #%d %s (line %d)\n", r.Path, r.Hunk, r.Header, r.Line)
+			} else {
+				fmt.Fprintf(&report, "  FAILED %s hunk #%d %s: %s\n", r.Path, r.Hunk, r.Header, r.Error)
+			}
+		}
+		report.WriteString("Re-read the affected files and regenerate the failed hunks with exact context lines.")
+		return failure(report.String(), fmt.Sprintf("%d of %d hunks failed to apply", failed, len(hunkResults)), hunkResults)
+	}
+
+	// Every hunk matched: stage every new file content first, so a failed write leaves
+	// the workspace untouched, then rename them into place and delete removed files
+	var staged []*stagedFile
+	var createdDirs []string
+	rollback := func() {
+		for _, f := range staged {
+			f.discard()
+		}
+		for _, dir := range createdDirs {
+			os.Remove(dir)
+		}
+	}
+	for _, resolved := range plan.order {
+		p := plan.pending[resolved]
+		if p.content == nil {
+			continue
+		}
+		created, err := createParentDirs(resolved)
+		createdDirs = append(created, createdDirs...)
+		if err != nil {
+			rollback()
+			return failure("Patch not applied (no files changed)", fmt.Sprintf("failed to create directory for '%s': %v", p.display, err), hunkResults)
+		}
+		f, err := stageFile(resolved, []byte(p.content.String()), p.mode)
+		if err != nil {
+			rollback()
+			return failure("Patch not applied (no files changed)", fmt.Sprintf("failed to write file '%s': %v", p.display, err), hunkResults)
+		}
+		staged = append(staged, f)
+	}
+
+	var changed []string
+	commitFailure := func(errMsg string) (*types.ToolResult, error) {
+		output := "Patch partially applied: no files changed"
+		if len(changed) > 0 {
+			output = fmt.Sprintf("Patch partially applied: %s changed before the failure; re-read them before retrying", strings.Join(changed, ", "))
+		}
+		return failure(output, errMsg, hunkResults)
+	}
+	next := 0
+	for _, resolved := range plan.order {
+		p := plan.pending[resolved]
+		if p.content == nil {
+			continue
+		}
+		if err := staged[next].commit(); err != nil {
+			for _, f := range staged[next+1:] {
+				f.discard()
+			}
+			return commitFailure(fmt.Sprintf("failed to write file '%s': %v", p.display, err))
+		}
+		next++
+		changed = append(changed, p.display)
+	}
+	for _, resolved := range plan.order {
+		p := plan.pending[resolved]
+		if p.content == nil && p.exists {
+			if err := os.Remove(resolved); err != nil {
+				return commitFailure(fmt.Sprintf("failed to delete file '%s': %v", p.display, err))
+			}
+			changed = append(changed, p.display)
+		}
+	}
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	logging.Info("Patch applied",
+		"files", len(summaries),
+		"hunks", len(hunkResults),
+		"execution_time_ms", executionTime,
+	)
+
+	output := fmt.Sprintf("Applied %d hunk(s) to %d file(s):\n%s", len(hunkResults), len(summaries), strings.Join(summaries, "\n"))
+	metadata := map[string]interface{}{
+		"hunks":         hunkResults,
+		"files_changed": len(summaries),
+	}
+	for _, result := range plan.postEdit {
+		output += result.notes()
+		result.addMetadata(metadata)
+	}
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          output,
+		ExecutionTimeMs: executionTime,
+		FilesAffected:   affected,
+		Metadata:        metadata,
+	}, nil
+}
+
+// createParentDirs creates the missing parent directories of path and returns the
+// ones it created, deepest first, so they can be removed again
+func createParentDirs(path string) ([]string, error) {
+	var missing []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil || dir == filepath.Dir(dir) {
+			break
+		}
+		missing = append(missing, dir)
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+	return missing, os.MkdirAll(filepath.Dir(path), 0755)
+}
+
+// hunkNotes summarizes hunks that needed an offset, fuzz or whitespace tolerance
+func hunkNotes(results []HunkResult) string {
+	var notes []string
+	for _, r := range results {
+		var details []string
+		if r.Offset != 0 {
+			details = append(details, fmt.Sprintf("offset %+d", r.Offset))
+		}
+		if r.Fuzz > 0 {
+			details = append(details, fmt.Sprintf("fuzz %d", r.Fuzz))
+		}
+		if r.Whitespace {
+			details = append(details, "ignoring whitespace")
+		}
+		if len(details) > 0 {
+			notes = append(notes, fmt.Sprintf("hunk #%d at line %d (%s)", r.Hunk, r.Line, strings.Join(details, ", ")))
+		}
+	}
+	if len(notes) == 0 {
+		return fmt.Sprintf(" (%d hunks)", len(results))
+	}
+	return fmt.Sprintf(" (%d hunks; %s)", len(results), strings.Join(notes, "; "))
+}
+
+// RequiresApproval returns true as patching files requires approval
+func (t *ApplyPatchTool) RequiresApproval() bool {
+	return true
+}
+
+// RiskLevel returns the risk level for this tool
+func (t *ApplyPatchTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelDangerous
+}
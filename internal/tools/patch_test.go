@@ -0,0 +1,316 @@
+package tools_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// TestApplyPatchTool tests the apply_patch tool
+func TestApplyPatchTool(t *testing.T) {
+	tool := tools.NewApplyPatchTool()
+
+	if tool.Name() != "apply_patch" {
+		t.Errorf("expected name 'apply_patch', got '%s'", tool.Name())
+	}
+	if !tool.RequiresApproval() {
+		t.Error("apply_patch should require approval")
+	}
+	if tool.RiskLevel() != types.RiskLevelDangerous {
+		t.Errorf("expected risk level %v, got %v", types.RiskLevelDangerous, tool.RiskLevel())
+	}
+
+	// A name that fits the file system but not with the temporary file's affixes
+	longName := strings.Repeat("n", 250) + ".txt"
+
+	tests := []struct {
+		name        string
+		files       map[string]string
+		patch       string
+		wantFiles   map[string]string // expected contents afterwards
+		wantGone    []string          // files expected to be deleted
+		wantErr     bool
+		errContains string
+		outContains string
+	}{
+		{
+			name:  "success - single hunk",
+			files: map[string]string{"main.go": "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"},
+			patch: `--- a/main.go
++++ b/main.go
+@@ -3,3 +3,4 @@
+ func main() {
+ 	println("hi")
++	println("bye")
+ }
+`,
+			wantFiles: map[string]string{"main.go": "package main\n\nfunc main() {\n\tprintln(\"hi\")\n\tprintln(\"bye\")\n}\n"},
+		},
+		{
+			name:  "success - multiple hunks with shifted line numbers",
+			files: map[string]string{"list.txt": "extra\nextra\na\nb\nc\nd\ne\nf\ng\nh\n"},
+			patch: `--- a/list.txt
++++ b/list.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+@@ -6,3 +6,3 @@
+ f
+-g
++G
+ h
+`,
+			wantFiles:   map[string]string{"list.txt": "extra\nextra\na\nB\nc\nd\ne\nf\nG\nh\n"},
+			outContains: "offset +2",
+		},
+		{
+			name: "success - create, modify and delete across files",
+			files: map[string]string{
+				"keep.txt": "one\ntwo\n",
+				"old.txt":  "bye\n",
+			},
+			patch: `diff --git a/keep.txt b/keep.txt
+--- a/keep.txt
++++ b/keep.txt
+@@ -1,2 +1,2 @@
+ one
+-two
++2
+diff --git a/dir/new.txt b/dir/new.txt
+new file mode 100644
+--- /dev/null
++++ b/dir/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+diff --git a/old.txt b/old.txt
+deleted file mode 100644
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`,
+			wantFiles: map[string]string{
+				"keep.txt":    "one\n2\n",
+				"dir/new.txt": "hello\nworld\n",
+			},
+			wantGone:    []string{"old.txt"},
+			outContains: "A dir/new.txt",
+		},
+		{
+			name:  "success - fuzz ignores stale outer context",
+			files: map[string]string{"f.txt": "1\n2\n3\n4\n5\n"},
+			patch: `--- f.txt
++++ f.txt
+@@ -1,5 +1,5 @@
+ one
+ 2
+-3
++three
+ 4
+ five
+`,
+			wantFiles:   map[string]string{"f.txt": "1\n2\nthree\n4\n5\n"},
+			outContains: "fuzz 1",
+		},
+		{
+			name:  "success - trailing whitespace differences",
+			files: map[string]string{"f.txt": "a  \nb\nc\t\n"},
+			patch: `--- f.txt
++++ f.txt
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+			wantFiles:   map[string]string{"f.txt": "a  \nB\nc\t\n"},
+			outContains: "ignoring whitespace",
+		},
+		{
+			name:      "success - CRLF line endings preserved",
+			files:     map[string]string{"win.txt": "a\r\nb\r\nc\r\n"},
+			patch:     "--- win.txt\n+++ win.txt\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+			wantFiles: map[string]string{"win.txt": "a\r\nB\r\nc\r\n"},
+		},
+		{
+			name:  "success - no newline at end of file",
+			files: map[string]string{"f.txt": "a\nb"},
+			patch: `--- f.txt
++++ f.txt
+@@ -1,2 +1,2 @@
+ a
+-b
+\ No newline at end of file
++b
+`,
+			wantFiles: map[string]string{"f.txt": "a\nb\n"},
+		},
+		{
+			name:  "success - removed lines that look like headers",
+			files: map[string]string{"q.sql": "-- comment\nSELECT 1;\n"},
+			patch: `--- q.sql
++++ q.sql
+@@ -1,2 +1,2 @@
+--- comment
++-- better comment
+ SELECT 1;
+`,
+			wantFiles: map[string]string{"q.sql": "-- better comment\nSELECT 1;\n"},
+		},
+		{
+			name:  "success - bare hunk header located by context",
+			files: map[string]string{"f.txt": "x\ny\nz\n"},
+			patch: `--- f.txt
++++ f.txt
+@@
+ y
+-z
++Z
+`,
+			wantFiles: map[string]string{"f.txt": "x\ny\nZ\n"},
+		},
+		{
+			name:  "success - rename with changes",
+			files: map[string]string{"a.txt": "hello\n"},
+			patch: `--- a/a.txt
++++ b/b.txt
+@@ -1 +1 @@
+-hello
++hi
+`,
+			wantFiles: map[string]string{"b.txt": "hi\n"},
+			wantGone:  []string{"a.txt"},
+		},
+		{
+			name: "error - failed hunk leaves all files unchanged",
+			files: map[string]string{
+				"a.txt": "1\n2\n",
+				"b.txt": "x\ny\n",
+			},
+			patch: `--- a.txt
++++ a.txt
+@@ -1,2 +1,2 @@
+ 1
+-2
++two
+--- b.txt
++++ b.txt
+@@ -1,2 +1,2 @@
+ nope
+-missing
++gone
+`,
+			wantFiles: map[string]string{
+				"a.txt": "1\n2\n",
+				"b.txt": "x\ny\n",
+			},
+			wantErr:     true,
+			errContains: "1 of 2 hunks failed",
+			outContains: "FAILED b.txt hunk #1",
+		},
+		{
+			name:  "error - failed write leaves all files unchanged",
+			files: map[string]string{"a.txt": "one\n"},
+			patch: `--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-one
++two
+--- /dev/null
++++ b/newdir/x.txt
+@@ -0,0 +1 @@
++x
+--- /dev/null
++++ b/newdir/` + longName + `
+@@ -0,0 +1 @@
++y
+`,
+			wantFiles:   map[string]string{"a.txt": "one\n"},
+			wantGone:    []string{"newdir"},
+			wantErr:     true,
+			errContains: "failed to write file",
+			outContains: "no files changed",
+		},
+		{
+			name:        "error - create existing file",
+			files:       map[string]string{"a.txt": "1\n"},
+			patch:       "--- /dev/null\n+++ a.txt\n@@ -0,0 +1 @@\n+new\n",
+			wantFiles:   map[string]string{"a.txt": "1\n"},
+			wantErr:     true,
+			errContains: "already exists",
+		},
+		{
+			name:        "error - path outside working directory",
+			patch:       "--- ../evil.txt\n+++ ../evil.txt\n@@ -1 +1 @@\n-a\n+b\n",
+			wantErr:     true,
+			errContains: "outside working directory",
+		},
+		{
+			name:        "error - not a unified diff",
+			patch:       "please change line 3",
+			wantErr:     true,
+			errContains: "unified diff",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			for path, content := range tt.files {
+				full := filepath.Join(workingDir, path)
+				if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+					t.Fatalf("setup failed: %v", err)
+				}
+				if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+					t.Fatalf("setup failed: %v", err)
+				}
+			}
+
+			params := map[string]interface{}{"patch": tt.patch}
+			var result *types.ToolResult
+			err := tool.Validate(params, workingDir)
+			if err == nil {
+				result, err = tool.Execute(context.Background(), params, workingDir)
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if tt.errContains != "" && !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("expected error containing %q, got %q", tt.errContains, err.Error())
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.outContains != "" && (result == nil || !strings.Contains(result.Output, tt.outContains)) {
+				t.Errorf("expected output containing %q, got %+v", tt.outContains, result)
+			}
+
+			for path, want := range tt.wantFiles {
+				got, err := os.ReadFile(filepath.Join(workingDir, path))
+				if err != nil {
+					t.Errorf("failed to read %s: %v", path, err)
+					continue
+				}
+				if string(got) != want {
+					t.Errorf("%s: expected %q, got %q", path, want, string(got))
+				}
+			}
+			for _, path := range tt.wantGone {
+				if _, err := os.Stat(filepath.Join(workingDir, path)); !os.IsNotExist(err) {
+					t.Errorf("expected %s to be deleted", path)
+				}
+			}
+		})
+	}
+}
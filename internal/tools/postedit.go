@@ -0,0 +1,309 @@
+// Package tools implements formatting and syntax checks of files after agent edits
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/shizhMSFT/wink-code/internal/logging"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+const (
+	// defaultFormatterTimeout bounds how long an external formatter may run
+	defaultFormatterTimeout = 10 * time.Second
+	// maxSyntaxErrors bounds the syntax errors reported for one file
+	maxSyntaxErrors = 10
+	// pathPlaceholder in formatter arguments is replaced by the file's path
+	pathPlaceholder = "{path}"
+)
+
+// PostEditPipeline formats the files the agent writes and checks their syntax, keyed
+// by file extension. Go files get gofmt and a parse check; JSON, YAML and TOML files a
+// parse check. External formatters configured for an extension replace the built-in
+// one and are skipped when their command is not installed.
+type PostEditPipeline struct {
+	format     bool
+	validate   bool
+	formatters map[string][]string
+	timeout    time.Duration
+}
+
+// NewPostEditPipeline creates a pipeline from the post_edit configuration; a nil
+// configuration enables the built-in steps
+func NewPostEditPipeline(cfg *types.PostEditConfig) *PostEditPipeline {
+	p := &PostEditPipeline{
+		format:     true,
+		validate:   true,
+		formatters: map[string][]string{},
+		timeout:    defaultFormatterTimeout,
+	}
+	if cfg == nil {
+		return p
+	}
+	if cfg.Format != nil {
+		p.format = *cfg.Format
+	}
+	if cfg.Validate != nil {
+		p.validate = *cfg.Validate
+	}
+	for ext, command := range cfg.Formatters {
+		if len(command) > 0 {
+			p.formatters[strings.ToLower(ext)] = command
+		}
+	}
+	if cfg.TimeoutSeconds > 0 {
+		p.timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	return p
+}
+
+// postEditStage attaches a post-edit pipeline to a file-writing tool
+type postEditStage struct {
+	postEdit *PostEditPipeline
+}
+
+// SetPostEditPipeline makes the tool format and check the files it writes; without a
+// pipeline content is written as given
+func (s *postEditStage) SetPostEditPipeline(p *PostEditPipeline) {
+	s.postEdit = p
+}
+
+// postEditResult is the content to write and what the pipeline found
+type postEditResult struct {
+	display string
+	content string
+	// formattedBy names the formatters that changed the content
+	formattedBy []string
+	// syntaxErrors are problems the model should fix
+	syntaxErrors []string
+	// warnings are formatter failures that did not block the write
+	warnings []string
+}
+
+// process runs the pipeline over new content for the file at resolvedPath; display is
+// the path used in messages. The file's BOM and line endings are kept.
+func (p *PostEditPipeline) process(resolvedPath, display, content string) postEditResult {
+	result := postEditResult{display: display, content: content}
+	if p == nil || (!p.format && !p.validate) {
+		return result
+	}
+
+	ext := strings.ToLower(filepath.Ext(resolvedPath))
+	conv := detectConventions(content)
+	body := strings.TrimPrefix(content, utf8BOM)
+	if conv.eol == "\r\n" {
+		body = strings.ReplaceAll(body, "\r\n", "\n")
+	}
+
+	formatted := body
+	if p.format {
+		if command, ok := p.formatters[ext]; ok {
+			out, err := p.runFormatter(command, resolvedPath, formatted)
+			switch {
+			case errors.Is(err, exec.ErrNotFound):
+				logging.Debug("post-edit formatter not installed", "command", command[0], "path", display)
+			case err != nil:
+				result.warnings = append(result.warnings, fmt.Sprintf("%s failed: %v", command[0], err))
+			default:
+				if out != formatted {
+					result.formattedBy = append(result.formattedBy, command[0])
+				}
+				formatted = out
+			}
+		} else if ext == ".go" {
+			// Syntax errors surface from the parse check below
+			if out, err := format.Source([]byte(formatted)); err == nil {
+				if string(out) != formatted {
+					result.formattedBy = append(result.formattedBy, "gofmt")
+				}
+				formatted = string(out)
+			}
+		}
+	}
+
+	if p.validate {
+		result.syntaxErrors = checkSyntax(ext, display, formatted)
+	}
+
+	if formatted != body {
+		result.content = conv.apply(formatted)
+	}
+	return result
+}
+
+// runFormatter pipes content through an external formatter and returns its output
+func (p *PostEditPipeline) runFormatter(command []string, resolvedPath, content string) (string, error) {
+	name, err := exec.LookPath(command[0])
+	if err != nil {
+		return "", err
+	}
+
+	args := make([]string, 0, len(command)-1)
+	for _, arg := range command[1:] {
+		args = append(args, strings.ReplaceAll(arg, pathPlaceholder, resolvedPath))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = existingDir(filepath.Dir(resolvedPath))
+	cmd.Stdin = strings.NewReader(content)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("timed out after %s", p.timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, firstLines(msg, maxSyntaxErrors))
+		}
+		return "", err
+	}
+	if stdout.Len() == 0 && content != "" {
+		return "", fmt.Errorf("produced no output; formatters must write the formatted file to stdout")
+	}
+	return stdout.String(), nil
+}
+
+// existingDir returns dir or its nearest existing ancestor, since new files may be
+// previewed before their directories are created
+func existingDir(dir string) string {
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// checkSyntax parses content by extension and returns its syntax errors as
+// "path:line:column: message"
+func checkSyntax(ext, display, content string) []string {
+	switch ext {
+	case ".go":
+		fset := token.NewFileSet()
+		_, err := parser.ParseFile(fset, display, content, parser.AllErrors|parser.SkipObjectResolution)
+		var list scanner.ErrorList
+		if errors.As(err, &list) {
+			var problems []string
+			for i, e := range list {
+				if i == maxSyntaxErrors {
+					problems = append(problems, fmt.Sprintf("... and %d more", len(list)-maxSyntaxErrors))
+					break
+				}
+				problems = append(problems, e.Error())
+			}
+			return problems
+		}
+		if err != nil {
+			return []string{err.Error()}
+		}
+	case ".json":
+		if json.Valid([]byte(content)) {
+			return nil
+		}
+		var v interface{}
+		err := json.Unmarshal([]byte(content), &v)
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line, col := lineColumnAt(content, int(syntaxErr.Offset)-1)
+			return []string{fmt.Sprintf("%s:%d:%d: %v", display, line, col, syntaxErr)}
+		}
+		if err != nil {
+			return []string{fmt.Sprintf("%s: %v", display, err)}
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(strings.NewReader(content))
+		for {
+			var node yaml.Node
+			err := decoder.Decode(&node)
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return []string{fmt.Sprintf("%s: %v", display, err)}
+			}
+		}
+	case ".toml":
+		var v map[string]interface{}
+		if err := toml.Unmarshal([]byte(content), &v); err != nil {
+			var decodeErr *toml.DecodeError
+			if errors.As(err, &decodeErr) {
+				line, col := decodeErr.Position()
+				return []string{fmt.Sprintf("%s:%d:%d: %v", display, line, col, decodeErr)}
+			}
+			return []string{fmt.Sprintf("%s: %v", display, err)}
+		}
+	}
+	return nil
+}
+
+// lineColumnAt converts the byte offset of a character into its 1-based line and column
+func lineColumnAt(content string, offset int) (int, int) {
+	offset = min(max(offset, 0), len(content))
+	before := content[:offset]
+	line := strings.Count(before, "\n") + 1
+	return line, offset - strings.LastIndex(before, "\n")
+}
+
+// firstLines keeps the first n lines of text
+func firstLines(text string, n int) string {
+	lines := strings.SplitN(text, "\n", n+1)
+	if len(lines) > n {
+		lines = append(lines[:n], "...")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// notes describes the pipeline's findings for the tool result
+func (r postEditResult) notes() string {
+	var b strings.Builder
+	if len(r.formattedBy) > 0 {
+		fmt.Fprintf(&b, "\nFormatted %s with %s.", r.display, strings.Join(r.formattedBy, ", "))
+	}
+	for _, w := range r.warnings {
+		fmt.Fprintf(&b, "\nWarning: formatter %s", w)
+	}
+	if len(r.syntaxErrors) > 0 {
+		fmt.Fprintf(&b, "\nSyntax check failed for %s (the file was written; fix it with another edit):\n  %s",
+			r.display, strings.Join(r.syntaxErrors, "\n  "))
+	}
+	return b.String()
+}
+
+// addMetadata records the pipeline's findings in tool result metadata, appending to
+// those of other files written by the same call
+func (r postEditResult) addMetadata(metadata map[string]interface{}) {
+	if len(r.formattedBy) > 0 {
+		formatted, _ := metadata["formatted_files"].([]string)
+		metadata["formatted_files"] = append(formatted, r.display)
+	}
+	if len(r.syntaxErrors) > 0 {
+		problems, _ := metadata["syntax_errors"].([]string)
+		metadata["syntax_errors"] = append(problems, r.syntaxErrors...)
+	}
+}
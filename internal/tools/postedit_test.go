@@ -0,0 +1,215 @@
+package tools_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// postEditTool is a file-writing tool that accepts a post-edit pipeline
+type postEditTool interface {
+	types.PreviewableTool
+	SetPostEditPipeline(p *tools.PostEditPipeline)
+}
+
+// TestPostEditPipeline tests formatting and syntax checks applied by file-writing tools
+func TestPostEditPipeline(t *testing.T) {
+	disabled := false
+
+	tests := []struct {
+		name        string
+		tool        postEditTool
+		config      *types.PostEditConfig
+		files       map[string]string
+		params      map[string]interface{}
+		file        string
+		wantContent string
+		wantOutput  []string
+		wantMissing []string
+		wantSyntax  bool
+		needs       string
+	}{
+		{
+			name:        "create_file formats Go",
+			tool:        tools.NewCreateFileTool(),
+			params:      map[string]interface{}{"path": "main.go", "content": "package main\nfunc main(){\nprintln( 1 )\n}\n"},
+			file:        "main.go",
+			wantContent: "package main\n\nfunc main() {\n\tprintln(1)\n}\n",
+			wantOutput:  []string{"Formatted main.go with gofmt."},
+		},
+		{
+			name:        "Go syntax errors are reported and the file is still written",
+			tool:        tools.NewCreateFileTool(),
+			params:      map[string]interface{}{"path": "bad.go", "content": "package main\n\nfunc main() {\n\tx := \n}\n"},
+			file:        "bad.go",
+			wantContent: "package main\n\nfunc main() {\n\tx := \n}\n",
+			wantOutput:  []string{"Syntax check failed for bad.go", "bad.go:5:1: expected operand"},
+			wantMissing: []string{"Formatted"},
+			wantSyntax:  true,
+		},
+		{
+			name:        "replace keeps CRLF line endings when formatting",
+			tool:        tools.NewReplaceStringInFileTool(),
+			files:       map[string]string{"a.go": "package a\r\n\r\nvar x = 1\r\n"},
+			params:      map[string]interface{}{"path": "a.go", "old_string": "var x = 1", "new_string": "var x   =   2"},
+			file:        "a.go",
+			wantContent: "package a\r\n\r\nvar x = 2\r\n",
+		},
+		{
+			name:        "already formatted Go is not reported",
+			tool:        tools.NewReplaceStringInFileTool(),
+			files:       map[string]string{"a.go": "package a\n\nvar x = 1\n"},
+			params:      map[string]interface{}{"path": "a.go", "old_string": "1", "new_string": "2"},
+			file:        "a.go",
+			wantContent: "package a\n\nvar x = 2\n",
+			wantMissing: []string{"Formatted", "Syntax"},
+		},
+		{
+			name:        "invalid JSON is reported with its position",
+			tool:        tools.NewMultiEditTool(),
+			files:       map[string]string{"c.json": "{\n  \"a\": 1\n}\n"},
+			params:      map[string]interface{}{"path": "c.json", "edits": []interface{}{map[string]interface{}{"old_string": "1", "new_string": "1,"}}},
+			file:        "c.json",
+			wantContent: "{\n  \"a\": 1,\n}\n",
+			wantOutput:  []string{"Syntax check failed for c.json", "c.json:3:1: invalid character '}'"},
+			wantSyntax:  true,
+		},
+		{
+			name:  "multi_edit omits line ranges moved by the formatter",
+			tool:  tools.NewMultiEditTool(),
+			files: map[string]string{"m.go": "package m\n\nvar x = 1\n"},
+			params: map[string]interface{}{"path": "m.go", "edits": []interface{}{
+				map[string]interface{}{"old_string": "var x = 1\n", "new_string": "import \"fmt\"\nvar x = 1\nvar y=fmt.Sprint(x)\n"},
+			}},
+			file:        "m.go",
+			wantContent: "package m\n\nimport \"fmt\"\n\nvar x = 1\nvar y = fmt.Sprint(x)\n",
+			wantOutput:  []string{"changed lines not reported", "Formatted m.go with gofmt."},
+			wantMissing: []string{"changed lines:"},
+		},
+		{
+			name:  "multi_edit reports line ranges of unformatted content",
+			tool:  tools.NewMultiEditTool(),
+			files: map[string]string{"m.go": "package m\n\nvar x = 1\n"},
+			params: map[string]interface{}{"path": "m.go", "edits": []interface{}{
+				map[string]interface{}{"old_string": "var x = 1\n", "new_string": "var x = 1\n\nvar y = 2\n"},
+			}},
+			file:        "m.go",
+			wantContent: "package m\n\nvar x = 1\n\nvar y = 2\n",
+			wantOutput:  []string{"changed lines: 3-5"},
+			wantMissing: []string{"Formatted"},
+		},
+		{
+			name:       "invalid YAML is reported",
+			tool:       tools.NewCreateFileTool(),
+			params:     map[string]interface{}{"path": "ci.yml", "content": "jobs:\n  build:\n   - run: x\n  - bad\n"},
+			file:       "ci.yml",
+			wantOutput: []string{"Syntax check failed for ci.yml", "yaml: line"},
+			wantSyntax: true,
+		},
+		{
+			name:        "apply_patch formats new Go files",
+			tool:        tools.NewApplyPatchTool(),
+			params:      map[string]interface{}{"patch": "--- /dev/null\n+++ b/pkg/p.go\n@@ -0,0 +1,2 @@\n+package p\n+const  X=1\n"},
+			file:        "pkg/p.go",
+			wantContent: "package p\n\nconst X = 1\n",
+			wantOutput:  []string{"Formatted pkg/p.go with gofmt."},
+		},
+		{
+			name:        "formatting can be turned off",
+			tool:        tools.NewCreateFileTool(),
+			config:      &types.PostEditConfig{Format: &disabled},
+			params:      map[string]interface{}{"path": "main.go", "content": "package main\nfunc main(){}\n"},
+			file:        "main.go",
+			wantContent: "package main\nfunc main(){}\n",
+			wantMissing: []string{"Formatted"},
+		},
+		{
+			name:        "external formatter",
+			tool:        tools.NewCreateFileTool(),
+			config:      &types.PostEditConfig{Formatters: map[string][]string{".txt": {"tr", "a-z", "A-Z"}}},
+			params:      map[string]interface{}{"path": "notes.txt", "content": "hello\n"},
+			file:        "notes.txt",
+			wantContent: "HELLO\n",
+			wantOutput:  []string{"Formatted notes.txt with tr."},
+			needs:       "tr",
+		},
+		{
+			name:        "missing external formatter is skipped",
+			tool:        tools.NewCreateFileTool(),
+			config:      &types.PostEditConfig{Formatters: map[string][]string{".py": {"wink-no-such-formatter", "-"}}},
+			params:      map[string]interface{}{"path": "a.py", "content": "x=1\n"},
+			file:        "a.py",
+			wantContent: "x=1\n",
+			wantMissing: []string{"Formatted", "Warning"},
+		},
+		{
+			name:        "failing external formatter leaves content unchanged",
+			tool:        tools.NewCreateFileTool(),
+			config:      &types.PostEditConfig{Formatters: map[string][]string{".sh": {"false"}}},
+			params:      map[string]interface{}{"path": "run.sh", "content": "echo hi\n"},
+			file:        "run.sh",
+			wantContent: "echo hi\n",
+			wantOutput:  []string{"Warning: formatter false failed"},
+			needs:       "false",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needs != "" {
+				if _, err := exec.LookPath(tt.needs); err != nil {
+					t.Skipf("%s not installed", tt.needs)
+				}
+			}
+
+			workingDir := t.TempDir()
+			setupTree(t, workingDir, tt.files)
+			tt.tool.SetPostEditPipeline(tools.NewPostEditPipeline(tt.config))
+			defer tt.tool.SetPostEditPipeline(nil)
+
+			preview, err := tt.tool.Preview(tt.params, workingDir)
+			if err != nil {
+				t.Fatalf("preview failed: %v", err)
+			}
+
+			result, err := runTool(tt.tool, tt.params, workingDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := os.ReadFile(filepath.Join(workingDir, tt.file))
+			if err != nil {
+				t.Fatalf("failed to read result: %v", err)
+			}
+			if tt.wantContent != "" && string(got) != tt.wantContent {
+				t.Errorf("expected content %q, got %q", tt.wantContent, string(got))
+			}
+
+			// The previewed diff shows the content that was written
+			for _, line := range strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(got), "\r\n", "\n"), "\n"), "\n") {
+				if !strings.Contains(preview.Diff, line) {
+					t.Errorf("expected preview to contain %q, got:\n%s", line, preview.Diff)
+				}
+			}
+
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(result.Output, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, result.Output)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(result.Output, missing) {
+					t.Errorf("expected output not to contain %q, got:\n%s", missing, result.Output)
+				}
+			}
+			if _, ok := result.Metadata["syntax_errors"]; ok != tt.wantSyntax {
+				t.Errorf("expected syntax_errors in metadata: %v, got %v", tt.wantSyntax, result.Metadata["syntax_errors"])
+			}
+		})
+	}
+}
@@ -0,0 +1,638 @@
+// Package tools implements the read_file tool
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shizhMSFT/wink-code/internal/logging"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+const (
+	// readPageChars is the character budget of one read_file response
+	readPageChars = 40000
+	// maxLineChars truncates very long lines, such as minified code
+	maxLineChars = 4000
+	// binarySniffBytes is how much of a file is inspected to decide whether it is binary
+	binarySniffBytes = 8000
+	// hexHeadBytes is how much of a binary file is shown as a hex dump
+	hexHeadBytes = 256
+	// csvSampleRows is the number of data rows shown in a CSV summary
+	csvSampleRows = 5
+	// maxOutlineEntries bounds the number of lines in a JSON key outline
+	maxOutlineEntries = 200
+	// maxOutlineDepth bounds how deep a JSON key outline descends
+	maxOutlineDepth = 4
+)
+
+// ReadFileTool implements the read_file tool
+type ReadFileTool struct{}
+
+// NewReadFileTool creates a new read_file tool instance
+func NewReadFileTool() *ReadFileTool {
+	return &ReadFileTool{}
+}
+
+// Name returns the tool name
+func (r *ReadFileTool) Name() string {
+	return "read_file"
+}
+
+// Description returns the tool description
+func (r *ReadFileTool) Description() string {
+	return "Read a file with line numbers (the 'N<tab>' prefixes are not part of the file). " +
+		"Large files are returned in pages; use start_line/end_line or page to read more. " +
+		"Binary files are summarized with a hex dump, and summary=true outlines JSON keys or shows a CSV header with sample rows."
+}
+
+// ParametersSchema returns the JSON schema for parameters
+func (r *ReadFileTool) ParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Relative path to the file to read",
+			},
+			"start_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "Starting line number (1-indexed, optional)",
+			},
+			"end_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "Ending line number (1-indexed, inclusive, optional)",
+			},
+			"page": map[string]interface{}{
+				"type":        "integer",
+				"description": "Page of a large file to read (1-indexed, optional; cannot be combined with start_line)",
+			},
+			"line_numbers": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Prefix each line with its number (default: true)",
+			},
+			"summary": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Return a structural summary instead of the content: a key outline for JSON, the header and sample rows for CSV/TSV (default: false)",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+// Validate checks if parameters are valid
+func (r *ReadFileTool) Validate(params map[string]interface{}, workingDir string) error {
+	// Check required parameters
+	path, ok := params["path"].(string)
+	if !ok || path == "" {
+		return fmt.Errorf("path parameter is required and must be a non-empty string")
+	}
+
+	// Validate path is within working directory and exists
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return err
+	}
+
+	// Check if file exists
+	fileInfo, err := os.Stat(resolvedPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("file '%s' not found", path)
+		}
+		return fmt.Errorf("cannot access file '%s': %w", path, err)
+	}
+
+	// Check if it's a regular file
+	if fileInfo.IsDir() {
+		return fmt.Errorf("path '%s' is a directory, not a file", path)
+	}
+
+	// Validate line range if specified
+	startLine, hasStart := params["start_line"].(float64)
+	if hasStart {
+		if startLine < 1 {
+			return fmt.Errorf("start_line must be positive, got %.0f", startLine)
+		}
+
+		if endLine, ok := params["end_line"].(float64); ok {
+			if endLine < startLine {
+				return fmt.Errorf("end_line (%.0f) must be >= start_line (%.0f)", endLine, startLine)
+			}
+		}
+	}
+
+	page, hasPage := params["page"].(float64)
+	if hasPage {
+		if page < 1 {
+			return fmt.Errorf("page must be positive, got %.0f", page)
+		}
+		if hasStart {
+			return fmt.Errorf("page cannot be combined with start_line")
+		}
+	}
+
+	if _, err := boolParam(params, "line_numbers"); err != nil {
+		return err
+	}
+	summary, err := boolParam(params, "summary")
+	if err != nil {
+		return err
+	}
+	if summary && (hasStart || hasPage) {
+		return fmt.Errorf("summary cannot be combined with start_line or page")
+	}
+
+	return nil
+}
+
+// Execute reads the file
+func (r *ReadFileTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
+
+	// Extract parameters
+	path := params["path"].(string)
+	startLine, _ := params["start_line"].(float64)
+	endLine, _ := params["end_line"].(float64)
+	page := 1
+	if p, ok := params["page"].(float64); ok {
+		page = int(p)
+	}
+	numbered := true
+	if v, ok := params["line_numbers"].(bool); ok {
+		numbered = v
+	}
+	summary, _ := params["summary"].(bool)
+
+	failure := func(err error) (*types.ToolResult, error) {
+		return &types.ToolResult{
+			Success:         false,
+			Error:           err.Error(),
+			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+		}, err
+	}
+
+	// Resolve path
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return failure(err)
+	}
+
+	file, err := os.Open(resolvedPath)
+	if err != nil {
+		return failure(fmt.Errorf("failed to read file '%s': %w", path, err))
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return failure(fmt.Errorf("failed to read file '%s': %w", path, err))
+	}
+	fileSize := info.Size()
+
+	// Sniff the start of the file to keep binary data out of the context
+	head := make([]byte, binarySniffBytes)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return failure(fmt.Errorf("failed to read file '%s': %w", path, err))
+	}
+	head = head[:n]
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return failure(fmt.Errorf("failed to read file '%s': %w", path, err))
+	}
+
+	if isBinary(head) {
+		return r.binarySummary(path, head, fileSize, startTime), nil
+	}
+
+	if summary {
+		output, format, err := summarizeStructured(path, file, head)
+		if err != nil {
+			return failure(err)
+		}
+		return &types.ToolResult{
+			Success:         true,
+			Output:          output,
+			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+			FilesAffected:   []string{},
+			Metadata: map[string]interface{}{
+				"summary":         format,
+				"file_size_bytes": fileSize,
+			},
+		}, nil
+	}
+
+	window, err := readWindow(file, int(startLine), int(endLine), page, numbered)
+	if err != nil {
+		return failure(fmt.Errorf("failed to read file '%s': %w", path, err))
+	}
+
+	if startLine > 0 && int(startLine) > window.totalLines {
+		return failure(fmt.Errorf("line range invalid - file has %d lines, requested start line %d", window.totalLines, int(startLine)))
+	}
+	if startLine == 0 && page > window.totalPages {
+		return failure(fmt.Errorf("page %d out of range - file has %d page(s)", page, window.totalPages))
+	}
+
+	executionTime := time.Since(startTime).Milliseconds()
+	linesReturned := window.linesReturned()
+
+	logging.Info("File read",
+		"path", SanitizePathForDisplay(workingDir, resolvedPath),
+		"size_bytes", fileSize,
+		"lines", linesReturned,
+		"page", window.page,
+		"execution_time_ms", executionTime,
+	)
+
+	// Format output
+	var outputMsg string
+	switch {
+	case startLine > 0:
+		outputMsg = fmt.Sprintf("Contents of %s (lines %d-%d of %d):\n%s", path, window.first, window.last, window.totalLines, window.text)
+		if window.truncated {
+			outputMsg += fmt.Sprintf("\n[Output stopped at the %d-character budget; call read_file with start_line=%d to continue]",
+				readPageChars, window.last+1)
+		}
+	case window.totalPages > 1:
+		outputMsg = fmt.Sprintf("Contents of %s (lines %d-%d of %d, page %d of %d):\n%s",
+			path, window.first, window.last, window.totalLines, window.page, window.totalPages, window.text)
+		if window.page < window.totalPages {
+			outputMsg += fmt.Sprintf("\n[Page %d of %d; call read_file with page=%d for more]", window.page, window.totalPages, window.page+1)
+		}
+	default:
+		outputMsg = fmt.Sprintf("Contents of %s:\n%s", path, window.text)
+	}
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          outputMsg,
+		ExecutionTimeMs: executionTime,
+		FilesAffected:   []string{},
+		Metadata: map[string]interface{}{
+			"total_lines":     window.totalLines,
+			"lines_returned":  linesReturned,
+			"file_size_bytes": fileSize,
+			"page":            window.page,
+			"total_pages":     window.totalPages,
+		},
+	}, nil
+}
+
+// binarySummary describes a binary file by type, size and a hex dump of its start
+func (r *ReadFileTool) binarySummary(path string, head []byte, fileSize int64, startTime time.Time) *types.ToolResult {
+	mimeType := http.DetectContentType(head)
+	if mimeType == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(filepath.Ext(path)); byExt != "" {
+			mimeType = byExt
+		}
+	}
+
+	dump := head[:min(len(head), hexHeadBytes)]
+	output := fmt.Sprintf("Binary file %s: %s, %d bytes\nFirst %d bytes:\n%s",
+		path, mimeType, fileSize, len(dump), strings.TrimSuffix(hex.Dump(dump), "\n"))
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          output,
+		ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+		FilesAffected:   []string{},
+		Metadata: map[string]interface{}{
+			"binary":          true,
+			"mime_type":       mimeType,
+			"file_size_bytes": fileSize,
+		},
+	}
+}
+
+// ReadPaths returns the file being read
+func (r *ReadFileTool) ReadPaths(params map[string]interface{}, workingDir string) ([]string, error) {
+	return singleTarget(params, "path", workingDir)
+}
+
+// RequiresApproval returns true as file reading requires approval
+func (r *ReadFileTool) RequiresApproval() bool {
+	return true
+}
+
+// RiskLevel returns the risk level for this tool
+func (r *ReadFileTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelReadOnly
+}
+
+// isBinary reports whether the start of a file looks like binary data: it contains a
+// NUL byte, or more than a tenth of it is control characters or invalid UTF-8
+func isBinary(head []byte) bool {
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
+	}
+
+	suspicious, total := 0, 0
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRune(head[i:])
+		switch {
+		case r == utf8.RuneError && size == 1 && len(head)-i < utf8.UTFMax:
+			// A rune cut off by the sniff window
+		case r == utf8.RuneError && size == 1:
+			suspicious++
+		case r < 0x20 && r != '\t' && r != '\n' && r != '\r' && r != '\f' && r != '\b' && r != 0x1b:
+			suspicious++
+		}
+		total++
+		i += size
+	}
+	return suspicious*10 > total
+}
+
+// lineWindow is the part of a file returned by one read_file call
+type lineWindow struct {
+	text       string
+	first      int // First and last line returned, 1-based; zero when none
+	last       int
+	totalLines int
+	page       int
+	totalPages int
+	truncated  bool // A line range was cut short by the character budget
+}
+
+// linesReturned returns the number of lines in the window
+func (w *lineWindow) linesReturned() int {
+	if w.first == 0 {
+		return 0
+	}
+	return w.last - w.first + 1
+}
+
+// readWindow streams r line by line, keeping only the requested lines. With a
+// start line the lines from start to end (0 for the end of file) are returned up to
+// the character budget; otherwise the file is split into pages of at most that
+// budget and the given page is returned. The whole file is scanned to count lines
+// and pages, but never held in memory.
+func readWindow(r io.Reader, start, end, page int, numbered bool) (*lineWindow, error) {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	w := &lineWindow{page: page}
+	if start > 0 {
+		w.page = 0
+	}
+
+	var out strings.Builder
+	pageNum, pageChars := 1, 0
+	for n := 1; ; n++ {
+		line, err := readCappedLine(reader, maxLineChars)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		w.totalLines = n
+
+		formatted := line + "\n"
+		if numbered {
+			formatted = fmt.Sprintf("%6d\t%s\n", n, line)
+		}
+
+		if start > 0 {
+			if n < start || (end > 0 && n > end) || w.truncated {
+				continue
+			}
+			if out.Len() > 0 && out.Len()+len(formatted) > readPageChars {
+				w.truncated = true
+				continue
+			}
+		} else {
+			if pageChars > 0 && pageChars+len(formatted) > readPageChars {
+				pageNum++
+				pageChars = 0
+			}
+			pageChars += len(formatted)
+			if pageNum != page {
+				continue
+			}
+		}
+
+		out.WriteString(formatted)
+		if w.first == 0 {
+			w.first = n
+		}
+		w.last = n
+	}
+
+	w.totalPages = pageNum
+	w.text = strings.TrimSuffix(out.String(), "\n")
+	return w, nil
+}
+
+// readCappedLine reads one line without its terminator, keeping at most limit bytes
+// of it so a huge single-line file can't exhaust memory. It returns io.EOF only when
+// no data is left.
+func readCappedLine(r *bufio.Reader, limit int) (string, error) {
+	line, dropped, err := readLineBytes(r, limit)
+	if err != nil {
+		return "", err
+	}
+	if dropped > 0 {
+		return fmt.Sprintf("%s [... line truncated, %d more bytes]", line, dropped), nil
+	}
+	return string(line), nil
+}
+
+// readLineBytes reads one line without its terminator, keeping at most limit bytes
+// (cut at a rune boundary) and returning how many bytes were dropped
+func readLineBytes(r *bufio.Reader, limit int) ([]byte, int, error) {
+	var buf []byte
+	dropped := 0
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return nil, 0, err
+		}
+		take := min(max(limit-len(buf), 0), len(chunk))
+		for take > 0 && take < len(chunk) && !utf8.RuneStart(chunk[take]) {
+			take--
+		}
+		buf = append(buf, chunk[:take]...)
+		dropped += len(chunk) - take
+		if !isPrefix {
+			return buf, dropped, nil
+		}
+	}
+}
+
+// summarizeStructured returns a structural summary of a JSON or CSV/TSV file and the
+// name of the format
+func summarizeStructured(path string, r io.Reader, head []byte) (string, string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(head, []byte(utf8BOM)), " \t\r\n")
+
+	switch {
+	case ext == ".csv":
+		output, err := summarizeCSV(path, r, ',', "CSV")
+		return output, "csv", err
+	case ext == ".tsv":
+		output, err := summarizeCSV(path, r, '\t', "TSV")
+		return output, "tsv", err
+	case ext == ".json" || bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")):
+		output, err := summarizeJSON(path, r)
+		return output, "json", err
+	default:
+		return "", "", fmt.Errorf("no structured summary for '%s'; summaries support JSON and CSV/TSV files", path)
+	}
+}
+
+// summarizeCSV returns the header, a few sample rows and the row count of a
+// delimited file
+func summarizeCSV(path string, r io.Reader, comma rune, format string) (string, error) {
+	reader := csv.NewReader(bufio.NewReader(r))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var sample bytes.Buffer
+	writer := csv.NewWriter(&sample)
+	writer.Comma = comma
+
+	rows, columns := 0, 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s file '%s': %w", format, path, err)
+		}
+		if rows == 0 {
+			columns = len(record)
+			record[0] = strings.TrimPrefix(record[0], utf8BOM)
+		}
+		if rows <= csvSampleRows {
+			if err := writer.Write(record); err != nil {
+				return "", fmt.Errorf("failed to format %s sample: %w", format, err)
+			}
+		}
+		rows++
+	}
+	writer.Flush()
+
+	if rows == 0 {
+		return fmt.Sprintf("Summary of %s (%s): empty file", path, format), nil
+	}
+
+	dataRows := rows - 1
+	output := fmt.Sprintf("Summary of %s (%s, %d columns, %d data rows):\nHeader and sample rows:\n%s",
+		path, format, columns, dataRows, strings.TrimSuffix(sample.String(), "\n"))
+	if more := dataRows - csvSampleRows; more > 0 {
+		output += fmt.Sprintf("\n... (%d more rows)", more)
+	}
+	return output, nil
+}
+
+// summarizeJSON returns an outline of the keys and value types of a JSON document,
+// streamed token by token so key order is kept
+func summarizeJSON(path string, r io.Reader) (string, error) {
+	decoder := json.NewDecoder(bufio.NewReader(r))
+	decoder.UseNumber()
+
+	outline := &jsonOutline{}
+	if err := outline.value(decoder, "(root)", 0, true); err != nil {
+		return "", fmt.Errorf("failed to parse JSON file '%s': %w", path, err)
+	}
+
+	output := fmt.Sprintf("Summary of %s (JSON key outline):\n%s", path, strings.Join(outline.lines, "\n"))
+	if outline.omitted > 0 {
+		output += fmt.Sprintf("\n... (%d more entries not shown)", outline.omitted)
+	}
+	return output, nil
+}
+
+// jsonOutline collects one line per key of a JSON document. Arrays are described by
+// their length and their first element.
+type jsonOutline struct {
+	lines   []string
+	omitted int
+}
+
+// add appends an outline line, returning its index or -1 when it is not shown
+func (o *jsonOutline) add(depth int, show bool, text string) int {
+	if !show {
+		return -1
+	}
+	if len(o.lines) >= maxOutlineEntries {
+		o.omitted++
+		return -1
+	}
+	o.lines = append(o.lines, strings.Repeat("  ", depth)+text)
+	return len(o.lines) - 1
+}
+
+// value consumes one JSON value from the decoder, outlining it under name when show
+// is set and the depth limit allows
+func (o *jsonOutline) value(decoder *json.Decoder, name string, depth int, show bool) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	show = show && depth <= maxOutlineDepth
+
+	switch v := token.(type) {
+	case json.Delim:
+		index := o.add(depth, show, name)
+		count := 0
+		for decoder.More() {
+			if v == '{' {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := keyToken.(string)
+				if err := o.value(decoder, key, depth+1, show); err != nil {
+					return err
+				}
+			} else if err := o.value(decoder, "[]", depth+1, show && count == 0); err != nil {
+				return err
+			}
+			count++
+		}
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+		if index >= 0 {
+			kind := fmt.Sprintf("object (%d keys)", count)
+			if v == '[' {
+				kind = fmt.Sprintf("array (%d items)", count)
+			}
+			o.lines[index] += ": " + kind
+		}
+	case string:
+		o.add(depth, show, fmt.Sprintf("%s: string %q", name, truncateSample(v)))
+	case json.Number:
+		o.add(depth, show, fmt.Sprintf("%s: number %s", name, truncateSample(v.String())))
+	case bool:
+		o.add(depth, show, fmt.Sprintf("%s: boolean %t", name, v))
+	case nil:
+		o.add(depth, show, name+": null")
+	}
+	return nil
+}
+
+// truncateSample shortens a sample value shown in an outline
+func truncateSample(s string) string {
+	const maxSample = 40
+	if utf8.RuneCountInString(s) <= maxSample {
+		return s
+	}
+	return string([]rune(s)[:maxSample]) + "..."
+}
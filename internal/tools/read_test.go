@@ -0,0 +1,211 @@
+package tools_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+)
+
+// TestReadFileModes tests line numbering, paging, binary detection and summaries
+func TestReadFileModes(t *testing.T) {
+	tool := tools.NewReadFileTool()
+
+	// 3000 lines of 30 characters span several 40k-character pages
+	var large strings.Builder
+	for i := 1; i <= 3000; i++ {
+		fmt.Fprintf(&large, "line %04d %s\n", i, strings.Repeat("x", 19))
+	}
+
+	tests := []struct {
+		name        string
+		file        string
+		content     string
+		params      map[string]interface{}
+		wantErr     string
+		wantOutput  []string
+		wantMissing []string
+		wantMeta    map[string]interface{}
+	}{
+		{
+			name:       "numbered lines",
+			file:       "a.txt",
+			content:    "alpha\nbeta\n",
+			params:     map[string]interface{}{},
+			wantOutput: []string{"Contents of a.txt:\n     1\talpha\n     2\tbeta"},
+			wantMeta:   map[string]interface{}{"total_lines": 2, "lines_returned": 2},
+		},
+		{
+			name:       "plain lines",
+			file:       "a.txt",
+			content:    "alpha\r\nbeta",
+			params:     map[string]interface{}{"line_numbers": false},
+			wantOutput: []string{"Contents of a.txt:\nalpha\nbeta"},
+		},
+		{
+			name:        "line range of a large file",
+			file:        "big.log",
+			content:     large.String(),
+			params:      map[string]interface{}{"start_line": float64(1500), "end_line": float64(1501)},
+			wantOutput:  []string{"(lines 1500-1501 of 3000)", "  1500\tline 1500", "  1501\tline 1501"},
+			wantMissing: []string{"line 1499", "line 1502", "Output stopped"},
+		},
+		{
+			name:       "open-ended range stops at the budget",
+			file:       "big.log",
+			content:    large.String(),
+			params:     map[string]interface{}{"start_line": float64(10)},
+			wantOutput: []string{"(lines 10-", "call read_file with start_line="},
+		},
+		{
+			name:        "first page",
+			file:        "big.log",
+			content:     large.String(),
+			params:      map[string]interface{}{},
+			wantOutput:  []string{"page 1 of 3", "     1\tline 0001", "call read_file with page=2"},
+			wantMissing: []string{"line 3000"},
+			wantMeta:    map[string]interface{}{"page": 1, "total_pages": 3, "total_lines": 3000},
+		},
+		{
+			name:        "last page",
+			file:        "big.log",
+			content:     large.String(),
+			params:      map[string]interface{}{"page": float64(3)},
+			wantOutput:  []string{"page 3 of 3", "line 3000"},
+			wantMissing: []string{"line 0001", "for more"},
+		},
+		{
+			name:    "page out of range",
+			file:    "a.txt",
+			content: "alpha\n",
+			params:  map[string]interface{}{"page": float64(2)},
+			wantErr: "page 2 out of range",
+		},
+		{
+			name:    "page with start_line",
+			file:    "a.txt",
+			content: "alpha\n",
+			params:  map[string]interface{}{"page": float64(1), "start_line": float64(1)},
+			wantErr: "cannot be combined",
+		},
+		{
+			name:       "very long line is truncated",
+			file:       "min.js",
+			content:    strings.Repeat("a", 10000),
+			params:     map[string]interface{}{},
+			wantOutput: []string{"[... line truncated, 6000 more bytes]"},
+		},
+		{
+			name:        "binary file",
+			file:        "blob.bin",
+			content:     "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+			params:      map[string]interface{}{},
+			wantOutput:  []string{"Binary file blob.bin: image/png, 16 bytes", "00000000  89 50 4e 47"},
+			wantMissing: []string{"IHDR\n"},
+			wantMeta:    map[string]interface{}{"binary": true, "mime_type": "image/png"},
+		},
+		{
+			name:    "JSON outline",
+			file:    "package.json",
+			content: `{"name": "wink", "version": 2, "private": true, "scripts": {"build": "go build"}, "files": [{"path": "a"}, {"path": "b"}], "extra": null}`,
+			params:  map[string]interface{}{"summary": true},
+			wantOutput: []string{
+				"(root): object (6 keys)",
+				"  name: string \"wink\"",
+				"  version: number 2",
+				"  scripts: object (1 keys)\n    build: string \"go build\"",
+				"  files: array (2 items)\n    []: object (1 keys)\n      path: string \"a\"",
+				"  extra: null",
+			},
+			wantMissing: []string{`"b"`},
+			wantMeta:    map[string]interface{}{"summary": "json"},
+		},
+		{
+			name:       "CSV summary",
+			file:       "people.csv",
+			content:    "id,name\n1,Ann\n2,\"Bo, Jr\"\n3,C\n4,D\n5,E\n6,F\n7,G\n",
+			params:     map[string]interface{}{"summary": true},
+			wantOutput: []string{"(CSV, 2 columns, 7 data rows)", "id,name\n1,Ann\n2,\"Bo, Jr\"", "5,E\n... (2 more rows)"},
+		},
+		{
+			name:    "summary of an unsupported format",
+			file:    "notes.txt",
+			content: "hello\n",
+			params:  map[string]interface{}{"summary": true},
+			wantErr: "summaries support JSON and CSV/TSV",
+		},
+		{
+			name:    "invalid JSON",
+			file:    "broken.json",
+			content: `{"a": }`,
+			params:  map[string]interface{}{"summary": true},
+			wantErr: "failed to parse JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(workingDir, tt.file), []byte(tt.content), 0644); err != nil {
+				t.Fatalf("setup failed: %v", err)
+			}
+
+			params := map[string]interface{}{"path": tt.file}
+			for k, v := range tt.params {
+				params[k] = v
+			}
+
+			err := tool.Validate(params, workingDir)
+			var output string
+			var metadata map[string]interface{}
+			if err == nil {
+				res, execErr := tool.Execute(context.Background(), params, workingDir)
+				err = execErr
+				if res != nil {
+					output, metadata = res.Output, res.Metadata
+				}
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(output, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, truncateForLog(output))
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(output, missing) {
+					t.Errorf("expected output not to contain %q, got:\n%s", missing, truncateForLog(output))
+				}
+			}
+			for key, want := range tt.wantMeta {
+				if got := metadata[key]; fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("expected metadata %s=%v, got %v", key, want, got)
+				}
+			}
+			if len(output) > 41000 {
+				t.Errorf("expected output within the page budget, got %d characters", len(output))
+			}
+		})
+	}
+}
+
+// truncateForLog keeps failure messages readable for large outputs
+func truncateForLog(s string) string {
+	if len(s) > 500 {
+		return s[:500] + "..."
+	}
+	return s
+}
@@ -36,6 +36,19 @@ func (r *Registry) Register(tool types.Tool) error {
 	return nil
 }
 
+// Unregister removes a tool from the registry, reporting whether it was registered
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tools[name]; !exists {
+		return false
+	}
+
+	delete(r.tools, name)
+	return true
+}
+
 // Get retrieves a tool by name
 func (r *Registry) Get(name string) (types.Tool, error) {
 	r.mu.RLock()
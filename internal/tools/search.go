@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io/fs"
@@ -9,8 +8,10 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/shizhMSFT/wink-code/internal/index"
 	"github.com/shizhMSFT/wink-code/internal/logging"
 	"github.com/shizhMSFT/wink-code/pkg/types"
 )
@@ -28,7 +29,8 @@ func (t *FileSearchTool) Name() string {
 }
 
 func (t *FileSearchTool) Description() string {
-	return "Search for files matching a glob pattern (e.g., '**/*.py', 'src/**/*.go')"
+	return "Search for files matching a glob pattern (e.g., '*.py', 'src/**/*.go'); patterns without a slash match file names at any depth. " +
+		"Files ignored by .gitignore or .winkignore are skipped unless include_ignored is set."
 }
 
 func (t *FileSearchTool) ParametersSchema() map[string]interface{} {
@@ -37,13 +39,17 @@ func (t *FileSearchTool) ParametersSchema() map[string]interface{} {
 		"properties": map[string]interface{}{
 			"pattern": map[string]interface{}{
 				"type":        "string",
-				"description": "Glob pattern (e.g., '**/*.py', 'src/**/*.go')",
+				"description": "Glob pattern relative to base_path (e.g., '*.py', 'src/**/*.go')",
 			},
 			"base_path": map[string]interface{}{
 				"type":        "string",
 				"description": "Base directory to search from (default: current directory)",
 				"default":     ".",
 			},
+			"include_ignored": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Also search files excluded by .gitignore, .winkignore and .git/info/exclude (default: false)",
+			},
 		},
 		"required": []string{"pattern"},
 	}
@@ -76,6 +82,10 @@ func (t *FileSearchTool) Validate(params map[string]interface{}, workingDir stri
 		}
 	}
 
+	if _, err := boolParam(params, "include_ignored"); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -88,25 +98,16 @@ func (t *FileSearchTool) Execute(ctx context.Context, params map[string]interfac
 		basePath = bp
 	}
 
+	includeIgnored, _ := params["include_ignored"].(bool)
+
 	absBase := filepath.Join(workingDir, basePath)
-	logging.Debug("file_search: pattern=%s base=%s", pattern, absBase)
+	logging.Debug("file_search: pattern=%s base=%s include_ignored=%v", pattern, absBase, includeIgnored)
 
 	var matches []string
 	const maxResults = 1000
 	const maxDepth = 20
 
-	err := filepath.WalkDir(absBase, func(path string, d fs.DirEntry, err error) error {
-		// Check for context cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		if err != nil {
-			return nil // Skip errors
-		}
-
+	err := walkWorkspace(ctx, workingDir, absBase, includeIgnored, func(path string, d fs.DirEntry) error {
 		// Check depth
 		relPath, _ := filepath.Rel(absBase, path)
 		depth := strings.Count(relPath, string(filepath.Separator))
@@ -122,14 +123,14 @@ func (t *FileSearchTool) Execute(ctx context.Context, params map[string]interfac
 			return nil
 		}
 
-		// Get relative path for matching
+		// Get relative path for output
 		relToWorking, err := filepath.Rel(workingDir, path)
 		if err != nil {
 			return nil
 		}
 
-		// Try to match the pattern against the relative path
-		matched, err := matchGlob(pattern, relToWorking)
+		// Match the pattern against the path relative to the base directory
+		matched, err := matchPathGlob(pattern, relPath)
 		if err != nil {
 			logging.Debug("file_search: pattern match error: %v", err)
 			return nil
@@ -215,11 +216,32 @@ func matchGlob(pattern, path string) (bool, error) {
 }
 
 // GrepSearchTool implements grep_search for content searching
-type GrepSearchTool struct{}
+type GrepSearchTool struct {
+	workers int
+	// autoIndex builds the workspace's trigram index on first use; an existing
+	// index is used either way
+	autoIndex bool
+
+	indexMu   sync.Mutex
+	index     *index.Index
+	indexRoot string
+}
 
 // NewGrepSearchTool creates a new grep_search tool
 func NewGrepSearchTool() *GrepSearchTool {
-	return &GrepSearchTool{}
+	return &GrepSearchTool{workers: defaultGrepWorkers()}
+}
+
+// SetAutoIndex makes grep_search build a trigram index under .wink/index on first use.
+// Without it, an index is only used once created with 'wink index build'.
+func (t *GrepSearchTool) SetAutoIndex(enabled bool) {
+	t.autoIndex = enabled
+}
+
+// SetConcurrency sets how many files are scanned in parallel; values below 1 scan
+// one file at a time
+func (t *GrepSearchTool) SetConcurrency(workers int) {
+	t.workers = max(workers, 1)
 }
 
 func (t *GrepSearchTool) Name() string {
@@ -227,7 +249,9 @@ func (t *GrepSearchTool) Name() string {
 }
 
 func (t *GrepSearchTool) Description() string {
-	return "Search file contents for text or regex pattern"
+	return "Search file contents for text or regex pattern. Results are grouped by file with line numbers " +
+		"(\"N:\" for matching lines, \"N-\" for context lines), so matches can be edited without reading the file first. " +
+		"Files ignored by .gitignore or .winkignore are skipped unless include_ignored is set."
 }
 
 func (t *GrepSearchTool) ParametersSchema() map[string]interface{} {
@@ -243,15 +267,44 @@ func (t *GrepSearchTool) ParametersSchema() map[string]interface{} {
 				"description": "Whether pattern is regex (default: false)",
 				"default":     false,
 			},
+			"case_insensitive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Ignore case when matching (default: false)",
+			},
+			"multiline": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Match against whole files so patterns can span lines with \\n; ^ and $ match at line boundaries (default: false)",
+			},
 			"file_pattern": map[string]interface{}{
 				"type":        "string",
-				"description": "Glob pattern to limit files searched (default: all files)",
+				"description": "Glob pattern to limit files searched; patterns without a slash match file names at any depth (default: all files)",
+			},
+			"include_ignored": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Also search files excluded by .gitignore, .winkignore and .git/info/exclude (default: false)",
+			},
+			"context_before": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Lines of context to show before each match, up to %d (default: 0)", maxGrepContext),
+			},
+			"context_after": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Lines of context to show after each match, up to %d (default: 0)", maxGrepContext),
+			},
+			"output_mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{grepOutputContent, grepOutputFiles, grepOutputCount},
+				"description": "content shows matching lines, files_with_matches only the file paths, count the matching lines per file (default: content)",
 			},
 			"max_results": map[string]interface{}{
 				"type":        "integer",
-				"description": "Maximum number of results to return (default: 100)",
+				"description": "Maximum number of matching lines to return, or of files in files_with_matches and count modes (default: 100)",
 				"default":     100,
 			},
+			"max_per_file": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of matching lines to show per file in content mode (default: %d)", defaultGrepPerFile),
+			},
 		},
 		"required": []string{"pattern"},
 	}
@@ -263,6 +316,12 @@ func (t *GrepSearchTool) Validate(params map[string]interface{}, workingDir stri
 		return fmt.Errorf("pattern parameter is required and must be a non-empty string")
 	}
 
+	for _, name := range []string{"is_regex", "case_insensitive", "multiline", "include_ignored"} {
+		if _, err := boolParam(params, name); err != nil {
+			return err
+		}
+	}
+
 	// If regex, validate it
 	if isRegex, ok := params["is_regex"].(bool); ok && isRegex {
 		_, err := regexp.Compile(pattern)
@@ -278,6 +337,26 @@ func (t *GrepSearchTool) Validate(params map[string]interface{}, workingDir stri
 		}
 	}
 
+	if maxPerFile, ok := params["max_per_file"].(float64); ok {
+		if maxPerFile < 1 || maxPerFile > 1000 {
+			return fmt.Errorf("max_per_file must be between 1 and 1000")
+		}
+	}
+
+	for _, name := range []string{"context_before", "context_after"} {
+		if lines, ok := params[name].(float64); ok && (lines < 0 || lines > maxGrepContext) {
+			return fmt.Errorf("%s must be between 0 and %d", name, maxGrepContext)
+		}
+	}
+
+	if mode, ok := params["output_mode"]; ok && mode != nil {
+		switch mode {
+		case grepOutputContent, grepOutputFiles, grepOutputCount:
+		default:
+			return fmt.Errorf("output_mode must be one of %s, %s or %s", grepOutputContent, grepOutputFiles, grepOutputCount)
+		}
+	}
+
 	return nil
 }
 
@@ -285,10 +364,10 @@ func (t *GrepSearchTool) Execute(ctx context.Context, params map[string]interfac
 	startTime := time.Now()
 
 	pattern := params["pattern"].(string)
-	isRegex := false
-	if ir, ok := params["is_regex"].(bool); ok {
-		isRegex = ir
-	}
+	isRegex, _ := params["is_regex"].(bool)
+	caseInsensitive, _ := params["case_insensitive"].(bool)
+	multiline, _ := params["multiline"].(bool)
+	includeIgnored, _ := params["include_ignored"].(bool)
 
 	filePattern := ""
 	if fp, ok := params["file_pattern"].(string); ok {
@@ -300,106 +379,86 @@ func (t *GrepSearchTool) Execute(ctx context.Context, params map[string]interfac
 		maxResults = int(mr)
 	}
 
-	logging.Debug("grep_search: pattern=%s is_regex=%v file_pattern=%s max=%d", pattern, isRegex, filePattern, maxResults)
-
-	// Compile regex if needed
-	var re *regexp.Regexp
-	var err error
-	if isRegex {
-		re, err = regexp.Compile(pattern)
-		if err != nil {
-			return &types.ToolResult{
-				Success: false,
-				Output:  fmt.Sprintf("Invalid regex pattern: %v", err),
-			}, err
-		}
+	maxPerFile := defaultGrepPerFile
+	if mp, ok := params["max_per_file"].(float64); ok {
+		maxPerFile = int(mp)
 	}
 
-	type match struct {
-		file string
-		line int
-		text string
+	outputMode := grepOutputContent
+	if mode, ok := params["output_mode"].(string); ok && mode != "" {
+		outputMode = mode
 	}
 
-	var matches []match
-	filesSearched := 0
+	logging.Debug("grep_search: pattern=%s is_regex=%v file_pattern=%s max=%d include_ignored=%v output_mode=%s",
+		pattern, isRegex, filePattern, maxResults, includeIgnored, outputMode)
 
-	err = filepath.WalkDir(workingDir, func(path string, d fs.DirEntry, err error) error {
-		// Check for context cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		if err != nil {
-			return nil // Skip errors
-		}
-
-		if d.IsDir() {
-			return nil
-		}
+	re, err := newGrepRegexp(pattern, isRegex, caseInsensitive, multiline)
+	if err != nil {
+		return &types.ToolResult{
+			Success: false,
+			Output:  fmt.Sprintf("Invalid regex pattern: %v", err),
+		}, err
+	}
 
-		// Get relative path
-		relPath, err := filepath.Rel(workingDir, path)
-		if err != nil {
-			return nil
-		}
+	opts := &grepOptions{
+		re:        re,
+		multiline: multiline,
+		countOnly: outputMode != grepOutputContent,
+		firstOnly: outputMode == grepOutputFiles,
+	}
+	switch {
+	case !isRegex && !caseInsensitive:
+		opts.literal = pattern
+	case !isRegex && !multiline:
+		opts.foldedLiteral = string(index.Fold([]byte(pattern)))
+	case !multiline:
+		opts.prefilter = newGrepPrefilter(pattern, isRegex, caseInsensitive)
+	}
+	if v, ok := params["context_before"].(float64); ok {
+		opts.before = int(v)
+	}
+	if v, ok := params["context_after"].(float64); ok {
+		opts.after = int(v)
+	}
 
-		// Filter by file pattern if provided
-		if filePattern != "" {
-			matched, err := matchGlob(filePattern, relPath)
-			if err != nil || !matched {
-				return nil
-			}
-		}
+	search := &grepSearch{
+		workingDir:     workingDir,
+		filePattern:    filePattern,
+		includeIgnored: includeIgnored,
+		opts:           opts,
+		limit:          maxResults,
+		limitFiles:     outputMode != grepOutputContent,
+		workers:        t.workers,
+	}
+	if outputMode == grepOutputContent {
+		search.perFile = maxPerFile
+	}
 
-		// Skip binary files
-		if isBinaryFile(path) {
-			return nil
+	// The index covers the files searched without include_ignored; it narrows which are
+	// read and is bypassed whenever it can't be used
+	indexed := false
+	if !includeIgnored && (t.autoIndex || index.Exists(workingDir)) {
+		query, err := grepIndexQuery(pattern, isRegex, caseInsensitive, multiline)
+		if err == nil {
+			search.files, err = t.indexedGrepJobs(ctx, workingDir, filePattern, query)
 		}
-
-		filesSearched++
-
-		// Search file contents
-		file, err := os.Open(path)
 		if err != nil {
-			return nil // Skip files we can't open
-		}
-		defer file.Close()
-
-		scanner := bufio.NewScanner(file)
-		lineNum := 0
-		for scanner.Scan() {
-			lineNum++
-			line := scanner.Text()
-
-			matched := false
-			if isRegex {
-				matched = re.MatchString(line)
-			} else {
-				matched = strings.Contains(line, pattern)
-			}
-
-			if matched {
-				matches = append(matches, match{
-					file: relPath,
-					line: lineNum,
-					text: strings.TrimSpace(line),
-				})
-
-				if len(matches) >= maxResults {
-					return fs.SkipAll
-				}
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return &types.ToolResult{
+					Success:         false,
+					Output:          fmt.Sprintf("Error during grep search: %v", ctxErr),
+					ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+				}, ctxErr
 			}
+			logging.Debug("grep_search: searching without the index: %v", err)
+			search.files = nil
 		}
+		indexed = search.files != nil
+	}
 
-		return nil
-	})
-
+	outcome, err := search.run(ctx)
 	executionTime := time.Since(startTime).Milliseconds()
-
-	if err != nil && err != fs.SkipAll {
+	if err != nil {
 		return &types.ToolResult{
 			Success:         false,
 			Output:          fmt.Sprintf("Error during grep search: %v", err),
@@ -409,32 +468,68 @@ func (t *GrepSearchTool) Execute(ctx context.Context, params map[string]interfac
 
 	// Format output
 	var output strings.Builder
-	if len(matches) == 0 {
+	switch {
+	case len(outcome.results) == 0:
 		output.WriteString(fmt.Sprintf("No matches found for '%s'", pattern))
-	} else {
-		output.WriteString(fmt.Sprintf("Found %d match(es) for '%s':\n", len(matches), pattern))
-		for _, m := range matches {
-			output.WriteString(fmt.Sprintf("  %s:%d: %s\n", m.file, m.line, m.text))
+	case outputMode == grepOutputFiles:
+		output.WriteString(fmt.Sprintf("Found %d file(s) matching '%s':\n", len(outcome.results), pattern))
+		for _, r := range outcome.results {
+			output.WriteString(fmt.Sprintf("  %s\n", r.path))
 		}
-		if len(matches) >= maxResults {
+	case outputMode == grepOutputCount:
+		output.WriteString(fmt.Sprintf("Found %d matching line(s) in %d file(s) for '%s':\n", outcome.totalMatches, len(outcome.results), pattern))
+		for _, r := range outcome.results {
+			output.WriteString(fmt.Sprintf("  %s: %d\n", r.path, r.matches))
+		}
+	default:
+		output.WriteString(fmt.Sprintf("Found %d match(es) in %d file(s) for '%s':\n", outcome.totalMatches, len(outcome.results), pattern))
+		for i, r := range outcome.results {
+			if output.Len() > readPageChars {
+				output.WriteString(fmt.Sprintf("\n[Output stopped at the %d-character budget; %d more file(s) not shown, narrow the search with file_pattern]\n",
+					readPageChars, len(outcome.results)-i))
+				break
+			}
+			output.WriteString("\n")
+			r.format(&output, maxPerFile)
+		}
+	}
+	if outcome.limitReached {
+		if outputMode == grepOutputContent {
 			output.WriteString(fmt.Sprintf("\nWarning: Reached limit of %d results", maxResults))
+		} else {
+			output.WriteString(fmt.Sprintf("\nWarning: Reached limit of %d files", maxResults))
 		}
 	}
 
-	logging.Debug("grep_search: found %d matches in %d files (%dms)", len(matches), filesSearched, executionTime)
+	logging.Debug("grep_search: found %d matches in %d of %d files (%dms)", outcome.totalMatches, len(outcome.results), outcome.filesSearched, executionTime)
 
 	return &types.ToolResult{
 		Success:         true,
 		Output:          output.String(),
 		ExecutionTimeMs: executionTime,
 		Metadata: map[string]interface{}{
-			"total_matches":  len(matches),
-			"files_searched": filesSearched,
+			"total_matches":  outcome.totalMatches,
+			"files_matched":  len(outcome.results),
+			"files_searched": outcome.filesSearched,
 			"pattern":        pattern,
+			"output_mode":    outputMode,
+			"indexed":        indexed,
 		},
 	}, nil
 }
 
+// grepIndexQuery converts a grep_search pattern into a search index query
+func grepIndexQuery(pattern string, isRegex, caseInsensitive, multiline bool) (*index.Query, error) {
+	if !isRegex {
+		return index.LiteralQuery(pattern), nil
+	}
+	re, err := newGrepRegexp(pattern, isRegex, caseInsensitive, multiline)
+	if err != nil {
+		return nil, err
+	}
+	return index.RegexpQuery(re.String())
+}
+
 func (t *GrepSearchTool) RequiresApproval() bool {
 	return true
 }
@@ -2,10 +2,13 @@ package tools_test
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/shizhMSFT/wink-code/internal/llm"
 	"github.com/shizhMSFT/wink-code/internal/tools"
 	"github.com/shizhMSFT/wink-code/pkg/types"
 )
@@ -330,6 +333,212 @@ func TestGrepSearchTool(t *testing.T) {
 	}
 }
 
+// TestGrepSearchOutput tests grep_search context lines, matching options and output modes
+func TestGrepSearchOutput(t *testing.T) {
+	workingDir := t.TempDir()
+	setupTree(t, workingDir, map[string]string{
+		"main.go":  "package main\n\nfunc main() {\n\treturn\n}\n\n// Helper does things\nfunc helper() {}\n\nvar x = 1\n",
+		"todo.txt": "TODO one\nTODO two\nTODO three\nTODO four\n",
+		"min.js":   strings.Repeat("a", 9000) + "needle" + strings.Repeat("b", 9000) + "\n",
+		"huge.txt": strings.Repeat("x", 100*1024) + "needle\n",
+		"crlf.txt": "alpha one\r\nbeta two\r\n",
+		"fold.txt": "ΟΔΟΣ\nſtop here\n",
+	})
+
+	tool := tools.NewGrepSearchTool()
+
+	tests := []struct {
+		name        string
+		params      map[string]interface{}
+		wantMatches int
+		wantOutput  []string
+		wantMissing []string
+	}{
+		{
+			name:        "context lines are grouped with gaps",
+			params:      map[string]interface{}{"pattern": "func ", "file_pattern": "*.go", "context_before": 1.0, "context_after": 1.0},
+			wantMatches: 2,
+			wantOutput: []string{
+				"main.go\n  2- \n  3: func main() {\n  4- \treturn\n  --\n  7- // Helper does things\n  8: func helper() {}\n  9- \n",
+			},
+		},
+		{
+			name:        "case insensitive",
+			params:      map[string]interface{}{"pattern": "HELPER", "case_insensitive": true},
+			wantMatches: 2,
+			wantOutput:  []string{"  7: // Helper does things", "  8: func helper() {}"},
+		},
+		{
+			name:        "anchored regex matches at line starts",
+			params:      map[string]interface{}{"pattern": "^func helper", "is_regex": true},
+			wantMatches: 1,
+			wantOutput:  []string{"  8: func helper() {}"},
+		},
+		{
+			name:        "line end anchor in CRLF files",
+			params:      map[string]interface{}{"pattern": "one$", "is_regex": true, "file_pattern": "crlf.txt"},
+			wantMatches: 1,
+			wantOutput:  []string{"  1: alpha one\n"},
+		},
+		{
+			name:        "case insensitive literal",
+			params:      map[string]interface{}{"pattern": "BETA Two", "case_insensitive": true},
+			wantMatches: 1,
+			wantOutput:  []string{"  2: beta two\n"},
+		},
+		{
+			name:        "case insensitive literal folds like the regexp",
+			params:      map[string]interface{}{"pattern": "οδος", "case_insensitive": true},
+			wantMatches: 1,
+			wantOutput:  []string{"  1: ΟΔΟΣ\n"},
+		},
+		{
+			name:        "case insensitive literal matches the long s",
+			params:      map[string]interface{}{"pattern": "STOP", "case_insensitive": true},
+			wantMatches: 1,
+			wantOutput:  []string{"  2: ſtop here\n"},
+		},
+		{
+			name:        "multiline regex spans lines",
+			params:      map[string]interface{}{"pattern": `main\(\) \{\n\s+return`, "is_regex": true, "multiline": true},
+			wantMatches: 2,
+			wantOutput:  []string{"  3: func main() {\n  4: \treturn\n"},
+		},
+		{
+			name:        "files_with_matches lists files",
+			params:      map[string]interface{}{"pattern": "TODO|func", "is_regex": true, "output_mode": "files_with_matches"},
+			wantMatches: 2,
+			wantOutput:  []string{"Found 2 file(s)", "  main.go\n", "  todo.txt\n"},
+			wantMissing: []string{"TODO one"},
+		},
+		{
+			name:        "count reports matching lines per file",
+			params:      map[string]interface{}{"pattern": "TODO", "output_mode": "count"},
+			wantMatches: 4,
+			wantOutput:  []string{"  todo.txt: 4"},
+		},
+		{
+			name:        "per-file cap",
+			params:      map[string]interface{}{"pattern": "TODO", "max_per_file": 2.0},
+			wantMatches: 2,
+			wantOutput:  []string{"  2: TODO two", "showing the first 2"},
+			wantMissing: []string{"TODO three"},
+		},
+		{
+			name:        "long lines are clipped around the match",
+			params:      map[string]interface{}{"pattern": "needle", "file_pattern": "min.js"},
+			wantMatches: 1,
+			wantOutput:  []string{"needle", "bytes] ...", "more bytes]"},
+		},
+		{
+			name:        "lines longer than 64KB are searched",
+			params:      map[string]interface{}{"pattern": "needle", "file_pattern": "huge.txt"},
+			wantMatches: 1,
+			wantOutput:  []string{"  1: [", "needle"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runTool(tool, tt.params, workingDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if matches := result.Metadata["total_matches"]; matches != tt.wantMatches {
+				t.Errorf("expected %d matches, got %v. Output: %s", tt.wantMatches, matches, result.Output)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(result.Output, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, result.Output)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(result.Output, missing) {
+					t.Errorf("expected output not to contain %q, got:\n%s", missing, result.Output)
+				}
+			}
+			if len(result.Output) > 2000 {
+				t.Errorf("expected clipped output, got %d characters", len(result.Output))
+			}
+		})
+	}
+
+	invalid := []map[string]interface{}{
+		{"pattern": "x", "output_mode": "lines"},
+		{"pattern": "x", "context_before": 21.0},
+		{"pattern": "x", "context_after": -1.0},
+		{"pattern": "x", "max_per_file": 0.0},
+		{"pattern": "x", "case_insensitive": "yes"},
+	}
+	for _, params := range invalid {
+		if err := tool.Validate(params, workingDir); err == nil {
+			t.Errorf("expected validation error for %v", params)
+		}
+	}
+}
+
+// TestGrepSearchConcurrency tests that results keep walk order and limits regardless
+// of how many files are scanned in parallel
+func TestGrepSearchConcurrency(t *testing.T) {
+	workingDir := t.TempDir()
+	files := map[string]string{}
+	for i := 0; i < 200; i++ {
+		files[fmt.Sprintf("pkg%d/file%03d.go", i%7, i)] = fmt.Sprintf("package p\n\n// TODO: item %d\nfunc f%d() {}\n", i, i)
+	}
+	setupTree(t, workingDir, files)
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+	}{
+		{name: "all matches", params: map[string]interface{}{"pattern": "TODO", "max_results": 1000.0}},
+		{name: "limited matches", params: map[string]interface{}{"pattern": "TODO", "max_results": 15.0}},
+		{name: "regex with context", params: map[string]interface{}{"pattern": `item \d+5$`, "is_regex": true, "context_after": 1.0}},
+		{name: "limited files", params: map[string]interface{}{"pattern": "func", "output_mode": "files_with_matches", "max_results": 9.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sequential := tools.NewGrepSearchTool()
+			sequential.SetConcurrency(1)
+			want, err := runTool(sequential, tt.params, workingDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, workers := range []int{2, 8, 32} {
+				parallel := tools.NewGrepSearchTool()
+				parallel.SetConcurrency(workers)
+				got, err := runTool(parallel, tt.params, workingDir)
+				if err != nil {
+					t.Fatalf("unexpected error with %d workers: %v", workers, err)
+				}
+				if got.Output != want.Output {
+					t.Errorf("output with %d workers differs from sequential:\n%s\nwant:\n%s", workers, got.Output, want.Output)
+				}
+				if got.Metadata["total_matches"] != want.Metadata["total_matches"] {
+					t.Errorf("expected %v matches with %d workers, got %v", want.Metadata["total_matches"], workers, got.Metadata["total_matches"])
+				}
+			}
+		})
+	}
+
+	// Results are in walk order: the first match is in the first file walked
+	result, err := runTool(tools.NewGrepSearchTool(), map[string]interface{}{"pattern": "TODO", "max_results": 1.0}, workingDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(filepath.ToSlash(result.Output), "pkg0/file000.go") {
+		t.Errorf("expected the first match in pkg0/file000.go, got:\n%s", result.Output)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tools.NewGrepSearchTool().Execute(ctx, map[string]interface{}{"pattern": "TODO"}, workingDir); err == nil {
+		t.Error("expected an error for a cancelled search")
+	}
+}
+
 // TestSearchToolsRiskLevel verifies risk levels
 func TestSearchToolsRiskLevel(t *testing.T) {
 	tests := []struct {
@@ -347,6 +556,11 @@ func TestSearchToolsRiskLevel(t *testing.T) {
 			tool:     tools.NewGrepSearchTool(),
 			expected: types.RiskLevelReadOnly,
 		},
+		{
+			name:     "semantic_search is read_only",
+			tool:     tools.NewSemanticSearchTool(llm.NewStubEmbedder(8)),
+			expected: types.RiskLevelReadOnly,
+		},
 	}
 
 	for _, tt := range tests {
@@ -376,6 +590,11 @@ func TestSearchToolsRequireApproval(t *testing.T) {
 			tool:     tools.NewGrepSearchTool(),
 			expected: true,
 		},
+		{
+			name:     "semantic_search requires approval",
+			tool:     tools.NewSemanticSearchTool(llm.NewStubEmbedder(8)),
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
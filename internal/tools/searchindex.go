@@ -0,0 +1,128 @@
+// Package tools implements the trigram index lookups that narrow grep_search
+package tools
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/shizhMSFT/wink-code/internal/index"
+	"github.com/shizhMSFT/wink-code/internal/logging"
+)
+
+// indexedFile is a file of a workspace walk, in walk order
+type indexedFile struct {
+	path string
+	rel  string
+}
+
+// listIndexFiles walks the workspace as grep_search does without include_ignored and
+// returns its files in walk order, with the size and modification time the index
+// compares against
+func listIndexFiles(ctx context.Context, workingDir string) ([]indexedFile, []index.File, error) {
+	var walked []indexedFile
+	var files []index.File
+	err := walkWorkspace(ctx, workingDir, workingDir, false, func(path string, d fs.DirEntry) error {
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(workingDir, path)
+		if err != nil {
+			return nil
+		}
+		walked = append(walked, indexedFile{path: path, rel: rel})
+
+		// Symlinks and other special files stay out of the index and are always searched
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, index.File{Path: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return walked, files, err
+}
+
+// BuildSearchIndex creates or updates the trigram index of the workspace at
+// workingDir, reading only the files that changed since the last update
+func BuildSearchIndex(ctx context.Context, workingDir string) (index.SyncStats, error) {
+	ix, err := index.Open(workingDir)
+	if err != nil {
+		return index.SyncStats{}, err
+	}
+	_, files, err := listIndexFiles(ctx, workingDir)
+	if err != nil {
+		return index.SyncStats{}, err
+	}
+	stats, err := ix.Sync(ctx, files)
+	if err != nil {
+		return stats, err
+	}
+	return stats, ix.Save()
+}
+
+// SearchIndexStatus describes the trigram index of the workspace at workingDir and how
+// many files changed since it was updated
+func SearchIndexStatus(ctx context.Context, workingDir string) (index.Status, error) {
+	if !index.Exists(workingDir) {
+		return index.Status{}, index.ErrNoIndex
+	}
+	ix, err := index.Open(workingDir)
+	if err != nil {
+		return index.Status{}, err
+	}
+	_, files, err := listIndexFiles(ctx, workingDir)
+	if err != nil {
+		return index.Status{}, err
+	}
+	status := ix.Status()
+	status.Changed, status.Removed = ix.Stale(files)
+	return status, nil
+}
+
+// indexedGrepJobs syncs the workspace index and lists the files grep_search would
+// walk, marking those the index rules out. The index is kept between calls.
+func (t *GrepSearchTool) indexedGrepJobs(ctx context.Context, workingDir, filePattern string, query *index.Query) ([]grepJob, error) {
+	t.indexMu.Lock()
+	defer t.indexMu.Unlock()
+
+	// Reopen when the index was cleared or rebuilt by 'wink index'
+	if t.index == nil || t.indexRoot != workingDir || !index.Exists(workingDir) {
+		ix, err := index.Open(workingDir)
+		if err != nil {
+			return nil, err
+		}
+		t.index, t.indexRoot = ix, workingDir
+	}
+
+	walked, files, err := listIndexFiles(ctx, workingDir)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := t.index.Sync(ctx, files)
+	if err != nil {
+		// A partial sync leaves changed files out of the index, where they are always searched
+		return nil, err
+	}
+	if err := t.index.Save(); err != nil {
+		logging.Debug("grep_search: failed to save search index: %v", err)
+	}
+	logging.Debug("grep_search: index synced %d files (%d read, %d removed) in %s",
+		stats.Files, stats.Added, stats.Removed, stats.Elapsed)
+
+	candidates := t.index.Candidates(query)
+	jobs := make([]grepJob, 0, len(walked))
+	for _, f := range walked {
+		if filePattern != "" {
+			if matched, err := matchPathGlob(filePattern, f.rel); err != nil || !matched {
+				continue
+			}
+		}
+		search, skipped := candidates.Check(filepath.ToSlash(f.rel))
+		jobs = append(jobs, grepJob{seq: len(jobs), path: f.path, rel: f.rel, ruledOut: !search, skipped: skipped})
+	}
+	return jobs, nil
+}
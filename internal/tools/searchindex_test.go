@@ -0,0 +1,137 @@
+package tools_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/index"
+	"github.com/shizhMSFT/wink-code/internal/tools"
+)
+
+// TestGrepSearchIndex tests that grep_search returns the same results with the
+// trigram index as without it, and keeps the index up to date between searches
+func TestGrepSearchIndex(t *testing.T) {
+	workingDir := t.TempDir()
+	files := map[string]string{
+		".gitignore":   "build/\n",
+		"build/out.go": "// TODO: generated\n",
+		"logo.png":     "\x89PNG\x00\x00TODO",
+		"README.md":    "Straße TODO list\n",
+	}
+	for i := 0; i < 60; i++ {
+		files[fmt.Sprintf("pkg%d/file%02d.go", i%4, i)] = fmt.Sprintf("package p\n\n// TODO: item %d\nfunc Handle%d() error { return nil }\n", i, i)
+	}
+	setupTree(t, workingDir, files)
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+	}{
+		{name: "literal", params: map[string]interface{}{"pattern": "Handle17"}},
+		{name: "literal in every file", params: map[string]interface{}{"pattern": "TODO", "max_results": 1000.0}},
+		{name: "case insensitive", params: map[string]interface{}{"pattern": "STRASSE", "case_insensitive": true}},
+		{name: "regex alternation", params: map[string]interface{}{"pattern": `Handle(3|42)\(`, "is_regex": true}},
+		{name: "regex without literals", params: map[string]interface{}{"pattern": `item \d+5$`, "is_regex": true, "context_before": 1.0}},
+		{name: "file pattern", params: map[string]interface{}{"pattern": "TODO", "file_pattern": "pkg1/*.go", "output_mode": "count"}},
+		{name: "no matches", params: map[string]interface{}{"pattern": "Websocket"}},
+	}
+
+	// Searches without an index come first, as an existing index is always used
+	want := make([]string, len(tests))
+	wantMatches := make([]interface{}, len(tests))
+	for i, tt := range tests {
+		result, err := runTool(tools.NewGrepSearchTool(), tt.params, workingDir)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if result.Metadata["indexed"] != false {
+			t.Fatalf("%s: expected a search without an index", tt.name)
+		}
+		want[i], wantMatches[i] = result.Output, result.Metadata["total_matches"]
+	}
+
+	indexed := tools.NewGrepSearchTool()
+	indexed.SetAutoIndex(true)
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runTool(indexed, tt.params, workingDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Metadata["indexed"] != true {
+				t.Error("expected the search to use the index")
+			}
+			if got.Output != want[i] {
+				t.Errorf("output with index differs:\n%s\nwant:\n%s", got.Output, want[i])
+			}
+			if got.Metadata["total_matches"] != wantMatches[i] {
+				t.Errorf("expected %v matches, got %v", wantMatches[i], got.Metadata["total_matches"])
+			}
+		})
+	}
+	if !index.Exists(workingDir) {
+		t.Fatal("expected the index to be saved in the workspace")
+	}
+
+	// Changed, new and removed files are picked up by the next search
+	setupTree(t, workingDir, map[string]string{
+		"pkg0/file00.go": "package p\n\nfunc Websocket() {}\n",
+		"pkg9/new.go":    "package p\n\n// Websocket support\n",
+	})
+	if err := os.Remove(filepath.Join(workingDir, "pkg1", "file17.go")); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	for _, tool := range []*tools.GrepSearchTool{indexed, tools.NewGrepSearchTool()} {
+		result, err := runTool(tool, map[string]interface{}{"pattern": "Websocket"}, workingDir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Metadata["total_matches"] != 2 {
+			t.Errorf("expected 2 matches after the update, got %v:\n%s", result.Metadata["total_matches"], result.Output)
+		}
+		result, err = runTool(tool, map[string]interface{}{"pattern": "Handle17"}, workingDir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Metadata["total_matches"] != 0 {
+			t.Errorf("expected no matches in a removed file, got:\n%s", result.Output)
+		}
+	}
+
+	// Ignored files are searched without the index, and the index itself never is
+	result, err := runTool(indexed, map[string]interface{}{"pattern": "generated", "include_ignored": true}, workingDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Metadata["indexed"] != false || !strings.Contains(filepath.ToSlash(result.Output), "build/out.go") {
+		t.Errorf("expected an unindexed match in build/out.go, got:\n%s", result.Output)
+	}
+	result, err = runTool(indexed, map[string]interface{}{"pattern": "trigram", "include_ignored": true, "output_mode": "files_with_matches"}, workingDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(filepath.ToSlash(result.Output), ".wink/index") {
+		t.Errorf("expected the index directory not to be searched, got:\n%s", result.Output)
+	}
+
+	// A cleared index is rebuilt by the next search
+	if err := index.Clear(workingDir); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if _, err := runTool(indexed, map[string]interface{}{"pattern": "TODO"}, workingDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !index.Exists(workingDir) {
+		t.Error("expected the index to be rebuilt after Clear")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := indexed.Execute(ctx, map[string]interface{}{"pattern": "TODO"}, workingDir); err == nil {
+		t.Error("expected an error for a cancelled indexed search")
+	}
+}
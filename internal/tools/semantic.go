@@ -0,0 +1,371 @@
+// Package tools implements the semantic_search tool
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/index"
+	"github.com/shizhMSFT/wink-code/internal/logging"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+const (
+	// defaultSemanticResults is how many chunks semantic_search returns by default
+	defaultSemanticResults = 10
+	// maxSemanticResults bounds max_results
+	maxSemanticResults = 50
+	// maxChunkLines splits long declarations and paragraphs into several chunks
+	maxChunkLines = 60
+	// maxChunkChars keeps chunks within the context of small embedding models
+	maxChunkChars = 3000
+	// minChunkChars is the size up to which short paragraphs are merged with the next
+	minChunkChars = 400
+	// maxSnippetLines is how much of each chunk semantic_search shows
+	maxSnippetLines = 15
+)
+
+// semanticTextExtensions are files without outline support that are still worth
+// embedding: documentation, configuration and schemas
+var semanticTextExtensions = map[string]bool{
+	".md": true, ".markdown": true, ".txt": true, ".rst": true, ".adoc": true,
+	".yaml": true, ".yml": true, ".toml": true, ".proto": true, ".sql": true, ".graphql": true,
+	".kt": true, ".swift": true, ".php": true, ".lua": true, ".scala": true,
+	".html": true, ".css": true, ".scss": true, ".vue": true, ".svelte": true, ".tf": true,
+}
+
+// semanticTextFiles are extensionless files worth embedding
+var semanticTextFiles = map[string]bool{
+	"Makefile": true, "Dockerfile": true, "README": true,
+}
+
+// SemanticSearchTool implements the semantic_search tool
+type SemanticSearchTool struct {
+	embedder index.Embedder
+
+	mu      sync.Mutex
+	vectors *index.VectorIndex
+	root    string
+}
+
+// NewSemanticSearchTool creates a semantic_search tool that embeds with embedder
+func NewSemanticSearchTool(embedder index.Embedder) *SemanticSearchTool {
+	return &SemanticSearchTool{embedder: embedder}
+}
+
+// Name returns the tool name
+func (t *SemanticSearchTool) Name() string {
+	return "semantic_search"
+}
+
+// Description returns the tool description
+func (t *SemanticSearchTool) Description() string {
+	return "Find code and documentation by meaning rather than exact text, e.g. \"where do we validate paths?\". " +
+		"Workspace files are split into functions and paragraphs, embedded with a local model and ranked by similarity to the query; " +
+		"only files changed since the last search are embedded again. Use grep_search when you know the exact text."
+}
+
+// ParametersSchema returns the JSON schema for parameters
+func (t *SemanticSearchTool) ParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Natural language description of the code or text to find",
+			},
+			"file_pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Glob pattern to limit results; patterns without a slash match file names at any depth (default: all files)",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of chunks to return, up to %d (default: %d)", maxSemanticResults, defaultSemanticResults),
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+// Validate checks if parameters are valid
+func (t *SemanticSearchTool) Validate(params map[string]interface{}, workingDir string) error {
+	query, ok := params["query"].(string)
+	if !ok || strings.TrimSpace(query) == "" {
+		return fmt.Errorf("query parameter is required and must be a non-empty string")
+	}
+
+	if fp, ok := params["file_pattern"]; ok && fp != nil {
+		pattern, isString := fp.(string)
+		if !isString {
+			return fmt.Errorf("file_pattern must be a string")
+		}
+		if _, err := matchPathGlob(pattern, "x"); err != nil {
+			return fmt.Errorf("invalid file_pattern: %w", err)
+		}
+	}
+
+	if maxResults, ok := params["max_results"].(float64); ok && (maxResults < 1 || maxResults > maxSemanticResults) {
+		return fmt.Errorf("max_results must be between 1 and %d", maxSemanticResults)
+	}
+
+	return nil
+}
+
+// Execute refreshes the workspace embeddings and returns the chunks closest to the query
+func (t *SemanticSearchTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
+
+	query := params["query"].(string)
+	filePattern, _ := params["file_pattern"].(string)
+	maxResults := defaultSemanticResults
+	if mr, ok := params["max_results"].(float64); ok {
+		maxResults = int(mr)
+	}
+
+	logging.Debug("semantic_search: query=%q file_pattern=%s max=%d model=%s", query, filePattern, maxResults, t.embedder.Model())
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats, err := t.syncVectors(ctx, workingDir)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &types.ToolResult{
+			Success: false,
+			Output:  fmt.Sprintf("Failed to embed workspace files: %v", err),
+		}, err
+	}
+
+	vectors, err := t.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return &types.ToolResult{
+			Success: false,
+			Output:  fmt.Sprintf("Failed to embed query: %v", err),
+		}, err
+	}
+
+	var filter func(path string) bool
+	if filePattern != "" {
+		filter = func(path string) bool {
+			matched, err := matchPathGlob(filePattern, path)
+			return err == nil && matched
+		}
+	}
+	hits := t.vectors.Search(vectors[0], maxResults, filter)
+
+	var output strings.Builder
+	if len(hits) == 0 {
+		output.WriteString(fmt.Sprintf("No results for \"%s\"", query))
+	} else {
+		output.WriteString(fmt.Sprintf("Found %d results for \"%s\":\n", len(hits), query))
+		for _, hit := range hits {
+			output.WriteString("\n")
+			writeSemanticHit(&output, hit)
+		}
+	}
+	if stats.Added > 0 {
+		output.WriteString(fmt.Sprintf("\n\n(Embedded %d new or changed files in %s)", stats.Added, stats.Elapsed.Round(time.Millisecond)))
+	}
+
+	executionTime := time.Since(startTime).Milliseconds()
+	logging.Debug("semantic_search: %d results from %d chunks, %d files embedded (%dms)", len(hits), t.vectors.Chunks(), stats.Added, executionTime)
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          output.String(),
+		ExecutionTimeMs: executionTime,
+		Metadata: map[string]interface{}{
+			"results":        len(hits),
+			"chunks":         t.vectors.Chunks(),
+			"files_indexed":  stats.Files,
+			"files_embedded": stats.Added,
+			"model":          t.embedder.Model(),
+		},
+	}, nil
+}
+
+// syncVectors embeds the workspace files that changed since the last search. The
+// index is kept between calls and saved even after a failed sync, so the next
+// search continues where it stopped.
+func (t *SemanticSearchTool) syncVectors(ctx context.Context, workingDir string) (index.SyncStats, error) {
+	// Reopen when the index was cleared by 'wink index clear'
+	if t.vectors == nil || t.root != workingDir || !index.VectorsExist(workingDir) {
+		vx, err := index.OpenVectors(workingDir)
+		if err != nil {
+			return index.SyncStats{}, err
+		}
+		t.vectors, t.root = vx, workingDir
+	}
+
+	_, walked, err := listIndexFiles(ctx, workingDir)
+	if err != nil {
+		return index.SyncStats{}, err
+	}
+	files := walked[:0]
+	for _, f := range walked {
+		if semanticIndexable(f.Path) {
+			files = append(files, f)
+		}
+	}
+
+	stats, err := t.vectors.Sync(ctx, files, semanticChunks, t.embedder)
+	if saveErr := t.vectors.Save(); saveErr != nil {
+		logging.Debug("semantic_search: failed to save vector index: %v", saveErr)
+	}
+	return stats, err
+}
+
+// writeSemanticHit formats a chunk as its location, symbol and score followed by its
+// first lines, numbered like grep_search output
+func writeSemanticHit(b *strings.Builder, hit index.Hit) {
+	c := hit.Chunk
+	b.WriteString(fmt.Sprintf("%s:%d-%d", filepath.FromSlash(hit.Path), c.StartLine, c.EndLine))
+	if c.Symbol != "" {
+		b.WriteString(" " + c.Symbol)
+	}
+	b.WriteString(fmt.Sprintf(" (score %.2f)\n", hit.Score))
+
+	lines := strings.Split(c.Text, "\n")
+	for i, line := range lines {
+		if i == maxSnippetLines {
+			b.WriteString(fmt.Sprintf("  ... (%d more lines)\n", len(lines)-i))
+			break
+		}
+		b.WriteString(fmt.Sprintf("%d: %s\n", c.StartLine+i, clipGrepLine(line, nil)))
+	}
+}
+
+// semanticIndexable reports whether a file is source, documentation or configuration
+// worth embedding, leaving out data, lock and generated files
+func semanticIndexable(path string) bool {
+	name := filepath.Base(path)
+	if strings.HasSuffix(name, ".min.js") || strings.HasSuffix(name, ".min.css") {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	return outlineSupported(name) || semanticTextExtensions[ext] || semanticTextFiles[name]
+}
+
+// semanticChunks splits a file into declarations, using the code_outline parsers, and
+// paragraphs for the text between them. Comments directly above a declaration stay
+// with it, and long chunks are split.
+func semanticChunks(path string, src []byte) []index.Chunk {
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+
+	var decls []codeSymbol
+	if outlineSupported(path) {
+		if outline, err := outlineSource(path, src); err == nil {
+			decls = leafSymbols(outline.Symbols)
+		}
+	}
+
+	var chunks []index.Chunk
+	next := 1 // first line not yet in a chunk
+	for _, s := range decls {
+		if s.StartLine < next || s.StartLine > len(lines) {
+			continue
+		}
+		start := s.StartLine
+		for start > next && strings.TrimSpace(lines[start-2]) != "" {
+			start--
+		}
+		end := min(max(s.EndLine, s.StartLine), len(lines))
+		chunks = appendParagraphs(chunks, lines, next, start-1)
+		chunks = appendSplit(chunks, lines, start, end, s.qualifiedName())
+		next = end + 1
+	}
+	return appendParagraphs(chunks, lines, next, len(lines))
+}
+
+// leafSymbols returns the declarations that contain no other declaration, such as the
+// methods of a class rather than the class, in line order
+func leafSymbols(symbols []codeSymbol) []codeSymbol {
+	sorted := append([]codeSymbol(nil), symbols...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StartLine < sorted[j].StartLine })
+
+	var leaves []codeSymbol
+	for i, s := range sorted {
+		end := max(s.EndLine, s.StartLine)
+		if i+1 < len(sorted) && sorted[i+1].StartLine > s.StartLine && sorted[i+1].StartLine <= end {
+			continue
+		}
+		leaves = append(leaves, s)
+	}
+	return leaves
+}
+
+// appendParagraphs chunks lines from..to (1-based, inclusive) at blank lines, merging
+// short paragraphs such as headings with the text that follows
+func appendParagraphs(chunks []index.Chunk, lines []string, from, to int) []index.Chunk {
+	start, chars := 0, 0
+	flush := func(end int) {
+		if start > 0 {
+			chunks = appendSplit(chunks, lines, start, end, "")
+		}
+		start, chars = 0, 0
+	}
+	for n := from; n <= to; n++ {
+		line := lines[n-1]
+		if strings.TrimSpace(line) == "" {
+			if chars >= minChunkChars {
+				flush(n - 1)
+			}
+			continue
+		}
+		if start == 0 {
+			start = n
+		}
+		chars += len(line)
+	}
+	if start > 0 {
+		end := to
+		for end > start && strings.TrimSpace(lines[end-1]) == "" {
+			end--
+		}
+		flush(end)
+	}
+	return chunks
+}
+
+// appendSplit adds lines start..end as one chunk, or several if it is too long
+func appendSplit(chunks []index.Chunk, lines []string, start, end int, symbol string) []index.Chunk {
+	for start <= end {
+		var text strings.Builder
+		n := start
+		for ; n <= end && n-start < maxChunkLines; n++ {
+			line := lines[n-1]
+			if n > start && text.Len()+len(line) > maxChunkChars {
+				break
+			}
+			if len(line) > maxChunkChars {
+				line = clipGrepLine(line, nil)
+			}
+			if n > start {
+				text.WriteByte('\n')
+			}
+			text.WriteString(line)
+		}
+		if strings.TrimSpace(text.String()) != "" {
+			chunks = append(chunks, index.Chunk{StartLine: start, EndLine: n - 1, Symbol: symbol, Text: text.String()})
+		}
+		start = n
+	}
+	return chunks
+}
+
+// RequiresApproval returns whether this tool requires approval
+func (t *SemanticSearchTool) RequiresApproval() bool {
+	return true
+}
+
+// RiskLevel returns the risk level
+func (t *SemanticSearchTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelReadOnly
+}
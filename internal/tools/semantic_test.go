@@ -0,0 +1,168 @@
+package tools_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/llm"
+	"github.com/shizhMSFT/wink-code/internal/tools"
+)
+
+// TestSemanticSearchTool tests chunking, ranking and incremental embedding with the stub embedder
+func TestSemanticSearchTool(t *testing.T) {
+	workingDir := t.TempDir()
+	var big strings.Builder
+	big.WriteString("package big\n\nfunc Generated() {\n")
+	for i := 0; i < 130; i++ {
+		big.WriteString("\tstep()\n")
+	}
+	big.WriteString("}\n")
+	setupTree(t, workingDir, map[string]string{
+		".gitignore": "vendor/\n",
+		"security.go": `package security
+
+import "strings"
+
+// ValidatePath checks that a path stays inside the workspace
+// and rejects paths that escape it
+func ValidatePath(root, path string) error {
+	if strings.HasPrefix(path, "..") {
+		return errEscape
+	}
+	return nil
+}
+
+func openDatabase(dsn string) error {
+	return nil
+}
+`,
+		"render.py": `class Renderer:
+    """Turns documents into output formats."""
+
+    def render_markdown(self, doc):
+        return doc
+
+    def render_html(self, doc):
+        return doc
+`,
+		"docs/guide.md": "# Guide\n\nInstall the binary and run it.\n\n## Databases\n\nConnect a database with a DSN string.\n",
+		"vendor/lib.go": "package lib\n\n// ValidatePath validates paths\nfunc ValidatePath() {}\n",
+		"big.go":        big.String(),
+		"logo.png":      "\x89PNG\x00\x00validate paths",
+	})
+	backdateTree(t, workingDir)
+
+	tool := tools.NewSemanticSearchTool(llm.NewStubEmbedder(256))
+
+	tests := []struct {
+		name     string
+		params   map[string]interface{}
+		want     []string
+		notWant  []string
+		results  int
+		embedded int
+	}{
+		{
+			name:     "declaration with its doc comment",
+			params:   map[string]interface{}{"query": "where do we validate paths", "max_results": 1.0},
+			want:     []string{"security.go:5-12 ValidatePath", "5: // ValidatePath checks that a path stays inside the workspace", "12: }"},
+			notWant:  []string{"vendor", "openDatabase"},
+			results:  1,
+			embedded: 4,
+		},
+		{
+			name:    "method of a class",
+			params:  map[string]interface{}{"query": "render markdown", "max_results": 1.0},
+			want:    []string{"render.py:4-5 Renderer.render_markdown"},
+			results: 1,
+		},
+		{
+			name:    "paragraphs of a document",
+			params:  map[string]interface{}{"query": "connect a database", "file_pattern": "*.md"},
+			want:    []string{filepath.FromSlash("docs/guide.md") + ":1-7", "7: Connect a database with a DSN string."},
+			notWant: []string{"openDatabase"},
+			results: 1,
+		},
+		{
+			name:    "long declarations are split",
+			params:  map[string]interface{}{"query": "generated step", "file_pattern": "big.go", "max_results": 50.0},
+			want:    []string{"big.go:3-62 Generated", "big.go:63-122 Generated", "big.go:123-134 Generated", "... (45 more lines)"},
+			results: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := runTool(tool, tt.params, workingDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result.Output, want) {
+					t.Errorf("expected output to contain %q, got:\n%s", want, result.Output)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(result.Output, notWant) {
+					t.Errorf("expected output not to contain %q, got:\n%s", notWant, result.Output)
+				}
+			}
+			if result.Metadata["results"] != tt.results {
+				t.Errorf("expected %d results, got %v", tt.results, result.Metadata["results"])
+			}
+			if result.Metadata["files_embedded"] != tt.embedded {
+				t.Errorf("expected %d files embedded, got %v", tt.embedded, result.Metadata["files_embedded"])
+			}
+		})
+	}
+
+	// Only the changed file is embedded again, by a new tool reading the saved index
+	setupTree(t, workingDir, map[string]string{"render.py": "def render_pdf(doc):\n    return doc\n"})
+	old := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(filepath.Join(workingDir, "render.py"), old, old); err != nil {
+		t.Fatalf("Chtimes failed: %v", err)
+	}
+	result, err := runTool(tools.NewSemanticSearchTool(llm.NewStubEmbedder(256)), map[string]interface{}{"query": "render markdown"}, workingDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Metadata["files_embedded"] != 1 {
+		t.Errorf("expected only render.py embedded again, got %v", result.Metadata["files_embedded"])
+	}
+	if strings.Contains(result.Output, "render_markdown") || !strings.Contains(result.Output, "render_pdf") {
+		t.Errorf("expected the updated render.py, got:\n%s", result.Output)
+	}
+
+	// Invalid parameters
+	for _, params := range []map[string]interface{}{
+		{},
+		{"query": "  "},
+		{"query": "paths", "max_results": 0.0},
+		{"query": "paths", "max_results": 51.0},
+		{"query": "paths", "file_pattern": "[unclosed"},
+		{"query": "paths", "file_pattern": 3.0},
+	} {
+		if err := tool.Validate(params, workingDir); err == nil {
+			t.Errorf("expected validation error for %v", params)
+		}
+	}
+}
+
+// backdateTree moves every file's modification time an hour back, so files written
+// by the test are not re-embedded as possibly changed within the timestamp granularity
+func backdateTree(t *testing.T, root string) {
+	t.Helper()
+	old := time.Now().Add(-time.Hour)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, old, old)
+	})
+	if err != nil {
+		t.Fatalf("backdate failed: %v", err)
+	}
+}
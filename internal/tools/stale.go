@@ -0,0 +1,189 @@
+// Package tools implements stale-read detection for file edits
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+const (
+	// maxTrackedContent is the largest file whose content is kept in memory so an
+	// external change can be shown as a diff
+	maxTrackedContent = 256 * 1024
+	// maxStaleDiffLines bounds the diff shown for an external change
+	maxStaleDiffLines = 40
+)
+
+// StaleReadError reports an edit to a file that changed on disk after the agent
+// last read it
+type StaleReadError struct {
+	Path string // Workspace-relative path
+	Diff string // What changed externally; empty when the earlier content is unknown
+}
+
+// Error implements the error interface
+func (e *StaleReadError) Error() string {
+	return fmt.Sprintf("file '%s' changed on disk since it was last read; re-read it with read_file before editing", e.Path)
+}
+
+// ReadTracker remembers the state of the files the agent has read, so edits based
+// on an outdated view can be refused. State lives in the session's FileReads map so
+// it survives --continue; contents are only kept in memory.
+type ReadTracker struct {
+	mu         sync.Mutex
+	workingDir string
+	files      map[string]types.FileRead
+	contents   map[string]string
+}
+
+// NewReadTracker creates a tracker backed by the session's file read records
+func NewReadTracker(session *types.Session) *ReadTracker {
+	if session.FileReads == nil {
+		session.FileReads = make(map[string]types.FileRead)
+	}
+	return &ReadTracker{
+		workingDir: session.WorkingDir,
+		files:      session.FileReads,
+		contents:   make(map[string]string),
+	}
+}
+
+// Record stores the current state of a file. Missing files and directories are
+// forgotten instead.
+func (t *ReadTracker) Record(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		t.forget(path)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to stat '%s': %w", path, err)
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", path, err)
+	}
+
+	t.files[path] = types.FileRead{Hash: hashContent(data), Size: info.Size(), ModTime: info.ModTime()}
+	if len(data) <= maxTrackedContent {
+		t.contents[path] = string(data)
+	} else {
+		delete(t.contents, path)
+	}
+	return nil
+}
+
+// Refresh re-records a path the agent just changed, along with any tracked files
+// beneath it when it is a directory
+func (t *ReadTracker) Refresh(path string) error {
+	for _, tracked := range t.trackedUnder(path) {
+		if err := t.Record(tracked); err != nil {
+			return err
+		}
+	}
+	return t.Record(path)
+}
+
+// Check returns a *StaleReadError when path, or a tracked file beneath it, changed
+// since it was recorded. Files that were never read are not checked, and deleted
+// files are left to the tools, which report them as missing.
+func (t *ReadTracker) Check(path string) error {
+	for _, tracked := range append(t.trackedUnder(path), path) {
+		if err := t.checkFile(tracked); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkFile compares one tracked file with the disk, trusting an unchanged size and
+// modification time and otherwise comparing content hashes
+func (t *ReadTracker) checkFile(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	read, ok := t.files[path]
+	if !ok {
+		return nil
+	}
+	display := filepath.ToSlash(SanitizePathForDisplay(t.workingDir, path))
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		t.forget(path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat '%s': %w", display, err)
+	}
+	if info.Size() == read.Size && info.ModTime().Equal(read.ModTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read '%s': %w", display, err)
+	}
+	if hashContent(data) == read.Hash {
+		// Touched but not changed
+		read.ModTime = info.ModTime()
+		t.files[path] = read
+		return nil
+	}
+
+	staleErr := &StaleReadError{Path: display}
+	if old, ok := t.contents[path]; ok {
+		staleErr.Diff = shortDiff(UnifiedDiff("a/"+display, "b/"+display, old, string(data)))
+	}
+	return staleErr
+}
+
+// trackedUnder returns the tracked files inside directory path, sorted
+func (t *ReadTracker) trackedUnder(path string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prefix := path + string(filepath.Separator)
+	var paths []string
+	for tracked := range t.files {
+		if strings.HasPrefix(tracked, prefix) {
+			paths = append(paths, tracked)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// forget drops a path; the caller holds the lock
+func (t *ReadTracker) forget(path string) {
+	delete(t.files, path)
+	delete(t.contents, path)
+}
+
+// hashContent returns the hex SHA-256 of data
+func hashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// shortDiff truncates a diff to maxStaleDiffLines lines
+func shortDiff(diff string) string {
+	lines := strings.SplitAfter(strings.TrimSuffix(diff, "\n"), "\n")
+	if len(lines) <= maxStaleDiffLines {
+		return diff
+	}
+	return strings.Join(lines[:maxStaleDiffLines], "") +
+		fmt.Sprintf("... (%d more diff lines)\n", len(lines)-maxStaleDiffLines)
+}
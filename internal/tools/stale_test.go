@@ -0,0 +1,100 @@
+package tools_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// TestReadTracker tests stale-read detection
+func TestReadTracker(t *testing.T) {
+	workingDir := t.TempDir()
+	setupTree(t, workingDir, map[string]string{
+		"main.go":     "package main\n\nfunc main() {}\n",
+		"pkg/util.go": "package pkg\n",
+	})
+	mainPath := filepath.Join(workingDir, "main.go")
+	utilPath := filepath.Join(workingDir, "pkg", "util.go")
+
+	session := &types.Session{WorkingDir: workingDir}
+	tracker := tools.NewReadTracker(session)
+	for _, path := range []string{mainPath, utilPath} {
+		if err := tracker.Record(path); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+	if len(session.FileReads) != 2 {
+		t.Fatalf("expected reads kept in the session, got %v", session.FileReads)
+	}
+
+	if err := tracker.Check(mainPath); err != nil {
+		t.Fatalf("expected unchanged file to pass, got %v", err)
+	}
+
+	// Touching without changing content is not a conflict
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(mainPath, later, later); err != nil {
+		t.Fatalf("chtimes failed: %v", err)
+	}
+	if err := tracker.Check(mainPath); err != nil {
+		t.Errorf("expected touched file to pass, got %v", err)
+	}
+
+	// An external edit is reported with a diff
+	if err := os.WriteFile(mainPath, []byte("package main\n\nfunc main() { run() }\n"), 0644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	err := tracker.Check(mainPath)
+	var staleErr *tools.StaleReadError
+	if !errors.As(err, &staleErr) {
+		t.Fatalf("expected a stale read error, got %v", err)
+	}
+	if staleErr.Path != "main.go" || !strings.Contains(staleErr.Diff, "+func main() { run() }") {
+		t.Errorf("unexpected stale read error: %+v", staleErr)
+	}
+
+	// Checking a directory covers the tracked files inside it
+	if err := os.WriteFile(utilPath, []byte("package util\n"), 0644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := tracker.Check(filepath.Join(workingDir, "pkg")); !errors.As(err, &staleErr) || staleErr.Path != "pkg/util.go" {
+		t.Errorf("expected pkg/util.go reported for the directory, got %v", err)
+	}
+
+	// Re-reading accepts the new content
+	if err := tracker.Refresh(mainPath); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if err := tracker.Check(mainPath); err != nil {
+		t.Errorf("expected re-read file to pass, got %v", err)
+	}
+
+	// Untracked and deleted files are left to the tools
+	if err := tracker.Check(filepath.Join(workingDir, "other.go")); err != nil {
+		t.Errorf("expected untracked file to pass, got %v", err)
+	}
+	if err := os.Remove(utilPath); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if err := tracker.Check(utilPath); err != nil {
+		t.Errorf("expected deleted file to pass, got %v", err)
+	}
+	if _, ok := session.FileReads[utilPath]; ok {
+		t.Error("expected deleted file to be forgotten")
+	}
+
+	// A tracker restored from the session still detects changes, without a diff
+	restored := tools.NewReadTracker(session)
+	if err := os.WriteFile(mainPath, []byte("changed\n"), 0644); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if err := restored.Check(mainPath); !errors.As(err, &staleErr) || staleErr.Diff != "" {
+		t.Errorf("expected a stale read error without diff, got %v", err)
+	}
+}
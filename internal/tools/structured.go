@@ -0,0 +1,609 @@
+// Package tools implements the structured_edit tool
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/logging"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+	"go.yaml.in/yaml/v3"
+)
+
+// Structured edit operations
+const (
+	StructuredSet    = "set"
+	StructuredDelete = "delete"
+	StructuredAppend = "append"
+	StructuredMerge  = "merge"
+)
+
+// StructuredEditTool implements the structured_edit tool
+type StructuredEditTool struct {
+	postEditStage
+}
+
+// NewStructuredEditTool creates a new structured_edit tool instance
+func NewStructuredEditTool() *StructuredEditTool {
+	return &StructuredEditTool{}
+}
+
+// Name returns the tool name
+func (t *StructuredEditTool) Name() string {
+	return "structured_edit"
+}
+
+// Description returns the tool description
+func (t *StructuredEditTool) Description() string {
+	return "Edit a JSON, YAML or TOML file by key path instead of by text, e.g. path 'scripts.test' or 'services[0].ports'. " +
+		"Operations: set (create or replace a value), delete, append (add to an array) and merge (deep-merge an object). " +
+		"Key order and formatting are kept as far as possible, and the result is validated before it is written. " +
+		"TOML comments stay with the keys and tables they belong to, but comments inside multi-line arrays move above the array, " +
+		"and blank lines between keys and value notation (such as literal strings) are normalized; use replace_string_in_file to keep them exactly."
+}
+
+// ParametersSchema returns the JSON schema for parameters
+func (t *StructuredEditTool) ParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Relative path to the JSON, YAML or TOML file",
+			},
+			"key_path": map[string]interface{}{
+				"type": "string",
+				"description": "Location in the document: dot-separated keys with [N] array indexes ([-1] is the last element); " +
+					"quote keys containing dots as [\"a.b\"]. Empty means the whole document",
+			},
+			"operation": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{StructuredSet, StructuredDelete, StructuredAppend, StructuredMerge},
+				"description": "set, delete, append or merge",
+			},
+			"value": map[string]interface{}{
+				"description": "JSON value to set, append or merge (objects for merge); not used by delete",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{FormatJSON, FormatYAML, FormatTOML},
+				"description": "File format (default: from the file extension)",
+			},
+		},
+		"required": []string{"path", "key_path", "operation"},
+	}
+}
+
+// Validate checks if parameters are valid
+func (t *StructuredEditTool) Validate(params map[string]interface{}, workingDir string) error {
+	path, ok := params["path"].(string)
+	if !ok || path == "" {
+		return fmt.Errorf("path parameter is required and must be a non-empty string")
+	}
+
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return err
+	}
+
+	fileInfo, err := os.Stat(resolvedPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("file '%s' not found", path)
+		}
+		return fmt.Errorf("cannot access file '%s': %w", path, err)
+	}
+	if fileInfo.IsDir() {
+		return fmt.Errorf("path '%s' is a directory, not a file", path)
+	}
+
+	if _, err := structuredFormat(params, path); err != nil {
+		return err
+	}
+
+	keyPath, ok := params["key_path"].(string)
+	if !ok {
+		return fmt.Errorf("key_path parameter is required and must be a string")
+	}
+	if _, err := parseKeyPath(keyPath); err != nil {
+		return err
+	}
+
+	operation, _ := params["operation"].(string)
+	_, hasValue := params["value"]
+	switch operation {
+	case StructuredSet, StructuredAppend:
+		if !hasValue {
+			return fmt.Errorf("value parameter is required for %s", operation)
+		}
+	case StructuredMerge:
+		if _, ok := params["value"].(map[string]interface{}); !ok {
+			return fmt.Errorf("value parameter must be an object for merge")
+		}
+	case StructuredDelete:
+		if keyPath == "" {
+			return fmt.Errorf("cannot delete the whole document")
+		}
+	default:
+		return fmt.Errorf("operation must be one of set, delete, append or merge, got '%s'", operation)
+	}
+
+	return nil
+}
+
+// structuredFormat returns the format parameter or the one implied by the file extension
+func structuredFormat(params map[string]interface{}, path string) (string, error) {
+	if format, ok := params["format"].(string); ok && format != "" {
+		switch format {
+		case FormatJSON, FormatYAML, FormatTOML:
+			return format, nil
+		default:
+			return "", fmt.Errorf("format must be json, yaml or toml, got '%s'", format)
+		}
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".toml":
+		return FormatTOML, nil
+	default:
+		return "", fmt.Errorf("cannot tell the format of '%s' from its extension; set format to json, yaml or toml", path)
+	}
+}
+
+// structuredPlan is the outcome of a structured edit, before writing
+type structuredPlan struct {
+	resolvedPath string
+	mode         os.FileMode
+	format       string
+	oldContent   string
+	newContent   string
+	postEdit     postEditResult
+}
+
+// plan parses the file, applies the operation and serializes and validates the result
+func (t *StructuredEditTool) plan(params map[string]interface{}, workingDir string) (*structuredPlan, error) {
+	path := params["path"].(string)
+	keyPath := params["key_path"].(string)
+	operation := params["operation"].(string)
+
+	format, err := structuredFormat(params, path)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := parseKeyPath(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedPath, err := ResolvePath(workingDir, path)
+	if err != nil {
+		return nil, err
+	}
+	fileInfo, err := os.Stat(resolvedPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access file '%s': %w", path, err)
+	}
+	content, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file '%s': %w", path, err)
+	}
+	oldContent := string(content)
+
+	doc, err := parseStructured(format, strings.TrimPrefix(oldContent, utf8BOM))
+	if err != nil {
+		return nil, fmt.Errorf("cannot edit '%s': %w", path, err)
+	}
+
+	var value *yaml.Node
+	if operation != StructuredDelete {
+		if value, err = valueNode(params["value"]); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyStructuredEdit(&doc.root, segments, operation, value); err != nil {
+		return nil, fmt.Errorf("%s '%s' in '%s': %w", operation, keyPath, path, err)
+	}
+
+	serialized, err := doc.serialize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize '%s': %w", path, err)
+	}
+	if err := validateStructured(format, serialized); err != nil {
+		return nil, fmt.Errorf("edit produced invalid %s, nothing was written: %w", strings.ToUpper(format), err)
+	}
+
+	postEdit := t.postEdit.process(resolvedPath, path, keepConventions(oldContent, serialized))
+	return &structuredPlan{
+		resolvedPath: resolvedPath,
+		mode:         fileInfo.Mode().Perm(),
+		format:       format,
+		oldContent:   oldContent,
+		newContent:   postEdit.content,
+		postEdit:     postEdit,
+	}, nil
+}
+
+// Preview returns the diff the edit would produce
+func (t *StructuredEditTool) Preview(params map[string]interface{}, workingDir string) (*types.ToolPreview, error) {
+	p, err := t.plan(params, workingDir)
+	if err != nil {
+		return nil, err
+	}
+	path := params["path"].(string)
+	return &types.ToolPreview{
+		Diff:          fileDiff(path, &p.oldContent, &p.newContent),
+		FilesAffected: []string{path},
+	}, nil
+}
+
+// TargetPaths returns the file to be modified
+func (t *StructuredEditTool) TargetPaths(params map[string]interface{}, workingDir string) ([]string, error) {
+	return singleTarget(params, "path", workingDir)
+}
+
+// Execute applies the edit and writes the file
+func (t *StructuredEditTool) Execute(ctx context.Context, params map[string]interface{}, workingDir string) (*types.ToolResult, error) {
+	startTime := time.Now()
+
+	path := params["path"].(string)
+	keyPath := params["key_path"].(string)
+	operation := params["operation"].(string)
+
+	failure := func(err error) (*types.ToolResult, error) {
+		return &types.ToolResult{
+			Success:         false,
+			Error:           err.Error(),
+			ExecutionTimeMs: time.Since(startTime).Milliseconds(),
+		}, err
+	}
+
+	p, err := t.plan(params, workingDir)
+	if err != nil {
+		return failure(err)
+	}
+
+	if p.newContent != p.oldContent {
+		if err := writeFileAtomic(p.resolvedPath, []byte(p.newContent), p.mode); err != nil {
+			return failure(fmt.Errorf("failed to write file '%s': %w", path, err))
+		}
+	}
+
+	executionTime := time.Since(startTime).Milliseconds()
+
+	logging.Info("Structured edit applied",
+		"path", SanitizePathForDisplay(workingDir, p.resolvedPath),
+		"format", p.format,
+		"operation", operation,
+		"key_path", keyPath,
+		"execution_time_ms", executionTime,
+	)
+
+	target := keyPath
+	if target == "" {
+		target = "(document)"
+	}
+	output := fmt.Sprintf("Applied %s at %s in %s", operation, target, path)
+	if p.newContent == p.oldContent {
+		output += " (no changes)"
+	}
+	output += p.postEdit.notes()
+
+	metadata := map[string]interface{}{
+		"format":    p.format,
+		"operation": operation,
+		"key_path":  keyPath,
+	}
+	p.postEdit.addMetadata(metadata)
+
+	return &types.ToolResult{
+		Success:         true,
+		Output:          output,
+		ExecutionTimeMs: executionTime,
+		FilesAffected:   []string{path},
+		Metadata:        metadata,
+	}, nil
+}
+
+// RequiresApproval returns true as file modification requires approval
+func (t *StructuredEditTool) RequiresApproval() bool {
+	return true
+}
+
+// RiskLevel returns the risk level for this tool
+func (t *StructuredEditTool) RiskLevel() types.RiskLevel {
+	return types.RiskLevelDangerous
+}
+
+// keySegment is one step of a key path: a mapping key or an array index
+type keySegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// String formats the segment as it appears in a key path
+func (s keySegment) String() string {
+	if s.isIndex {
+		return fmt.Sprintf("[%d]", s.index)
+	}
+	return s.key
+}
+
+// parseKeyPath parses paths such as `scripts.test`, `services[0].ports` and `a["b.c"]`
+func parseKeyPath(path string) ([]keySegment, error) {
+	var segments []keySegment
+	i := 0
+	expectKey := true
+	for i < len(path) {
+		switch c := path[i]; {
+		case c == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid key_path '%s': unclosed '['", path)
+			}
+			inner := path[i+1 : i+end]
+			if strings.HasPrefix(inner, `"`) {
+				// Quoted keys may contain ']'
+				key, rest, err := unquoteKey(path[i+1:])
+				if err != nil || !strings.HasPrefix(rest, "]") {
+					return nil, fmt.Errorf("invalid key_path '%s': bad quoted key", path)
+				}
+				segments = append(segments, keySegment{key: key})
+				i = len(path) - len(rest) + 1
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid key_path '%s': '%s' is not an array index", path, inner)
+				}
+				segments = append(segments, keySegment{index: index, isIndex: true})
+				i += end + 1
+			}
+			expectKey = false
+		case c == '.':
+			if expectKey {
+				return nil, fmt.Errorf("invalid key_path '%s': empty key", path)
+			}
+			expectKey = true
+			i++
+		case c == '"':
+			key, rest, err := unquoteKey(path[i:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid key_path '%s': bad quoted key", path)
+			}
+			segments = append(segments, keySegment{key: key})
+			i = len(path) - len(rest)
+			expectKey = false
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			segments = append(segments, keySegment{key: path[i : i+end]})
+			i += end
+			expectKey = false
+		}
+	}
+	if expectKey && len(path) > 0 {
+		return nil, fmt.Errorf("invalid key_path '%s': trailing '.'", path)
+	}
+	return segments, nil
+}
+
+// unquoteKey reads a double-quoted key from the start of s, returning the rest
+func unquoteKey(s string) (string, string, error) {
+	prefix, err := strconv.QuotedPrefix(s)
+	if err != nil {
+		return "", "", err
+	}
+	key, err := strconv.Unquote(prefix)
+	if err != nil {
+		return "", "", err
+	}
+	return key, s[len(prefix):], nil
+}
+
+// formatKeyPath renders segments back into a key path
+func formatKeyPath(segments []keySegment) string {
+	var b strings.Builder
+	for i, s := range segments {
+		if i > 0 && !s.isIndex {
+			b.WriteByte('.')
+		}
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
+
+// applyStructuredEdit applies one operation at the key path below *root
+func applyStructuredEdit(root **yaml.Node, segments []keySegment, operation string, value *yaml.Node) error {
+	if len(segments) == 0 {
+		switch operation {
+		case StructuredSet:
+			copyComments(value, *root)
+			*root = value
+			return nil
+		case StructuredAppend:
+			return appendNode(*root, value)
+		case StructuredMerge:
+			return mergeNode(*root, value)
+		}
+		return fmt.Errorf("cannot delete the whole document")
+	}
+
+	// Walk to the parent of the last segment, creating missing mappings when adding
+	create := operation != StructuredDelete
+	parent := *root
+	for i, seg := range segments[:len(segments)-1] {
+		child, err := childNode(parent, seg, create && !segments[i+1].isIndex, segments[:i+1])
+		if err != nil {
+			return err
+		}
+		parent = child
+	}
+
+	last := segments[len(segments)-1]
+	lastPath := formatKeyPath(segments)
+	slot, err := childSlot(parent, last, lastPath)
+	if err != nil {
+		return err
+	}
+
+	switch operation {
+	case StructuredSet:
+		if slot < 0 {
+			if last.isIndex {
+				return fmt.Errorf("index %d is out of range at '%s'; use append to add elements", last.index, lastPath)
+			}
+			parent.Content = append(parent.Content, scalarNode(tagString, last.key), value)
+			return nil
+		}
+		copyComments(value, parent.Content[slot])
+		parent.Content[slot] = value
+	case StructuredDelete:
+		if slot < 0 {
+			return fmt.Errorf("'%s' not found", lastPath)
+		}
+		if last.isIndex {
+			parent.Content = append(parent.Content[:slot], parent.Content[slot+1:]...)
+		} else {
+			parent.Content = append(parent.Content[:slot-1], parent.Content[slot+1:]...)
+		}
+	case StructuredAppend:
+		if slot < 0 {
+			if last.isIndex {
+				return fmt.Errorf("'%s' not found", lastPath)
+			}
+			sequence := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{value}}
+			parent.Content = append(parent.Content, scalarNode(tagString, last.key), sequence)
+			return nil
+		}
+		if err := appendNode(parent.Content[slot], value); err != nil {
+			return fmt.Errorf("%w at '%s'", err, lastPath)
+		}
+	case StructuredMerge:
+		if slot < 0 {
+			if last.isIndex {
+				return fmt.Errorf("'%s' not found", lastPath)
+			}
+			parent.Content = append(parent.Content, scalarNode(tagString, last.key), value)
+			return nil
+		}
+		if err := mergeNode(parent.Content[slot], value); err != nil {
+			return fmt.Errorf("%w at '%s'", err, lastPath)
+		}
+	}
+	return nil
+}
+
+// childSlot returns the index in parent.Content of the value a segment refers to, or
+// -1 when it doesn't exist
+func childSlot(parent *yaml.Node, seg keySegment, path string) (int, error) {
+	if seg.isIndex {
+		if parent.Kind != yaml.SequenceNode {
+			return 0, fmt.Errorf("'%s' indexes a %s, not an array", path, nodeKindName(parent))
+		}
+		index := seg.index
+		if index < 0 {
+			index += len(parent.Content)
+		}
+		if index < 0 || index >= len(parent.Content) {
+			return -1, nil
+		}
+		return index, nil
+	}
+
+	if parent.Kind != yaml.MappingNode {
+		return 0, fmt.Errorf("'%s' looks up a key in a %s, not an object", path, nodeKindName(parent))
+	}
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == seg.key {
+			return i + 1, nil
+		}
+	}
+	return -1, nil
+}
+
+// childNode returns the child a segment refers to, creating an empty mapping for a
+// missing key when create is set
+func childNode(parent *yaml.Node, seg keySegment, create bool, segments []keySegment) (*yaml.Node, error) {
+	path := formatKeyPath(segments)
+	slot, err := childSlot(parent, seg, path)
+	if err != nil {
+		return nil, err
+	}
+	if slot >= 0 {
+		child := parent.Content[slot]
+		if child.Kind == yaml.AliasNode {
+			child = child.Alias
+		}
+		return child, nil
+	}
+	if !create || seg.isIndex {
+		return nil, fmt.Errorf("'%s' not found", path)
+	}
+
+	child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	parent.Content = append(parent.Content, scalarNode(tagString, seg.key), child)
+	return child, nil
+}
+
+// appendNode adds value to the end of an array
+func appendNode(target, value *yaml.Node) error {
+	if target.Kind != yaml.SequenceNode {
+		return fmt.Errorf("cannot append to a %s", nodeKindName(target))
+	}
+	target.Content = append(target.Content, value)
+	return nil
+}
+
+// mergeNode deep-merges the keys of value into target; nested objects are merged and
+// any other value replaces the existing one
+func mergeNode(target, value *yaml.Node) error {
+	if target.Kind != yaml.MappingNode || value.Kind != yaml.MappingNode {
+		return fmt.Errorf("cannot merge an object into a %s", nodeKindName(target))
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, child := value.Content[i], value.Content[i+1]
+		slot, _ := childSlot(target, keySegment{key: key.Value}, key.Value)
+		switch {
+		case slot < 0:
+			target.Content = append(target.Content, key, child)
+		case target.Content[slot].Kind == yaml.MappingNode && child.Kind == yaml.MappingNode:
+			if err := mergeNode(target.Content[slot], child); err != nil {
+				return err
+			}
+		default:
+			copyComments(child, target.Content[slot])
+			target.Content[slot] = child
+		}
+	}
+	return nil
+}
+
+// copyComments keeps the comments of a replaced YAML node
+func copyComments(to, from *yaml.Node) {
+	to.HeadComment = from.HeadComment
+	to.LineComment = from.LineComment
+	to.FootComment = from.FootComment
+}
+
+// nodeKindName names a node kind for error messages
+func nodeKindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "object"
+	case yaml.SequenceNode:
+		return "array"
+	default:
+		return "scalar value"
+	}
+}
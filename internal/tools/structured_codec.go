@@ -0,0 +1,837 @@
+// Package tools implements parsing and serialization of JSON, YAML and TOML for structured edits
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"go.yaml.in/yaml/v3"
+)
+
+// Structured file formats supported by structured_edit
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// YAML tags used for scalar nodes of every format
+const (
+	tagString    = "!!str"
+	tagInt       = "!!int"
+	tagFloat     = "!!float"
+	tagBool      = "!!bool"
+	tagNull      = "!!null"
+	tagTimestamp = "!!timestamp"
+)
+
+// structuredDoc is a parsed config file. Every format is held as a YAML node tree,
+// which keeps key order (and, for YAML, comments).
+type structuredDoc struct {
+	format string
+	root   *yaml.Node
+	indent string // JSON indent unit; empty for compact JSON
+	spaces int    // YAML indentation width
+	inline map[string]bool
+}
+
+// parseStructured parses content in the given format
+func parseStructured(format, content string) (*structuredDoc, error) {
+	switch format {
+	case FormatJSON:
+		return parseJSONDoc(content)
+	case FormatYAML:
+		return parseYAMLDoc(content)
+	case FormatTOML:
+		return parseTOMLDoc(content)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// serialize renders the document back to text in its format
+func (d *structuredDoc) serialize() (string, error) {
+	switch d.format {
+	case FormatJSON:
+		var b strings.Builder
+		if err := writeJSONNode(&b, d.root, d.indent, 0); err != nil {
+			return "", err
+		}
+		if d.indent != "" {
+			b.WriteByte('\n')
+		}
+		return b.String(), nil
+	case FormatYAML:
+		var b bytes.Buffer
+		encoder := yaml.NewEncoder(&b)
+		encoder.SetIndent(d.spaces)
+		if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{d.root}}); err != nil {
+			return "", fmt.Errorf("failed to encode YAML: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return "", fmt.Errorf("failed to encode YAML: %w", err)
+		}
+		return b.String(), nil
+	case FormatTOML:
+		if d.root.Kind != yaml.MappingNode {
+			return "", fmt.Errorf("a TOML document must be a table")
+		}
+		var b strings.Builder
+		if err := writeTOMLTable(&b, nil, d.root, d.inline); err != nil {
+			return "", err
+		}
+		writeTOMLComment(&b, d.root.FootComment)
+		return strings.TrimPrefix(b.String(), "\n"), nil
+	default:
+		return "", fmt.Errorf("unsupported format '%s'", d.format)
+	}
+}
+
+// validateStructured checks that content parses in the given format
+func validateStructured(format, content string) error {
+	var v interface{}
+	var err error
+	switch format {
+	case FormatJSON:
+		err = json.Unmarshal([]byte(content), &v)
+	case FormatYAML:
+		err = yaml.Unmarshal([]byte(content), &v)
+	case FormatTOML:
+		err = toml.Unmarshal([]byte(content), &v)
+	}
+	return err
+}
+
+// scalarNode creates a scalar node with the given tag
+func scalarNode(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// valueNode converts a decoded JSON parameter value into a node. Object keys are
+// sorted since their order was lost in decoding.
+func valueNode(v interface{}) (*yaml.Node, error) {
+	switch v := v.(type) {
+	case nil:
+		return scalarNode(tagNull, "null"), nil
+	case bool:
+		return scalarNode(tagBool, strconv.FormatBool(v)), nil
+	case string:
+		return scalarNode(tagString, v), nil
+	case float64:
+		return numberNode(v), nil
+	case int:
+		return scalarNode(tagInt, strconv.Itoa(v)), nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			child, err := valueNode(item)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			child, err := valueNode(v[k])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, scalarNode(tagString, k), child)
+		}
+		return node, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// numberNode turns a float into an integer node when it has no fractional part
+func numberNode(f float64) *yaml.Node {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return scalarNode(tagInt, strconv.FormatInt(int64(f), 10))
+	}
+	return scalarNode(tagFloat, formatFloat(f))
+}
+
+// formatFloat formats a float so it always reads back as a float
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// parseJSONDoc builds a node tree from the JSON token stream, keeping key order and
+// the original number text
+func parseJSONDoc(content string) (*structuredDoc, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+
+	root, err := jsonNode(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, err := decoder.Token(); err == nil {
+		return nil, fmt.Errorf("invalid JSON: unexpected data after the top-level value")
+	}
+
+	return &structuredDoc{format: FormatJSON, root: root, indent: detectJSONIndent(content)}, nil
+}
+
+// jsonNode reads one JSON value from the decoder
+func jsonNode(decoder *json.Decoder) (*yaml.Node, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := token.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		if v == '[' {
+			node = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+		for decoder.More() {
+			if v == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				name, _ := key.(string)
+				node.Content = append(node.Content, scalarNode(tagString, name))
+			}
+			child, err := jsonNode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case string:
+		return scalarNode(tagString, v), nil
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return scalarNode(tagFloat, v.String()), nil
+		}
+		return scalarNode(tagInt, v.String()), nil
+	case bool:
+		return scalarNode(tagBool, strconv.FormatBool(v)), nil
+	default:
+		return scalarNode(tagNull, "null"), nil
+	}
+}
+
+// detectJSONIndent returns the indent unit of the first indented line, or "" when
+// the document is on one line
+func detectJSONIndent(content string) string {
+	for _, line := range strings.Split(content, "\n")[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	if strings.Contains(strings.TrimSpace(content), "\n") {
+		return "  "
+	}
+	return ""
+}
+
+// writeJSONNode renders a node as JSON with the given indent unit
+func writeJSONNode(b *strings.Builder, node *yaml.Node, indent string, depth int) error {
+	newline := func(d int) {
+		if indent != "" {
+			b.WriteByte('\n')
+			b.WriteString(strings.Repeat(indent, d))
+		}
+	}
+	separator := ":"
+	if indent != "" {
+		separator = ": "
+	}
+
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		open, close, step := "{", "}", 2
+		if node.Kind == yaml.SequenceNode {
+			open, close, step = "[", "]", 1
+		}
+		b.WriteString(open)
+		if len(node.Content) == 0 {
+			b.WriteString(close)
+			return nil
+		}
+		for i := 0; i < len(node.Content); i += step {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			newline(depth + 1)
+			value := node.Content[i]
+			if step == 2 {
+				b.WriteString(jsonString(node.Content[i].Value))
+				b.WriteString(separator)
+				value = node.Content[i+1]
+			}
+			if err := writeJSONNode(b, value, indent, depth+1); err != nil {
+				return err
+			}
+		}
+		newline(depth)
+		b.WriteString(close)
+	case yaml.AliasNode:
+		return writeJSONNode(b, node.Alias, indent, depth)
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case tagInt, tagFloat, tagBool:
+			b.WriteString(node.Value)
+		case tagNull:
+			b.WriteString("null")
+		default:
+			b.WriteString(jsonString(node.Value))
+		}
+	default:
+		return fmt.Errorf("cannot represent YAML node kind %d in JSON", node.Kind)
+	}
+	return nil
+}
+
+// jsonString quotes s as a JSON string without HTML escaping
+func jsonString(s string) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// parseYAMLDoc parses a single-document YAML file, keeping comments
+func parseYAMLDoc(content string) (*structuredDoc, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	var document yaml.Node
+	if err := decoder.Decode(&document); err != nil {
+		if errors.Is(err, io.EOF) {
+			// An empty file is an empty mapping
+			return &structuredDoc{format: FormatYAML, root: &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, spaces: 2}, nil
+		}
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	var next yaml.Node
+	if err := decoder.Decode(&next); err == nil {
+		return nil, fmt.Errorf("multi-document YAML files are not supported")
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if len(document.Content) > 0 {
+		root = document.Content[0]
+	}
+	root.HeadComment = joinComments(document.HeadComment, root.HeadComment)
+	root.FootComment = joinComments(root.FootComment, document.FootComment)
+
+	return &structuredDoc{format: FormatYAML, root: root, spaces: detectYAMLIndent(content)}, nil
+}
+
+// joinComments combines two comment blocks
+func joinComments(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	default:
+		return a + "\n\n" + b
+	}
+}
+
+// detectYAMLIndent returns the smallest indentation used for nested keys, defaulting to 2
+func detectYAMLIndent(content string) int {
+	smallest := 0
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if n := len(line) - len(trimmed); n > 0 && (smallest == 0 || n < smallest) {
+			smallest = n
+		}
+	}
+	if smallest < 2 {
+		return 2
+	}
+	return smallest
+}
+
+// parseTOMLDoc decodes a TOML file and rebuilds it as a node tree in document order.
+// Values come from the decoder; the key order, which tables were written inline and
+// the comments come from the parser's syntax tree.
+func parseTOMLDoc(content string) (*structuredDoc, error) {
+	var values map[string]interface{}
+	if err := toml.Unmarshal([]byte(content), &values); err != nil {
+		return nil, fmt.Errorf("invalid TOML: %w", err)
+	}
+
+	layout := &tomlLayout{
+		order:    make(map[string]int),
+		inline:   make(map[string]bool),
+		arrays:   make(map[string]int),
+		comments: make(map[string]tomlComments),
+	}
+	parser := unstable.Parser{KeepComments: true}
+	parser.Reset([]byte(content))
+	var prefix, at, pending []string
+	for parser.NextExpression() {
+		expr := parser.Expression()
+		switch expr.Kind {
+		case unstable.Comment:
+			pending = append(pending, commentLines(expr)...)
+		case unstable.Table, unstable.ArrayTable:
+			prefix = tomlKey(expr.Key())
+			layout.see(prefix)
+			at = layout.resolve(prefix, expr.Kind == unstable.ArrayTable)
+			layout.comment(at, pending, expr)
+			pending = nil
+		case unstable.KeyValue:
+			layout.keyValue(prefix, expr)
+			// Comments inside a multi-line array move above its key
+			head := append(pending, valueComments(expr.Value())...)
+			layout.comment(append(append([]string(nil), at...), tomlKey(expr.Key())...), head, expr)
+			pending = nil
+		}
+	}
+	if err := parser.Error(); err != nil {
+		return nil, fmt.Errorf("invalid TOML: %w", err)
+	}
+
+	root, err := layout.node(nil, values)
+	if err != nil {
+		return nil, err
+	}
+	layout.attachComments(root, nil)
+	root.FootComment = strings.Join(pending, "\n")
+	return &structuredDoc{format: FormatTOML, root: root, inline: layout.inline}, nil
+}
+
+// tomlLayout records the order in which TOML keys first appear, which values were
+// written as inline tables or arrays, and the comments of entries. Paths ignore array
+// indexes, except those of comments, which also count the [[array.of.tables]] elements.
+type tomlLayout struct {
+	order    map[string]int
+	inline   map[string]bool
+	arrays   map[string]int
+	comments map[string]tomlComments
+}
+
+// tomlComments are the comments on the lines before an entry and after it on its line
+type tomlComments struct {
+	head string
+	line string
+}
+
+// tomlIndex is the path segment of an array element
+func tomlIndex(i int) string {
+	return "\x01" + strconv.Itoa(i)
+}
+
+// resolve returns the path of a table header with the index of every array-of-tables
+// element on the way, counting a new element for a [[header]]
+func (l *tomlLayout) resolve(keys []string, arrayTable bool) []string {
+	var at []string
+	for i, key := range keys {
+		at = append(at, key)
+		if arrayTable && i == len(keys)-1 {
+			l.arrays[tomlPath(at)]++
+		}
+		if n, ok := l.arrays[tomlPath(at)]; ok {
+			at = append(at, tomlIndex(n-1))
+		}
+	}
+	return at
+}
+
+// comment records the comments before an expression and the one that follows it
+func (l *tomlLayout) comment(at, head []string, expr *unstable.Node) {
+	c := tomlComments{head: strings.Join(head, "\n")}
+	if next := expr.Next(); next != nil && next.Kind == unstable.Comment {
+		c.line = commentText(next)
+	}
+	if c.head != "" || c.line != "" {
+		l.comments[tomlPath(at)] = c
+	}
+}
+
+// attachComments puts the recorded comments on the key nodes of table entries and on
+// the elements of arrays of tables
+func (l *tomlLayout) attachComments(node *yaml.Node, at []string) {
+	attach := func(target *yaml.Node, path []string) {
+		if c, ok := l.comments[tomlPath(path)]; ok {
+			target.HeadComment, target.LineComment = c.head, c.line
+		}
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childAt := append(append([]string(nil), at...), node.Content[i].Value)
+			attach(node.Content[i], childAt)
+			l.attachComments(node.Content[i+1], childAt)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			itemAt := append(append([]string(nil), at...), tomlIndex(i))
+			attach(item, itemAt)
+			l.attachComments(item, itemAt)
+		}
+	}
+}
+
+// commentText returns a comment without its line ending
+func commentText(n *unstable.Node) string {
+	return strings.TrimRight(string(n.Data), " \t\r")
+}
+
+// commentLines returns the lines of a comment and of the comments chained to it
+func commentLines(n *unstable.Node) []string {
+	lines := []string{commentText(n)}
+	for it := n.Children(); it.Next(); {
+		lines = append(lines, commentText(it.Node()))
+	}
+	return lines
+}
+
+// valueComments returns the comments inside a value written over several lines
+func valueComments(value *unstable.Node) []string {
+	var lines []string
+	for it := value.Children(); it.Next(); {
+		switch child := it.Node(); child.Kind {
+		case unstable.Comment:
+			lines = append(lines, commentLines(child)...)
+		case unstable.Array, unstable.InlineTable:
+			lines = append(lines, valueComments(child)...)
+		case unstable.KeyValue:
+			lines = append(lines, valueComments(child.Value())...)
+		}
+	}
+	return lines
+}
+
+// tomlPath joins key names into a layout key
+func tomlPath(keys []string) string {
+	return strings.Join(keys, "\x00")
+}
+
+// tomlKey returns the parts of a dotted key
+func tomlKey(it unstable.Iterator) []string {
+	var parts []string
+	for it.Next() {
+		parts = append(parts, string(it.Node().Data))
+	}
+	return parts
+}
+
+// see records every prefix of a key path in first-seen order
+func (l *tomlLayout) see(path []string) {
+	for i := 1; i <= len(path); i++ {
+		key := tomlPath(path[:i])
+		if _, ok := l.order[key]; !ok {
+			l.order[key] = len(l.order)
+		}
+	}
+}
+
+// keyValue records a key/value expression and the keys of any inline tables in it
+func (l *tomlLayout) keyValue(prefix []string, expr *unstable.Node) {
+	path := append(append([]string(nil), prefix...), tomlKey(expr.Key())...)
+	l.see(path)
+	l.value(path, expr.Value())
+}
+
+// value records the layout of a value at path
+func (l *tomlLayout) value(path []string, value *unstable.Node) {
+	switch value.Kind {
+	case unstable.InlineTable:
+		l.inline[tomlPath(path)] = true
+		for it := value.Children(); it.Next(); {
+			if child := it.Node(); child.Kind == unstable.KeyValue {
+				l.keyValue(path, child)
+			}
+		}
+	case unstable.Array:
+		l.inline[tomlPath(path)] = true
+		for it := value.Children(); it.Next(); {
+			l.value(path, it.Node())
+		}
+	}
+}
+
+// node converts a decoded TOML value into a node, ordering table keys as in the source
+func (l *tomlLayout) node(path []string, v interface{}) (*yaml.Node, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		position := func(key string) (int, bool) {
+			index, ok := l.order[tomlPath(append(append([]string(nil), path...), key))]
+			return index, ok
+		}
+		sort.SliceStable(keys, func(i, j int) bool {
+			oi, iok := position(keys[i])
+			oj, jok := position(keys[j])
+			if iok != jok {
+				return iok
+			}
+			if iok {
+				return oi < oj
+			}
+			return keys[i] < keys[j]
+		})
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			child, err := l.node(append(append([]string(nil), path...), k), v[k])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, scalarNode(tagString, k), child)
+		}
+		return node, nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range v {
+			child, err := l.node(path, item)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	case string:
+		return scalarNode(tagString, v), nil
+	case int64:
+		return scalarNode(tagInt, strconv.FormatInt(v, 10)), nil
+	case float64:
+		return scalarNode(tagFloat, formatFloat(v)), nil
+	case bool:
+		return scalarNode(tagBool, strconv.FormatBool(v)), nil
+	case time.Time:
+		return scalarNode(tagTimestamp, v.Format(time.RFC3339Nano)), nil
+	case toml.LocalDate, toml.LocalTime, toml.LocalDateTime:
+		return scalarNode(tagTimestamp, fmt.Sprint(v)), nil
+	default:
+		return nil, fmt.Errorf("unsupported TOML value type %T", v)
+	}
+}
+
+// bareTOMLKey matches keys that need no quoting
+var bareTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlKeyText quotes a key when it is not a valid bare key
+func tomlKeyText(key string) string {
+	if bareTOMLKey.MatchString(key) {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString renders s as a TOML basic string
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// isTableArray reports whether a sequence is written as [[array.of.tables]]
+func isTableArray(node *yaml.Node, path []string, inline map[string]bool) bool {
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 || inline[tomlPath(path)] {
+		return false
+	}
+	for _, item := range node.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTOMLTable writes the key/values of a table followed by its sub-tables
+func writeTOMLTable(b *strings.Builder, path []string, table *yaml.Node, inline map[string]bool) error {
+	var tables []int
+	written := 0
+	for i := 0; i < len(table.Content); i += 2 {
+		key, value := table.Content[i], table.Content[i+1]
+		childPath := append(append([]string(nil), path...), key.Value)
+		if (value.Kind == yaml.MappingNode && !inline[tomlPath(childPath)]) || isTableArray(value, childPath, inline) {
+			tables = append(tables, i)
+			continue
+		}
+		text, err := tomlValue(value, childPath, inline)
+		if err != nil {
+			return err
+		}
+		// A commented entry starts a new block, unless it's the first of its table
+		if key.HeadComment != "" && written > 0 {
+			b.WriteByte('\n')
+		}
+		writeTOMLComment(b, key.HeadComment)
+		fmt.Fprintf(b, "%s = %s%s\n", tomlKeyText(key.Value), text, tomlLineComment(key))
+		written++
+	}
+
+	for _, i := range tables {
+		key, value := table.Content[i], table.Content[i+1]
+		childPath := append(append([]string(nil), path...), key.Value)
+		header := make([]string, len(childPath))
+		for j, part := range childPath {
+			header[j] = tomlKeyText(part)
+		}
+
+		if value.Kind == yaml.SequenceNode {
+			for _, item := range value.Content {
+				b.WriteByte('\n')
+				writeTOMLComment(b, item.HeadComment)
+				fmt.Fprintf(b, "[[%s]]%s\n", strings.Join(header, "."), tomlLineComment(item))
+				if err := writeTOMLTable(b, childPath, item, inline); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		// Tables holding only sub-tables don't need their own header, unless it has comments
+		if hasTOMLKeyValues(value, childPath, inline) || len(value.Content) == 0 || key.HeadComment != "" || key.LineComment != "" {
+			b.WriteByte('\n')
+			writeTOMLComment(b, key.HeadComment)
+			fmt.Fprintf(b, "[%s]%s\n", strings.Join(header, "."), tomlLineComment(key))
+		}
+		if err := writeTOMLTable(b, childPath, value, inline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTOMLComment writes a comment on the lines before an entry
+func writeTOMLComment(b *strings.Builder, comment string) {
+	if comment != "" {
+		b.WriteString(comment)
+		b.WriteByte('\n')
+	}
+}
+
+// tomlLineComment returns the comment written after an entry on its line
+func tomlLineComment(node *yaml.Node) string {
+	if node.LineComment == "" {
+		return ""
+	}
+	return " " + node.LineComment
+}
+
+// hasTOMLKeyValues reports whether a table has entries written as key = value
+func hasTOMLKeyValues(table *yaml.Node, path []string, inline map[string]bool) bool {
+	for i := 0; i < len(table.Content); i += 2 {
+		childPath := append(append([]string(nil), path...), table.Content[i].Value)
+		value := table.Content[i+1]
+		if !(value.Kind == yaml.MappingNode && !inline[tomlPath(childPath)]) && !isTableArray(value, childPath, inline) {
+			return true
+		}
+	}
+	return false
+}
+
+// tomlValue renders a value written after "key = "
+func tomlValue(node *yaml.Node, path []string, inline map[string]bool) (string, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		parts := make([]string, 0, len(node.Content)/2)
+		for i := 0; i < len(node.Content); i += 2 {
+			text, err := tomlValue(node.Content[i+1], append(append([]string(nil), path...), node.Content[i].Value), inline)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, tomlKeyText(node.Content[i].Value)+" = "+text)
+		}
+		if len(parts) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	case yaml.SequenceNode:
+		parts := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			text, err := tomlValue(item, path, inline)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, text)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	case yaml.AliasNode:
+		return tomlValue(node.Alias, path, inline)
+	case yaml.ScalarNode:
+		switch node.Tag {
+		case tagInt, tagBool, tagTimestamp:
+			return node.Value, nil
+		case tagFloat:
+			if f, err := strconv.ParseFloat(node.Value, 64); err == nil {
+				return formatFloat(f), nil
+			}
+			return node.Value, nil
+		case tagNull:
+			return "", fmt.Errorf("TOML has no null value (at '%s')", strings.Join(path, "."))
+		default:
+			return tomlString(node.Value), nil
+		}
+	default:
+		return "", fmt.Errorf("cannot represent YAML node kind %d in TOML", node.Kind)
+	}
+}
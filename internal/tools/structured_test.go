@@ -0,0 +1,339 @@
+package tools_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// TestStructuredEditTool tests path-based edits of JSON, YAML and TOML files
+func TestStructuredEditTool(t *testing.T) {
+	tool := tools.NewStructuredEditTool()
+
+	if tool.Name() != "structured_edit" {
+		t.Errorf("expected name 'structured_edit', got '%s'", tool.Name())
+	}
+	if !tool.RequiresApproval() {
+		t.Error("structured_edit should require approval")
+	}
+	if tool.RiskLevel() != types.RiskLevelDangerous {
+		t.Errorf("expected risk level %v, got %v", types.RiskLevelDangerous, tool.RiskLevel())
+	}
+
+	const packageJSON = `{
+  "name": "app",
+  "scripts": {
+    "build": "tsc"
+  },
+  "files": ["dist"]
+}
+`
+	const composeYAML = `# Services for local development
+services:
+  - name: web # the frontend
+    ports:
+      - 8080
+  - name: db
+version: "3"
+`
+	const cargoTOML = `[package]
+name = "wink"
+version = "0.1.0"
+authors = ["a", "b"]
+
+[dependencies]
+serde = { version = "1", features = ["derive"] }
+
+[[bin]]
+name = "wink"
+`
+	const commentedTOML = `# Project metadata
+[project]
+name = "wink"
+version = "0.2.0" # bumped by release.sh
+
+# Pinned for the plugin API
+dependencies = [
+  "requests>=2",
+  # keep in sync with docs
+  "rich",
+]
+
+# Linting is optional
+[tool.lint]
+strict = true
+
+# The default profile
+[[profiles]]
+name = "dev"
+
+[[profiles]] # used in CI
+name = "ci"
+# end of file
+`
+
+	tests := []struct {
+		name        string
+		file        string
+		content     string
+		params      map[string]interface{}
+		wantErr     string
+		wantContent string
+		wantOutput  string
+	}{
+		{
+			name:    "JSON set new nested key keeps order and indent",
+			file:    "package.json",
+			content: packageJSON,
+			params:  map[string]interface{}{"key_path": "scripts.test", "operation": "set", "value": "go test ./..."},
+			wantContent: `{
+  "name": "app",
+  "scripts": {
+    "build": "tsc",
+    "test": "go test ./..."
+  },
+  "files": [
+    "dist"
+  ]
+}
+`,
+		},
+		{
+			name:        "JSON delete and compact file",
+			file:        "data.json",
+			content:     `{"b":1,"a":{"x":true,"y":null},"c":[1.5,2]}`,
+			params:      map[string]interface{}{"key_path": "a.x", "operation": "delete"},
+			wantContent: `{"b":1,"a":{"y":null},"c":[1.5,2]}`,
+		},
+		{
+			name:        "JSON append to array with negative index lookup",
+			file:        "data.json",
+			content:     `{"groups":[["a"],["b"]]}`,
+			params:      map[string]interface{}{"key_path": "groups[-1]", "operation": "append", "value": "c"},
+			wantContent: `{"groups":[["a"],["b","c"]]}`,
+		},
+		{
+			name:    "JSON merge deep",
+			file:    "settings.json",
+			content: "{\n\t\"editor\": {\"tabSize\": 4, \"rulers\": [80]},\n\t\"theme\": \"dark\"\n}\n",
+			params: map[string]interface{}{"key_path": "", "operation": "merge", "value": map[string]interface{}{
+				"editor": map[string]interface{}{"tabSize": float64(2)},
+				"zoom":   float64(1.5),
+			}},
+			wantContent: "{\n\t\"editor\": {\n\t\t\"tabSize\": 2,\n\t\t\"rulers\": [\n\t\t\t80\n\t\t]\n\t},\n\t\"theme\": \"dark\",\n\t\"zoom\": 1.5\n}\n",
+		},
+		{
+			name:        "JSON keys with dots are quoted",
+			file:        "data.json",
+			content:     `{"a.b":{"c":1}}`,
+			params:      map[string]interface{}{"key_path": `["a.b"].c`, "operation": "set", "value": "<x>"},
+			wantContent: `{"a.b":{"c":"<x>"}}`,
+		},
+		{
+			name:    "YAML set keeps comments",
+			file:    "compose.yaml",
+			content: composeYAML,
+			params:  map[string]interface{}{"key_path": "services[0].ports", "operation": "append", "value": float64(8443)},
+			wantContent: `# Services for local development
+services:
+  - name: web # the frontend
+    ports:
+      - 8080
+      - 8443
+  - name: db
+version: "3"
+`,
+		},
+		{
+			name:    "YAML set string that looks like a bool",
+			file:    "compose.yml",
+			content: "enabled: false\n",
+			params:  map[string]interface{}{"key_path": "mode", "operation": "set", "value": "true"},
+			wantContent: `enabled: false
+mode: "true"
+`,
+		},
+		{
+			name:    "TOML set keeps key order and inline tables",
+			file:    "Cargo.toml",
+			content: cargoTOML,
+			params:  map[string]interface{}{"key_path": "dependencies.tokio", "operation": "set", "value": "1.40"},
+			wantContent: `[package]
+name = "wink"
+version = "0.1.0"
+authors = ["a", "b"]
+
+[dependencies]
+serde = { version = "1", features = ["derive"] }
+tokio = "1.40"
+
+[[bin]]
+name = "wink"
+`,
+		},
+		{
+			name:    "TOML set keeps comments",
+			file:    "pyproject.toml",
+			content: commentedTOML,
+			params:  map[string]interface{}{"key_path": "project.version", "operation": "set", "value": "0.3.0"},
+			wantContent: `# Project metadata
+[project]
+name = "wink"
+version = "0.3.0" # bumped by release.sh
+
+# Pinned for the plugin API
+# keep in sync with docs
+dependencies = ["requests>=2", "rich"]
+
+# Linting is optional
+[tool.lint]
+strict = true
+
+# The default profile
+[[profiles]]
+name = "dev"
+
+[[profiles]] # used in CI
+name = "ci"
+# end of file
+`,
+		},
+		{
+			name:    "TOML comments follow their entries",
+			file:    "pyproject.toml",
+			content: commentedTOML,
+			params:  map[string]interface{}{"key_path": "tool.lint", "operation": "delete"},
+			wantContent: `# Project metadata
+[project]
+name = "wink"
+version = "0.2.0" # bumped by release.sh
+
+# Pinned for the plugin API
+# keep in sync with docs
+dependencies = ["requests>=2", "rich"]
+
+[tool]
+
+# The default profile
+[[profiles]]
+name = "dev"
+
+[[profiles]] # used in CI
+name = "ci"
+# end of file
+`,
+		},
+		{
+			name:    "TOML has no null",
+			file:    "config.toml",
+			content: "port = 80\n",
+			params:  map[string]interface{}{"key_path": "port", "operation": "set", "value": nil},
+			wantErr: "TOML has no null value",
+		},
+		{
+			name:    "invalid source file is refused",
+			file:    "broken.json",
+			content: `{"a": }`,
+			params:  map[string]interface{}{"key_path": "a", "operation": "set", "value": "x"},
+			wantErr: "invalid JSON",
+		},
+		{
+			name:    "delete missing key",
+			file:    "data.json",
+			content: `{"a":1}`,
+			params:  map[string]interface{}{"key_path": "b", "operation": "delete"},
+			wantErr: "'b' not found",
+		},
+		{
+			name:    "index into an object",
+			file:    "data.json",
+			content: `{"a":{"b":1}}`,
+			params:  map[string]interface{}{"key_path": "a[0]", "operation": "set", "value": "x"},
+			wantErr: "not an array",
+		},
+		{
+			name:    "set past the end of an array",
+			file:    "data.json",
+			content: `{"a":[1]}`,
+			params:  map[string]interface{}{"key_path": "a[3]", "operation": "set", "value": float64(2)},
+			wantErr: "use append",
+		},
+		{
+			name:    "merge requires an object",
+			file:    "data.json",
+			content: `{"a":1}`,
+			params:  map[string]interface{}{"key_path": "a", "operation": "merge", "value": "x"},
+			wantErr: "must be an object",
+		},
+		{
+			name:    "unknown extension needs format",
+			file:    "config.conf",
+			content: `a: 1`,
+			params:  map[string]interface{}{"key_path": "a", "operation": "delete"},
+			wantErr: "set format",
+		},
+		{
+			name:        "format override",
+			file:        "config.conf",
+			content:     "a: 1\nb: 2\n",
+			params:      map[string]interface{}{"key_path": "a", "operation": "delete", "format": "yaml"},
+			wantContent: "b: 2\n",
+		},
+		{
+			name:    "malformed key path",
+			file:    "data.json",
+			content: `{"a":1}`,
+			params:  map[string]interface{}{"key_path": "a..b", "operation": "delete"},
+			wantErr: "invalid key_path",
+		},
+		{
+			name:        "CRLF line endings are kept",
+			file:        "data.json",
+			content:     "{\r\n  \"a\": 1\r\n}\r\n",
+			params:      map[string]interface{}{"key_path": "b", "operation": "set", "value": false},
+			wantContent: "{\r\n  \"a\": 1,\r\n  \"b\": false\r\n}\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			path := filepath.Join(workingDir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("setup failed: %v", err)
+			}
+
+			params := map[string]interface{}{"path": tt.file}
+			for k, v := range tt.params {
+				params[k] = v
+			}
+
+			result, err := runTool(tool, params, workingDir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				got, _ := os.ReadFile(path)
+				if string(got) != tt.content {
+					t.Errorf("failed edit modified the file: %q", string(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, _ := os.ReadFile(path)
+			if string(got) != tt.wantContent {
+				t.Errorf("expected content:\n%s\ngot:\n%s", tt.wantContent, string(got))
+			}
+			if tt.wantOutput != "" && !strings.Contains(result.Output, tt.wantOutput) {
+				t.Errorf("expected output to contain %q, got %q", tt.wantOutput, result.Output)
+			}
+		})
+	}
+}
@@ -0,0 +1,522 @@
+// Package tools implements source symbol extraction for code_outline and find_symbol
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxSignatureChars truncates long declarations in outlines
+	maxSignatureChars = 200
+	// maxSignatureLines is how far a regex-matched declaration may span before its body opens
+	maxSignatureLines = 10
+)
+
+// codeSymbol is a declaration found in a source file
+type codeSymbol struct {
+	Name string
+	// Kind is func, method, type, struct, interface, const, var, class, enum, trait, impl or module
+	Kind string
+	// Container is the receiver type of a Go method or the enclosing class of a nested declaration
+	Container string
+	Signature string
+	StartLine int
+	EndLine   int
+	Depth     int
+}
+
+// qualifiedName returns Container.Name for members and Name otherwise
+func (s codeSymbol) qualifiedName() string {
+	if s.Container != "" {
+		return s.Container + "." + s.Name
+	}
+	return s.Name
+}
+
+// lineRange formats the symbol's line span
+func (s codeSymbol) lineRange() string {
+	if s.EndLine <= s.StartLine {
+		return fmt.Sprintf("%d", s.StartLine)
+	}
+	return fmt.Sprintf("%d-%d", s.StartLine, s.EndLine)
+}
+
+// fileOutline is the symbol list of one source file
+type fileOutline struct {
+	Language string
+	// Package is the Go package name
+	Package string
+	Symbols []codeSymbol
+	// ParseError notes a syntax error; Symbols then holds what could be recovered
+	ParseError string
+}
+
+// blockStyle is how a regex-outlined language delimits declaration bodies
+type blockStyle int
+
+const (
+	blockBraces blockStyle = iota
+	blockIndent
+	blockEnd
+)
+
+// symbolPattern matches one kind of declaration; the first submatch is its name
+type symbolPattern struct {
+	kind string
+	re   *regexp.Regexp
+	// member marks patterns that only count inside a class, such as method definitions
+	member bool
+}
+
+// languageSpec describes how to outline a language without a parser
+type languageSpec struct {
+	name     string
+	block    blockStyle
+	patterns []symbolPattern
+}
+
+// containerKinds are symbol kinds whose bodies hold members
+var containerKinds = map[string]bool{
+	"class": true, "struct": true, "interface": true, "enum": true,
+	"trait": true, "impl": true, "module": true, "namespace": true,
+}
+
+// memberKeywords are words that look like method names to the member patterns but are statements
+var memberKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true,
+	"function": true, "else": true, "do": true, "try": true, "new": true, "throw": true,
+	"using": true, "lock": true, "foreach": true, "sizeof": true, "typeof": true,
+}
+
+func pattern(kind, expr string) symbolPattern {
+	return symbolPattern{kind: kind, re: regexp.MustCompile(expr)}
+}
+
+func memberPattern(kind, expr string) symbolPattern {
+	return symbolPattern{kind: kind, re: regexp.MustCompile(expr), member: true}
+}
+
+var (
+	pythonSpec = &languageSpec{name: "Python", block: blockIndent, patterns: []symbolPattern{
+		pattern("class", `^\s*class\s+(\w+)`),
+		pattern("func", `^\s*(?:async\s+)?def\s+(\w+)`),
+	}}
+	javaScriptPatterns = []symbolPattern{
+		pattern("class", `^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+(\w+)`),
+		pattern("interface", `^\s*(?:export\s+)?(?:declare\s+)?interface\s+(\w+)`),
+		pattern("enum", `^\s*(?:export\s+)?(?:const\s+)?enum\s+(\w+)`),
+		pattern("type", `^\s*(?:export\s+)?type\s+(\w+)\s*(?:<[^>]*>)?\s*=`),
+		pattern("func", `^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)`),
+		pattern("func", `^\s*(?:export\s+)?(?:const|let|var)\s+(\w+)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|(?:\([^)]*\)|\w+)\s*(?::[^=]+)?=>)`),
+		memberPattern("method", `^\s+(?:(?:public|private|protected|static|async|readonly|override|abstract|get|set)\s+)*\*?(\w+)\s*(?:<[^>]*>)?\([^;]*$`),
+	}
+	rustSpec = &languageSpec{name: "Rust", block: blockBraces, patterns: []symbolPattern{
+		pattern("func", `^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+"[^"]*"\s+)?fn\s+(\w+)`),
+		pattern("struct", `^\s*(?:pub(?:\([^)]*\))?\s+)?struct\s+(\w+)`),
+		pattern("enum", `^\s*(?:pub(?:\([^)]*\))?\s+)?enum\s+(\w+)`),
+		pattern("trait", `^\s*(?:pub(?:\([^)]*\))?\s+)?(?:unsafe\s+)?trait\s+(\w+)`),
+		pattern("impl", `^\s*(?:unsafe\s+)?impl\b(?:\s*<[^>]*>)?\s+(?:[\w:]+(?:<[^>]*>)?\s+for\s+)?(\w+)`),
+		pattern("module", `^\s*(?:pub(?:\([^)]*\))?\s+)?mod\s+(\w+)\s*\{`),
+		pattern("type", `^\s*(?:pub(?:\([^)]*\))?\s+)?type\s+(\w+)`),
+	}}
+	javaSpec = &languageSpec{name: "Java", block: blockBraces, patterns: []symbolPattern{
+		pattern("class", `^\s*(?:(?:public|private|protected|static|final|abstract|sealed)\s+)*(?:class|record)\s+(\w+)`),
+		pattern("interface", `^\s*(?:(?:public|private|protected|static|sealed)\s+)*@?interface\s+(\w+)`),
+		pattern("enum", `^\s*(?:(?:public|private|protected|static)\s+)*enum\s+(\w+)`),
+		memberPattern("method", `^\s+(?:(?:public|private|protected|static|final|abstract|synchronized|native|default)\s+)*(?:<[^>]*>\s+)?[\w<>\[\],.?]+\s+(\w+)\s*\([^;]*$`),
+	}}
+	cSharpSpec = &languageSpec{name: "C#", block: blockBraces, patterns: []symbolPattern{
+		pattern("namespace", `^\s*namespace\s+([\w.]+)\s*\{?\s*$`),
+		pattern("class", `^\s*(?:(?:public|private|protected|internal|static|sealed|abstract|partial)\s+)*(?:class|record)\s+(\w+)`),
+		pattern("struct", `^\s*(?:(?:public|private|protected|internal|readonly|partial|ref)\s+)*struct\s+(\w+)`),
+		pattern("interface", `^\s*(?:(?:public|private|protected|internal|partial)\s+)*interface\s+(\w+)`),
+		pattern("enum", `^\s*(?:(?:public|private|protected|internal)\s+)*enum\s+(\w+)`),
+		memberPattern("method", `^\s+(?:(?:public|private|protected|internal|static|virtual|override|abstract|sealed|async|extern|unsafe|new|partial)\s+)*[\w<>\[\],.?]+\s+(\w+)\s*(?:<[^>]*>)?\([^;]*$`),
+	}}
+	cSpec = &languageSpec{name: "C/C++", block: blockBraces, patterns: []symbolPattern{
+		pattern("namespace", `^\s*namespace\s+(\w+)\s*\{?\s*$`),
+		pattern("class", `^\s*(?:template\s*<[^>]*>\s*)?class\s+(\w+)[^;]*$`),
+		pattern("struct", `^\s*(?:typedef\s+)?struct\s+(\w+)[^;]*$`),
+		pattern("enum", `^\s*(?:typedef\s+)?enum\s+(?:class\s+)?(\w+)[^;]*$`),
+		pattern("func", `^(?:[\w*&:<>,]+\s+)+[*&]*((?:\w+::)*~?\w+)\s*\([^;]*$`),
+		memberPattern("method", `^\s+(?:(?:virtual|static|inline|explicit|constexpr)\s+)*(?:[\w*&:<>,]+\s+)*[*&]*(~?\w+)\s*\([^;]*$`),
+	}}
+	rubySpec = &languageSpec{name: "Ruby", block: blockEnd, patterns: []symbolPattern{
+		pattern("module", `^\s*module\s+([\w:]+)`),
+		pattern("class", `^\s*class\s+([\w:]+)`),
+		pattern("func", `^\s*def\s+((?:self\.)?[\w?!=]+)`),
+	}}
+	shellSpec = &languageSpec{name: "Shell", block: blockBraces, patterns: []symbolPattern{
+		pattern("func", `^\s*(?:function\s+)?([\w-]+)\s*\(\)`),
+		pattern("func", `^\s*function\s+([\w-]+)\s*\{?\s*$`),
+	}}
+)
+
+// languageSpecs maps file extensions to regex-based outline rules; Go files use go/parser instead
+var languageSpecs = map[string]*languageSpec{
+	".py":   pythonSpec,
+	".pyi":  pythonSpec,
+	".js":   {name: "JavaScript", block: blockBraces, patterns: javaScriptPatterns},
+	".jsx":  {name: "JavaScript", block: blockBraces, patterns: javaScriptPatterns},
+	".mjs":  {name: "JavaScript", block: blockBraces, patterns: javaScriptPatterns},
+	".cjs":  {name: "JavaScript", block: blockBraces, patterns: javaScriptPatterns},
+	".ts":   {name: "TypeScript", block: blockBraces, patterns: javaScriptPatterns},
+	".tsx":  {name: "TypeScript", block: blockBraces, patterns: javaScriptPatterns},
+	".rs":   rustSpec,
+	".java": javaSpec,
+	".cs":   cSharpSpec,
+	".c":    cSpec,
+	".h":    cSpec,
+	".cc":   cSpec,
+	".cpp":  cSpec,
+	".cxx":  cSpec,
+	".hpp":  cSpec,
+	".rb":   rubySpec,
+	".sh":   shellSpec,
+	".bash": shellSpec,
+}
+
+// outlineSupported reports whether symbols can be extracted from the file
+func outlineSupported(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".go" || languageSpecs[ext] != nil
+}
+
+// outlineSource extracts the declarations of a source file, using go/ast for Go
+// and line patterns for other languages
+func outlineSource(path string, src []byte) (*fileOutline, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".go" {
+		return outlineGo(path, src), nil
+	}
+	spec := languageSpecs[ext]
+	if spec == nil {
+		return nil, fmt.Errorf("no outline support for '%s' files", ext)
+	}
+	return &fileOutline{Language: spec.name, Symbols: outlineByPattern(spec, src)}, nil
+}
+
+// outlineGo lists the top-level declarations of a Go file
+func outlineGo(path string, src []byte) *fileOutline {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.SkipObjectResolution)
+	outline := &fileOutline{Language: "Go"}
+	if err != nil {
+		outline.ParseError = err.Error()
+		if list, ok := err.(interface{ Unwrap() []error }); ok && len(list.Unwrap()) > 0 {
+			outline.ParseError = list.Unwrap()[0].Error()
+		}
+	}
+	if file == nil {
+		return outline
+	}
+	outline.Package = file.Name.Name
+
+	line := func(pos token.Pos) int {
+		return fset.Position(pos).Line
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			sym := codeSymbol{
+				Name:      d.Name.Name,
+				Kind:      "func",
+				Signature: goSignature(fset, &ast.FuncDecl{Recv: d.Recv, Name: d.Name, Type: d.Type}),
+				StartLine: line(d.Pos()),
+				EndLine:   line(d.End()),
+			}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				sym.Kind = "method"
+				sym.Container = receiverTypeName(d.Recv.List[0].Type)
+			}
+			outline.Symbols = append(outline.Symbols, sym)
+		case *ast.GenDecl:
+			grouped := d.Lparen.IsValid()
+			for _, spec := range d.Specs {
+				start, end := spec.Pos(), spec.End()
+				if !grouped {
+					start, end = d.Pos(), d.End()
+				}
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					kind, sig := "type", "type "+s.Name.Name
+					switch s.Type.(type) {
+					case *ast.StructType:
+						kind, sig = "struct", sig+" struct"
+					case *ast.InterfaceType:
+						kind, sig = "interface", sig+" interface"
+					default:
+						if s.Assign.IsValid() {
+							sig += " ="
+						}
+						sig += " " + goSignature(fset, s.Type)
+					}
+					outline.Symbols = append(outline.Symbols, codeSymbol{
+						Name: s.Name.Name, Kind: kind, Signature: sig,
+						StartLine: line(start), EndLine: line(end),
+					})
+				case *ast.ValueSpec:
+					kind := d.Tok.String()
+					for _, name := range s.Names {
+						if name.Name == "_" {
+							continue
+						}
+						sig := kind + " " + name.Name
+						if s.Type != nil {
+							sig += " " + goSignature(fset, s.Type)
+						}
+						outline.Symbols = append(outline.Symbols, codeSymbol{
+							Name: name.Name, Kind: kind, Signature: sig,
+							StartLine: line(start), EndLine: line(end),
+						})
+					}
+				}
+			}
+		}
+	}
+	return outline
+}
+
+// receiverTypeName returns T for receivers of type T, *T, T[K] and *T[K]
+func receiverTypeName(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// goSignature prints a node on one line
+func goSignature(fset *token.FileSet, node interface{}) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return compactSignature(buf.String())
+}
+
+// compactSignature collapses whitespace and truncates long declarations
+func compactSignature(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > maxSignatureChars {
+		s = s[:maxSignatureChars] + "..."
+	}
+	return s
+}
+
+// outlineByPattern finds declarations line by line and works out where each body ends
+func outlineByPattern(spec *languageSpec, src []byte) []codeSymbol {
+	text := strings.ReplaceAll(string(src), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+
+	var symbols []codeSymbol
+	for i, line := range lines {
+		for _, p := range spec.patterns {
+			m := p.re.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			name := m[1]
+			if p.member && memberKeywords[name] {
+				continue
+			}
+			symbols = append(symbols, codeSymbol{
+				Name:      name,
+				Kind:      p.kind,
+				Signature: compactSignature(strings.TrimRight(strings.TrimSpace(line), "{:")),
+				StartLine: i + 1,
+				EndLine:   blockEndLine(spec.block, lines, i) + 1,
+			})
+			break
+		}
+	}
+
+	// Attach declarations to the innermost container whose body holds them
+	var nested []codeSymbol
+	var stack []codeSymbol
+	for _, sym := range symbols {
+		for len(stack) > 0 && stack[len(stack)-1].EndLine < sym.StartLine {
+			stack = stack[:len(stack)-1]
+		}
+		var parent *codeSymbol
+		for i := len(stack) - 1; i >= 0; i-- {
+			if containerKinds[stack[i].Kind] {
+				parent = &stack[i]
+				break
+			}
+		}
+		member := false
+		for _, p := range spec.patterns {
+			if p.kind == sym.Kind && p.member {
+				member = true
+			}
+		}
+		if member && parent == nil {
+			continue
+		}
+		sym.Depth = len(stack)
+		if parent != nil {
+			sym.Container = parent.Name
+			if sym.Kind == "func" {
+				sym.Kind = "method"
+			}
+		}
+		nested = append(nested, sym)
+		if sym.EndLine > sym.StartLine {
+			stack = append(stack, sym)
+		}
+	}
+	return nested
+}
+
+// blockEndLine returns the index of the last line of the declaration starting at lines[start]
+func blockEndLine(style blockStyle, lines []string, start int) int {
+	switch style {
+	case blockIndent, blockEnd:
+		return indentBlockEnd(style, lines, start)
+	default:
+		return braceBlockEnd(lines, start)
+	}
+}
+
+// braceBlockEnd finds the brace closing a declaration's body; declarations without
+// a body (prototypes, forward declarations) end on their first line
+func braceBlockEnd(lines []string, start int) int {
+	depth := 0
+	opened := false
+	for i := start; i < len(lines); i++ {
+		for _, c := range stripLiterals(lines[i]) {
+			switch c {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+			case ';':
+				if !opened {
+					return i
+				}
+			}
+			if opened && depth <= 0 {
+				return i
+			}
+		}
+		if !opened && i-start >= maxSignatureLines {
+			return start
+		}
+	}
+	if !opened {
+		return start
+	}
+	return len(lines) - 1
+}
+
+// stripLiterals blanks out string and character literals and line comments so braces
+// inside them are not counted
+func stripLiterals(line string) string {
+	var out strings.Builder
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '"' || c == '`':
+			i = closingQuote(line, i)
+		case c == '\'':
+			// Only short character literals; a lone quote is a Rust lifetime
+			if m := charLiteral.FindString(line[i:]); m != "" {
+				i += len(m) - 1
+			}
+		case strings.HasPrefix(line[i:], "//"):
+			return out.String()
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			// Shell comments; C preprocessor lines hold no braces worth counting
+			return out.String()
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
+
+// charLiteral matches a character literal such as 'x' or '\n'
+var charLiteral = regexp.MustCompile(`^'(?:\\.[^']*|[^\\'])'`)
+
+// closingQuote returns the index of the quote ending the literal opened at line[open]
+func closingQuote(line string, open int) int {
+	for i := open + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case line[open]:
+			return i
+		}
+	}
+	return len(line)
+}
+
+// indentBlockEnd ends a block at the first non-blank line indented no deeper than its
+// header; for blockEnd languages that line is included when it is the closing 'end'
+func indentBlockEnd(style blockStyle, lines []string, start int) int {
+	indent := indentWidth(lines[start])
+	body := start
+	// Python headers may wrap; the body starts after the line ending in ':'
+	if style == blockIndent {
+		for body < len(lines)-1 && body-start < maxSignatureLines && !strings.HasSuffix(strings.TrimSpace(lines[body]), ":") {
+			body++
+		}
+		if !strings.HasSuffix(strings.TrimSpace(lines[body]), ":") {
+			body = start
+		}
+	}
+
+	last := body
+	for i := body + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			continue
+		}
+		if indentWidth(lines[i]) <= indent {
+			if style == blockEnd && (trimmed == "end" || strings.HasPrefix(trimmed, "end ") || strings.HasPrefix(trimmed, "end#")) {
+				return i
+			}
+			break
+		}
+		last = i
+	}
+	return last
+}
+
+// indentWidth counts leading whitespace, with tabs as four columns
+func indentWidth(line string) int {
+	width := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
@@ -0,0 +1,302 @@
+// Package tools implements the workspace walker shared by the search and listing tools
+package tools
+
+import (
+	"bufio"
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// vcsDirs are version control directories that are never walked
+var vcsDirs = map[string]bool{
+	".git":   true,
+	".hg":    true,
+	".svn":   true,
+	".bzr":   true,
+	"_darcs": true,
+	".jj":    true,
+}
+
+// isSearchIndexDir reports whether dir holds a workspace's search index, which is
+// never walked
+func isSearchIndexDir(dir string) bool {
+	return filepath.Base(dir) == "index" && filepath.Base(filepath.Dir(dir)) == ".wink"
+}
+
+// ignoreFiles are read in every directory, later files taking precedence
+var ignoreFiles = []string{".gitignore", ".winkignore"}
+
+// ignoreRule is one pattern line of an ignore file
+type ignoreRule struct {
+	// base is the directory holding the ignore file, relative to the matcher root
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// matches reports whether the rule applies to rel, a slash-separated path relative
+// to the matcher root
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = rel[len(r.base)+1:]
+	}
+	return r.re.MatchString(rel)
+}
+
+// ignoreMatcher decides which paths are ignored by .gitignore and .winkignore files
+// (nested ones included) and .git/info/exclude. Its root is the enclosing git
+// repository, so ignore files above the working directory apply too.
+type ignoreMatcher struct {
+	root  string
+	rules map[string][]ignoreRule // by directory relative to root, loaded on first use
+}
+
+// newIgnoreMatcher creates a matcher for the workspace at workingDir
+func newIgnoreMatcher(workingDir string) *ignoreMatcher {
+	return &ignoreMatcher{
+		root:  repositoryRoot(workingDir),
+		rules: map[string][]ignoreRule{},
+	}
+}
+
+// repositoryRoot returns the nearest directory at or above dir that contains .git,
+// or dir itself outside a repository
+func repositoryRoot(dir string) string {
+	for current := dir; ; {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
+
+// ignored reports whether the file or directory at the absolute path is ignored. The
+// last matching rule wins, and rules in deeper directories come later.
+func (m *ignoreMatcher) ignored(absPath string, isDir bool) bool {
+	rel, err := filepath.Rel(m.root, absPath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+
+	ignored := false
+	dir := ""
+	for {
+		for _, rule := range m.rulesFor(dir) {
+			if rule.matches(rel, isDir) {
+				ignored = !rule.negate
+			}
+		}
+		rest := strings.TrimPrefix(rel, dir)
+		rest = strings.TrimPrefix(rest, "/")
+		i := strings.IndexByte(rest, '/')
+		if i < 0 {
+			return ignored
+		}
+		if dir == "" {
+			dir = rest[:i]
+		} else {
+			dir += "/" + rest[:i]
+		}
+	}
+}
+
+// rulesFor loads the ignore rules declared in dir
+func (m *ignoreMatcher) rulesFor(dir string) []ignoreRule {
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+
+	var rules []ignoreRule
+	abs := filepath.Join(m.root, filepath.FromSlash(dir))
+	if dir == "" {
+		rules = append(rules, readIgnoreFile(filepath.Join(abs, ".git", "info", "exclude"), dir)...)
+	}
+	for _, name := range ignoreFiles {
+		rules = append(rules, readIgnoreFile(filepath.Join(abs, name), dir)...)
+	}
+	m.rules[dir] = rules
+	return rules
+}
+
+// readIgnoreFile parses an ignore file; a missing or unreadable file has no rules
+func readIgnoreFile(filename, base string) []ignoreRule {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text(), base); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parseIgnoreLine converts one line of gitignore syntax into a rule
+func parseIgnoreLine(line, base string) (ignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	// Trailing spaces are dropped unless escaped with a backslash
+	trimmed := strings.TrimRight(line, " ")
+	if strings.HasSuffix(trimmed, `\`) && len(trimmed) < len(line) {
+		trimmed += " "
+	}
+	line = trimmed
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	switch {
+	case strings.HasPrefix(line, "!"):
+		rule.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to the ignore file's directory;
+	// otherwise it matches a name at any depth
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		line = "**/" + line
+	}
+
+	re, err := regexp.Compile(globToRegexp(line))
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globToRegexp translates a gitignore-style glob into an anchored regular expression:
+// '*' and '?' stay within a path segment, "**/" matches zero or more directories and
+// a trailing "**" everything below
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := i + 1
+			if end < len(glob) && (glob[end] == '!' || glob[end] == '^') {
+				end++
+			}
+			if end < len(glob) && glob[end] == ']' {
+				end++
+			}
+			for end < len(glob) && glob[end] != ']' {
+				end++
+			}
+			if end >= len(glob) {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = end
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(glob[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// walkWorkspace walks the tree rooted at start, calling fn for every file and
+// directory that is not ignored. VCS directories are always skipped; ignore files
+// are honored unless includeIgnored is set, and the search index under .wink/index
+// is never walked. Unreadable entries are skipped, and the walk stops when ctx is
+// cancelled.
+func walkWorkspace(ctx context.Context, workingDir, start string, includeIgnored bool, fn func(path string, d fs.DirEntry) error) error {
+	var matcher *ignoreMatcher
+	if !includeIgnored {
+		matcher = newIgnoreMatcher(workingDir)
+	}
+
+	return filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return nil // Skip unreadable entries
+		}
+
+		if p != start {
+			if d.IsDir() && (vcsDirs[d.Name()] || isSearchIndexDir(p)) {
+				return fs.SkipDir
+			}
+			if matcher != nil && matcher.ignored(p, d.IsDir()) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+		}
+		return fn(p, d)
+	})
+}
+
+// matchPathGlob matches a file glob against a slash-or-OS-separated relative path.
+// Patterns without a slash match the file name at any depth, so "*.go" finds Go files
+// in subdirectories; other patterns match the whole path and support "**".
+func matchPathGlob(pattern, rel string) (bool, error) {
+	pattern = filepath.ToSlash(pattern)
+	rel = filepath.ToSlash(rel)
+	if !strings.Contains(pattern, "/") {
+		return path.Match(pattern, path.Base(rel))
+	}
+	return matchGlob(pattern, rel)
+}
@@ -0,0 +1,175 @@
+package tools_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+)
+
+// TestIgnoreFiles tests how .gitignore, .winkignore and .git/info/exclude shape the
+// files the search tools see
+func TestIgnoreFiles(t *testing.T) {
+	tests := []struct {
+		name           string
+		files          map[string]string
+		includeIgnored bool
+		wantFound      []string
+		wantMissing    []string
+	}{
+		{
+			name: "name patterns match at any depth",
+			files: map[string]string{
+				".gitignore":      "*.log\n",
+				"app.log":         "x",
+				"logs/server.log": "x",
+				"main.go":         "x",
+			},
+			wantFound:   []string{"main.go"},
+			wantMissing: []string{"app.log", "server.log"},
+		},
+		{
+			name: "directory patterns skip the whole directory",
+			files: map[string]string{
+				".gitignore":           "build/\n",
+				"build/out.bin":        "x",
+				"build/nested/out.txt": "x",
+				"src/build.go":         "x",
+			},
+			wantFound:   []string{"src/build.go"},
+			wantMissing: []string{"out.bin", "out.txt"},
+		},
+		{
+			name: "anchored patterns only match from the ignore file's directory",
+			files: map[string]string{
+				".gitignore":    "/dist\n",
+				"dist/a.js":     "x",
+				"web/dist/b.js": "x",
+			},
+			wantFound:   []string{"web/dist/b.js"},
+			wantMissing: []string{"dist/a.js"},
+		},
+		{
+			name: "double star matches any number of directories",
+			files: map[string]string{
+				".gitignore":        "docs/**/draft.md\n",
+				"docs/draft.md":     "x",
+				"docs/a/b/draft.md": "x",
+				"notes/draft.md":    "x",
+			},
+			wantFound:   []string{"notes/draft.md"},
+			wantMissing: []string{"docs/draft.md", "docs/a/b/draft.md"},
+		},
+		{
+			name: "negation re-includes files",
+			files: map[string]string{
+				".gitignore":  "*.env\n!example.env\n",
+				"prod.env":    "x",
+				"example.env": "x",
+			},
+			wantFound:   []string{"example.env"},
+			wantMissing: []string{"prod.env"},
+		},
+		{
+			name: "nested ignore files apply below their directory",
+			files: map[string]string{
+				"pkg/.gitignore":  "*.gen.go\n!keep.gen.go\n",
+				"pkg/a.gen.go":    "x",
+				"pkg/keep.gen.go": "x",
+				"b.gen.go":        "x",
+			},
+			wantFound:   []string{"pkg/keep.gen.go", "b.gen.go"},
+			wantMissing: []string{"pkg/a.gen.go"},
+		},
+		{
+			name: "winkignore and info/exclude are honored",
+			files: map[string]string{
+				".winkignore":       "fixtures/\n",
+				".git/info/exclude": "scratch.txt\n",
+				"fixtures/big.json": "x",
+				"scratch.txt":       "x",
+				"main.go":           "x",
+			},
+			wantFound:   []string{"main.go"},
+			wantMissing: []string{"big.json", "scratch.txt"},
+		},
+		{
+			name: "VCS directories are always skipped",
+			files: map[string]string{
+				".git/config":    "x",
+				".hg/store/data": "x",
+				"main.go":        "x",
+			},
+			includeIgnored: true,
+			wantFound:      []string{"main.go"},
+			wantMissing:    []string{"config", "data"},
+		},
+		{
+			name: "include_ignored searches everything",
+			files: map[string]string{
+				".gitignore":    "build/\n*.log\n",
+				"build/out.bin": "x",
+				"app.log":       "x",
+			},
+			includeIgnored: true,
+			wantFound:      []string{"build/out.bin", "app.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			tt.files[".git/HEAD"] = "ref: refs/heads/main\n"
+			setupTree(t, workingDir, tt.files)
+
+			result, err := runTool(tools.NewFileSearchTool(), map[string]interface{}{
+				"pattern":         "*",
+				"include_ignored": tt.includeIgnored,
+			}, workingDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output := filepath.ToSlash(result.Output)
+			for _, want := range tt.wantFound {
+				if !strings.Contains(output, want) {
+					t.Errorf("expected %s to be found, got:\n%s", want, output)
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(output, missing) {
+					t.Errorf("expected %s to be ignored, got:\n%s", missing, output)
+				}
+			}
+		})
+	}
+}
+
+// TestGrepSearchIgnoredFiles tests that grep_search skips ignored files unless asked
+func TestGrepSearchIgnoredFiles(t *testing.T) {
+	workingDir := t.TempDir()
+	setupTree(t, workingDir, map[string]string{
+		".gitignore":              "node_modules/\n",
+		"node_modules/pkg/lib.js": "const needle = 1\n",
+		"src/app.js":              "const needle = 2\n",
+	})
+
+	tool := tools.NewGrepSearchTool()
+	for _, includeIgnored := range []bool{false, true} {
+		result, err := runTool(tool, map[string]interface{}{
+			"pattern":         "needle",
+			"include_ignored": includeIgnored,
+		}, workingDir)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		output := filepath.ToSlash(result.Output)
+		if !strings.Contains(output, "src/app.js") {
+			t.Errorf("expected src/app.js to match, got:\n%s", output)
+		}
+		if got := strings.Contains(output, "node_modules/pkg/lib.js"); got != includeIgnored {
+			t.Errorf("include_ignored=%v: expected node_modules match %v, got:\n%s", includeIgnored, includeIgnored, output)
+		}
+	}
+}
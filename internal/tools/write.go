@@ -0,0 +1,142 @@
+// Package tools implements atomic file writes that keep a file's conventions
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// utf8BOM is the byte order mark some editors put at the start of UTF-8 files
+const utf8BOM = "\ufeff"
+
+// textConventions are the encoding details of an existing file that a rewrite keeps
+type textConventions struct {
+	bom bool
+	eol string // "\r\n" or "\n"; empty when the file is mixed or has no line breaks
+}
+
+// detectConventions reports the BOM and the line ending content uses consistently
+func detectConventions(content string) textConventions {
+	conv := textConventions{bom: strings.HasPrefix(content, utf8BOM)}
+
+	crlf := strings.Count(content, "\r\n")
+	lf := strings.Count(content, "\n") - crlf
+	switch {
+	case crlf > 0 && lf == 0:
+		conv.eol = "\r\n"
+	case lf > 0 && crlf == 0:
+		conv.eol = "\n"
+	}
+	return conv
+}
+
+// normalizeEOL converts the line endings of text to the file's; mixed files are
+// left alone
+func (c textConventions) normalizeEOL(text string) string {
+	switch c.eol {
+	case "\r\n":
+		return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+	case "\n":
+		return strings.ReplaceAll(text, "\r\n", "\n")
+	default:
+		return text
+	}
+}
+
+// apply gives whole-file content the file's line endings and BOM
+func (c textConventions) apply(content string) string {
+	content = c.normalizeEOL(content)
+	if c.bom && !strings.HasPrefix(content, utf8BOM) {
+		content = utf8BOM + content
+	}
+	return content
+}
+
+// keepConventions rewrites newContent to use the line endings and BOM of oldContent
+func keepConventions(oldContent, newContent string) string {
+	return detectConventions(oldContent).apply(newContent)
+}
+
+// writeFileAtomic replaces path with data without ever leaving a partially written
+// file: the data goes to a temporary file in the same directory, is synced and then
+// renamed over the target. An existing file keeps its permissions and, where the
+// platform allows, its owner; perm only applies to new files. Symlinks are followed
+// so the link itself survives.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	staged, err := stageFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+	return staged.commit()
+}
+
+// stagedFile is new content written and synced next to its target, waiting to be
+// renamed over it
+type stagedFile struct {
+	tmp    string
+	target string
+}
+
+// stageFile writes data to a temporary file next to path, as writeFileAtomic does,
+// without replacing path yet. Several files can be staged and then committed
+// together, so a failed write changes none of them.
+func stageFile(path string, data []byte, perm os.FileMode) (staged *stagedFile, err error) {
+	if target, evalErr := filepath.EvalSymlinks(path); evalErr == nil {
+		path = target
+	}
+
+	existing, statErr := os.Stat(path)
+	switch {
+	case statErr == nil && !existing.Mode().IsRegular():
+		return nil, fmt.Errorf("'%s' is not a regular file", path)
+	case statErr == nil:
+		perm = existing.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	case !os.IsNotExist(statErr):
+		return nil, fmt.Errorf("failed to stat '%s': %w", path, statErr)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".wink-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return nil, fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if existing != nil {
+		preserveOwner(tmp, existing)
+	}
+	if err = tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	return &stagedFile{tmp: tmp.Name(), target: path}, nil
+}
+
+// commit renames the staged file over its target
+func (f *stagedFile) commit() error {
+	if err := os.Rename(f.tmp, f.target); err != nil {
+		os.Remove(f.tmp)
+		return fmt.Errorf("failed to replace '%s': %w", f.target, err)
+	}
+	syncDir(filepath.Dir(f.target))
+	return nil
+}
+
+// discard removes the staged file, leaving its target untouched
+func (f *stagedFile) discard() {
+	os.Remove(f.tmp)
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+// Package tools implements the non-Unix parts of atomic file writes
+package tools
+
+import "os"
+
+// preserveOwner is a no-op where file ownership is not expressed as uid/gid
+func preserveOwner(f *os.File, original os.FileInfo) {}
+
+// syncDir is a no-op where directories cannot be opened for syncing
+func syncDir(dir string) {}
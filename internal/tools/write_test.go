@@ -0,0 +1,173 @@
+package tools_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// TestWritesKeepFileConventions tests that edits keep line endings, BOM and mode
+func TestWritesKeepFileConventions(t *testing.T) {
+	const bom = "\ufeff"
+
+	tests := []struct {
+		name     string
+		tool     types.Tool
+		original string
+		mode     os.FileMode
+		params   map[string]interface{}
+		want     string
+	}{
+		{
+			name:     "replace keeps CRLF",
+			tool:     tools.NewReplaceStringInFileTool(),
+			original: "a\r\nb\r\n",
+			mode:     0644,
+			params:   map[string]interface{}{"old_string": "b", "new_string": "b\nc"},
+			want:     "a\r\nb\r\nc\r\n",
+		},
+		{
+			name:     "replace keeps BOM",
+			tool:     tools.NewReplaceStringInFileTool(),
+			original: bom + "first\nsecond\n",
+			mode:     0644,
+			params:   map[string]interface{}{"old_string": bom + "first", "new_string": "1st"},
+			want:     bom + "1st\nsecond\n",
+		},
+		{
+			name:     "replace keeps executable bit",
+			tool:     tools.NewReplaceStringInFileTool(),
+			original: "#!/bin/sh\necho hi\n",
+			mode:     0755,
+			params:   map[string]interface{}{"old_string": "echo hi", "new_string": "echo bye"},
+			want:     "#!/bin/sh\necho bye\n",
+		},
+		{
+			name:     "multi_edit matches LF strings in a CRLF file",
+			tool:     tools.NewMultiEditTool(),
+			original: "one\r\ntwo\r\nthree\r\n",
+			mode:     0600,
+			params: map[string]interface{}{"edits": []interface{}{
+				map[string]interface{}{"old_string": "one\ntwo", "new_string": "1\n2\n2.5"},
+			}},
+			want: "1\r\n2\r\n2.5\r\nthree\r\n",
+		},
+		{
+			name:     "multi_edit converts CRLF strings in an LF file",
+			tool:     tools.NewMultiEditTool(),
+			original: "one\ntwo\n",
+			mode:     0644,
+			params: map[string]interface{}{"edits": []interface{}{
+				map[string]interface{}{"old_string": "two", "new_string": "2\r\n3"},
+			}},
+			want: "one\n2\n3\n",
+		},
+		{
+			name:     "apply_patch keeps BOM and CRLF",
+			tool:     tools.NewApplyPatchTool(),
+			original: bom + "package main\r\n\r\nfunc main() {}\r\n",
+			mode:     0644,
+			params: map[string]interface{}{
+				"patch": "--- f.txt\n+++ f.txt\n@@ -1,3 +1,3 @@\n-package main\n+package app\n \n func main() {}\n",
+			},
+			want: bom + "package app\r\n\r\nfunc main() {}\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			path := filepath.Join(workingDir, "f.txt")
+			if err := os.WriteFile(path, []byte(tt.original), 0644); err != nil {
+				t.Fatalf("setup failed: %v", err)
+			}
+			if err := os.Chmod(path, tt.mode); err != nil {
+				t.Fatalf("setup failed: %v", err)
+			}
+
+			params := map[string]interface{}{"path": "f.txt"}
+			for k, v := range tt.params {
+				params[k] = v
+			}
+			if _, err := runTool(tt.tool, params, workingDir); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, _ := os.ReadFile(path)
+			if string(got) != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, string(got))
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("stat failed: %v", err)
+			}
+			if runtime.GOOS != "windows" && info.Mode().Perm() != tt.mode {
+				t.Errorf("expected mode %v, got %v", tt.mode, info.Mode().Perm())
+			}
+
+			// The temporary file must not be left behind
+			entries, _ := os.ReadDir(workingDir)
+			if len(entries) != 1 {
+				t.Errorf("expected only f.txt in the workspace, got %d entries", len(entries))
+			}
+		})
+	}
+}
+
+// TestWriteFollowsSymlinks tests that editing through a symlink keeps the link
+func TestWriteFollowsSymlinks(t *testing.T) {
+	workingDir := t.TempDir()
+	setupTree(t, workingDir, map[string]string{"real.txt": "old\n"})
+	link := filepath.Join(workingDir, "link.txt")
+	if err := os.Symlink("real.txt", link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	params := map[string]interface{}{"path": "link.txt", "old_string": "old", "new_string": "new"}
+	if _, err := runTool(tools.NewReplaceStringInFileTool(), params, workingDir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("expected link.txt to remain a symlink, got %v, %v", info, err)
+	}
+	got, _ := os.ReadFile(filepath.Join(workingDir, "real.txt"))
+	if string(got) != "new\n" {
+		t.Errorf("expected the link target to be updated, got %q", string(got))
+	}
+}
+
+// TestCreateFileExecutable tests the executable parameter of create_file
+func TestCreateFileExecutable(t *testing.T) {
+	tool := tools.NewCreateFileTool()
+	workingDir := t.TempDir()
+
+	params := map[string]interface{}{"path": "bin/run.sh", "content": "#!/bin/sh\n", "executable": true}
+	result, err := runTool(tool, params, workingDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Metadata["executable"] != true {
+		t.Errorf("expected executable metadata, got %v", result.Metadata)
+	}
+
+	info, err := os.Stat(filepath.Join(workingDir, "bin", "run.sh"))
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0755 {
+		t.Errorf("expected mode 0755, got %v", info.Mode().Perm())
+	}
+
+	params = map[string]interface{}{"path": "plain.txt", "content": "x", "executable": "yes"}
+	if err := tool.Validate(params, workingDir); err == nil || !strings.Contains(err.Error(), "executable") {
+		t.Errorf("expected executable type error, got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+// Package tools implements the Unix parts of atomic file writes
+package tools
+
+import (
+	"os"
+	"syscall"
+)
+
+// preserveOwner gives the temporary file the owner of the file it replaces. This
+// fails without privileges when the owner differs, which is accepted.
+func preserveOwner(f *os.File, original os.FileInfo) {
+	stat, ok := original.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	_ = f.Chown(int(stat.Uid), int(stat.Gid))
+}
+
+// syncDir flushes a directory entry change, such as a rename, to disk
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	_ = d.Sync()
+}
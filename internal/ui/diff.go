@@ -0,0 +1,96 @@
+// Package ui renders change previews
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ANSI colors used for diffs
+const (
+	ansiReset = "\033[0m"
+	ansiBold  = "\033[1m"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+	ansiCyan  = "\033[36m"
+)
+
+// DiffStat counts the added and removed lines of a unified diff
+func DiffStat(diff string) (added, removed int) {
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+		case strings.HasPrefix(line, "+"):
+			added++
+		case strings.HasPrefix(line, "-"):
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// ColorizeDiff colors a unified diff line by line for terminal display
+func ColorizeDiff(diff string) string {
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	for i, line := range lines {
+		if color := diffLineColor(line); color != "" {
+			lines[i] = color + line + ansiReset
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// diffLineColor picks the color for one diff line
+func diffLineColor(line string) string {
+	switch {
+	case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+		return ansiBold
+	case strings.HasPrefix(line, "@@"):
+		return ansiCyan
+	case strings.HasPrefix(line, "+"):
+		return ansiGreen
+	case strings.HasPrefix(line, "-"):
+		return ansiRed
+	default:
+		return ""
+	}
+}
+
+// PageDiff writes a diff to w, pausing after every pageSize lines until the user
+// presses Enter; entering "q" skips the rest. A pageSize of zero or less disables paging.
+func PageDiff(w io.Writer, r *bufio.Reader, diff string, pageSize int, color bool) error {
+	if color {
+		diff = ColorizeDiff(diff)
+	}
+	lines := strings.SplitAfter(strings.TrimSuffix(diff, "\n")+"\n", "\n")
+	lines = lines[:len(lines)-1]
+
+	for shown := 0; shown < len(lines); {
+		end := len(lines)
+		if pageSize > 0 {
+			end = min(len(lines), shown+pageSize)
+		}
+		for _, line := range lines[shown:end] {
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+		shown = end
+		if shown == len(lines) {
+			break
+		}
+
+		fmt.Fprintf(w, "-- %d more diff lines: Enter to continue, q to skip --", len(lines)-shown)
+		input, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+		if strings.TrimSpace(strings.ToLower(input)) == "q" {
+			fmt.Fprintf(w, "(%d diff lines skipped)\n", len(lines)-shown)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package ui_test
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/shizhMSFT/wink-code/internal/ui"
+)
+
+const sampleDiff = "--- a/f.txt\n+++ b/f.txt\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"
+
+func TestDiffStat(t *testing.T) {
+	added, removed := ui.DiffStat(sampleDiff)
+	if added != 1 || removed != 1 {
+		t.Errorf("Expected +1 -1, got +%d -%d", added, removed)
+	}
+}
+
+func TestColorizeDiff(t *testing.T) {
+	colored := ui.ColorizeDiff(sampleDiff)
+
+	for _, want := range []string{"\033[31m-b\033[0m", "\033[32m+B\033[0m", "\033[36m@@ -1,3 +1,3 @@\033[0m", "\n a\n"} {
+		if !strings.Contains(colored, want) {
+			t.Errorf("Expected colored diff to contain %q, got %q", want, colored)
+		}
+	}
+}
+
+func TestPageDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		pageSize    int
+		input       string
+		wantLines   []string
+		wantMissing []string
+	}{
+		{
+			name:      "No paging",
+			pageSize:  0,
+			wantLines: []string{"--- a/f.txt", " c"},
+		},
+		{
+			name:      "Continue through pages",
+			pageSize:  3,
+			input:     "\n\n",
+			wantLines: []string{"-- 4 more diff lines", "-- 1 more diff lines", " c"},
+		},
+		{
+			name:        "Skip remaining pages",
+			pageSize:    3,
+			input:       "q\n",
+			wantLines:   []string{"@@ -1,3 +1,3 @@", "(4 diff lines skipped)"},
+			wantMissing: []string{"+B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			reader := bufio.NewReader(strings.NewReader(tt.input))
+			if err := ui.PageDiff(&out, reader, sampleDiff, tt.pageSize, false); err != nil {
+				t.Fatalf("PageDiff failed: %v", err)
+			}
+
+			for _, want := range tt.wantLines {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Expected output to contain %q, got %q", want, out.String())
+				}
+			}
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(out.String(), missing) {
+					t.Errorf("Expected output not to contain %q, got %q", missing, out.String())
+				}
+			}
+		})
+	}
+}
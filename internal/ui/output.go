@@ -5,10 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/shizhMSFT/wink-code/pkg/types"
+	"golang.org/x/term"
 )
 
+// maxThinkingLines is how many reasoning lines are shown before collapsing the rest
+const maxThinkingLines = 8
+
 // Formatter handles output formatting
 type Formatter struct {
 	format types.OutputFormat
@@ -94,3 +99,22 @@ func PrintSuccess(message string) {
 func PrintWarning(message string) {
 	fmt.Fprintf(os.Stderr, "⚠ %s\n", message)
 }
+
+// PrintThinking prints model reasoning dimmed to stderr, collapsed to the first few lines
+func PrintThinking(reasoning string) {
+	lines := strings.Split(strings.TrimSpace(reasoning), "\n")
+	shown := lines
+	if len(lines) > maxThinkingLines {
+		shown = lines[:maxThinkingLines]
+	}
+
+	text := "💭 " + strings.Join(shown, "\n   ")
+	if len(lines) > maxThinkingLines {
+		text += fmt.Sprintf("\n   … (%d more lines of reasoning)", len(lines)-maxThinkingLines)
+	}
+
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		text = "\033[2m" + text + "\033[0m"
+	}
+	fmt.Fprintln(os.Stderr, text)
+}
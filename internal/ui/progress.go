@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"time"
 
 	"golang.org/x/term"
@@ -104,3 +105,75 @@ func formatDuration(d time.Duration) string {
 	seconds := int(d.Seconds()) % 60
 	return fmt.Sprintf("%dm%ds", minutes, seconds)
 }
+
+// ProgressBar displays determinate progress such as a model download
+type ProgressBar struct {
+	writer     io.Writer
+	lastStatus string
+	isTTY      bool
+}
+
+// progressBarWidth is the number of cells in the rendered bar
+const progressBarWidth = 30
+
+// NewProgressBar creates a new progress bar
+func NewProgressBar() *ProgressBar {
+	return &ProgressBar{
+		writer: os.Stderr,
+		isTTY:  term.IsTerminal(int(os.Stderr.Fd())),
+	}
+}
+
+// Update renders the bar for the given status and byte counts (total may be 0 if unknown)
+func (b *ProgressBar) Update(status string, completed, total int64) {
+	if !b.isTTY {
+		// In non-TTY environments, only print status transitions
+		if status != b.lastStatus {
+			fmt.Fprintf(b.writer, "%s\n", status)
+			b.lastStatus = status
+		}
+		return
+	}
+
+	if status != b.lastStatus && b.lastStatus != "" {
+		// Keep the finished step visible and start a new line
+		fmt.Fprintf(b.writer, "\n")
+	}
+	b.lastStatus = status
+
+	if total <= 0 {
+		fmt.Fprintf(b.writer, "\r\033[K%s", status)
+		return
+	}
+
+	if completed > total {
+		completed = total
+	}
+	filled := int(float64(progressBarWidth) * float64(completed) / float64(total))
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	percent := float64(completed) * 100 / float64(total)
+
+	fmt.Fprintf(b.writer, "\r\033[K%s [%s] %3.0f%% (%s/%s)",
+		status, bar, percent, FormatBytes(completed), FormatBytes(total))
+}
+
+// Finish ends the progress bar output
+func (b *ProgressBar) Finish() {
+	if b.isTTY && b.lastStatus != "" {
+		fmt.Fprintf(b.writer, "\n")
+	}
+}
+
+// FormatBytes formats a byte count for display (e.g. "1.2 GB")
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/shizhMSFT/wink-code/pkg/types"
+	"golang.org/x/term"
 )
 
 // ApprovalResponse represents the user's approval decision
@@ -23,8 +24,20 @@ const (
 	ApprovalResponseAlways ApprovalResponse = "always"
 )
 
-// PromptForApproval asks the user to approve a tool operation
-func PromptForApproval(toolName string, params map[string]interface{}, tool types.Tool) (ApprovalResponse, error) {
+// previewedParams are parameters whose effect is shown by the diff preview instead
+var previewedParams = map[string]bool{
+	"content":    true,
+	"old_string": true,
+	"new_string": true,
+	"edits":      true,
+	"patch":      true,
+}
+
+// PromptForApproval asks the user to approve a tool operation. When preview is
+// non-nil its diff is shown (colored and paged on a terminal) instead of the raw content.
+func PromptForApproval(toolName string, params map[string]interface{}, tool types.Tool, preview *types.ToolPreview) (ApprovalResponse, error) {
+	reader := bufio.NewReader(os.Stdin)
+
 	// Display operation details to stderr (keeps stdout clean)
 	fmt.Fprintf(os.Stderr, "\n┌─────────────────────────────────────────┐\n")
 	fmt.Fprintf(os.Stderr, "│ Tool Approval Required                  │\n")
@@ -41,6 +54,14 @@ func PromptForApproval(toolName string, params map[string]interface{}, tool type
 
 	// Display parameters more nicely
 	for key, value := range params {
+		if preview != nil && previewedParams[key] {
+			fmt.Fprintf(os.Stderr, "│   %-37s │\n", truncate(key+": (see diff below)", 37))
+			continue
+		}
+		if key == "patch" {
+			fmt.Fprintf(os.Stderr, "│   %-37s │\n", "patch: (shown in full below)")
+			continue
+		}
 		valueStr := formatParamValue(value)
 		lines := splitIntoLines(fmt.Sprintf("%s: %s", key, valueStr), 37)
 		for i, line := range lines {
@@ -52,13 +73,34 @@ func PromptForApproval(toolName string, params map[string]interface{}, tool type
 		}
 	}
 
-	// Show files affected if path parameter exists
-	if path, ok := params["path"].(string); ok {
+	// Show files affected, preferring what the preview computed
+	if preview != nil && len(preview.FilesAffected) > 0 {
+		added, removed := DiffStat(preview.Diff)
+		fmt.Fprintf(os.Stderr, "├─────────────────────────────────────────┤\n")
+		fmt.Fprintf(os.Stderr, "│ Files affected: %-23s │\n", truncate(strings.Join(preview.FilesAffected, ", "), 23))
+		fmt.Fprintf(os.Stderr, "│ Changes: %-30s │\n", fmt.Sprintf("+%d -%d lines", added, removed))
+	} else if path, ok := params["path"].(string); ok {
 		fmt.Fprintf(os.Stderr, "├─────────────────────────────────────────┤\n")
 		fmt.Fprintf(os.Stderr, "│ Files affected: %-23s │\n", truncate(path, 23))
 	}
 
 	fmt.Fprintf(os.Stderr, "└─────────────────────────────────────────┘\n")
+
+	switch {
+	case preview != nil && preview.Diff != "":
+		fmt.Fprintln(os.Stderr)
+		if err := PageDiff(os.Stderr, reader, preview.Diff, diffPageSize(), term.IsTerminal(int(os.Stderr.Fd()))); err != nil {
+			return ApprovalResponseNo, err
+		}
+	case preview != nil:
+		fmt.Fprintf(os.Stderr, "\n(no changes)\n")
+	default:
+		// Patches are reviewed in full rather than truncated
+		if patch, ok := params["patch"].(string); ok {
+			fmt.Fprintf(os.Stderr, "\n%s\n", strings.TrimRight(patch, "\n"))
+		}
+	}
+
 	fmt.Fprintf(os.Stderr, "\nApprove this operation?\n")
 	fmt.Fprintf(os.Stderr, "  (y)es    - Approve once\n")
 	fmt.Fprintf(os.Stderr, "  (n)o     - Reject\n")
@@ -66,7 +108,6 @@ func PromptForApproval(toolName string, params map[string]interface{}, tool type
 	fmt.Fprintf(os.Stderr, "\nYour choice: ")
 
 	// Read user input
-	reader := bufio.NewReader(os.Stdin)
 	input, err := reader.ReadString('\n')
 	if err != nil {
 		return ApprovalResponseNo, fmt.Errorf("failed to read input: %w", err)
@@ -87,6 +128,20 @@ func PromptForApproval(toolName string, params map[string]interface{}, tool type
 	}
 }
 
+// diffPageSize returns how many diff lines fit on the terminal, or 0 to disable
+// paging when not interactive
+func diffPageSize() int {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return 0
+	}
+	_, height, err := term.GetSize(int(os.Stderr.Fd()))
+	if err != nil || height < 10 {
+		return 0
+	}
+	// Leave room for the pager prompt
+	return height - 2
+}
+
 // formatRiskLevel formats risk level with appropriate label
 func formatRiskLevel(level types.RiskLevel) string {
 	switch level {
@@ -0,0 +1,137 @@
+// Package usage records LLM token usage to a local ledger
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	usageDir = ".wink/usage"
+)
+
+// Record is a single LLM call in the ledger
+type Record struct {
+	Timestamp        time.Time `json:"timestamp"`
+	SessionID        string    `json:"session_id"`
+	Model            string    `json:"model"`
+	ProjectDir       string    `json:"project_dir"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+}
+
+// Ledger appends usage records to monthly JSONL files under ~/.wink/usage
+type Ledger struct {
+	usagePath string
+}
+
+// NewLedger creates a ledger in the user's home directory
+func NewLedger() (*Ledger, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	usagePath := filepath.Join(homeDir, usageDir)
+
+	// Create usage directory if it doesn't exist
+	if err := os.MkdirAll(usagePath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create usage directory: %w", err)
+	}
+
+	return &Ledger{
+		usagePath: usagePath,
+	}, nil
+}
+
+// Append adds a record to the ledger file for the record's month
+func (l *Ledger) Append(record Record) error {
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record: %w", err)
+	}
+
+	filePath := filepath.Join(l.usagePath, record.Timestamp.Format("2006-01")+".jsonl")
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write usage record: %w", err)
+	}
+
+	return nil
+}
+
+// Read returns all records at or after since (zero time means all records)
+func (l *Ledger) Read(since time.Time) ([]Record, error) {
+	entries, err := os.ReadDir(l.usagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage directory: %w", err)
+	}
+
+	var records []Record
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".jsonl" {
+			continue
+		}
+
+		// Skip monthly files entirely before the cutoff
+		month, err := time.ParseInLocation("2006-01", strings.TrimSuffix(entry.Name(), ".jsonl"), time.Local)
+		if err == nil && !since.IsZero() && month.AddDate(0, 1, 0).Before(since) {
+			continue
+		}
+
+		fileRecords, err := readLedgerFile(filepath.Join(l.usagePath, entry.Name()), since)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, fileRecords...)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+
+	return records, nil
+}
+
+// readLedgerFile parses one JSONL ledger file, skipping malformed lines
+func readLedgerFile(path string, since time.Time) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage ledger: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // Tolerate partially written lines
+		}
+		if !since.IsZero() && record.Timestamp.Before(since) {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage ledger: %w", err)
+	}
+
+	return records, nil
+}
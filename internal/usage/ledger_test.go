@@ -0,0 +1,102 @@
+package usage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/usage"
+)
+
+// newTestLedger creates a ledger in a temporary home directory
+func newTestLedger(t *testing.T) *usage.Ledger {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	ledger, err := usage.NewLedger()
+	if err != nil {
+		t.Fatalf("NewLedger failed: %v", err)
+	}
+	return ledger
+}
+
+func TestLedgerAppendAndRead(t *testing.T) {
+	ledger := newTestLedger(t)
+
+	now := time.Now()
+	records := []usage.Record{
+		{Timestamp: now.AddDate(0, -2, 0), SessionID: "s0", Model: "qwen3:8b", PromptTokens: 10, CompletionTokens: 5},
+		{Timestamp: now.Add(-time.Hour), SessionID: "s1", Model: "qwen3:8b", PromptTokens: 100, CompletionTokens: 20, LatencyMs: 1000},
+		{Timestamp: now, SessionID: "s1", Model: "qwen3:8b", PromptTokens: 150, CompletionTokens: 30, LatencyMs: 3000},
+	}
+	for _, r := range records {
+		if err := ledger.Append(r); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	all, err := ledger.Read(time.Time{})
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(all))
+	}
+	if all[0].SessionID != "s0" {
+		t.Errorf("expected records sorted by time, got first %s", all[0].SessionID)
+	}
+
+	recent, err := ledger.Read(now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(recent) != 2 {
+		t.Errorf("expected 2 recent records, got %d", len(recent))
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	day1 := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+
+	records := []usage.Record{
+		{Timestamp: day1, SessionID: "a", Model: "qwen3:8b", ProjectDir: "/p1", PromptTokens: 100, CompletionTokens: 10, LatencyMs: 100},
+		{Timestamp: day1, SessionID: "a", Model: "qwen3:8b", ProjectDir: "/p1", PromptTokens: 200, CompletionTokens: 20, LatencyMs: 300},
+		{Timestamp: day2, SessionID: "b", Model: "qwen3-coder:30b", ProjectDir: "/p2", PromptTokens: 1000, CompletionTokens: 100, LatencyMs: 2000},
+	}
+
+	byDay := usage.Aggregate(records, usage.GroupByDay)
+	if len(byDay) != 2 || byDay[0].Key != "2025-03-01" || byDay[1].Key != "2025-03-02" {
+		t.Fatalf("unexpected day grouping: %+v", byDay)
+	}
+	if byDay[0].Calls != 2 || byDay[0].TotalTokens != 330 || byDay[0].AvgLatencyMs != 200 {
+		t.Errorf("unexpected day summary: %+v", byDay[0])
+	}
+
+	byModel := usage.Aggregate(records, usage.GroupByModel)
+	if byModel[0].Key != "qwen3-coder:30b" {
+		t.Errorf("expected highest-usage model first, got %s", byModel[0].Key)
+	}
+
+	bySession := usage.Aggregate(records, usage.GroupBySession)
+	if len(bySession) != 2 {
+		t.Errorf("expected 2 sessions, got %d", len(bySession))
+	}
+
+	total := usage.Total(byDay)
+	if total.Calls != 3 || total.TotalTokens != 1430 {
+		t.Errorf("unexpected total: %+v", total)
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	for _, valid := range []string{"day", "model", "project", "session"} {
+		if _, err := usage.ParseGroupBy(valid); err != nil {
+			t.Errorf("ParseGroupBy(%q) failed: %v", valid, err)
+		}
+	}
+	if _, err := usage.ParseGroupBy("week"); err == nil {
+		t.Error("expected error for invalid grouping")
+	}
+}
@@ -0,0 +1,109 @@
+// Package usage aggregates ledger records into reports
+package usage
+
+import (
+	"fmt"
+	"sort"
+)
+
+// GroupBy selects the dimension records are aggregated by
+type GroupBy string
+
+const (
+	// GroupByDay - Aggregate by local calendar day
+	GroupByDay GroupBy = "day"
+	// GroupByModel - Aggregate by model name
+	GroupByModel GroupBy = "model"
+	// GroupByProject - Aggregate by project (working) directory
+	GroupByProject GroupBy = "project"
+	// GroupBySession - Aggregate by session ID
+	GroupBySession GroupBy = "session"
+)
+
+// Summary is the aggregated usage for one group
+type Summary struct {
+	Key              string `json:"key"`
+	Calls            int    `json:"calls"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+	TotalLatencyMs   int64  `json:"total_latency_ms"`
+	AvgLatencyMs     int64  `json:"avg_latency_ms"`
+}
+
+// ParseGroupBy validates a grouping name
+func ParseGroupBy(s string) (GroupBy, error) {
+	switch GroupBy(s) {
+	case GroupByDay, GroupByModel, GroupByProject, GroupBySession:
+		return GroupBy(s), nil
+	default:
+		return "", fmt.Errorf("invalid grouping '%s' (expected day, model, project or session)", s)
+	}
+}
+
+// Aggregate groups records and sums their usage. Days are sorted chronologically,
+// other groupings by total tokens (highest first).
+func Aggregate(records []Record, by GroupBy) []Summary {
+	byKey := make(map[string]*Summary)
+	for _, r := range records {
+		key := groupKey(r, by)
+		s, ok := byKey[key]
+		if !ok {
+			s = &Summary{Key: key}
+			byKey[key] = s
+		}
+		s.Calls++
+		s.PromptTokens += r.PromptTokens
+		s.CompletionTokens += r.CompletionTokens
+		s.TotalTokens += r.PromptTokens + r.CompletionTokens
+		s.TotalLatencyMs += r.LatencyMs
+	}
+
+	summaries := make([]Summary, 0, len(byKey))
+	for _, s := range byKey {
+		s.AvgLatencyMs = s.TotalLatencyMs / int64(s.Calls)
+		summaries = append(summaries, *s)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if by == GroupByDay {
+			return summaries[i].Key < summaries[j].Key
+		}
+		if summaries[i].TotalTokens != summaries[j].TotalTokens {
+			return summaries[i].TotalTokens > summaries[j].TotalTokens
+		}
+		return summaries[i].Key < summaries[j].Key
+	})
+
+	return summaries
+}
+
+// Total sums all summaries into a single row
+func Total(summaries []Summary) Summary {
+	total := Summary{Key: "total"}
+	for _, s := range summaries {
+		total.Calls += s.Calls
+		total.PromptTokens += s.PromptTokens
+		total.CompletionTokens += s.CompletionTokens
+		total.TotalTokens += s.TotalTokens
+		total.TotalLatencyMs += s.TotalLatencyMs
+	}
+	if total.Calls > 0 {
+		total.AvgLatencyMs = total.TotalLatencyMs / int64(total.Calls)
+	}
+	return total
+}
+
+// groupKey returns the grouping key of a record
+func groupKey(r Record, by GroupBy) string {
+	switch by {
+	case GroupByModel:
+		return r.Model
+	case GroupByProject:
+		return r.ProjectDir
+	case GroupBySession:
+		return r.SessionID
+	default:
+		return r.Timestamp.Local().Format("2006-01-02")
+	}
+}
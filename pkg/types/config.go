@@ -13,13 +13,52 @@ const (
 
 // Config represents user configuration and preferences
 type Config struct {
-	ConfigVersion      string         `json:"config_version"`
-	DefaultModel       string         `json:"default_model"`
-	OllamaBaseURL      string         `json:"ollama_base_url"`
-	APITimeoutSeconds  int            `json:"api_timeout_seconds"`
-	MaxSessionMessages int            `json:"max_session_messages"`
-	AutoApprovalRules  []ApprovalRule `json:"auto_approval_rules"`
-	OutputFormat       OutputFormat   `json:"output_format"`
+	ConfigVersion      string                  `json:"config_version"`
+	DefaultModel       string                  `json:"default_model"`
+	OllamaBaseURL      string                  `json:"ollama_base_url"`
+	APITimeoutSeconds  int                     `json:"api_timeout_seconds"`
+	MaxSessionMessages int                     `json:"max_session_messages"`
+	AutoApprovalRules  []ApprovalRule          `json:"auto_approval_rules"`
+	OutputFormat       OutputFormat            `json:"output_format"`
+	Profiles           map[string]ModelProfile `json:"profiles,omitempty"`
+	MaxImageDimension  int                     `json:"max_image_dimension,omitempty"`
+	LoadTimeoutSeconds int                     `json:"load_timeout_seconds,omitempty"`
+	KeepAlive          string                  `json:"keep_alive,omitempty"`
+	PostEdit           *PostEditConfig         `json:"post_edit,omitempty"`
+	SearchIndex        bool                    `json:"search_index,omitempty"`
+	EmbeddingModel     string                  `json:"embedding_model,omitempty"`
+}
+
+// PostEditConfig controls the formatting and syntax checks run on files the agent writes,
+// e.g. "post_edit": {"formatters": {".py": ["black", "-q", "-"]}}
+type PostEditConfig struct {
+	// Format applies gofmt to Go files and the configured formatters (default: true)
+	Format *bool `json:"format,omitempty"`
+	// Validate reports syntax errors in Go, JSON, YAML and TOML files to the model (default: true)
+	Validate *bool `json:"validate,omitempty"`
+	// Formatters maps a file extension to a command that reads the file on stdin and writes
+	// it formatted to stdout; "{path}" in an argument is replaced by the file's path
+	Formatters map[string][]string `json:"formatters,omitempty"`
+	// TimeoutSeconds bounds each formatter run (default: 10)
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// GenerationOptions holds per-request sampling options (nil fields use the server default)
+type GenerationOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	NumCtx      *int     `json:"num_ctx,omitempty"`
+	Think       *bool    `json:"think,omitempty"`
+}
+
+// ModelProfile is a named model with its generation options,
+// e.g. "coder": {"model": "qwen3-coder:30b", "temperature": 0.2, "num_ctx": 32768}
+type ModelProfile struct {
+	Model string `json:"model"`
+	GenerationOptions
 }
 
 // DefaultConfig returns a config with sensible defaults
@@ -32,5 +71,7 @@ func DefaultConfig() *Config {
 		MaxSessionMessages: 100,
 		AutoApprovalRules:  []ApprovalRule{},
 		OutputFormat:       OutputFormatHuman,
+		MaxImageDimension:  1024,
+		LoadTimeoutSeconds: 300,
 	}
 }
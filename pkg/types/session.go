@@ -33,25 +33,56 @@ const (
 
 // Session represents a conversation session
 type Session struct {
-	ID          string        `json:"id"`
-	WorkingDir  string        `json:"working_dir"`
-	Model       string        `json:"model"`
-	CreatedAt   time.Time     `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
-	Messages    []Message     `json:"messages"`
-	ToolResults []ToolResult  `json:"tool_results"`
-	Status      SessionStatus `json:"status"`
+	ID                string              `json:"id"`
+	WorkingDir        string              `json:"working_dir"`
+	Model             string              `json:"model"`
+	Profile           string              `json:"profile,omitempty"`
+	GenerationOptions *GenerationOptions  `json:"generation_options,omitempty"`
+	CreatedAt         time.Time           `json:"created_at"`
+	UpdatedAt         time.Time           `json:"updated_at"`
+	Messages          []Message           `json:"messages"`
+	ToolResults       []ToolResult        `json:"tool_results"`
+	Status            SessionStatus       `json:"status"`
+	Turns             int                 `json:"turns,omitempty"`      // Number of prompts run in this session
+	FileReads         map[string]FileRead `json:"file_reads,omitempty"` // Files as the agent last saw them, by absolute path
 }
 
+// FileRead records the state of a file when the agent last read or wrote it
+type FileRead struct {
+	Hash    string    `json:"hash"` // Hex SHA-256 of the content
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// ContentPartType identifies the kind of a message content part
+type ContentPartType string
+
+const (
+	// ContentPartText - Plain text
+	ContentPartText ContentPartType = "text"
+	// ContentPartImage - Base64-encoded image
+	ContentPartImage ContentPartType = "image"
+)
+
 // Message represents a single message in the conversation
 type Message struct {
 	Role      MessageRole            `json:"role"`
 	Content   string                 `json:"content"`
+	Parts     []ContentPart          `json:"parts,omitempty"` // Additional parts (e.g. images) sent after Content
 	Timestamp time.Time              `json:"timestamp"`
 	ToolCalls []ToolCall             `json:"tool_calls,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 }
 
+// ContentPart is one part of a multi-part message
+type ContentPart struct {
+	Type     ContentPartType `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	MIMEType string          `json:"mime_type,omitempty"`
+	Data     string          `json:"data,omitempty"`   // Base64-encoded image bytes
+	Source   string          `json:"source,omitempty"` // Workspace path the image was loaded from
+}
+
 // ToolCall represents a request from the LLM to execute a tool
 type ToolCall struct {
 	ID         string                 `json:"id"`
@@ -48,4 +48,38 @@ type ToolResult struct {
 	ExecutionTimeMs int64                  `json:"execution_time_ms"`
 	FilesAffected   []string               `json:"files_affected,omitempty"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	Attachments     []ContentPart          `json:"-"` // Content (e.g. images) to attach to the next request
+}
+
+// ToolPreview describes the change a tool call would make, computed before it runs
+type ToolPreview struct {
+	// Diff is a unified diff of the change; new files appear as all-added diffs against /dev/null
+	Diff          string   `json:"diff"`
+	FilesAffected []string `json:"files_affected,omitempty"`
+}
+
+// PreviewableTool is a tool that can show its effect before execution
+type PreviewableTool interface {
+	Tool
+
+	// Preview computes the change Execute would make without writing anything
+	Preview(params map[string]interface{}, workingDir string) (*ToolPreview, error)
+}
+
+// FileReadingTool is a tool that shows file contents to the model, so later edits
+// made from what it showed can be checked for staleness
+type FileReadingTool interface {
+	Tool
+
+	// ReadPaths returns the absolute paths of the files the call reads
+	ReadPaths(params map[string]interface{}, workingDir string) ([]string, error)
+}
+
+// FileModifyingTool is a tool whose changes to workspace files can be checkpointed
+type FileModifyingTool interface {
+	Tool
+
+	// TargetPaths returns the absolute paths (files or directories) the call may
+	// create, change or delete
+	TargetPaths(params map[string]interface{}, workingDir string) ([]string, error)
 }
@@ -0,0 +1,362 @@
+package integration_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shizhMSFT/wink-code/internal/agent"
+	"github.com/shizhMSFT/wink-code/internal/checkpoint"
+	"github.com/shizhMSFT/wink-code/internal/llm/llmtest"
+	"github.com/shizhMSFT/wink-code/internal/tools"
+	"github.com/shizhMSFT/wink-code/internal/ui"
+	"github.com/shizhMSFT/wink-code/pkg/types"
+)
+
+// newScriptedAgent creates an agent talking to a fake LLM server, with sessions and
+// usage kept in a temporary home directory and every tool call approved
+func newScriptedAgent(t *testing.T, server *llmtest.Server) *agent.Agent {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	a, err := agent.NewAgent(server.URL, "fake:latest", 5)
+	if err != nil {
+		t.Fatalf("Failed to create agent: %v", err)
+	}
+	for _, tool := range []types.Tool{
+		tools.NewCreateFileTool(),
+		tools.NewReadFileTool(),
+		tools.NewRunInTerminalTool(),
+	} {
+		if err := a.RegisterTool(tool); err != nil {
+			t.Fatalf("Failed to register %s: %v", tool.Name(), err)
+		}
+	}
+	a.SetApprovalPrompter(func(string, map[string]interface{}, types.Tool, *types.ToolPreview) (ui.ApprovalResponse, error) {
+		return ui.ApprovalResponseYes, nil
+	})
+	return a
+}
+
+// TestAgentScriptedWorkflow runs complete agent turns against a scripted model
+func TestAgentScriptedWorkflow(t *testing.T) {
+	t.Run("Create file, run command, answer", func(t *testing.T) {
+		workDir := t.TempDir()
+
+		catCmd := "cat hello.txt"
+		if runtime.GOOS == "windows" {
+			catCmd = "type hello.txt"
+		}
+
+		server := llmtest.NewServer(t,
+			llmtest.CallTool("create_file", map[string]interface{}{
+				"path":    "hello.txt",
+				"content": "hello from the agent",
+			}).Expect(func(t testing.TB, req *llmtest.Request) {
+				if !req.HasTool("create_file") || !req.HasTool("run_in_terminal") {
+					t.Errorf("Expected create_file and run_in_terminal to be offered")
+				}
+				if got := req.LastMessage().Content; got != "create hello.txt and show it" {
+					t.Errorf("Expected user prompt as last message, got %q", got)
+				}
+			}),
+			llmtest.CallTool("run_in_terminal", map[string]interface{}{
+				"command": catCmd,
+			}).Expect(func(t testing.TB, req *llmtest.Request) {
+				last := req.LastMessage()
+				if last.Role != "tool" || last.ToolCallID != "call_1" {
+					t.Errorf("Expected tool result for call_1, got role=%q id=%q", last.Role, last.ToolCallID)
+				}
+			}),
+			llmtest.Reply("The file says hello.").Expect(func(t testing.TB, req *llmtest.Request) {
+				last := req.LastMessage()
+				if !strings.Contains(last.Content, "hello from the agent") {
+					t.Errorf("Expected command output in tool result, got %q", last.Content)
+				}
+			}),
+		)
+
+		a := newScriptedAgent(t, server)
+		if err := a.Run(context.Background(), "create hello.txt and show it", workDir, false); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		content, err := os.ReadFile(filepath.Join(workDir, "hello.txt"))
+		if err != nil {
+			t.Fatalf("Expected hello.txt to be created: %v", err)
+		}
+		if string(content) != "hello from the agent" {
+			t.Errorf("Unexpected file content: %q", content)
+		}
+		if n := len(server.Requests()); n != 3 {
+			t.Errorf("Expected 3 LLM requests, got %d", n)
+		}
+		if n := len(server.Loads()); n != 1 {
+			t.Errorf("Expected the model to be loaded once before the first request, got %d loads", n)
+		}
+	})
+
+	t.Run("Loaded model is not loaded again", func(t *testing.T) {
+		server := llmtest.NewServer(t, llmtest.Reply("hi"))
+		server.SetLoaded(true)
+
+		a := newScriptedAgent(t, server)
+		if err := a.Run(context.Background(), "hi", t.TempDir(), false); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		if n := len(server.Loads()); n != 0 {
+			t.Errorf("Expected no load request, got %d", n)
+		}
+	})
+
+	t.Run("Context window is sent as num_ctx", func(t *testing.T) {
+		numCtxOf := func(body map[string]interface{}) interface{} {
+			options, _ := body["options"].(map[string]interface{})
+			return options["num_ctx"]
+		}
+		tests := []struct {
+			name          string
+			contextLength int
+			profileNumCtx int
+			want          float64
+		}{
+			{name: "model context length", contextLength: 16384, want: 16384},
+			{name: "large model context is capped", contextLength: 131072, want: 32768},
+			{name: "profile num_ctx wins", contextLength: 131072, profileNumCtx: 65536, want: 65536},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := llmtest.NewServer(t, llmtest.Reply("hi").Expect(func(t testing.TB, req *llmtest.Request) {
+					if got := numCtxOf(req.Raw); got != tt.want {
+						t.Errorf("Expected options.num_ctx %v in the chat request, got %v", tt.want, got)
+					}
+				}))
+				server.SetCapabilities(tt.contextLength, "completion", "tools")
+
+				a := newScriptedAgent(t, server)
+				if tt.profileNumCtx > 0 {
+					numCtx := tt.profileNumCtx
+					a.SetGenerationOptions("big", types.GenerationOptions{NumCtx: &numCtx})
+				}
+				if err := a.Run(context.Background(), "hi", t.TempDir(), false); err != nil {
+					t.Fatalf("Run failed: %v", err)
+				}
+				if loads := server.Loads(); len(loads) != 1 || numCtxOf(loads[0]) != tt.want {
+					t.Errorf("Expected the model loaded with num_ctx %v, got %v", tt.want, loads)
+				}
+			})
+		}
+	})
+
+	t.Run("Slow model load is reported as a load timeout", func(t *testing.T) {
+		server := llmtest.NewServer(t)
+		server.SetLoadDelay(2 * time.Second)
+
+		a := newScriptedAgent(t, server)
+		a.SetModelLoading(100*time.Millisecond, "10m")
+		err := a.Run(context.Background(), "hi", t.TempDir(), false)
+		if err == nil || !strings.Contains(err.Error(), "did not finish loading") || !strings.Contains(err.Error(), "--load-timeout") {
+			t.Fatalf("Expected load timeout error, got %v", err)
+		}
+		if loads := server.Loads(); len(loads) != 1 || loads[0]["keep_alive"] != "10m" {
+			t.Errorf("Expected one load request with keep_alive 10m, got %v", loads)
+		}
+	})
+
+	t.Run("Slow response is reported as a generation timeout", func(t *testing.T) {
+		server := llmtest.NewServer(t, llmtest.Reply("late").After(2*time.Second))
+		server.SetLoaded(true)
+
+		t.Setenv("HOME", t.TempDir())
+		a, err := agent.NewAgent(server.URL, "fake:latest", 1)
+		if err != nil {
+			t.Fatalf("Failed to create agent: %v", err)
+		}
+		err = a.Run(context.Background(), "hi", t.TempDir(), false)
+		if err == nil || !strings.Contains(err.Error(), "timed out after 1s") || !strings.Contains(err.Error(), "--timeout") {
+			t.Fatalf("Expected generation timeout error, got %v", err)
+		}
+	})
+
+	t.Run("Rejected tool call is reported to the model", func(t *testing.T) {
+		workDir := t.TempDir()
+
+		server := llmtest.NewServer(t,
+			llmtest.CallTool("create_file", map[string]interface{}{
+				"path":    "denied.txt",
+				"content": "nope",
+			}),
+			llmtest.Reply("Okay, I won't.").Expect(func(t testing.TB, req *llmtest.Request) {
+				if last := req.LastMessage(); last.Role != "tool" {
+					t.Errorf("Expected tool result as last message, got role %q", last.Role)
+				}
+			}),
+		)
+
+		a := newScriptedAgent(t, server)
+		a.SetApprovalPrompter(func(string, map[string]interface{}, types.Tool, *types.ToolPreview) (ui.ApprovalResponse, error) {
+			return ui.ApprovalResponseNo, nil
+		})
+		if err := a.Run(context.Background(), "create denied.txt", workDir, false); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		if _, err := os.Stat(filepath.Join(workDir, "denied.txt")); !os.IsNotExist(err) {
+			t.Errorf("Expected denied.txt not to be created")
+		}
+	})
+
+	t.Run("Approval prompt shows a diff preview", func(t *testing.T) {
+		workDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(workDir, "notes.txt"), []byte("keep\nold line\n"), 0644); err != nil {
+			t.Fatalf("Failed to write notes.txt: %v", err)
+		}
+
+		server := llmtest.NewServer(t,
+			llmtest.CallTool("replace_string_in_file", map[string]interface{}{
+				"path":       "notes.txt",
+				"old_string": "old line",
+				"new_string": "new line",
+			}),
+			llmtest.Reply("Updated."),
+		)
+
+		a := newScriptedAgent(t, server)
+		if err := a.RegisterTool(tools.NewReplaceStringInFileTool()); err != nil {
+			t.Fatalf("Failed to register replace_string_in_file: %v", err)
+		}
+		var preview *types.ToolPreview
+		a.SetApprovalPrompter(func(_ string, _ map[string]interface{}, _ types.Tool, p *types.ToolPreview) (ui.ApprovalResponse, error) {
+			preview = p
+			return ui.ApprovalResponseYes, nil
+		})
+		if err := a.Run(context.Background(), "update notes.txt", workDir, false); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+
+		if preview == nil {
+			t.Fatal("Expected a preview for replace_string_in_file")
+		}
+		if !strings.Contains(preview.Diff, "-old line\n+new line\n") {
+			t.Errorf("Expected preview diff of the replacement, got %q", preview.Diff)
+		}
+		content, _ := os.ReadFile(filepath.Join(workDir, "notes.txt"))
+		if string(content) != "keep\nnew line\n" {
+			t.Errorf("Expected replacement to be applied, got %q", string(content))
+		}
+	})
+
+	t.Run("File changes can be undone per turn", func(t *testing.T) {
+		workDir := t.TempDir()
+		notes := filepath.Join(workDir, "notes.txt")
+		extra := filepath.Join(workDir, "extra.txt")
+
+		server := llmtest.NewServer(t,
+			// Turn 1
+			llmtest.CallTool("create_file", map[string]interface{}{"path": "notes.txt", "content": "first\n"}),
+			llmtest.Reply("Created."),
+			// Turn 2
+			llmtest.CallTools(
+				llmtest.ToolCall{Name: "replace_string_in_file", Arguments: map[string]interface{}{
+					"path": "notes.txt", "old_string": "first", "new_string": "second",
+				}},
+				llmtest.ToolCall{Name: "create_file", Arguments: map[string]interface{}{"path": "extra.txt", "content": "x"}},
+			),
+			llmtest.Reply("Updated."),
+		)
+
+		a := newScriptedAgent(t, server)
+		if err := a.RegisterTool(tools.NewReplaceStringInFileTool()); err != nil {
+			t.Fatalf("Failed to register replace_string_in_file: %v", err)
+		}
+		if err := a.Run(context.Background(), "create notes", workDir, false); err != nil {
+			t.Fatalf("Run 1 failed: %v", err)
+		}
+		if err := a.Run(context.Background(), "update notes", workDir, true); err != nil {
+			t.Fatalf("Run 2 failed: %v", err)
+		}
+
+		store, err := checkpoint.Latest(workDir)
+		if err != nil {
+			t.Fatalf("Expected checkpoints for the workspace: %v", err)
+		}
+		if got := store.LastTurn(); got != 2 {
+			t.Fatalf("Expected last turn 2, got %d", got)
+		}
+
+		// Undo turn 2
+		if _, err := store.Undo(1, false); err != nil {
+			t.Fatalf("Undo failed: %v", err)
+		}
+		if content, _ := os.ReadFile(notes); string(content) != "first\n" {
+			t.Errorf("Expected notes.txt as after turn 1, got %q", string(content))
+		}
+		if _, err := os.Stat(extra); !os.IsNotExist(err) {
+			t.Errorf("Expected extra.txt removed")
+		}
+
+		// Undo turn 1
+		if _, err := store.Undo(0, false); err != nil {
+			t.Fatalf("Undo failed: %v", err)
+		}
+		if _, err := os.Stat(notes); !os.IsNotExist(err) {
+			t.Errorf("Expected notes.txt removed")
+		}
+	})
+
+	t.Run("Edits after an external change require a re-read", func(t *testing.T) {
+		workDir := t.TempDir()
+		notes := filepath.Join(workDir, "notes.txt")
+		if err := os.WriteFile(notes, []byte("draft\n"), 0644); err != nil {
+			t.Fatalf("setup failed: %v", err)
+		}
+
+		replace := map[string]interface{}{"path": "notes.txt", "old_string": "draft", "new_string": "final"}
+		server := llmtest.NewServer(t,
+			llmtest.CallTool("read_file", map[string]interface{}{"path": "notes.txt"}),
+			// A command changes the file behind the agent's back
+			llmtest.CallTool("run_in_terminal", map[string]interface{}{"command": "echo appended>> notes.txt"}),
+			llmtest.CallTool("replace_string_in_file", replace),
+			llmtest.CallTool("read_file", map[string]interface{}{"path": "notes.txt"}).Expect(func(t testing.TB, req *llmtest.Request) {
+				msg := req.LastMessage().Content
+				if !strings.Contains(msg, "changed on disk since it was last read") || !strings.Contains(msg, "+appended") {
+					t.Errorf("Expected a stale read refusal with a diff, got %q", msg)
+				}
+			}),
+			llmtest.CallTool("replace_string_in_file", replace),
+			llmtest.Reply("Done.").Expect(func(t testing.TB, req *llmtest.Request) {
+				if msg := req.LastMessage().Content; !strings.Contains(msg, "Replaced 1 occurrence") {
+					t.Errorf("Expected the edit to succeed after re-reading, got %q", msg)
+				}
+			}),
+		)
+
+		a := newScriptedAgent(t, server)
+		if err := a.RegisterTool(tools.NewReplaceStringInFileTool()); err != nil {
+			t.Fatalf("Failed to register replace_string_in_file: %v", err)
+		}
+		if err := a.Run(context.Background(), "finalize notes", workDir, false); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+		server.AssertDone()
+
+		content, _ := os.ReadFile(notes)
+		if got := strings.ReplaceAll(string(content), "\r\n", "\n"); !strings.HasPrefix(got, "final\nappended") {
+			t.Errorf("Expected the edit on top of the external change, got %q", got)
+		}
+	})
+
+	t.Run("Server error surfaces as run error", func(t *testing.T) {
+		server := llmtest.NewServer(t, llmtest.Fail(500, "model crashed"))
+
+		a := newScriptedAgent(t, server)
+		err := a.Run(context.Background(), "hi", t.TempDir(), false)
+		if err == nil || !strings.Contains(err.Error(), "model crashed") {
+			t.Errorf("Expected error mentioning server failure, got %v", err)
+		}
+	})
+}
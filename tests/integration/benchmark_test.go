@@ -5,7 +5,10 @@ package integration_test
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -85,6 +88,71 @@ func BenchmarkToolExecutionOverhead(b *testing.B) {
 	}
 }
 
+// BenchmarkGrepSearch compares sequential and parallel grep_search on a large
+// synthetic tree (Constitution: search should stay interactive on large workspaces)
+func BenchmarkGrepSearch(b *testing.B) {
+	logging.InitLogger(false)
+
+	tempDir := b.TempDir()
+	createSearchTree(b, tempDir, 40, 50, 400)
+
+	searches := []struct {
+		name   string
+		params map[string]interface{}
+	}{
+		{name: "literal", params: map[string]interface{}{"pattern": "needle_42", "max_results": 1000.0}},
+		{name: "regex", params: map[string]interface{}{"pattern": `//\s*needle_\d+$`, "is_regex": true, "max_results": 1000.0}},
+		{name: "case_insensitive", params: map[string]interface{}{"pattern": "NEEDLE_42", "case_insensitive": true, "max_results": 1000.0}},
+		{name: "early_stop", params: map[string]interface{}{"pattern": "func", "max_results": 50.0}},
+	}
+	workerCounts := []int{1, 4, 16}
+
+	ctx := context.Background()
+	for _, search := range searches {
+		for _, workers := range workerCounts {
+			b.Run(fmt.Sprintf("%s/workers=%d", search.name, workers), func(b *testing.B) {
+				grep := tools.NewGrepSearchTool()
+				grep.SetConcurrency(workers)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := grep.Execute(ctx, search.params, tempDir); err != nil {
+						b.Fatalf("grep_search failed: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// createSearchTree writes dirs×files Go-like source files of lines lines each, with a
+// rare literal in a few of them
+func createSearchTree(tb testing.TB, root string, dirs, files, lines int) {
+	tb.Helper()
+
+	var content strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&content, "func handler%d(w http.ResponseWriter, r *http.Request) { _ = r.URL.Query().Get(\"id\") }\n", i)
+	}
+	body := content.String()
+
+	for d := 0; d < dirs; d++ {
+		dir := filepath.Join(root, fmt.Sprintf("pkg%02d", d))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			tb.Fatalf("Failed to create directory: %v", err)
+		}
+		for f := 0; f < files; f++ {
+			data := body
+			if (d*files+f)%97 == 0 {
+				data += "// needle_42\n"
+			}
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%03d.go", f)), []byte(data), 0644); err != nil {
+				tb.Fatalf("Failed to create file: %v", err)
+			}
+		}
+	}
+}
+
 // TestStartupPerformance validates startup time requirement
 func TestStartupPerformance(t *testing.T) {
 	logging.InitLogger(false)